@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Entry 一次管理操作的审计信息
+type Entry struct {
+	Actor      string
+	Action     string
+	Target     string
+	TargetID   string
+	Before     interface{}
+	After      interface{}
+	IP         string
+	StatusCode int
+}
+
+// Record 写入一条审计日志，自动计算变更字段并追加到hash链
+func Record(entry Entry) error {
+	before, err := toJSON(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := toJSON(entry.After)
+	if err != nil {
+		return err
+	}
+	diff, err := Diff(entry.Before, entry.After)
+	if err != nil {
+		return err
+	}
+	log := dbMod.AuditLog{
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		Target:     entry.Target,
+		TargetID:   entry.TargetID,
+		Before:     before,
+		After:      after,
+		Diff:       diff,
+		IP:         entry.IP,
+		StatusCode: entry.StatusCode,
+		CreateTime: utils.GetNowTimeString(),
+	}
+	return dbMod.AppendAuditLog(&log)
+}
+
+// FieldChange 单个字段的变更
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Diff 比较操作前后的数据，返回发生变化的字段
+func Diff(before, after interface{}) (string, error) {
+	beforeMap, err := toMap(before)
+	if err != nil {
+		return "", err
+	}
+	afterMap, err := toMap(after)
+	if err != nil {
+		return "", err
+	}
+	changes := make(map[string]FieldChange)
+	for key, b := range beforeMap {
+		a, ok := afterMap[key]
+		if !ok || !reflect.DeepEqual(a, b) {
+			changes[key] = FieldChange{Before: b, After: a}
+		}
+	}
+	for key, a := range afterMap {
+		if _, ok := beforeMap[key]; !ok {
+			changes[key] = FieldChange{After: a}
+		}
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal diff:%s", err.Error())
+	}
+	return string(data), nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return "", nil
+	}
+	if raw, ok := v.(json.RawMessage); ok {
+		return string(raw), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit data:%s", err.Error())
+	}
+	return string(data), nil
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := toJSON(v)
+	if err != nil || data == "" {
+		return map[string]interface{}{}, err
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		//非对象数据整体比较
+		return map[string]interface{}{"value": data}, nil
+	}
+	return m, nil
+}
@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type fund struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	}
+	diff, err := Diff(&fund{Name: "维修基金", Status: "active"}, &fund{Name: "维修基金", Status: "closed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes map[string]FieldChange
+	if err := json.Unmarshal([]byte(diff), &changes); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes["status"].Before != "active" || changes["status"].After != "closed" {
+		t.Errorf("unexpected diff:%s", diff)
+	}
+	//删除后对象不存在，所有字段都视为变更
+	diff, err = Diff(&fund{Name: "维修基金"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(diff), &changes); err != nil || len(changes) != 2 {
+		t.Errorf("unexpected diff:%s", diff)
+	}
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+const verifyBatchSize = 500
+
+// VerifyResult hash链校验结果
+type VerifyResult struct {
+	Valid        bool   `json:"valid"`
+	Checked      int    `json:"checked"`       // 已校验日志条数
+	BrokenLogID  uint64 `json:"broken_log_id"` // 第一条被篡改的日志ID
+	Reason       string `json:"reason"`        // 校验失败原因
+	AnchoredID   uint64 `json:"anchored_id"`   // 链上最近锚定的日志ID
+	AnchorTxID   string `json:"anchor_tx_id"`  // 链上最近锚定交易
+	AnchorPassed bool   `json:"anchor_passed"` // 链上锚定hash与本地一致
+}
+
+// VerifyChain 从头校验审计日志hash链，并与链上最近一次锚定的链头比对
+func VerifyChain() (VerifyResult, error) {
+	result := VerifyResult{Valid: true}
+	anchored := make(map[uint64]string)
+	records, err := fabric.GetAuditAnchorHistory()
+	if err != nil {
+		return result, err
+	}
+	for _, r := range records {
+		anchored[r.Anchor.LastLogID] = r.Anchor.HeadHash
+		if r.Anchor.LastLogID >= result.AnchoredID {
+			result.AnchoredID = r.Anchor.LastLogID
+			result.AnchorTxID = r.Tx
+		}
+	}
+	prevHash := ""
+	var lastID uint64
+	for {
+		logs, err := dbMod.GetAuditLogsAfter(lastID, verifyBatchSize)
+		if err != nil {
+			return result, err
+		}
+		for i := range logs {
+			entry := &logs[i]
+			result.Checked++
+			if entry.PrevHash != prevHash {
+				return broken(result, entry.LogID, "prev hash mismatch"), nil
+			}
+			if dbMod.ComputeAuditHash(entry) != entry.Hash {
+				return broken(result, entry.LogID, "content hash mismatch"), nil
+			}
+			if h, ok := anchored[entry.LogID]; ok && h != entry.Hash {
+				return broken(result, entry.LogID, "hash differs from anchored head on ledger"), nil
+			}
+			prevHash = entry.Hash
+			lastID = entry.LogID
+		}
+		if len(logs) < verifyBatchSize {
+			break
+		}
+	}
+	if result.AnchoredID > lastID {
+		return broken(result, result.AnchoredID, "anchored log missing from database"), nil
+	}
+	result.AnchorPassed = true
+	return result, nil
+}
+
+func broken(result VerifyResult, logID uint64, reason string) VerifyResult {
+	result.Valid = false
+	result.BrokenLogID = logID
+	result.Reason = reason
+	return result
+}
+
+// AnchorOnce 若存在未锚定的日志，则将当前链头写入区块链
+func AnchorOnce() (*dbMod.AuditAnchor, error) {
+	head, err := dbMod.GetLastAuditLog()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	last, err := dbMod.GetLastAuditAnchor()
+	if err != nil {
+		return nil, err
+	}
+	if head.LogID <= last.LastLogID {
+		return nil, nil
+	}
+	txID, err := fabric.AnchorAuditHead(head.LogID, head.Hash)
+	if err != nil {
+		return nil, err
+	}
+	anchor := dbMod.AuditAnchor{
+		LastLogID:  head.LogID,
+		HeadHash:   head.Hash,
+		TxID:       txID,
+		AnchorTime: utils.GetNowTimeString(),
+	}
+	return &anchor, dbMod.CreateAuditAnchor(&anchor)
+}
+
+// StartAnchor 定期锚定审计链头
+func StartAnchor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			if _, err := AnchorOnce(); err != nil {
+				log.Printf("failed to anchor audit head:%s", err.Error())
+			}
+		}
+	}()
+}
@@ -0,0 +1,106 @@
+package bank
+
+import (
+	dbMod "community-governance/db/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Line 银行流水中的一笔交易，收入金额为正，支出金额为负
+type Line struct {
+	Date         string      `json:"date"` // 记账日期，2006-01-02
+	Amount       dbMod.Money `json:"amount"`
+	Reference    string      `json:"reference"`    // 银行流水号或交易参考号
+	Counterparty string      `json:"counterparty"` // 对方户名
+	Description  string      `json:"description"`  // 摘要
+}
+
+// Candidate 可与银行流水匹配的账簿记录
+type Candidate struct {
+	EntryID   string
+	Date      string      // 记账日期，2006-01-02
+	Amount    dbMod.Money // 收入为正，支出为负
+	Reference string
+	Text      string // 来源与说明，用于查找流水号
+}
+
+// Suggestion 银行流水的匹配建议
+type Suggestion struct {
+	Line    int    // 流水在导入列表中的下标
+	EntryID string // 建议匹配的账簿记录
+	Score   int    // 匹配得分，满分100
+}
+
+// 匹配规则：金额必须相等，日期越近、参考号越吻合得分越高
+const (
+	maxDateDiff   = 7 // 最多相差的天数
+	minMatchScore = 30
+)
+
+// scoreMatch 计算流水与记录的匹配得分，不能匹配时返回0
+func scoreMatch(line Line, candidate Candidate) int {
+	if line.Amount != candidate.Amount {
+		return 0
+	}
+	lineDate, err := time.Parse("2006-01-02", line.Date)
+	if err != nil {
+		return 0
+	}
+	entryDate, err := time.Parse("2006-01-02", candidate.Date)
+	if err != nil {
+		return 0
+	}
+	days := int(lineDate.Sub(entryDate).Hours() / 24)
+	if days < 0 {
+		days = -days
+	}
+	if days > maxDateDiff {
+		return 0
+	}
+	var score int
+	switch {
+	case days == 0:
+		score = 50
+	case days <= 3:
+		score = 30
+	default:
+		score = 10
+	}
+	if ref := strings.TrimSpace(line.Reference); ref != "" {
+		switch {
+		case strings.EqualFold(ref, candidate.Reference):
+			score += 50
+		case strings.Contains(candidate.Text, ref):
+			score += 30
+		}
+	}
+	return score
+}
+
+// Match 为每笔流水找出得分最高的记录，每条记录最多匹配一笔流水
+func Match(lines []Line, candidates []Candidate) []Suggestion {
+	var pairs []Suggestion
+	for i, line := range lines {
+		for _, candidate := range candidates {
+			if score := scoreMatch(line, candidate); score >= minMatchScore {
+				pairs = append(pairs, Suggestion{Line: i, EntryID: candidate.EntryID, Score: score})
+			}
+		}
+	}
+	//按得分从高到低贪心分配，得分相同时按流水顺序
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Score > pairs[j].Score })
+	usedLines := make(map[int]bool)
+	usedEntries := make(map[string]bool)
+	var result []Suggestion
+	for _, pair := range pairs {
+		if usedLines[pair.Line] || usedEntries[pair.EntryID] {
+			continue
+		}
+		usedLines[pair.Line] = true
+		usedEntries[pair.EntryID] = true
+		result = append(result, pair)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Line < result[j].Line })
+	return result
+}
@@ -0,0 +1,89 @@
+package bank
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	data := "\uFEFF交易日期,收入,支出,流水号,对方户名,摘要\n" +
+		"2026/03/01,\"1,200.00\",,R001,张三,物业费\n" +
+		"2026/03/02,,350.5,R002,维修公司,电梯维修\n" +
+		",,,,,\n"
+	lines, err := ParseCSV(strings.NewReader(data), CSVMapping{
+		Date: "交易日期", DateFormat: "2006/01/02", Credit: "收入", Debit: "支出",
+		Reference: "流水号", Counterparty: "对方户名", Description: "摘要",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("lines = %+v", lines)
+	}
+	if lines[0].Date != "2026-03-01" || lines[0].Amount != 120000 || lines[0].Reference != "R001" {
+		t.Fatalf("line 0 = %+v", lines[0])
+	}
+	if lines[1].Amount != -35050 || lines[1].Counterparty != "维修公司" {
+		t.Fatalf("line 1 = %+v", lines[1])
+	}
+	if _, err := ParseCSV(strings.NewReader(data), CSVMapping{Date: "日期", Amount: "金额"}); err == nil {
+		t.Fatalf("expected missing column error")
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt><Stmt>
+    <Ntry>
+      <Amt Ccy="CNY">500.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+      <BookgDt><Dt>2026-03-05</Dt></BookgDt><AcctSvcrRef>BANK-1</AcctSvcrRef>
+      <NtryDtls><TxDtls><Refs><EndToEndId>E2E-1</EndToEndId></Refs>
+        <RltdPties><Dbtr><Pty><Nm>李四</Nm></Pty></Dbtr></RltdPties>
+        <RmtInf><Ustrd>3月物业费</Ustrd></RmtInf></TxDtls></NtryDtls>
+    </Ntry>
+    <Ntry>
+      <Amt Ccy="CNY">80.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+      <BookgDt><DtTm>2026-03-06T10:00:00</DtTm></BookgDt><AcctSvcrRef>BANK-2</AcctSvcrRef>
+    </Ntry>
+    <Ntry>
+      <Amt Ccy="CNY">10.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>PDNG</Cd></Sts>
+      <BookgDt><Dt>2026-03-07</Dt></BookgDt>
+    </Ntry>
+  </Stmt></BkToCstmrStmt>
+</Document>`
+	lines, err := ParseCAMT053(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("lines = %+v", lines)
+	}
+	if lines[0].Amount != 50000 || lines[0].Reference != "E2E-1" || lines[0].Counterparty != "李四" || lines[0].Description != "3月物业费" {
+		t.Fatalf("line 0 = %+v", lines[0])
+	}
+	if lines[1].Amount != -8000 || lines[1].Date != "2026-03-06" || lines[1].Reference != "BANK-2" {
+		t.Fatalf("line 1 = %+v", lines[1])
+	}
+}
+
+func TestMatch(t *testing.T) {
+	lines := []Line{
+		{Date: "2026-03-01", Amount: 10000, Reference: "R1"},
+		{Date: "2026-03-01", Amount: 10000},
+		{Date: "2026-03-10", Amount: -5000},
+	}
+	candidates := []Candidate{
+		{EntryID: "e1", Date: "2026-03-02", Amount: 10000},
+		{EntryID: "e2", Date: "2026-03-03", Amount: 10000, Text: "物业费 R1"},
+		{EntryID: "e3", Date: "2026-03-30", Amount: -5000},
+	}
+	suggestions := Match(lines, candidates)
+	if len(suggestions) != 2 {
+		t.Fatalf("suggestions = %+v", suggestions)
+	}
+	// 参考号吻合的记录优先分配给第一笔流水，第二笔流水匹配剩下的记录，日期相差过大的不匹配
+	if suggestions[0].Line != 0 || suggestions[0].EntryID != "e2" || suggestions[1].Line != 1 || suggestions[1].EntryID != "e1" {
+		t.Fatalf("suggestions = %+v", suggestions)
+	}
+}
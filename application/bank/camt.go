@@ -0,0 +1,101 @@
+package bank
+
+import (
+	dbMod "community-governance/db/models"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// camtDocument CAMT.053银行对账单中用到的字段，不限定命名空间以兼容各版本
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount      string `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	Status      struct {
+		Text string `xml:",chardata"` // 早期版本直接写状态
+		Code string `xml:"Cd"`        // 新版本写在Cd中
+	} `xml:"Sts"`
+	BookingDate struct {
+		Date     string `xml:"Dt"`
+		DateTime string `xml:"DtTm"`
+	} `xml:"BookgDt"`
+	ServicerRef string `xml:"AcctSvcrRef"`
+	Details     []struct {
+		EndToEndID string `xml:"Refs>EndToEndId"`
+		Debtor     string `xml:"RltdPties>Dbtr>Nm"`
+		DebtorPty  string `xml:"RltdPties>Dbtr>Pty>Nm"`
+		Creditor   string `xml:"RltdPties>Cdtr>Nm"`
+		CreditorPt string `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Remittance string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+	AddtlInfo string `xml:"AddtlNtryInf"`
+}
+
+// ParseCAMT053 解析CAMT.053格式的银行对账单，只导入已记账的交易
+func ParseCAMT053(r io.Reader) ([]Line, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	var lines []Line
+	for _, stmt := range doc.Statements {
+		for i, entry := range stmt.Entries {
+			status := firstNonEmpty(entry.Status.Code, entry.Status.Text)
+			if status != "" && status != "BOOK" {
+				continue
+			}
+			amount, err := dbMod.ParseMoney(strings.TrimSpace(entry.Amount))
+			if err != nil {
+				return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidStatement, i+1, err)
+			}
+			switch strings.TrimSpace(entry.CreditDebit) {
+			case "CRDT":
+			case "DBIT":
+				amount = -amount
+			default:
+				return nil, fmt.Errorf("%w: entry %d: invalid CdtDbtInd %s", ErrInvalidStatement, i+1, entry.CreditDebit)
+			}
+			date := strings.TrimSpace(entry.BookingDate.Date)
+			if date == "" && len(entry.BookingDate.DateTime) >= 10 {
+				date = entry.BookingDate.DateTime[:10]
+			}
+			if len(date) != 10 {
+				return nil, fmt.Errorf("%w: entry %d: missing booking date", ErrInvalidStatement, i+1)
+			}
+			line := Line{Date: date, Amount: amount, Reference: strings.TrimSpace(entry.ServicerRef), Description: strings.TrimSpace(entry.AddtlInfo)}
+			if len(entry.Details) > 0 {
+				detail := entry.Details[0]
+				if ref := strings.TrimSpace(detail.EndToEndID); ref != "" && ref != "NOTPROVIDED" {
+					line.Reference = ref
+				}
+				//收入取付款人，支出取收款人
+				if amount > 0 {
+					line.Counterparty = firstNonEmpty(detail.Debtor, detail.DebtorPty)
+				} else {
+					line.Counterparty = firstNonEmpty(detail.Creditor, detail.CreditorPt)
+				}
+				if remittance := strings.TrimSpace(detail.Remittance); remittance != "" {
+					line.Description = remittance
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
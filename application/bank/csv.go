@@ -0,0 +1,117 @@
+package bank
+
+import (
+	dbMod "community-governance/db/models"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrInvalidStatement = errors.New("invalid bank statement")
+
+// CSVMapping CSV流水的列映射，列名取自首行表头
+// 金额可以是一列带符号的金额，也可以是收入、支出两列
+type CSVMapping struct {
+	Date         string `json:"date"`         // 日期列
+	DateFormat   string `json:"date_format"`  // 日期格式，默认2006-01-02
+	Amount       string `json:"amount"`       // 带符号金额列
+	Credit       string `json:"credit"`       // 收入金额列
+	Debit        string `json:"debit"`        // 支出金额列
+	Reference    string `json:"reference"`    // 流水号列
+	Counterparty string `json:"counterparty"` // 对方户名列
+	Description  string `json:"description"`  // 摘要列
+	Delimiter    string `json:"delimiter"`    // 分隔符，默认逗号
+}
+
+// ParseCSV 按列映射解析CSV格式的银行流水
+func ParseCSV(r io.Reader, mapping CSVMapping) ([]Line, error) {
+	if mapping.Date == "" || (mapping.Amount == "" && mapping.Credit == "" && mapping.Debit == "") {
+		return nil, fmt.Errorf("%w: date and amount columns are required", ErrInvalidStatement)
+	}
+	if mapping.DateFormat == "" {
+		mapping.DateFormat = "2006-01-02"
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if mapping.Delimiter != "" {
+		reader.Comma = []rune(mapping.Delimiter)[0]
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidStatement, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF"))] = i
+	}
+	for _, name := range []string{mapping.Date, mapping.Amount, mapping.Credit, mapping.Debit, mapping.Reference, mapping.Counterparty, mapping.Description} {
+		if _, ok := columns[name]; name != "" && !ok {
+			return nil, fmt.Errorf("%w: column %s not found", ErrInvalidStatement, name)
+		}
+	}
+	var lines []Line
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if name == "" || !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		//跳过空行
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		date, err := time.Parse(mapping.DateFormat, field(mapping.Date))
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: invalid date %s", ErrInvalidStatement, row, field(mapping.Date))
+		}
+		var amount dbMod.Money
+		if mapping.Amount != "" {
+			if amount, err = parseAmount(field(mapping.Amount)); err != nil {
+				return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+			}
+		} else {
+			credit, err := parseAmount(field(mapping.Credit))
+			if err != nil {
+				return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+			}
+			debit, err := parseAmount(field(mapping.Debit))
+			if err != nil {
+				return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+			}
+			amount = credit.Abs() - debit.Abs()
+		}
+		if amount == 0 {
+			continue
+		}
+		lines = append(lines, Line{
+			Date:         date.Format("2006-01-02"),
+			Amount:       amount,
+			Reference:    field(mapping.Reference),
+			Counterparty: field(mapping.Counterparty),
+			Description:  field(mapping.Description),
+		})
+	}
+	return lines, nil
+}
+
+// parseAmount 解析银行流水中的金额，忽略千分位分隔符与货币符号，空值为0
+func parseAmount(s string) (dbMod.Money, error) {
+	s = strings.NewReplacer(",", "", " ", "", "¥", "", "￥", "").Replace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return dbMod.ParseMoney(s)
+}
@@ -8,11 +8,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
-	"strconv"
-)
-
-const (
-	assetStatusInit = "active"
 )
 
 func AddAsset(c *gin.Context) {
@@ -22,16 +17,28 @@ func AddAsset(c *gin.Context) {
 		return
 	}
 
+	cost, residual, ok := parseAssetCost(c, assetReq.Cost, assetReq.Residual)
+	if !ok {
+		return
+	}
 	assetId := uuid.New().String()
 	asset := dbMod.Asset{
 		AssetID:      assetId,
 		Name:         assetReq.Name,
 		Type:         assetReq.Type,
 		Description:  assetReq.Description,
-		Status:       assetStatusInit,
+		Status:       dbMod.AssetStatusRegistered,
 		Location:     assetReq.Location,
 		PurchaseDate: assetReq.PurchaseDate,
 		Owner:        assetReq.Owner,
+		Cost:         cost,
+		Residual:     residual,
+		UsefulLife:   assetReq.UsefulLife,
+		Method:       assetReq.Method,
+		FundID:       assetReq.FundID,
+	}
+	if !checkAssetDepreciation(c, asset) {
+		return
 	}
 	err := dbMod.CreateAsset(&asset)
 	if err != nil {
@@ -48,30 +55,23 @@ func AddAsset(c *gin.Context) {
 	userId := c.MustGet("userId").(string)
 	err = fabric.CreateAsset(assetId, hash, assetReq.Owner, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": err.Error()})
 	}
 	c.JSON(http.StatusOK, gin.H{"asset_id": assetId})
 }
 
 func GetAssetAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	assets, err := dbMod.GetAllAssetWithPagination(iPage, iPageSize)
+	assets, err := dbMod.GetAllAssetWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": assets, "total": len(assets)})
+	c.JSON(http.StatusOK, pageResult(assets, page))
 }
 func UpdateAsset(c *gin.Context) {
 	//获取路径id值
@@ -97,7 +97,7 @@ func UpdateAsset(c *gin.Context) {
 	userId := c.MustGet("userId").(string)
 	err = fabric.UpdateAsset(assetReq.AssetID, hash, assetReq.Owner, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "更新资产成功"})
@@ -106,6 +106,9 @@ func UpdateAsset(c *gin.Context) {
 func DeleteAsset(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
+	if rejectUndeletable(c, "asset", id, "删除资产") {
+		return
+	}
 	err := dbMod.DeleteAsset(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除资产失败:" + err.Error()})
@@ -116,29 +119,22 @@ func DeleteAsset(c *gin.Context) {
 }
 func GetAssetByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	assets, err := dbMod.GetAllAssetWithConditions(conditions, iPage, iPageSize)
+	assets, err := dbMod.GetAllAssetWithConditions(query, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产失败:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取资产失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": assets, "total": len(assets)})
+	c.JSON(http.StatusOK, pageResult(assets, page))
 }
 func GetAssetDetail(c *gin.Context) {
 	type Info struct {
@@ -155,7 +151,7 @@ func GetAssetDetail(c *gin.Context) {
 
 	records, err := fabric.GetAssetHistory(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产记录失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取资产记录失败:" + err.Error()})
 		return
 	}
 	info := Info{
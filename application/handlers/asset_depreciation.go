@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	"community-governance/application/report"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// parseAssetCost 解析购置成本与净残值，未填写时为0
+func parseAssetCost(c *gin.Context, cost, residual string) (dbMod.Money, dbMod.Money, bool) {
+	var amounts [2]dbMod.Money
+	for i, text := range []string{cost, residual} {
+		if text == "" {
+			continue
+		}
+		amount, err := dbMod.ParseMoney(text)
+		if err != nil || amount < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的金额不合法:" + text})
+			return 0, 0, false
+		}
+		amounts[i] = amount
+	}
+	return amounts[0], amounts[1], true
+}
+
+// checkAssetDepreciation 校验资产的折旧参数与购置款项
+func checkAssetDepreciation(c *gin.Context, asset dbMod.Asset) bool {
+	if err := dbMod.CheckDepreciation(asset); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "折旧参数不合法:" + err.Error()})
+		return false
+	}
+	if asset.FundID != "" {
+		if _, err := dbMod.GetFundByID(asset.FundID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "款项不存在:" + asset.FundID})
+			return false
+		}
+	}
+	return true
+}
+
+// queryDate 解析查询参数中的日期，未指定时为当天
+func queryDate(c *gin.Context) (time.Time, bool) {
+	text := c.DefaultQuery("date", utils.GetNowTimeString()[:10])
+	date, err := time.ParseInLocation("2006-01-02", text, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的日期不合法:" + text})
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// SetAssetDepreciation 设置资产的购置成本与折旧参数
+func SetAssetDepreciation(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var depReq models.AssetDepreciation
+	if err := c.ShouldBindJSON(&depReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	asset, err := dbMod.GetAssetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "资产不存在:" + id})
+		return
+	}
+	cost, residual, ok := parseAssetCost(c, depReq.Cost, depReq.Residual)
+	if !ok {
+		return
+	}
+	asset.Cost = cost
+	asset.Residual = residual
+	asset.UsefulLife = depReq.UsefulLife
+	asset.Method = depReq.Method
+	asset.FundID = depReq.FundID
+	if !checkAssetDepreciation(c, *asset) {
+		return
+	}
+	err = dbMod.SetAssetDepreciation(*asset)
+	if errors.Is(err, dbMod.ErrAssetDepreciated) {
+		c.JSON(http.StatusConflict, gin.H{"error": "资产已计提折旧，不能修改折旧参数"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "设置折旧参数失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": asset})
+}
+
+// GetAssetBookValue 计算资产在指定日期的累计折旧与账面价值，并返回历年折旧记录
+func GetAssetBookValue(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	date, ok := queryDate(c)
+	if !ok {
+		return
+	}
+	asset, err := dbMod.GetAssetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "资产不存在:" + id})
+		return
+	}
+	value, err := dbMod.CalculateBookValue(*asset, date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "折旧参数不合法:" + err.Error()})
+		return
+	}
+	records, err := dbMod.GetAssetDepreciations(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取折旧记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": value, "records": records})
+}
+
+// RunDepreciation 计提已结束年度的资产折旧，折旧分录记入各资产的购置款项，年度或12月已结账时拒绝
+func RunDepreciation(c *gin.Context) {
+	var runReq models.RunDepreciation
+	if err := c.ShouldBindJSON(&runReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	now := utils.GetNowTimeString()
+	if runReq.Year < 1900 || strconv.Itoa(runReq.Year) >= now[:4] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只能计提已结束年度的折旧:" + strconv.Itoa(runReq.Year)})
+		return
+	}
+	periods, err := fabric.GetClosedPeriods()
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取已结账期间失败:" + err.Error()})
+		return
+	}
+	for _, period := range periods {
+		if period.Period == strconv.Itoa(runReq.Year) || period.Period == fmt.Sprintf("%d-12", runReq.Year) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "会计期间已结账:" + period.Period})
+			return
+		}
+	}
+	records, err := dbMod.RunDepreciation(runReq.Year, c.MustGet("userId").(string), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计提折旧失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": records, "total": len(records)})
+}
+
+// ExportAssetRegister 导出资产台账，format为json或csv
+func ExportAssetRegister(c *gin.Context) {
+	date, ok := queryDate(c)
+	if !ok {
+		return
+	}
+	register, err := dbMod.GetAssetRegister(date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成资产台账失败:" + err.Error()})
+		return
+	}
+	day := date.Format("2006-01-02")
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{"data": register, "date": day})
+	case "csv":
+		data, err := report.AssetRegisterCSV(day, register)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成CSV台账失败:" + err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename=asset-register-"+day+".csv")
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的台账格式:" + c.Query("format")})
+	}
+}
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"community-governance/application/middleware"
 	"community-governance/application/models"
 	"community-governance/application/utils"
 	dbMod "community-governance/db/models"
 	"community-governance/fabric"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"net/http"
-	"strconv"
+	"time"
 )
 
 func AddAssetRequestRecord(c *gin.Context) {
@@ -24,7 +28,7 @@ func AddAssetRequestRecord(c *gin.Context) {
 		Name:         assetReq.Name,
 		RequestDate:  utils.GetNowTimeString(),
 		Description:  assetReq.Description,
-		Status:       "active",
+		Status:       dbMod.AssetRequestStatusActive,
 		PurchaseTime: assetReq.PurchaseTime,
 		Type:         assetReq.Type,
 		Location:     assetReq.Location,
@@ -33,65 +37,51 @@ func AddAssetRequestRecord(c *gin.Context) {
 		Asset:        assetReq.Asset,
 		Requester:    userId,
 	}
-	err := dbMod.CreateAssetRequest(request)
+	if !checkAssetRequest(c, &request, assetReq) {
+		return
+	}
+	//申请与审批流程在同一事务中创建
+	instance, err := startWorkflow(dbMod.WorkflowKindAssetRequest, request.RequestID, request.Name, userId, userId, &request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加资产申请失败:" + err.Error()})
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "添加资产申请失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": "添加资产申请成功"})
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"request": request, "workflow": instance}})
 
 }
 
 func GetAssetRequestAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	if page == "" && pageSize == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page和page size参数不合法"})
-		return
-	}
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	assetRequests, err := dbMod.GetAllAsseRequestWithPagination(iPage, iPageSize)
+	assetRequests, err := dbMod.GetAllAsseRequestWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产申请失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": assetRequests, "total": len(assetRequests)})
+	c.JSON(http.StatusOK, pageResult(assetRequests, page))
 }
 
 func GetAssetRequestByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	assetRequests, err := dbMod.GetAllAsseRequestWithConditions(iPage, iPageSize, conditions)
+	assetRequests, err := dbMod.GetAllAsseRequestWithConditions(page, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产申请失败:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取资产申请失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": assetRequests, "total": len(assetRequests)})
+	c.JSON(http.StatusOK, pageResult(assetRequests, page))
 }
 func DeleteAssetRequest(c *gin.Context) {
 	//获取路径id值
@@ -103,111 +93,367 @@ func DeleteAssetRequest(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "删除资产申请成功"})
 }
+
+// AuditRequestRecord 审核资产申请，status为pass或reject，通过审批流程完成审批
+// 审批流程上线前创建的申请在首次审核时发起审批流程
 func AuditRequestRecord(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
-	status := c.Query("status")
+	decisions := map[string]string{
+		dbMod.AssetRequestStatusPass:   dbMod.DecisionApprove,
+		dbMod.AssetRequestStatusReject: dbMod.DecisionReject,
+	}
+	decision, ok := decisions[c.Query("status")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "状态不合法"})
+		return
+	}
 	request, err := dbMod.GetAssetRequestByID(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产申请失败:" + err.Error()})
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "获取资产申请失败:" + err.Error()})
+		return
+	}
+	instance, err := dbMod.GetWorkflowInstanceByTarget(dbMod.WorkflowKindAssetRequest, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) && request.Status == dbMod.AssetRequestStatusActive {
+		instance, err = startWorkflow(dbMod.WorkflowKindAssetRequest, id, request.Name, request.Requester, c.MustGet("userId").(string), nil)
+	}
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "获取审批流程失败:" + err.Error()})
 		return
 	}
-	request.Status = status
-	request.PurchaseTime = utils.GetNowTimeString()
+	instance, err = decideWorkflow(instance.InstanceID, "", c.MustGet("userId").(string), decision, c.Query("comment"))
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "审核资产申请失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": instance})
+}
 
-	userId := c.MustGet("userId").(string)
-	//判断状态
-	if status == "pass" {
-		//判断申请类型
-		if request.RequestType == "add" {
-			asset := dbMod.Asset{
-				AssetID:      id,
-				Name:         request.Name,
-				Type:         request.Type,
-				Description:  request.Description,
-				Status:       "pending",
-				Location:     request.Location,
-				PurchaseDate: request.PurchaseTime,
-				Owner:        request.Requester,
-			}
-			//计算hash值
-			hash, err := utils.ComputeHash(asset)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "计算资产hash失败:" + err.Error()})
-				return
-			}
-			//将hash值写入区块链
-			err = fabric.CreateAsset(asset.AssetID, hash, asset.Owner, userId)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "更新资产失败:" + err.Error()})
-				return
+// checkAssetRequest 按申请类型校验申请，处置、调拨与借用的资产必须存在且未处置
+func checkAssetRequest(c *gin.Context, request *dbMod.AssetRequest, assetReq models.CreateAssetRequest) bool {
+	if request.RequestType == dbMod.AssetRequestTypeAdd {
+		if request.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "资产名称不能为空"})
+			return false
+		}
+		return true
+	}
+	if request.RequestType != dbMod.AssetRequestTypeDisposal && request.RequestType != dbMod.AssetRequestTypeTransfer &&
+		request.RequestType != dbMod.AssetRequestTypeLoan {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "申请类型不合法:" + request.RequestType})
+		return false
+	}
+	asset, err := dbMod.GetAssetByID(request.Asset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "资产不存在:" + request.Asset})
+		return false
+	}
+	if asset.Status == dbMod.AssetStatusDisposed {
+		c.JSON(http.StatusConflict, gin.H{"error": "资产已处置:" + request.Asset})
+		return false
+	}
+	if request.Name == "" {
+		request.Name = asset.Name
+	}
+	switch request.RequestType {
+	case dbMod.AssetRequestTypeDisposal:
+		if !dbMod.DisposalMethods[assetReq.Method] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "处置方式不合法:" + assetReq.Method})
+			return false
+		}
+		var proceeds dbMod.Money
+		if assetReq.Proceeds != "" {
+			if proceeds, err = dbMod.ParseMoney(assetReq.Proceeds); err != nil || proceeds < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "传入的处置收入不合法:" + assetReq.Proceeds})
+				return false
 			}
-			err = dbMod.CreateAsset(&asset)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "创建资产失败:" + err.Error()})
-				return
+		}
+		if assetReq.Method == dbMod.DisposalSale && proceeds == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "出售资产必须填写处置收入"})
+			return false
+		}
+		if assetReq.Method == dbMod.DisposalDonate && proceeds > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "捐赠资产不能有处置收入"})
+			return false
+		}
+		if proceeds > 0 {
+			if _, err := dbMod.GetFundByID(assetReq.FundID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "处置收入入账的款项不存在:" + assetReq.FundID})
+				return false
 			}
-		} else if request.RequestType == "update" {
-			asset, err := dbMod.GetAssetByID(request.Asset)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产失败:" + err.Error()})
-				return
+			request.FundID = assetReq.FundID
+		}
+		request.Method = assetReq.Method
+		request.Proceeds = proceeds
+	case dbMod.AssetRequestTypeTransfer:
+		if _, err := dbMod.GetMemberByID(assetReq.Recipient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "新保管人不存在:" + assetReq.Recipient})
+			return false
+		}
+		if assetReq.Recipient == asset.Owner {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "新保管人与当前保管人相同"})
+			return false
+		}
+		request.Recipient = assetReq.Recipient
+	case dbMod.AssetRequestTypeLoan:
+		if _, err := time.Parse("2006-01-02", assetReq.DueDate); err != nil || assetReq.DueDate < request.RequestDate[:10] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的归还期限不合法:" + assetReq.DueDate})
+			return false
+		}
+		if asset.Status == dbMod.AssetStatusRepair {
+			c.JSON(http.StatusConflict, gin.H{"error": "资产维修中:" + request.Asset})
+			return false
+		}
+		request.DueDate = assetReq.DueDate
+	}
+	return true
+}
+
+// approveAssetRequest 资产申请审批通过，按申请类型新增、处置、调拨或借出资产
+// 审批事务回滚后重试时资产可能已上链或已入库，此时跳过已完成的操作
+func approveAssetRequest(instance *dbMod.WorkflowInstance, operator string) error {
+	request, err := dbMod.GetAssetRequestByID(instance.TargetID)
+	if err != nil {
+		return err
+	}
+	switch request.RequestType {
+	case dbMod.AssetRequestTypeAdd:
+		return approveAssetAdd(request, instance.FinishTime, operator)
+	case dbMod.AssetRequestTypeDisposal:
+		return dbMod.DisposeAssetByRequest(request, instance.FinishTime, func() error {
+			chain, err := fabric.GetAsset(request.Asset)
+			if err != nil || chain.Status == dbMod.AssetStatusDisposed {
+				return err
 			}
-			asset.Owner = request.RequestValue
-			//将hash值写入区块链
-			err = fabric.ExchangeOwner(asset.AssetID, asset.Owner)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "更新资产失败:" + err.Error()})
-				return
+			_, err = fabric.DisposeAsset(request.Asset, request.RequestID, request.Method, request.Proceeds.String(), request.FundID, request.Description, operator)
+			return err
+		})
+	case dbMod.AssetRequestTypeTransfer, dbMod.AssetRequestTypeUpdate:
+		//存量的变更所有人申请按调拨处理，同样需要新保管人接收
+		recipient := request.Recipient
+		if request.RequestType == dbMod.AssetRequestTypeUpdate {
+			recipient = request.RequestValue
+		}
+		chain, err := fabric.GetAsset(request.Asset)
+		if err != nil {
+			return err
+		}
+		if chain.Transferee != recipient {
+			if _, err := fabric.StartAssetTransfer(request.Asset, request.RequestID, recipient, operator); err != nil {
+				return err
 			}
-			err = dbMod.UpdateAsset(asset.AssetID, asset)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "更新资产失败:" + err.Error()})
-				return
+		}
+		return dbMod.UpdateAssetRequest(request.RequestID, dbMod.AssetRequest{Status: dbMod.AssetRequestStatusAwaiting, ProcessDate: instance.FinishTime, Recipient: recipient})
+	case dbMod.AssetRequestTypeLoan:
+		chain, err := fabric.GetAsset(request.Asset)
+		if err != nil {
+			return err
+		}
+		if chain.Borrower != request.Requester {
+			if _, err := fabric.LendAsset(request.Asset, request.RequestID, request.Requester, request.DueDate, operator); err != nil {
+				return err
 			}
+		}
+		return dbMod.UpdateAssetRequest(request.RequestID, dbMod.AssetRequest{Status: dbMod.AssetRequestStatusLent, ProcessDate: instance.FinishTime})
+	}
+	return fmt.Errorf("%w: request type %s", dbMod.ErrInvalidWorkflow, request.RequestType)
+}
 
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "申请类型不合法"})
-			return
+// approveAssetAdd 新增资产申请通过，资产以申请ID登记，申请人为资产保管人
+func approveAssetAdd(request *dbMod.AssetRequest, processDate, operator string) error {
+	purchaseDate := request.PurchaseTime
+	if purchaseDate == "" {
+		purchaseDate = utils.GetNowTimeString()
+	}
+	asset := dbMod.Asset{
+		AssetID:      request.RequestID,
+		Name:         request.Name,
+		Type:         request.Type,
+		Description:  request.Description,
+		Status:       dbMod.AssetStatusRegistered,
+		Location:     request.Location,
+		PurchaseDate: purchaseDate,
+		Owner:        request.Requester,
+	}
+	if _, err := fabric.GetAsset(asset.AssetID); err != nil {
+		//计算hash值
+		hash, err := utils.ComputeHash(asset)
+		if err != nil {
+			return err
+		}
+		//将hash值写入区块链
+		if err := fabric.CreateAsset(asset.AssetID, hash, asset.Owner, operator); err != nil {
+			return err
 		}
-	} else if status != "reject" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "状态不合法"})
-		return
 	}
-	err = dbMod.UpdateAssetRequest(id, request)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "审核资产申请失败:" + err.Error()})
-		return
+	if _, err := dbMod.GetAssetByID(asset.AssetID); errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := dbMod.CreateAsset(&asset); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
 	}
-	c.JSON(http.StatusOK, gin.H{"data": "审核资产申请成功"})
+	return dbMod.UpdateAssetRequest(request.RequestID, dbMod.AssetRequest{Status: dbMod.AssetRequestStatusPass, ProcessDate: processDate})
+}
+
+// rejectAssetRequest 资产申请被驳回
+func rejectAssetRequest(instance *dbMod.WorkflowInstance, operator string) error {
+	return dbMod.UpdateAssetRequest(instance.TargetID, dbMod.AssetRequest{Status: dbMod.AssetRequestStatusReject, ProcessDate: instance.FinishTime})
 }
+
 func GetAssetRequestByPerson(c *gin.Context) {
 	//获取userId
 	userId := c.MustGet("userId").(string)
 	//获取page 和 pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
 
-	condition := make(map[string]interface{})
-	condition["requester"] = userId
-	assetRequests, err := dbMod.GetAllAsseRequestWithConditions(iPage, iPageSize, condition)
+	query := dbMod.Query{Filters: []dbMod.Filter{{Field: "requester", Op: dbMod.OpEq, Value: userId}}}
+	assetRequests, err := dbMod.GetAllAsseRequestWithConditions(page, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产申请失败:" + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": assetRequests, "total": len(assetRequests)})
+	c.JSON(http.StatusOK, pageResult(assetRequests, page))
 }
 
 //func GetAssetRequestDetail(c *gin.Context) {
 //
 //}
+
+func assetRequestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrAssetRequestState):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return chainErrorStatus(err)
+}
+
+// AcceptAssetTransfer 新保管人接收调拨的资产
+func AcceptAssetTransfer(c *gin.Context) {
+	finishAssetTransfer(c, true)
+}
+
+// DeclineAssetTransfer 新保管人拒绝接收调拨的资产
+func DeclineAssetTransfer(c *gin.Context) {
+	finishAssetTransfer(c, false)
+}
+
+// finishAssetTransfer 只有调拨申请的新保管人本人可以接收或拒绝，以其身份上链
+func finishAssetTransfer(c *gin.Context, accept bool) {
+	//获取路径id值
+	id := c.Param("id")
+	userId := c.MustGet("userId").(string)
+	request, err := dbMod.GetAssetRequestByID(id)
+	if err != nil {
+		c.JSON(assetRequestErrorStatus(err), gin.H{"error": "获取资产申请失败:" + err.Error()})
+		return
+	}
+	if request.Recipient != userId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有新保管人可以接收或拒绝调拨"})
+		return
+	}
+	request, err = dbMod.FinishAssetTransfer(id, accept, utils.GetNowTimeString(), func(request *dbMod.AssetRequest) error {
+		chain, err := fabric.GetAsset(request.Asset)
+		if err != nil || chain.Transferee != userId {
+			return err
+		}
+		if accept {
+			_, err = fabric.AcceptAssetTransfer(request.Asset, userId)
+		} else {
+			_, err = fabric.DeclineAssetTransfer(request.Asset, userId)
+		}
+		return err
+	})
+	if err != nil {
+		c.JSON(assetRequestErrorStatus(err), gin.H{"error": "处理资产调拨失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": request})
+}
+
+// ReturnLentAsset 借出资产归还验收，由资产保管人或业委会成员操作
+func ReturnLentAsset(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var returnReq models.ReturnAsset
+	if err := c.ShouldBindJSON(&returnReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	request, err := dbMod.GetAssetRequestByID(id)
+	if err != nil {
+		c.JSON(assetRequestErrorStatus(err), gin.H{"error": "获取资产申请失败:" + err.Error()})
+		return
+	}
+	asset, err := dbMod.GetAssetByID(request.Asset)
+	if err != nil {
+		c.JSON(assetRequestErrorStatus(err), gin.H{"error": "获取资产失败:" + err.Error()})
+		return
+	}
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	if asset.Owner != userId && member.Type != middleware.RoleCommittee && member.Type != middleware.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有资产保管人或业委会成员可以验收归还"})
+		return
+	}
+	request, err = dbMod.ReturnLentAsset(id, utils.GetNowTimeString(), func(request *dbMod.AssetRequest) error {
+		chain, err := fabric.GetAsset(request.Asset)
+		if err != nil || chain.Borrower == "" {
+			return err
+		}
+		_, err = fabric.ReturnAsset(request.Asset, returnReq.Reason, userId)
+		return err
+	})
+	if err != nil {
+		c.JSON(assetRequestErrorStatus(err), gin.H{"error": "归还资产失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": request})
+}
+
+// CreditDisposalProceeds 财务负责人确认收款后将处置收入记入申请指定的款项
+func CreditDisposalProceeds(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	userId := c.MustGet("userId").(string)
+	var totals fabric.FinancialTotals
+	request, err := dbMod.CreditDisposalProceeds(id, userId, utils.GetNowTimeString(), func(request *dbMod.AssetRequest, entry *dbMod.JournalEntry) error {
+		var err error
+		totals, err = fabric.AddFinancialRecord(entry.FundID, entry.Type, entry.Source, entry.Explain, entry.Recorder,
+			request.Proceeds.String(), entry.InfoHash, entry.Category, entry.EntryTime[:10])
+		return err
+	})
+	if err != nil {
+		status, msg := fundRecordError(err)
+		if status == http.StatusInternalServerError {
+			status, msg = assetRequestErrorStatus(err), "处置收入入账失败"
+		}
+		c.JSON(status, gin.H{"error": msg + ":" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": request, "totals": totals})
+}
+
+// GetOverdueLoanPage 分页查询超过归还期限仍未归还的借用
+func GetOverdueLoanPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	requests, err := dbMod.GetOverdueLoans(utils.GetNowTimeString()[:10], page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取逾期借用失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(requests, page))
+}
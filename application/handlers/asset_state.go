@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+)
+
+// ChangeAssetState 变更资产状态，由链码校验状态转换并记录操作人与原因，本地状态随链上同步
+func ChangeAssetState(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var stateReq models.ChangeAssetState
+	if err := c.ShouldBindJSON(&stateReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	var asset fabric.Asset
+	err := dbMod.ChangeAssetStatus(id, stateReq.State, func() error {
+		var err error
+		asset, err = fabric.ChangeAssetState(id, stateReq.State, stateReq.Reason, userId)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "资产不存在:" + id})
+		return
+	}
+	if err != nil && strings.Contains(err.Error(), "illegal state transition") {
+		c.JSON(http.StatusConflict, gin.H{"error": "资产状态变更不合法:" + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "变更资产状态失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": asset})
+}
+
+// GetAssetTimeline 查询资产的链上状态时间线
+func GetAssetTimeline(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	timeline, err := fabric.GetAssetTimeline(id)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取资产状态记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": timeline, "total": len(timeline)})
+}
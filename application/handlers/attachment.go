@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"community-governance/storage"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// maxAttachmentSize 附件大小上限10MB
+const maxAttachmentSize = 10 << 20
+
+// attachmentTypes 允许上传的附件类型，以文件内容识别
+var attachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+// readAttachment 读取表单中的file文件，超过大小上限或类型不允许时返回错误信息
+func readAttachment(c *gin.Context) (string, []byte, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传文件失败:" + err.Error()})
+		return "", nil, false
+	}
+	if fileHeader.Size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "上传文件不能超过10MB"})
+		return "", nil, false
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传文件失败:" + err.Error()})
+		return "", nil, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传文件失败:" + err.Error()})
+		return "", nil, false
+	}
+	return fileHeader.Filename, data, true
+}
+
+// UploadAttachment 上传收据、发票等证明文件，返回文件SHA-256用于添加收支记录
+func UploadAttachment(c *gin.Context) {
+	fileName, data, ok := readAttachment(c)
+	if !ok {
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !attachmentTypes[contentType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件类型:" + contentType})
+		return
+	}
+	hash, err := storage.Save(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存附件失败:" + err.Error()})
+		return
+	}
+	attachment := dbMod.Attachment{
+		Hash:        hash,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Uploader:    c.MustGet("userId").(string),
+		UploadTime:  utils.GetNowTimeString(),
+	}
+	if err := dbMod.CreateAttachment(&attachment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存附件失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": attachment})
+}
+
+// DownloadAttachment 按SHA-256下载附件
+func DownloadAttachment(c *gin.Context) {
+	hash := c.Param("hash")
+	attachment, err := dbMod.GetAttachmentByHash(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "附件不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取附件失败:" + err.Error()})
+		return
+	}
+	reader, err := storage.Open(hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "附件文件不存在"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取附件失败:" + err.Error()})
+		return
+	}
+	defer reader.Close()
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader, map[string]string{
+		"Content-Disposition": "attachment; filename*=UTF-8''" + url.PathEscape(attachment.FileName),
+	})
+}
+
+// VerifyAttachment 校验上传的文件是否与款项链上收支记录登记的证明文件一致
+func VerifyAttachment(c *gin.Context) {
+	type Result struct {
+		Hash    string                        `json:"hash"`
+		Matched bool                          `json:"matched"`
+		Proofs  []fabric.FinancialTransaction `json:"proofs"`
+	}
+	//获取路径id值
+	id := c.Param("id")
+	_, data, ok := readAttachment(c)
+	if !ok {
+		return
+	}
+	hash := storage.Hash(data)
+	proofs, err := fabric.VerifyAttachment(id, hash)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "校验附件失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": Result{Hash: hash, Matched: len(proofs) > 0, Proofs: proofs}})
+}
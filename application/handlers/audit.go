@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"community-governance/application/audit"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// GetAuditLogs 分页查询审计日志，支持按操作人、操作、对象与时间范围筛选
+func GetAuditLogs(c *gin.Context) {
+	//获取page和pageSize
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	conditions := make(map[string]interface{})
+	for _, key := range []string{"actor", "action", "target", "target_id"} {
+		if value := c.Query(key); value != "" {
+			conditions[key] = value
+		}
+	}
+	logs, err := dbMod.GetAuditLogsWithConditions(conditions, c.Query("start_time"), c.Query("end_time"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审计日志失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(logs, page))
+}
+
+func GetAuditLogDetail(c *gin.Context) {
+	//获取路径id值
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的id参数不合法:" + err.Error()})
+		return
+	}
+	log, err := dbMod.GetAuditLogByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审计日志失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": log})
+}
+
+// VerifyAuditChain 校验审计日志hash链及链上锚定
+func VerifyAuditChain(c *gin.Context) {
+	result, err := audit.VerifyChain()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "校验审计日志失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// GetAuditAnchors 查询审计链头的锚定记录
+func GetAuditAnchors(c *gin.Context) {
+	type Info struct {
+		Local []dbMod.AuditAnchor        `json:"local"`
+		Chain []fabric.AuditAnchorRecord `json:"chain"`
+	}
+	//获取page和pageSize
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	anchors, err := dbMod.GetAuditAnchorsWithPagination(page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取锚定记录失败:" + err.Error()})
+		return
+	}
+	records, err := fabric.GetAuditAnchorHistory()
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上锚定记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(Info{Local: anchors, Chain: records}, page))
+}
+
+// AnchorAuditHead 立即锚定审计链头
+func AnchorAuditHead(c *gin.Context) {
+	anchor, err := audit.AnchorOnce()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "锚定审计链头失败:" + err.Error()})
+		return
+	}
+	if anchor == nil {
+		c.JSON(http.StatusOK, gin.H{"data": "没有需要锚定的审计日志"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": anchor})
+}
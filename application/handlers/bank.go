@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"bytes"
+	"community-governance/application/bank"
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// 银行流水文件格式
+const (
+	bankFormatCSV     = "csv"
+	bankFormatCAMT053 = "camt053"
+)
+
+// matchWindow 匹配时在流水日期范围前后多查询的天数
+const matchWindow = 7
+
+// parseBankStatement 按格式解析上传的流水文件，csv格式需在mapping中提供列映射JSON
+func parseBankStatement(format, mapping string, data []byte) ([]bank.Line, error) {
+	switch format {
+	case bankFormatCSV:
+		var csvMapping bank.CSVMapping
+		if err := json.Unmarshal([]byte(mapping), &csvMapping); err != nil {
+			return nil, errors.New("列映射不合法:" + err.Error())
+		}
+		return bank.ParseCSV(bytes.NewReader(data), csvMapping)
+	case bankFormatCAMT053:
+		return bank.ParseCAMT053(bytes.NewReader(data))
+	}
+	return nil, errors.New("不支持的流水格式:" + format)
+}
+
+// bankLineErrorStatus 流水已处理或分录已对账返回409，流水不存在返回404
+func bankLineErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrBankLineResolved), errors.Is(err, dbMod.ErrEntryReconciled):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// ImportBankStatement 导入款项的银行流水，按金额、日期与流水号匹配未对账的分录并给出建议
+// 与已导入流水的日期、金额及流水号均相同的流水标记为重复，duplicates返回重复的条数
+func ImportBankStatement(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	if _, err := dbMod.GetFundByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "款项不存在:" + id})
+		return
+	}
+	fileName, data, ok := readAttachment(c)
+	if !ok {
+		return
+	}
+	format := c.PostForm("format")
+	lines, err := parseBankStatement(format, c.PostForm("mapping"), data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "解析流水失败:" + err.Error()})
+		return
+	}
+	if len(lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "流水文件中没有交易"})
+		return
+	}
+	startDate, endDate := lines[0].Date, lines[0].Date
+	for _, line := range lines {
+		if line.Date < startDate {
+			startDate = line.Date
+		}
+		if line.Date > endDate {
+			endDate = line.Date
+		}
+	}
+	start, _ := time.Parse("2006-01-02", startDate)
+	end, _ := time.Parse("2006-01-02", endDate)
+	entries, err := dbMod.GetReconcileCandidates(id,
+		start.AddDate(0, 0, -matchWindow).Format("2006-01-02"), end.AddDate(0, 0, matchWindow).Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取待对账分录失败:" + err.Error()})
+		return
+	}
+	candidates := make([]bank.Candidate, 0, len(entries))
+	for _, entry := range entries {
+		candidates = append(candidates, bank.Candidate{
+			EntryID:   entry.EntryID,
+			Date:      entry.EntryTime[:10],
+			Amount:    entry.Amount,
+			Reference: entry.Reference,
+			Text:      entry.Source + " " + entry.Explain,
+		})
+	}
+	bankImport := dbMod.BankImport{
+		ImportID:   uuid.New().String(),
+		FundID:     id,
+		FileName:   fileName,
+		Format:     format,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Importer:   c.MustGet("userId").(string),
+		ImportTime: utils.GetNowTimeString(),
+		Lines:      make([]dbMod.BankLine, len(lines)),
+	}
+	for i, line := range lines {
+		bankImport.Lines[i] = dbMod.BankLine{
+			FundID:       id,
+			LineDate:     line.Date,
+			Amount:       line.Amount,
+			Reference:    line.Reference,
+			Counterparty: line.Counterparty,
+			Description:  line.Description,
+			Status:       dbMod.BankLineUnmatched,
+		}
+	}
+	//已导入过的流水标记为重复，只对其余流水给出匹配建议
+	duplicates, err := dbMod.FlagDuplicateBankLines(id, bankImport.Lines)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查重复流水失败:" + err.Error()})
+		return
+	}
+	fresh := make([]bank.Line, 0, len(lines))
+	index := make([]int, 0, len(lines))
+	for i, line := range lines {
+		if bankImport.Lines[i].Status != dbMod.BankLineDuplicate {
+			fresh = append(fresh, line)
+			index = append(index, i)
+		}
+	}
+	for _, suggestion := range bank.Match(fresh, candidates) {
+		line := &bankImport.Lines[index[suggestion.Line]]
+		line.Status = dbMod.BankLineSuggested
+		line.EntryID = suggestion.EntryID
+		line.Score = suggestion.Score
+	}
+	if err := dbMod.CreateBankImport(&bankImport); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存流水失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": bankImport, "duplicates": duplicates})
+}
+
+// GetBankImportDetail 获取导入批次及流水明细
+func GetBankImportDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	bankImport, err := dbMod.GetBankImportByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "导入批次不存在:" + id})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取导入批次失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": bankImport})
+}
+
+// GetBankImportAllPage 分页查询导入批次，可按fund_id筛选
+func GetBankImportAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	imports, err := dbMod.GetBankImportsWithPagination(c.Query("fund_id"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取导入批次失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(imports, page))
+}
+
+// ConfirmBankLine 确认流水与分录匹配
+func ConfirmBankLine(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var confirmReq models.ConfirmBankLine
+	if err := c.ShouldBindJSON(&confirmReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	line, err := dbMod.ConfirmBankLine(id, confirmReq.EntryID)
+	if err != nil {
+		c.JSON(bankLineErrorStatus(err), gin.H{"error": "确认匹配失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": line})
+}
+
+// IgnoreBankLine 忽略不需要入账的流水，如款项间划转
+func IgnoreBankLine(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	line, err := dbMod.IgnoreBankLine(id)
+	if err != nil {
+		c.JSON(bankLineErrorStatus(err), gin.H{"error": "忽略流水失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": line})
+}
+
+// RecordBankLine 为没有对应分录的流水创建收支记录，记账与上链流程与添加记录相同
+// 超过审批阈值的支出需先按添加记录提交审批，入账后再确认匹配
+func RecordBankLine(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var recordReq models.RecordBankLine
+	if err := c.ShouldBindJSON(&recordReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	if recordReq.InfoHash != "" {
+		if _, err := dbMod.GetAttachmentByHash(recordReq.InfoHash); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "证明文件不存在:" + recordReq.InfoHash})
+			return
+		}
+	}
+	current, err := dbMod.GetBankLineByID(id)
+	if err != nil {
+		c.JSON(bankLineErrorStatus(err), gin.H{"error": "获取流水失败:" + err.Error()})
+		return
+	}
+	if current.Amount < 0 {
+		policy, err := fabric.GetApprovalPolicy()
+		if err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "获取审批策略失败:" + err.Error()})
+			return
+		}
+		if policy.RequiresApproval(int64(current.Amount.Abs())) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "支出超过审批阈值，请先提交审批，入账后再确认匹配"})
+			return
+		}
+	}
+	userId := c.MustGet("userId").(string)
+	var totals fabric.FinancialTotals
+	line, warning, err := dbMod.RecordBankLine(id, func(line *dbMod.BankLine) *dbMod.JournalEntry {
+		entry := dbMod.JournalEntry{
+			EntryID:   uuid.New().String(),
+			FundID:    line.FundID,
+			Type:      dbMod.EntryTypeIncome,
+			Source:    recordReq.Source,
+			Explain:   recordReq.Explain,
+			Category:  dbMod.CategoryOf(recordReq.Category),
+			InfoHash:  recordReq.InfoHash,
+			Reference: line.Reference,
+			Recorder:  userId,
+			EntryTime: utils.GetNowTimeString(),
+		}
+		if line.Amount < 0 {
+			entry.Type = dbMod.EntryTypeExpense
+		}
+		if entry.Source == "" {
+			entry.Source = line.Counterparty
+		}
+		if entry.Explain == "" {
+			entry.Explain = line.Description
+		}
+		return &entry
+	}, func(entry *dbMod.JournalEntry, amount dbMod.Money) error {
+		var err error
+		totals, err = anchorFundEntry(entry, amount)
+		return err
+	})
+	if errors.Is(err, dbMod.ErrBankLineResolved) || errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(bankLineErrorStatus(err), gin.H{"error": "流水入账失败:" + err.Error()})
+		return
+	}
+	if err != nil {
+		status, msg := fundRecordError(err)
+		c.JSON(status, gin.H{"error": msg + ":" + err.Error()})
+		return
+	}
+	if warning != nil {
+		c.JSON(http.StatusOK, gin.H{"data": line, "totals": totals, "warning": warning})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": line, "totals": totals})
+}
+
+// GetUnreconciledEntries 分页查询款项中没有银行流水对应的分录，可按start、end日期筛选
+func GetUnreconciledEntries(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	start, end := c.Query("start"), c.Query("end")
+	for _, date := range []string{start, end} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的日期不合法:" + date})
+			return
+		}
+	}
+	entries, err := dbMod.GetUnreconciledEntries(id, start, end, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取未对账分录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(entries, page))
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// billingErrorStatus 收费标准或账期不合法、超额缴费返回400，账单已关闭返回409，记录不存在返回404
+func billingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrInvalidFee), errors.Is(err, dbMod.ErrInvalidPeriod), errors.Is(err, dbMod.ErrOverpayment):
+		return http.StatusBadRequest
+	case errors.Is(err, dbMod.ErrInvoiceClosed), errors.Is(err, dbMod.ErrInvoiceHasPaid):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return chainErrorStatus(err)
+}
+
+// SaveHousehold 登记或更新房屋面积，按面积计费时使用
+func SaveHousehold(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var householdReq models.Household
+	if err := c.ShouldBindJSON(&householdReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	if householdReq.Area < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的面积不合法"})
+		return
+	}
+	household := dbMod.Household{
+		HouseholdID: id,
+		Address:     householdReq.Address,
+		Area:        householdReq.Area,
+		UpdateTime:  utils.GetNowTimeString(),
+	}
+	if err := dbMod.SaveHousehold(&household); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存房屋信息失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": household})
+}
+
+// GetHouseholdAllPage 分页查询房屋信息
+func GetHouseholdAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	households, err := dbMod.GetHouseholdsWithPagination(page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取房屋信息失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(households, page))
+}
+
+func AddFeeSchedule(c *gin.Context) {
+	var scheduleReq models.CreateFeeSchedule
+	if err := c.ShouldBindJSON(&scheduleReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	rate, err := dbMod.ParseMoney(scheduleReq.Rate)
+	if err != nil || scheduleReq.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的名称或费率不合法"})
+		return
+	}
+	if _, err := dbMod.GetFundByID(scheduleReq.FundID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "款项不存在:" + scheduleReq.FundID})
+		return
+	}
+	schedule := dbMod.FeeSchedule{
+		ScheduleID: uuid.New().String(),
+		Name:       scheduleReq.Name,
+		FundID:     scheduleReq.FundID,
+		Method:     scheduleReq.Method,
+		Rate:       rate,
+		Cycle:      scheduleReq.Cycle,
+		DueDays:    scheduleReq.DueDays,
+		Status:     dbMod.FeeScheduleStatusActive,
+		Creator:    c.MustGet("userId").(string),
+		CreateTime: utils.GetNowTimeString(),
+	}
+	if err := dbMod.CheckFeeSchedule(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "收费标准不合法:" + err.Error()})
+		return
+	}
+	if err := dbMod.CreateFeeSchedule(&schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建收费标准失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": schedule})
+}
+
+func GetFeeScheduleDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	schedule, err := dbMod.GetFeeScheduleByID(id)
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "获取收费标准失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": schedule})
+}
+
+// GetFeeScheduleAllPage 分页查询收费标准，可按fund_id筛选
+func GetFeeScheduleAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	schedules, err := dbMod.GetFeeSchedulesWithPagination(c.Query("fund_id"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取收费标准失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(schedules, page))
+}
+
+func UpdateFeeSchedule(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var scheduleReq models.UpdateFeeSchedule
+	if err := c.ShouldBindJSON(&scheduleReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	schedule, err := dbMod.GetFeeScheduleByID(id)
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "获取收费标准失败:" + err.Error()})
+		return
+	}
+	if scheduleReq.Name != "" {
+		schedule.Name = scheduleReq.Name
+	}
+	if scheduleReq.Rate != "" {
+		if schedule.Rate, err = dbMod.ParseMoney(scheduleReq.Rate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的费率不合法:" + scheduleReq.Rate})
+			return
+		}
+	}
+	if scheduleReq.DueDays != nil {
+		schedule.DueDays = *scheduleReq.DueDays
+	}
+	if scheduleReq.Status != "" {
+		if scheduleReq.Status != dbMod.FeeScheduleStatusActive && scheduleReq.Status != dbMod.StatusArchived {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的状态不合法:" + scheduleReq.Status})
+			return
+		}
+		schedule.Status = scheduleReq.Status
+	}
+	if err := dbMod.CheckFeeSchedule(*schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "收费标准不合法:" + err.Error()})
+		return
+	}
+	if err := dbMod.UpdateFeeSchedule(schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新收费标准失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "更新收费标准成功"})
+}
+
+func DeleteFeeSchedule(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	if err := dbMod.DeleteFeeSchedule(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除收费标准失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "删除收费标准成功"})
+}
+
+// GenerateInvoices 按收费标准为各户生成指定账期的账单，重复生成时跳过已出账的户号
+func GenerateInvoices(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var generateReq models.GenerateInvoices
+	if err := c.ShouldBindJSON(&generateReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	schedule, err := dbMod.GetFeeScheduleByID(id)
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "获取收费标准失败:" + err.Error()})
+		return
+	}
+	if schedule.Status == dbMod.StatusArchived {
+		c.JSON(http.StatusConflict, gin.H{"error": "收费标准已归档:" + id})
+		return
+	}
+	if generateReq.IssueDate == "" {
+		generateReq.IssueDate = time.Now().Format("2006-01-02")
+	}
+	created, skipped, err := dbMod.GenerateInvoices(*schedule, generateReq.Period, generateReq.IssueDate,
+		utils.GetNowTimeString(), func() string { return uuid.New().String() })
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "生成账单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": created, "skipped": skipped})
+}
+
+func GetInvoiceDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	invoice, err := dbMod.GetInvoiceByID(id)
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "获取账单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invoice})
+}
+
+// GetInvoiceAllPage 分页查询账单，可按household_id、status与period筛选
+func GetInvoiceAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	invoices, err := dbMod.GetInvoicesWithPagination(c.Query("household_id"), c.Query("status"), c.Query("period"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取账单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(invoices, page))
+}
+
+// PayInvoice 登记缴费，缴费作为收入计入账单对应的款项并上链
+func PayInvoice(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var payReq models.PayInvoice
+	if err := c.ShouldBindJSON(&payReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	amount, err := dbMod.ParseMoney(payReq.Amount)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的金额不合法:" + payReq.Amount})
+		return
+	}
+	payment := dbMod.Payment{
+		PaymentID: uuid.New().String(),
+		Amount:    amount,
+		Reference: payReq.Reference,
+		Recorder:  c.MustGet("userId").(string),
+		PayTime:   utils.GetNowTimeString(),
+	}
+	var totals fabric.FinancialTotals
+	invoice, err := dbMod.PayInvoice(id, &payment, func(invoice *dbMod.Invoice) error {
+		var err error
+		totals, err = fabric.AddFinancialRecord(invoice.FundID, dbMod.EntryTypeIncome, invoice.HouseholdID,
+			invoice.Name+" "+invoice.Period, payment.Recorder, amount.String(), "", invoice.Name, payment.PayTime[:10])
+		return err
+	})
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "登记缴费失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invoice, "payment": payment, "totals": totals})
+}
+
+// VoidInvoice 作废尚未缴费的账单
+func VoidInvoice(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	invoice, err := dbMod.VoidInvoice(id)
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "作废账单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": invoice})
+}
+
+// GetArrearsReport 欠费账龄，as_of为空时截至当天，可按fund_id筛选
+func GetArrearsReport(c *gin.Context) {
+	asOf := c.Query("as_of")
+	if asOf == "" {
+		asOf = time.Now().Format("2006-01-02")
+	}
+	report, err := dbMod.GetArrearsReport(asOf, c.Query("fund_id"))
+	if err != nil {
+		c.JSON(billingErrorStatus(err), gin.H{"error": "获取欠费账龄失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// GetHouseholdStatement 获取指定户号的账单与缴费记录
+func GetHouseholdStatement(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	statement, err := dbMod.GetHouseholdStatement(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取住户账单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": statement})
+}
+
+// GetMyStatement 住户查看本户的账单与缴费记录
+func GetMyStatement(c *gin.Context) {
+	member, err := dbMod.GetMemberByID(c.MustGet("userId").(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	statement, err := dbMod.GetHouseholdStatement(member.HouseholdID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取住户账单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": statement})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+// parseBudgetLines 解析预算行金额
+func parseBudgetLines(lines []models.BudgetLine) ([]dbMod.BudgetLine, error) {
+	result := make([]dbMod.BudgetLine, 0, len(lines))
+	for _, line := range lines {
+		amount, err := dbMod.ParseMoney(line.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("%s的预算金额不合法:%s", line.Category, line.Amount)
+		}
+		result = append(result, dbMod.BudgetLine{Category: line.Category, Amount: amount, Description: line.Description})
+	}
+	return result, nil
+}
+
+// checkBudgetMode 超支处理方式只能为提醒或拦截，为空时默认提醒
+func checkBudgetMode(mode string) (string, bool) {
+	switch mode {
+	case "":
+		return dbMod.BudgetModeWarn, true
+	case dbMod.BudgetModeWarn, dbMod.BudgetModeBlock:
+		return mode, true
+	}
+	return "", false
+}
+
+// budgetErrorStatus 预算不合法返回400，重复创建返回409，预算不存在返回404
+func budgetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrInvalidBudget):
+		return http.StatusBadRequest
+	case errors.Is(err, dbMod.ErrBudgetExists):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return chainErrorStatus(err)
+}
+
+func AddBudget(c *gin.Context) {
+	var budgetReq models.CreateBudget
+	if err := c.ShouldBindJSON(&budgetReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	mode, ok := checkBudgetMode(budgetReq.Mode)
+	if !ok || budgetReq.Year <= 0 || budgetReq.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的预算名称、年度或超支处理方式不合法"})
+		return
+	}
+	lines, err := parseBudgetLines(budgetReq.Lines)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := dbMod.GetFundByID(budgetReq.FundID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "款项不存在:" + budgetReq.FundID})
+		return
+	}
+	budget := dbMod.Budget{
+		BudgetID:   uuid.New().String(),
+		FundID:     budgetReq.FundID,
+		Year:       budgetReq.Year,
+		Name:       budgetReq.Name,
+		Mode:       mode,
+		Status:     dbMod.BudgetStatusActive,
+		Creator:    c.MustGet("userId").(string),
+		CreateTime: utils.GetNowTimeString(),
+		Lines:      lines,
+	}
+	if err := dbMod.CreateBudget(&budget); err != nil {
+		c.JSON(budgetErrorStatus(err), gin.H{"error": "创建预算失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": budget})
+}
+
+func GetBudgetDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	budget, err := dbMod.GetBudgetByID(id)
+	if err != nil {
+		c.JSON(budgetErrorStatus(err), gin.H{"error": "获取预算失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": budget})
+}
+
+// GetBudgetAllPage 分页查询预算，可按款项与年度筛选
+func GetBudgetAllPage(c *gin.Context) {
+	//获取page和pageSize
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	var year int
+	if value := c.Query("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的年度不合法:" + value})
+			return
+		}
+	}
+	budgets, err := dbMod.GetBudgetsWithPagination(c.Query("fund_id"), year, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预算失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(budgets, page))
+}
+
+func UpdateBudget(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var budgetReq models.UpdateBudget
+	if err := c.ShouldBindJSON(&budgetReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	if budgetReq.Mode != "" {
+		if _, ok := checkBudgetMode(budgetReq.Mode); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的超支处理方式不合法:" + budgetReq.Mode})
+			return
+		}
+	}
+	if budgetReq.Status != "" && budgetReq.Status != dbMod.BudgetStatusActive && budgetReq.Status != dbMod.StatusArchived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的预算状态不合法:" + budgetReq.Status})
+		return
+	}
+	budget := dbMod.Budget{Name: budgetReq.Name, Mode: budgetReq.Mode, Status: budgetReq.Status}
+	if budgetReq.Lines != nil {
+		lines, err := parseBudgetLines(budgetReq.Lines)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		budget.Lines = lines
+	}
+	if err := dbMod.UpdateBudget(id, budget); err != nil {
+		c.JSON(budgetErrorStatus(err), gin.H{"error": "更新预算失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "更新预算成功"})
+}
+
+func DeleteBudget(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	if err := dbMod.DeleteBudget(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除预算失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "删除预算成功"})
+}
+
+// GetBudgetReport 预算执行情况，按科目与月份对比预算与实际支出
+func GetBudgetReport(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	report, err := dbMod.GetBudgetReport(id)
+	if err != nil {
+		c.JSON(budgetErrorStatus(err), gin.H{"error": "获取预算执行情况失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"community-governance/fabric"
+	"errors"
+	"net/http"
+)
+
+// chainErrorStatus 链码拒绝访问时，未携带身份返回401，其余访问控制错误返回403，其他错误返回500
+func chainErrorStatus(err error) int {
+	var accessErr *fabric.AccessError
+	if !errors.As(err, &accessErr) {
+		return http.StatusInternalServerError
+	}
+	if accessErr.Code == fabric.AccessNoIdentity {
+		return http.StatusUnauthorized
+	}
+	return http.StatusForbidden
+}
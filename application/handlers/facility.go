@@ -8,7 +8,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
-	"strconv"
 )
 
 const (
@@ -29,7 +28,7 @@ func GetFacilityDetail(c *gin.Context) {
 	}
 	history, err := fabric.GetFacilityUsageHistory(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设施记录失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取设施记录失败:" + err.Error()})
 		return
 	}
 
@@ -68,9 +67,10 @@ func AddFacility(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算设施hash失败:" + err.Error()})
 		return
 	}
-	err = fabric.RegisterFacility(facilityId, hash)
+	userId := c.MustGet("userId").(string)
+	err = fabric.RegisterFacility(facilityId, hash, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加设施失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "添加设施失败:" + err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "添加设施成功"})
@@ -78,23 +78,16 @@ func AddFacility(c *gin.Context) {
 
 func GetFacilityAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	facilities, err := dbMod.GetAllFacilityWithPagination(iPage, iPageSize)
+	facilities, err := dbMod.GetAllFacilityWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设施失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": facilities, "total": len(facilities)})
+	c.JSON(http.StatusOK, pageResult(facilities, page))
 }
 func UpdateFacility(c *gin.Context) {
 	var facilityReq dbMod.PublicFacility
@@ -115,9 +108,10 @@ func UpdateFacility(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算设施hash失败:" + err.Error()})
 		return
 	}
-	err = fabric.UpdateFacility(facilityReq.FacilityID, hash, facilityReq.Status)
+	userId := c.MustGet("userId").(string)
+	err = fabric.UpdateFacility(facilityReq.FacilityID, hash, facilityReq.Status, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新设施失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "更新设施失败:" + err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "更新设施成功"})
@@ -125,6 +119,9 @@ func UpdateFacility(c *gin.Context) {
 func DeleteFacility(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
+	if rejectUndeletable(c, "facility", id, "删除设施") {
+		return
+	}
 	err := dbMod.DeleteFacility(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除设施失败:" + err.Error()})
@@ -135,42 +132,22 @@ func DeleteFacility(c *gin.Context) {
 }
 func GetFacilityByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	facilities, err := dbMod.GetAllFacilityWithConditions(conditions, iPage, iPageSize)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设施失败:" + err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"data": facilities, "total": len(facilities)})
-}
-func RequestFacility(c *gin.Context) {
-	//获取路径id值
-	id := c.Param("id")
-	//获取userId
-	userId := c.MustGet("userId").(string)
-	err := fabric.RequestFacility(id, userId)
+	facilities, err := dbMod.GetAllFacilityWithConditions(query, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "请求设施失败:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取设施失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": "请求设施成功"})
-
+	c.JSON(http.StatusOK, pageResult(facilities, page))
 }
 func ReleaseFacility(c *gin.Context) {
 	//获取路径id值
@@ -179,7 +156,7 @@ func ReleaseFacility(c *gin.Context) {
 	userId := c.MustGet("userId").(string)
 	err := fabric.ReleaseFacility(id, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "释放设施失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "释放设施失败:" + err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "释放设施成功"})
@@ -5,11 +5,12 @@ import (
 	"community-governance/application/utils"
 	dbMod "community-governance/db/models"
 	"community-governance/fabric"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
-	"strconv"
+	"time"
 )
 
 const (
@@ -26,6 +27,12 @@ func AddFund(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	totalAmount, err := dbMod.ParseMoney(fundReq.TotalAmount)
+	if err != nil || totalAmount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的总金额不合法:" + fundReq.TotalAmount})
+		return
+	}
+	userId := c.MustGet("userId").(string)
 	fundId := uuid.New().String()
 	fund := dbMod.Fund{
 		FundID:            fundId,
@@ -34,24 +41,21 @@ func AddFund(c *gin.Context) {
 		Name:              fundReq.Name,
 		Source:            fundReq.Source,
 		SourceDescription: fundReq.SourceDescription,
-		TotalAmount:       fundReq.TotalAmount,
-		CurrentBalance:    fundReq.TotalAmount,
+		TotalAmount:       totalAmount,
 		Manager:           fundReq.Manager,
 		EstablishDate:     fundReq.EstablishDate,
 	}
-	if err := dbMod.CreateFunds(&fund); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	//计算hash值
-	hash, err := utils.ComputeHash(fund)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算公告hash失败:" + err.Error()})
-		return
-	}
-	err = fabric.CreateFinancial(fundId, hash)
+	//款项上链后才提交，上链失败时不留下没有链上记录的款项与账户
+	err = dbMod.CreateFunds(&fund, userId, utils.GetNowTimeString(), func() error {
+		//计算hash值
+		hash, err := utils.ComputeHash(fund)
+		if err != nil {
+			return fmt.Errorf("计算款项hash失败:%v", err)
+		}
+		return fabric.CreateFinancial(fundId, hash, fund.TotalAmount.String(), userId)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "创建财务款项失败:" + err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "创建财务款项成功"})
@@ -73,7 +77,7 @@ func GetFundDetail(c *gin.Context) {
 	detail.Base = *base
 	chainDetail, err := fabric.GetFinancialDetail(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投票详情失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取投票详情失败:" + err.Error()})
 		return
 	}
 	detail.ChainFundDetail = chainDetail
@@ -82,27 +86,16 @@ func GetFundDetail(c *gin.Context) {
 
 func GetFundAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	if page == "" || pageSize == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page或pageSize参数不合法"})
-		return
-	}
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	funds, err := dbMod.GetAllFundsWithPagination(iPage, iPageSize)
+	funds, err := dbMod.GetAllFundsWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取公告失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": funds, "total": len(funds)})
+	c.JSON(http.StatusOK, pageResult(funds, page))
 
 }
 func UpdateFund(c *gin.Context) {
@@ -113,6 +106,8 @@ func UpdateFund(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	//期初金额已记入账户，不允许修改
+	fundReq.TotalAmount = 0
 	if err := dbMod.UpdateFund(id, fundReq); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败:" + err.Error()})
 		return
@@ -123,9 +118,10 @@ func UpdateFund(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算公告hash失败:" + err.Error()})
 		return
 	}
-	err = fabric.UpdateFinancial(id, hash, fundReq.Status)
+	userId := c.MustGet("userId").(string)
+	err = fabric.UpdateFinancial(id, hash, fundReq.Status, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "更新公告失败:" + err.Error()})
 		return
 	}
 
@@ -134,12 +130,15 @@ func UpdateFund(c *gin.Context) {
 func DeleteFund(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
+	if rejectUndeletable(c, "fund", id, "删除款项") {
+		return
+	}
 	err := dbMod.DeleteFund(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除公告失败:" + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除款项失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": "删除公告成功"})
+	c.JSON(http.StatusOK, gin.H{"data": "删除款项成功"})
 }
 func AddFundRecord(c *gin.Context) {
 	//获取路径id值
@@ -150,76 +149,166 @@ func AddFundRecord(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	//获取fund信息
-	fund, err := dbMod.GetFundByID(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取公告失败:" + err.Error()})
+	amount, err := dbMod.ParseMoney(recordReq.Amount)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的金额不合法:" + recordReq.Amount})
 		return
 	}
-	currentBalance, err := strconv.ParseFloat(fund.CurrentBalance, 64)
-	if err != nil {
-		fmt.Println("转换current balance错误:", err)
+	if recordReq.Type != dbMod.EntryTypeIncome && recordReq.Type != dbMod.EntryTypeExpense {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的记录类型不合法:" + recordReq.Type})
 		return
 	}
-	recordAmount, err := strconv.ParseFloat(recordReq.Amount, 64)
-	if err != nil {
-		fmt.Println("转换record amount错误:", err)
+	//获取fund信息
+	if _, err := dbMod.GetFundByID(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取款项失败:" + err.Error()})
+		return
 	}
-	if recordReq.Type == "1" { //支出
-		if currentBalance < recordAmount {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "余额不足"})
+	//证明文件需先上传
+	if recordReq.InfoHash != "" {
+		if _, err := dbMod.GetAttachmentByHash(recordReq.InfoHash); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "证明文件不存在:" + recordReq.InfoHash})
 			return
 		}
-		recordAmount = -recordAmount
 	}
-	//计算fund剩余余额
-	nowBalance := currentBalance + recordAmount
-	nowBalanceStr := strconv.FormatFloat(nowBalance, 'f', -1, 64)
-	if err := dbMod.UpdateFund(id, *fund); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败:" + err.Error()})
+	recordReq.Category = dbMod.CategoryOf(recordReq.Category)
+	userId := c.MustGet("userId").(string)
+	entryTime, ok := recordEntryTime(c, recordReq.Date)
+	if !ok {
 		return
 	}
-	//更新记录
-	updateMap := map[string]interface{}{
-		"current_balance": nowBalanceStr,
+	//超过审批阈值的支出需业委会审批后入账
+	if recordReq.Type == dbMod.EntryTypeExpense {
+		policy, err := fabric.GetApprovalPolicy()
+		if err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "获取审批策略失败:" + err.Error()})
+			return
+		}
+		if policy.RequiresApproval(int64(amount)) {
+			//审批通过时按审批日期入账，不能补记
+			if recordReq.Date != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "需审批的支出不能指定记录日期"})
+				return
+			}
+			proposeFundExpense(c, id, recordReq, amount, userId)
+			return
+		}
+	}
+	entry := dbMod.JournalEntry{
+		EntryID:   uuid.New().String(),
+		FundID:    id,
+		Type:      recordReq.Type,
+		Source:    recordReq.Source,
+		Explain:   recordReq.Explain,
+		Category:  recordReq.Category,
+		InfoHash:  recordReq.InfoHash,
+		Reference: recordReq.Reference,
+		Recorder:  userId,
+		EntryTime: entryTime,
 	}
-	err = dbMod.UpdateFund(fund.FundID, updateMap)
+	totals, warning, err := postFundEntry(&entry, amount)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败:" + err.Error()})
+		status, msg := fundRecordError(err)
+		c.JSON(status, gin.H{"error": msg + ":" + err.Error()})
 		return
 	}
-	userId := c.MustGet("userId").(string)
-	err = fabric.AddFinancialRecord(id, recordReq.Type, recordReq.Source, recordReq.Explain, userId, recordReq.Amount)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加记录失败:" + err.Error()})
+	//返回链上的收支汇总作为权威余额
+	if warning != nil {
+		c.JSON(http.StatusOK, gin.H{"data": "添加记录成功，科目已超出预算", "totals": totals, "warning": warning})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": "添加记录成功"})
+	c.JSON(http.StatusOK, gin.H{"data": "添加记录成功", "totals": totals})
+}
+
+// recordEntryTime 补记的记录日期不能晚于当天，记账时间取该日期加当前时刻，未指定日期时取当前时间
+func recordEntryTime(c *gin.Context, date string) (string, bool) {
+	now := utils.GetNowTimeString()
+	if date == "" {
+		return now, true
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil || date > now[:10] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的记录日期不合法:" + date})
+		return "", false
+	}
+	return date + now[10:], true
+}
+
+// postFundEntry 记账并上链，上链失败时分录回滚
+func postFundEntry(entry *dbMod.JournalEntry, amount dbMod.Money) (fabric.FinancialTotals, *dbMod.BudgetWarning, error) {
+	var totals fabric.FinancialTotals
+	warning, err := dbMod.PostFundRecord(entry, amount, func() error {
+		var err error
+		totals, err = anchorFundEntry(entry, amount)
+		return err
+	})
+	return totals, warning, err
+}
+
+// anchorFundEntry 将收支分录同步上链，返回链上款项合计
+func anchorFundEntry(entry *dbMod.JournalEntry, amount dbMod.Money) (fabric.FinancialTotals, error) {
+	return fabric.AddFinancialRecord(entry.FundID, entry.Type, entry.Source, entry.Explain, entry.Recorder, amount.String(), entry.InfoHash, entry.Category, entry.EntryTime[:10])
+}
+
+// fundRecordError 记账失败的状态码与提示，本地账户与链上余额任一不足或超出预算时返回400
+func fundRecordError(err error) (int, string) {
+	switch {
+	case isInsufficientBalance(err):
+		return http.StatusBadRequest, "余额不足"
+	case isClosedPeriod(err):
+		return http.StatusBadRequest, "会计期间已结账"
+	case errors.Is(err, dbMod.ErrBudgetExceeded):
+		return http.StatusBadRequest, "超出预算"
+	}
+	return chainErrorStatus(err), "添加记录失败"
 }
 
 func GetFundByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
+	funds, err := dbMod.GetAllFundsWithConditions(query, page)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取公告失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(funds, page))
+}
+
+// GetFundRecords 游标分页获取款项收支记录，按(记账时间, 分录ID)倒序读取链下分录，翻页代价不随页数增长
+func GetFundRecords(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	cursor, limit, ok := parseCursor(c)
+	if !ok {
 		return
 	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	entries, next, hasNext, err := dbMod.GetFundJournalByCursor(id, cursor, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取收支记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cursorResult(entries, next, hasNext, limit))
+}
+
+// GetFundJournal 分页获取款项的分录及过账明细
+func GetFundJournal(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	funds, err := dbMod.GetAllFundsWithConditions(conditions, iPage, iPageSize)
+	entries, err := dbMod.GetFundJournalWithPagination(id, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取公告失败:" + err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分录失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": funds, "total": len(funds)})
+	c.JSON(http.StatusOK, pageResult(entries, page))
 }
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// isInsufficientBalance 本地账户或链上余额不足
+func isInsufficientBalance(err error) bool {
+	return errors.Is(err, dbMod.ErrInsufficientBalance) || strings.Contains(err.Error(), "insufficient balance")
+}
+
+// isClosedPeriod 记录日期所在的会计期间已在链上结账
+func isClosedPeriod(err error) bool {
+	return strings.Contains(err.Error(), "fiscal period")
+}
+
+// pendingErrorStatus 待审批支出不存在返回404，已处理返回409，余额不足、期间已结账或超出预算返回400
+func pendingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, dbMod.ErrPendingResolved):
+		return http.StatusConflict
+	case isInsufficientBalance(err), isClosedPeriod(err), errors.Is(err, dbMod.ErrBudgetExceeded):
+		return http.StatusBadRequest
+	}
+	return chainErrorStatus(err)
+}
+
+// syncPendingExpense 以链上审批结果更新本地待审批支出
+func syncPendingExpense(pending *dbMod.PendingExpense, chain fabric.PendingExpense) {
+	pending.Approvers = strings.Join(chain.Approvers, ",")
+	pending.Rejecter = chain.Rejecter
+	pending.Reason = chain.Reason
+	pending.Status = chain.Status
+}
+
+// proposeFundExpense 支出超过审批阈值时提交链上审批，批准人数达到要求后才入账
+func proposeFundExpense(c *gin.Context, id string, recordReq models.FinancialRecord, amount dbMod.Money, userId string) {
+	//提交前检查预算，拦截模式下超支不能提交
+	warning, err := dbMod.CheckBudget(id, recordReq.Category, utils.GetNowTimeString(), amount)
+	if errors.Is(err, dbMod.ErrBudgetExceeded) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "超出预算:" + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查预算失败:" + err.Error()})
+		return
+	}
+	chainPending, err := fabric.ProposeExpense(id, recordReq.Source, recordReq.Explain, amount.String(), recordReq.InfoHash, recordReq.Category, userId)
+	if err != nil && isInsufficientBalance(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "余额不足:" + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "提交支出审批失败:" + err.Error()})
+		return
+	}
+	pending := dbMod.PendingExpense{
+		PendingID:  chainPending.ID,
+		FundID:     id,
+		Amount:     amount,
+		Source:     recordReq.Source,
+		Explain:    recordReq.Explain,
+		Category:   recordReq.Category,
+		InfoHash:   recordReq.InfoHash,
+		Reference:  recordReq.Reference,
+		Proposer:   userId,
+		Required:   chainPending.Required,
+		Status:     chainPending.Status,
+		CreateTime: utils.GetNowTimeString(),
+	}
+	if err := dbMod.CreatePendingExpense(&pending); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存待审批支出失败:" + err.Error()})
+		return
+	}
+	if warning != nil {
+		c.JSON(http.StatusAccepted, gin.H{"data": "支出超过审批阈值，已提交审批，科目已超出预算", "pending": pending, "warning": warning})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"data": "支出超过审批阈值，已提交审批", "pending": pending})
+}
+
+// GetApprovalPolicy 查询大额支出审批策略
+func GetApprovalPolicy(c *gin.Context) {
+	type Policy struct {
+		Threshold dbMod.Money `json:"threshold"`
+		Required  int         `json:"required"`
+	}
+	policy, err := fabric.GetApprovalPolicy()
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取审批策略失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": Policy{
+		Threshold: dbMod.Money(policy.Threshold),
+		Required:  policy.Required,
+	}})
+}
+
+// SetApprovalPolicy 设置大额支出审批策略，required为0时关闭审批
+func SetApprovalPolicy(c *gin.Context) {
+	var policyReq models.ApprovalPolicy
+	if err := c.ShouldBindJSON(&policyReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	threshold, err := dbMod.ParseMoney(policyReq.Threshold)
+	if err != nil || threshold < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的审批阈值不合法:" + policyReq.Threshold})
+		return
+	}
+	if policyReq.Required < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "批准人数不能为负数"})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	if err := fabric.SetApprovalPolicy(threshold.String(), policyReq.Required, userId); err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "设置审批策略失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "设置审批策略成功"})
+}
+
+// GetPendingExpenses 分页查询待审批支出，可按款项与状态筛选
+func GetPendingExpenses(c *gin.Context) {
+	//获取page和pageSize
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	pending, err := dbMod.GetPendingExpensesWithPagination(c.Query("fund_id"), c.Query("status"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取待审批支出失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(pending, page))
+}
+
+// GetChainPendingExpenses 查询款项在链上的待审批支出及审批人
+func GetChainPendingExpenses(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	pending, err := fabric.GetPendingExpenses(id, c.Query("status"))
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上待审批支出失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": pending})
+}
+
+// ApproveFundExpense 业委会成员批准待审批支出，批准人数达到要求时入账
+func ApproveFundExpense(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	userId := c.MustGet("userId").(string)
+	pending, err := dbMod.ResolvePendingExpense(id, utils.GetNowTimeString(), true, func(pending *dbMod.PendingExpense) error {
+		//重试时链上可能已批准或已入账，按链上状态同步
+		chainPending, err := fabric.GetPendingExpense(pending.FundID, pending.PendingID)
+		if err != nil {
+			return err
+		}
+		if chainPending.Status == dbMod.PendingStatusPending && !slices.Contains(chainPending.Approvers, userId) {
+			if chainPending, err = fabric.ApproveExpense(pending.FundID, pending.PendingID, userId); err != nil {
+				return err
+			}
+		}
+		syncPendingExpense(pending, chainPending)
+		return nil
+	})
+	if err != nil {
+		c.JSON(pendingErrorStatus(err), gin.H{"error": "批准支出失败:" + err.Error()})
+		return
+	}
+	if pending.Status == dbMod.PendingStatusPosted {
+		c.JSON(http.StatusOK, gin.H{"data": "批准成功，支出已入账", "pending": pending})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "批准成功", "pending": pending})
+}
+
+// RejectFundExpense 业委会成员驳回待审批支出
+func RejectFundExpense(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var rejectReq models.RejectExpense
+	if err := c.ShouldBindJSON(&rejectReq); err != nil || rejectReq.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "驳回原因不能为空"})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	pending, err := dbMod.ResolvePendingExpense(id, utils.GetNowTimeString(), false, func(pending *dbMod.PendingExpense) error {
+		//重试时链上可能已驳回，按链上状态同步
+		chainPending, err := fabric.GetPendingExpense(pending.FundID, pending.PendingID)
+		if err != nil {
+			return err
+		}
+		if chainPending.Status == dbMod.PendingStatusPending {
+			if chainPending, err = fabric.RejectExpense(pending.FundID, pending.PendingID, rejectReq.Reason, userId); err != nil {
+				return err
+			}
+		}
+		syncPendingExpense(pending, chainPending)
+		return nil
+	})
+	if err != nil {
+		c.JSON(pendingErrorStatus(err), gin.H{"error": "驳回支出失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "驳回支出成功", "pending": pending})
+}
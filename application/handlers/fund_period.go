@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+)
+
+// ClosePeriod 链上结账，结账后期间内日期的收支记录被拒绝，只能记更正记录
+func ClosePeriod(c *gin.Context) {
+	var periodReq models.ClosePeriod
+	if err := c.ShouldBindJSON(&periodReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	closed, err := fabric.ClosePeriod(periodReq.Period, c.MustGet("userId").(string))
+	if err != nil && (strings.Contains(err.Error(), "illegal period") || strings.Contains(err.Error(), "has not ended")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的会计期间不合法:" + err.Error()})
+		return
+	}
+	if err != nil && isClosedPeriod(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "会计期间已结账:" + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "结账失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": closed})
+}
+
+// GetClosedPeriods 查询全部已结账的会计期间
+func GetClosedPeriods(c *gin.Context) {
+	periods, err := fabric.GetClosedPeriods()
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取已结账期间失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": periods})
+}
+
+// CorrectFundRecord 在当前期间记一笔更正记录，关联被更正的原记录所在链上交易
+// 更正记录按当天入账，超过审批阈值的支出不能作为更正记录提交
+func CorrectFundRecord(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var correctReq models.CorrectRecord
+	if err := c.ShouldBindJSON(&correctReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	amount, err := dbMod.ParseMoney(correctReq.Amount)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的金额不合法:" + correctReq.Amount})
+		return
+	}
+	if correctReq.Type != dbMod.EntryTypeIncome && correctReq.Type != dbMod.EntryTypeExpense {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的记录类型不合法:" + correctReq.Type})
+		return
+	}
+	if correctReq.OriginalTxID == "" || correctReq.Date != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "更正记录需指定原记录交易ID，且不能指定记录日期"})
+		return
+	}
+	if _, err := dbMod.GetFundByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "款项不存在:" + id})
+		return
+	}
+	if correctReq.InfoHash != "" {
+		if _, err := dbMod.GetAttachmentByHash(correctReq.InfoHash); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "证明文件不存在:" + correctReq.InfoHash})
+			return
+		}
+	}
+	if correctReq.Type == dbMod.EntryTypeExpense {
+		policy, err := fabric.GetApprovalPolicy()
+		if err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "获取审批策略失败:" + err.Error()})
+			return
+		}
+		if policy.RequiresApproval(int64(amount)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "支出超过审批阈值，请按添加记录提交审批"})
+			return
+		}
+	}
+	entry := dbMod.JournalEntry{
+		EntryID:   uuid.New().String(),
+		FundID:    id,
+		Type:      correctReq.Type,
+		Source:    correctReq.Source,
+		Explain:   correctReq.Explain,
+		Category:  dbMod.CategoryOf(correctReq.Category),
+		InfoHash:  correctReq.InfoHash,
+		Reference: correctReq.Reference,
+		Corrects:  correctReq.OriginalTxID,
+		Recorder:  c.MustGet("userId").(string),
+		EntryTime: utils.GetNowTimeString(),
+	}
+	var totals fabric.FinancialTotals
+	warning, err := dbMod.PostFundRecord(&entry, amount, func() error {
+		var err error
+		totals, err = fabric.CorrectFinancialRecord(id, entry.Corrects, entry.Type, entry.Source, entry.Explain, amount.String(), entry.InfoHash, entry.Category, entry.Recorder)
+		return err
+	})
+	if err != nil && strings.Contains(err.Error(), "not found in") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "原记录不存在:" + err.Error()})
+		return
+	}
+	if err != nil {
+		status, msg := fundRecordError(err)
+		c.JSON(status, gin.H{"error": msg + ":" + err.Error()})
+		return
+	}
+	if warning != nil {
+		c.JSON(http.StatusOK, gin.H{"data": "添加更正记录成功，科目已超出预算", "totals": totals, "warning": warning})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "添加更正记录成功", "totals": totals})
+}
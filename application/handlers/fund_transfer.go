@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// TransferFund 款项间划转，转出与转入的分录和链上记录共用划转ID，任一步失败时整体回滚
+func TransferFund(c *gin.Context) {
+	var transferReq models.FundTransfer
+	if err := c.ShouldBindJSON(&transferReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	amount, err := dbMod.ParseMoney(transferReq.Amount)
+	if err != nil || amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的金额不合法:" + transferReq.Amount})
+		return
+	}
+	if transferReq.From == transferReq.To {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "转出与转入款项不能相同"})
+		return
+	}
+	for _, id := range []string{transferReq.From, transferReq.To} {
+		if _, err := dbMod.GetFundByID(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "款项不存在:" + id})
+			return
+		}
+	}
+	userId := c.MustGet("userId").(string)
+	transferID := uuid.New().String()
+	var transfer fabric.FundTransfer
+	err = dbMod.PostFundTransfer(transferID, transferReq.From, transferReq.To, amount, transferReq.Explain, userId, utils.GetNowTimeString(), func() error {
+		var err error
+		transfer, err = fabric.Transfer(transferID, transferReq.From, transferReq.To, amount.String(), transferReq.Explain, userId)
+		return err
+	})
+	if err != nil && isInsufficientBalance(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "余额不足:" + err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "款项划转失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": transfer})
+}
+
+// GetFundTransfer 查询划转的链上记录与两条分录
+func GetFundTransfer(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	transfer, err := fabric.GetTransfer(id)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取划转记录失败:" + err.Error()})
+		return
+	}
+	entries, err := dbMod.GetTransferEntries(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取划转分录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": transfer, "entries": entries})
+}
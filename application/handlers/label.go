@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"community-governance/application/label"
+	"community-governance/application/models"
+	"community-governance/application/qrcode"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+// labelItem 查询标签对象的名称与位置
+func labelItem(kind, id string) (label.Item, error) {
+	switch kind {
+	case label.KindAsset:
+		asset, err := dbMod.GetAssetByID(id)
+		if err != nil {
+			return label.Item{}, err
+		}
+		return label.Item{Kind: kind, ID: id, Name: asset.Name, Location: asset.Location}, nil
+	case label.KindFacility:
+		facility, err := dbMod.GetFacilityByID(id)
+		if err != nil {
+			return label.Item{}, err
+		}
+		return label.Item{Kind: kind, ID: id, Name: facility.Name, Location: facility.Location}, nil
+	}
+	return label.Item{}, errors.New("unknown label kind " + kind)
+}
+
+func labelErrorStatus(err error) int {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// GetLabel 生成单个资产或设施的二维码标签，format为png或svg，size为图像边长的像素数
+func GetLabel(c *gin.Context) {
+	item, err := labelItem(c.Param("kind"), c.Param("id"))
+	if err != nil {
+		c.JSON(labelErrorStatus(err), gin.H{"error": "获取标签对象失败:" + err.Error()})
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", "300"))
+	if err != nil || size < 50 || size > 2000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的尺寸不合法:" + c.Query("size")})
+		return
+	}
+	code, err := qrcode.Encode(label.Link(item.Kind, item.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败:" + err.Error()})
+		return
+	}
+	fileName := "label-" + item.Kind + "-" + item.ID
+	switch c.DefaultQuery("format", "png") {
+	case "png":
+		data, err := code.PNG(max(size/(code.Size+2*qrcode.Border), 1))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败:" + err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", "inline; filename="+fileName+".png")
+		c.Data(http.StatusOK, "image/png", data)
+	case "svg":
+		c.Header("Content-Disposition", "inline; filename="+fileName+".svg")
+		c.Data(http.StatusOK, "image/svg+xml", code.SVG(size))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的格式不合法:" + c.Query("format")})
+	}
+}
+
+// GetLabelSheet 批量生成A4标签纸PDF
+func GetLabelSheet(c *gin.Context) {
+	var sheetReq models.LabelSheet
+	if err := c.ShouldBindJSON(&sheetReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	items := make([]label.Item, 0, len(sheetReq.Items))
+	for _, req := range sheetReq.Items {
+		item, err := labelItem(req.Kind, req.ID)
+		if err != nil {
+			c.JSON(labelErrorStatus(err), gin.H{"error": "获取标签对象失败:" + req.ID + ":" + err.Error()})
+			return
+		}
+		items = append(items, item)
+	}
+	data, err := label.Sheet(items)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成标签纸失败:" + err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=labels.pdf")
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+// ScanLabel 公开的扫码接口，校验标签签名后返回对象信息与链上状态，以及可进行的操作
+func ScanLabel(c *gin.Context) {
+	kind, id := c.Query("t"), c.Query("id")
+	if !label.Verify(kind, id, c.Query("sig")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "标签签名无效"})
+		return
+	}
+	item, err := labelItem(kind, id)
+	if err != nil {
+		c.JSON(labelErrorStatus(err), gin.H{"error": "获取标签对象失败:" + err.Error()})
+		return
+	}
+	result := gin.H{
+		"kind":     item.Kind,
+		"id":       item.ID,
+		"name":     item.Name,
+		"location": item.Location,
+	}
+	actions := gin.H{"report": label.ScanPath + "/fault"}
+	var chain interface{}
+	if kind == label.KindAsset {
+		var asset fabric.Asset
+		asset, err = fabric.GetAsset(id)
+		chain = gin.H{"status": asset.Status, "reason": asset.Reason, "record_date": asset.RecordDate, "work_order": asset.WorkOrder}
+	} else {
+		var facility fabric.Facility
+		facility, err = fabric.GetFacility(id)
+		chain = gin.H{"status": facility.State, "update_date": facility.UpdateDate, "work_order": facility.WorkOrder}
+		if err == nil && facility.State == "available" {
+			actions["book"] = "/api/v1/facilities/reserve/" + id
+		}
+	}
+	//链上查询失败时仍返回基本信息，verified为false表示状态未经链上核实
+	result["verified"] = err == nil
+	if err == nil {
+		result["chain"] = chain
+	} else {
+		result["chain_error"] = err.Error()
+	}
+	result["actions"] = actions
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// ReportFault 扫码后提交故障报告，需登录，标签签名用于确认对象来自实物标签
+func ReportFault(c *gin.Context) {
+	var faultReq models.ReportFault
+	if err := c.ShouldBindJSON(&faultReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	if !label.Verify(faultReq.Kind, faultReq.ID, faultReq.Sig) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "标签签名无效"})
+		return
+	}
+	if _, err := labelItem(faultReq.Kind, faultReq.ID); err != nil {
+		c.JSON(labelErrorStatus(err), gin.H{"error": "获取标签对象失败:" + err.Error()})
+		return
+	}
+	report := dbMod.FaultReport{
+		ReportID:    uuid.New().String(),
+		TargetType:  faultReq.Kind,
+		TargetID:    faultReq.ID,
+		Description: faultReq.Description,
+		Reporter:    c.MustGet("userId").(string),
+		Status:      dbMod.FaultStatusOpen,
+		CreateTime:  utils.GetNowTimeString(),
+	}
+	if err := dbMod.CreateFaultReport(&report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交故障报告失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// GetFaultReportAllPage 分页查询故障报告，可按target_id、status筛选
+func GetFaultReportAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	reports, err := dbMod.GetFaultReportsWithPagination(c.Query("target_id"), c.Query("status"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取故障报告失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(reports, page))
+}
+
+// HandleFaultReport 登记故障报告的处理结果
+func HandleFaultReport(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var handleReq models.HandleFault
+	if err := c.ShouldBindJSON(&handleReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	report, err := dbMod.GetFaultReportByID(id)
+	if err != nil {
+		c.JSON(labelErrorStatus(err), gin.H{"error": "获取故障报告失败:" + err.Error()})
+		return
+	}
+	report.Status = dbMod.FaultStatusHandled
+	report.Handler = c.MustGet("userId").(string)
+	report.Result = handleReq.Result
+	report.HandleTime = utils.GetNowTimeString()
+	if err := dbMod.HandleFaultReport(report); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, dbMod.ErrFaultHandled) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": "处理故障报告失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
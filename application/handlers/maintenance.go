@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"community-governance/application/middleware"
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+func maintenanceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrInvalidPlan), errors.Is(err, dbMod.ErrInvalidWorkEntry), isInsufficientBalance(err),
+		isClosedPeriod(err), errors.Is(err, dbMod.ErrBudgetExceeded):
+		return http.StatusBadRequest
+	case errors.Is(err, dbMod.ErrWorkOrderState), errors.Is(err, dbMod.ErrWorkEntryLinked), errors.Is(err, dbMod.ErrTargetUnavailable):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return chainErrorStatus(err)
+}
+
+// checkMaintenancePlan 校验维护计划的排期、维护对象、负责人与默认款项
+func checkMaintenancePlan(c *gin.Context, plan dbMod.MaintenancePlan) bool {
+	if plan.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "维护内容不能为空"})
+		return false
+	}
+	if err := dbMod.CheckMaintenancePlan(plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "维护计划不合法:" + err.Error()})
+		return false
+	}
+	var err error
+	if plan.TargetType == dbMod.MaintenanceTargetAsset {
+		_, err = dbMod.GetAssetByID(plan.TargetID)
+	} else {
+		_, err = dbMod.GetFacilityByID(plan.TargetID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "维护对象不存在:" + plan.TargetID})
+		return false
+	}
+	if plan.Assignee != "" {
+		if _, err := dbMod.GetMemberByID(plan.Assignee); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "负责人不存在:" + plan.Assignee})
+			return false
+		}
+	}
+	if plan.FundID != "" {
+		if _, err := dbMod.GetFundByID(plan.FundID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "款项不存在:" + plan.FundID})
+			return false
+		}
+	}
+	return true
+}
+
+// AddMaintenancePlan 为资产或设施创建预防性维护计划
+func AddMaintenancePlan(c *gin.Context) {
+	var planReq models.MaintenancePlan
+	if err := c.ShouldBindJSON(&planReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	plan := dbMod.MaintenancePlan{
+		PlanID:       uuid.New().String(),
+		TargetType:   planReq.TargetType,
+		TargetID:     planReq.TargetID,
+		Title:        planReq.Title,
+		Description:  planReq.Description,
+		Mode:         planReq.Mode,
+		IntervalDays: planReq.IntervalDays,
+		Cycle:        planReq.Cycle,
+		NextDue:      planReq.NextDue,
+		Assignee:     planReq.Assignee,
+		FundID:       planReq.FundID,
+		Status:       dbMod.MaintenancePlanStatusActive,
+		Creator:      c.MustGet("userId").(string),
+		CreateTime:   utils.GetNowTimeString(),
+	}
+	if !checkMaintenancePlan(c, plan) {
+		return
+	}
+	if err := dbMod.CreateMaintenancePlan(&plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建维护计划失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plan})
+}
+
+func GetMaintenancePlanDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	plan, err := dbMod.GetMaintenancePlanByID(id)
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "获取维护计划失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plan})
+}
+
+// GetMaintenancePlanAllPage 分页查询维护计划，可按target_id筛选
+func GetMaintenancePlanAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	plans, err := dbMod.GetMaintenancePlansWithPagination(c.Query("target_id"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取维护计划失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(plans, page))
+}
+
+// UpdateMaintenancePlan 更新维护计划，维护对象不能修改
+func UpdateMaintenancePlan(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var planReq models.MaintenancePlan
+	if err := c.ShouldBindJSON(&planReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	plan, err := dbMod.GetMaintenancePlanByID(id)
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "获取维护计划失败:" + err.Error()})
+		return
+	}
+	plan.Title = planReq.Title
+	plan.Description = planReq.Description
+	plan.Mode = planReq.Mode
+	plan.IntervalDays = planReq.IntervalDays
+	plan.Cycle = planReq.Cycle
+	plan.NextDue = planReq.NextDue
+	plan.Assignee = planReq.Assignee
+	plan.FundID = planReq.FundID
+	if planReq.Status != "" {
+		plan.Status = planReq.Status
+	}
+	if plan.Status != dbMod.MaintenancePlanStatusActive && plan.Status != dbMod.StatusArchived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的状态不合法:" + plan.Status})
+		return
+	}
+	if !checkMaintenancePlan(c, *plan) {
+		return
+	}
+	if err := dbMod.UpdateMaintenancePlan(plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新维护计划失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plan})
+}
+
+func DeleteMaintenancePlan(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	if err := dbMod.DeleteMaintenancePlan(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除维护计划失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "删除维护计划成功"})
+}
+
+// GenerateWorkOrders 为已到期的维护计划生成工单，计划已有未完成的工单时跳过
+func GenerateWorkOrders(c *gin.Context) {
+	var generateReq models.GenerateWorkOrders
+	if err := c.ShouldBindJSON(&generateReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	now := utils.GetNowTimeString()
+	if generateReq.AsOf == "" {
+		generateReq.AsOf = now[:10]
+	}
+	if _, err := time.Parse("2006-01-02", generateReq.AsOf); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的日期不合法:" + generateReq.AsOf})
+		return
+	}
+	orders, err := dbMod.GenerateWorkOrders(generateReq.AsOf, now, func() string { return uuid.New().String() })
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成维护工单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": orders, "total": len(orders)})
+}
+
+func GetWorkOrderDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	order, err := dbMod.GetWorkOrderByID(id)
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "获取维护工单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+// GetWorkOrderAllPage 分页查询维护工单，可按status、assignee与target_id筛选
+func GetWorkOrderAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	orders, err := dbMod.GetWorkOrdersWithPagination(c.Query("status"), c.Query("assignee"), c.Query("target_id"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取维护工单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(orders, page))
+}
+
+// GetMyWorkOrders 分页查询指派给当前用户的维护工单，可按status筛选
+func GetMyWorkOrders(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	orders, err := dbMod.GetWorkOrdersWithPagination(c.Query("status"), c.MustGet("userId").(string), "", page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取维护工单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(orders, page))
+}
+
+// AssignWorkOrder 指派待开工工单的负责人
+func AssignWorkOrder(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var assignReq models.AssignWorkOrder
+	if err := c.ShouldBindJSON(&assignReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	if _, err := dbMod.GetMemberByID(assignReq.Assignee); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "负责人不存在:" + assignReq.Assignee})
+		return
+	}
+	order, err := dbMod.AssignWorkOrder(id, assignReq.Assignee)
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "指派维护工单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+// StartWorkOrder 开工，维护对象在链上转入维护状态，期间资产不能变更、设施不能借用
+func StartWorkOrder(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	userId := c.MustGet("userId").(string)
+	order, err := dbMod.StartWorkOrder(id, utils.GetNowTimeString(), func(order *dbMod.WorkOrder) error {
+		if order.TargetType == dbMod.MaintenanceTargetAsset {
+			return fabric.StartAssetMaintenance(order.TargetID, order.OrderID, order.Assignee, "维护工单:"+order.Title, userId)
+		}
+		return fabric.StartFacilityMaintenance(order.TargetID, order.OrderID, order.Assignee, userId)
+	})
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "维护工单开工失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order})
+}
+
+// endMaintenance 结束链上维护状态，链上已不在该工单的维护中时跳过，以便上次提交失败后重试
+func endMaintenance(order *dbMod.WorkOrder, reason, operator string) error {
+	if order.TargetType == dbMod.MaintenanceTargetAsset {
+		asset, err := fabric.GetAsset(order.TargetID)
+		if err != nil {
+			return err
+		}
+		if asset.WorkOrder != order.OrderID {
+			return nil
+		}
+		return fabric.EndAssetMaintenance(order.TargetID, order.OrderID, reason, operator)
+	}
+	facility, err := fabric.GetFacility(order.TargetID)
+	if err != nil {
+		return err
+	}
+	if facility.WorkOrder != order.OrderID {
+		return nil
+	}
+	return fabric.EndFacilityMaintenance(order.TargetID, order.OrderID, operator)
+}
+
+// CompleteWorkOrder 完成工单，只有负责人或业委会成员可以操作
+// 维护费用可新记一笔支出(需财务负责人操作)，或关联已入账的支出，例如经审批后入账的大额支出
+func CompleteWorkOrder(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var completeReq models.CompleteWorkOrder
+	if err := c.ShouldBindJSON(&completeReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	order, err := dbMod.GetWorkOrderByID(id)
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "获取维护工单失败:" + err.Error()})
+		return
+	}
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "获取用户角色失败:" + err.Error()})
+		return
+	}
+	if userId != order.Assignee && member.Type != middleware.RoleAdmin && member.Type != middleware.RoleCommittee {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有工单负责人或业委会成员可以完成工单"})
+		return
+	}
+	now := utils.GetNowTimeString()
+	completion := dbMod.WorkOrderCompletion{Result: completeReq.Result, CompleteTime: now, EntryID: completeReq.EntryID}
+	if completeReq.Cost != "" {
+		entry, cost, ok := workOrderExpense(c, order, completeReq, member.Type, now)
+		if !ok {
+			return
+		}
+		completion.Entry = entry
+		completion.Cost = cost
+	}
+	var totals *fabric.FinancialTotals
+	order, warning, err := dbMod.CompleteWorkOrder(id, completion, func(order *dbMod.WorkOrder) error {
+		if err := endMaintenance(order, "维护完成:"+order.Title, userId); err != nil {
+			return err
+		}
+		entry := completion.Entry
+		if entry == nil {
+			return nil
+		}
+		result, err := fabric.AddFinancialRecord(entry.FundID, entry.Type, entry.Source, entry.Explain, entry.Recorder,
+			completion.Cost.String(), entry.InfoHash, entry.Category, entry.EntryTime[:10])
+		totals = &result
+		return err
+	})
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "完成维护工单失败:" + err.Error()})
+		return
+	}
+	if warning != nil {
+		c.JSON(http.StatusOK, gin.H{"data": order, "totals": totals, "warning": warning})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order, "totals": totals})
+}
+
+// workOrderExpense 校验维护费用并生成支出分录，超过审批阈值的支出需先审批入账后再关联
+func workOrderExpense(c *gin.Context, order *dbMod.WorkOrder, completeReq models.CompleteWorkOrder, role, now string) (*dbMod.JournalEntry, dbMod.Money, bool) {
+	if completeReq.EntryID != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "维护费用与关联支出只能二选一"})
+		return nil, 0, false
+	}
+	if role != middleware.RoleAdmin && role != middleware.RoleTreasurer {
+		c.JSON(http.StatusForbidden, gin.H{"error": "新记维护费用需财务负责人操作，请关联已入账的支出"})
+		return nil, 0, false
+	}
+	cost, err := dbMod.ParseMoney(completeReq.Cost)
+	if err != nil || cost <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的金额不合法:" + completeReq.Cost})
+		return nil, 0, false
+	}
+	fundID := completeReq.FundID
+	if fundID == "" {
+		if plan, err := dbMod.GetMaintenancePlanByID(order.PlanID); err == nil {
+			fundID = plan.FundID
+		}
+	}
+	if _, err := dbMod.GetFundByID(fundID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "款项不存在:" + fundID})
+		return nil, 0, false
+	}
+	if completeReq.InfoHash != "" {
+		if _, err := dbMod.GetAttachmentByHash(completeReq.InfoHash); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "证明文件不存在:" + completeReq.InfoHash})
+			return nil, 0, false
+		}
+	}
+	policy, err := fabric.GetApprovalPolicy()
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取审批策略失败:" + err.Error()})
+		return nil, 0, false
+	}
+	if policy.RequiresApproval(int64(cost)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "支出超过审批阈值，请先提交支出审批，入账后关联该支出"})
+		return nil, 0, false
+	}
+	entry := &dbMod.JournalEntry{
+		EntryID:   uuid.New().String(),
+		FundID:    fundID,
+		Type:      dbMod.EntryTypeExpense,
+		Source:    order.TargetID,
+		Explain:   "维护工单:" + order.Title,
+		Category:  dbMod.MaintenanceCategory,
+		InfoHash:  completeReq.InfoHash,
+		Recorder:  c.MustGet("userId").(string),
+		EntryTime: now,
+	}
+	return entry, cost, true
+}
+
+// CancelWorkOrder 取消工单，维护中的对象在链上恢复使用
+func CancelWorkOrder(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var cancelReq models.CancelWorkOrder
+	if err := c.ShouldBindJSON(&cancelReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	order, err := dbMod.CancelWorkOrder(id, cancelReq.Reason, utils.GetNowTimeString(), func(order *dbMod.WorkOrder) error {
+		return endMaintenance(order, "维护取消:"+cancelReq.Reason, userId)
+	})
+	if err != nil {
+		c.JSON(maintenanceErrorStatus(err), gin.H{"error": "取消维护工单失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": order})
+}
@@ -1,12 +1,13 @@
 package handlers
 
 import (
+	"community-governance/application/middleware"
 	"community-governance/application/models"
 	dbMod "community-governance/db/models"
+	"community-governance/fabric"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
-	"strconv"
 )
 
 const (
@@ -48,12 +49,48 @@ func AddMember(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加成员失败:" + err.Error()})
 		return
 	}
+	//为成员登记链上身份，交易将以成员自己的证书签名
+	err = fabric.EnrollMember(member.MemberID, member.Type, member.HouseholdID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "成员已添加，登记链上身份失败:" + err.Error(), "member_id": member.MemberID})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"data": "添加成员成功"})
 }
 
+// EnrollMember 为已有成员(重新)登记链上身份
+func EnrollMember(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	member, err := dbMod.GetMemberByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败:" + err.Error()})
+		return
+	}
+	err = fabric.EnrollMember(member.MemberID, member.Type, member.HouseholdID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登记链上身份失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "登记链上身份成功"})
+}
+
 func GetMember(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
+	//非管理员只能查看自己的信息
+	userId := c.MustGet("userId").(string)
+	if id != userId {
+		operator, err := dbMod.GetMemberByID(userId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+			return
+		}
+		if operator.Type != middleware.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能查看自己的成员信息"})
+			return
+		}
+	}
 	member, err := dbMod.GetMemberByID(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败:" + err.Error()})
@@ -65,23 +102,16 @@ func GetMember(c *gin.Context) {
 // GetMemberAllPage 获取分页获取所有成员信息
 func GetMemberAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	members, err := dbMod.GetAllMembersWithPagination(iPage, iPageSize)
+	members, err := dbMod.GetAllMembersWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": members, "total": len(members)})
+	c.JSON(http.StatusOK, pageResult(members, page))
 }
 
 // UpdateMember 更新成员
@@ -92,11 +122,28 @@ func UpdateMember(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
+	previous, err := dbMod.GetMemberByID(member.MemberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败:" + err.Error()})
+		return
+	}
 	err = dbMod.UpdateMember(member.MemberID, &member)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新成员失败:" + err.Error()})
 		return
 	}
+	updated, err := dbMod.GetMemberByID(member.MemberID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败:" + err.Error()})
+		return
+	}
+	//角色或户号变更后重新登记，旧证书被吊销
+	if updated.State == MemberStateActive && (updated.Type != previous.Type || updated.HouseholdID != previous.HouseholdID) {
+		if err := fabric.EnrollMember(updated.MemberID, updated.Type, updated.HouseholdID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "成员已更新，重新登记链上身份失败:" + err.Error(), "member_id": updated.MemberID})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{"data": "更新成员成功"})
 }
 
@@ -109,6 +156,11 @@ func DeleteMember(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除成员失败:" + err.Error()})
 		return
 	}
+	//吊销证书，删除的成员不能再以其身份签名交易
+	if err := fabric.RevokeMember(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "成员已删除，吊销链上身份失败:" + err.Error(), "member_id": id})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"data": "删除成员成功"})
 }
 
@@ -117,6 +169,10 @@ func UpdateMemberState(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
 	state := c.Query("state")
+	if state != MemberStateActive && state != MemberStateInactive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的状态不合法:" + state})
+		return
+	}
 	updateMap := map[string]interface{}{
 		"state": state,
 	}
@@ -125,37 +181,40 @@ func UpdateMemberState(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新成员状态失败:" + err.Error()})
 		return
 	}
+	//停用时吊销证书，重新启用时重新登记
+	if state == MemberStateInactive {
+		err = fabric.RevokeMember(id)
+	} else {
+		var member *dbMod.Member
+		member, err = dbMod.GetMemberByID(id)
+		if err == nil {
+			err = fabric.EnrollMember(member.MemberID, member.Type, member.HouseholdID)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "成员状态已更新，同步链上身份失败:" + err.Error(), "member_id": id})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"data": "更新成员状态成功"})
 }
 
 // GetMemberByConditions 获取基于条件获取成员信息
 func GetMemberByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	if page == "" || pageSize == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page和pageSize参数不合法"})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	members, err := dbMod.GetAllMembersWithConditions(conditions, iPage, iPageSize)
+	members, err := dbMod.GetAllMembersWithConditions(query, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员失败:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取成员失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": members, "total": len(members)})
+	c.JSON(http.StatusOK, pageResult(members, page))
 }
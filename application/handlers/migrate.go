@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// MigrateFundTotals 迁移链上收支汇总上线前创建的款项，链上余额取本地资金账户余额，已迁移的款项跳过
+func MigrateFundTotals(c *gin.Context) {
+	ids, err := dbMod.GetAllFundIDs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取款项失败:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	migrated := make(map[string]fabric.FinancialTotals)
+	for _, id := range ids {
+		financial, err := fabric.GetFinancial(id)
+		if err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上款项失败:" + err.Error(), "fund_id": id, "migrated": migrated})
+			return
+		}
+		if financial.Version >= fabric.FinancialTotalsVersion {
+			continue
+		}
+		balance, err := dbMod.GetAccountBalance(dbMod.FundAccountID(id, dbMod.AccountTypeAsset))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取款项余额失败:" + err.Error(), "fund_id": id, "migrated": migrated})
+			return
+		}
+		totals, err := fabric.MigrateFinancialTotals(id, balance.String(), userId)
+		if err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "迁移链上汇总失败:" + err.Error(), "fund_id": id, "migrated": migrated})
+			return
+		}
+		migrated[id] = totals
+	}
+	c.JSON(http.StatusOK, gin.H{"data": migrated})
+}
+
+// MigrateWorkflows 将审批流程同步到链上：各对象类型的生效流程定义写入链上，
+// 链上审批流程上线前发起、仍在审批中的实例按本地进度在链上恢复，已在链上的实例跳过
+func MigrateWorkflows(c *gin.Context) {
+	userId := c.MustGet("userId").(string)
+	kinds := make([]string, 0, len(dbMod.WorkflowKinds))
+	for kind := range dbMod.WorkflowKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		steps := workflowClients[kind].steps
+		definition, err := dbMod.GetWorkflowDefinition(kind)
+		switch {
+		case err == nil:
+			steps = definition.Steps
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审批流程失败:" + err.Error(), "kind": kind})
+			return
+		}
+		if err := fabric.SetWorkflowDefinition(kind, chainSteps(steps), userId); err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "同步链上审批流程失败:" + err.Error(), "kind": kind})
+			return
+		}
+	}
+	instances, err := dbMod.GetPendingWorkflowInstances()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审批中的流程失败:" + err.Error()})
+		return
+	}
+	restored := make([]string, 0)
+	for _, instance := range instances {
+		_, found, err := fabric.GetWorkflowInstance(instance.InstanceID)
+		if err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上审批流程失败:" + err.Error(), "instance_id": instance.InstanceID, "restored": restored})
+			return
+		}
+		if found {
+			continue
+		}
+		chain := fabric.WorkflowInstance{
+			InstanceID: instance.InstanceID,
+			Kind:       instance.Kind,
+			TargetID:   instance.TargetID,
+			Requester:  instance.Requester,
+			Stage:      instance.Stage,
+			StageStart: stageStart(&instance),
+			Status:     instance.Status,
+			Tasks:      chainTasks(instance.Tasks),
+		}
+		if _, err := fabric.RestoreWorkflowInstance(chain, instance.DecidedStages(), userId); err != nil {
+			c.JSON(chainErrorStatus(err), gin.H{"error": "恢复链上审批流程失败:" + err.Error(), "instance_id": instance.InstanceID, "restored": restored})
+			return
+		}
+		restored = append(restored, instance.InstanceID)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"kinds": kinds, "restored": restored}})
+}
+
+// stageStart 实例当前阶段的开始时间，本地时间转换为链上使用的UTC时间
+func stageStart(instance *dbMod.WorkflowInstance) string {
+	start := instance.CreateTime
+	for _, task := range instance.Tasks {
+		if task.Stage == instance.Stage && task.StartTime != "" {
+			start = task.StartTime
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", start, time.Local)
+	if err != nil {
+		return start
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
-	"strconv"
 )
 
 func AddNotice(c *gin.Context) {
@@ -28,6 +27,7 @@ func AddNotice(c *gin.Context) {
 		Author:      userId,
 		PublishTime: utils.GetNowTimeString(),
 		Version:     1,
+		Status:      dbMod.NoticeStatusPublished,
 	}
 	err = dbMod.CreateNotice(notice)
 	if err != nil {
@@ -42,7 +42,7 @@ func AddNotice(c *gin.Context) {
 	}
 	err = fabric.CreateNotice(noticeId, hash, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建公告失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "创建公告失败:" + err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "添加公告成功"})
@@ -62,7 +62,7 @@ func GetNoticeDetail(c *gin.Context) {
 	}
 	history, err := fabric.GetNoticeHistory(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取公告历史失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取公告历史失败:" + err.Error()})
 		return
 	}
 	detail.Base = *notice
@@ -71,54 +71,36 @@ func GetNoticeDetail(c *gin.Context) {
 }
 func GetNoticeAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	notices, err := dbMod.GetAllNoticesWithPagination(iPage, iPageSize)
+	notices, err := dbMod.GetAllNoticesWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取公告失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": notices, "total": len(notices)})
+	c.JSON(http.StatusOK, pageResult(notices, page))
 }
 
 func GetNoticeByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	if page == "" && pageSize == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page和pageSize参数不合法:"})
-		return
-	}
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	notices, err := dbMod.GetAllNoticesWithConditions(iPage, iPageSize, conditions)
+	notices, err := dbMod.GetAllNoticesWithConditions(page, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取公告失败:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取公告失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": notices, "total": len(notices)})
+	c.JSON(http.StatusOK, pageResult(notices, page))
 
 }
 func UpdateNotice(c *gin.Context) {
@@ -130,6 +112,8 @@ func UpdateNotice(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
+	//公告状态只能通过归档修改
+	noticeReq.Status = ""
 	err = dbMod.UpdateNotice(id, noticeReq)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败:" + err.Error()})
@@ -144,7 +128,7 @@ func UpdateNotice(c *gin.Context) {
 	userId := c.MustGet("userId").(string)
 	err = fabric.UpdateNotice(id, hash, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公告失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "更新公告失败:" + err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "更新公告成功"})
@@ -152,6 +136,9 @@ func UpdateNotice(c *gin.Context) {
 func DeleteNotice(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
+	if rejectUndeletable(c, "notice", id, "删除公告") {
+		return
+	}
 	err := dbMod.DeleteNotice(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除公告失败:" + err.Error()})
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// parsePage 解析page与pageSize参数，不合法时返回400
+func parsePage(c *gin.Context) (*dbMod.Page, bool) {
+	page, err := dbMod.NewPage(c.Query("page"), c.Query("pageSize"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的分页参数不合法:" + err.Error()})
+		return nil, false
+	}
+	return page, true
+}
+
+// pageResult 分页查询的返回数据，total为满足条件的总条数
+func pageResult(data interface{}, page *dbMod.Page) gin.H {
+	return gin.H{"data": data, "total": page.Total, "page": page.Page, "pageSize": page.PageSize, "hasNext": page.HasNext}
+}
+
+// parseCursor 解析游标分页的cursor与limit参数，不合法时返回400
+func parseCursor(c *gin.Context) (string, int, bool) {
+	limit, err := dbMod.NewCursorLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的limit参数不合法:" + err.Error()})
+		return "", 0, false
+	}
+	return c.Query("cursor"), limit, true
+}
+
+// cursorResult 游标分页的返回数据，nextCursor用于请求下一页
+func cursorResult(data interface{}, nextCursor string, hasNext bool, limit int) gin.H {
+	return gin.H{"data": data, "nextCursor": nextCursor, "hasNext": hasNext, "limit": limit}
+}
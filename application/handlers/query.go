@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	dbMod "community-governance/db/models"
+	"errors"
+	"net/http"
+)
+
+// queryErrorStatus 查询条件或游标不合法时返回400，其余错误返回500
+func queryErrorStatus(err error) int {
+	if errors.Is(err, dbMod.ErrInvalidQuery) || errors.Is(err, dbMod.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
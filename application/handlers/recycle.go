@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// checkDeletable 检查记录能否删除，已上链或存在关联数据的记录只能归档，返回拒绝原因
+func checkDeletable(kind, id string) (string, error) {
+	switch kind {
+	case fabric.AnchorVote:
+		hasRecords, err := fabric.HasVoteRecords(id)
+		if err != nil {
+			return "", err
+		}
+		if hasRecords {
+			return "投票已有成员参与", nil
+		}
+	case fabric.AnchorFund:
+		hasRecords, err := fabric.HasFinancialRecords(id)
+		if err != nil {
+			return "", err
+		}
+		if hasRecords {
+			return "款项已有收支记录", nil
+		}
+	case "vote_rule":
+		count, err := dbMod.CountVotesByRule(id)
+		if err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return "投票规则已被投票使用", nil
+		}
+		return "", nil
+	}
+	anchored, err := fabric.IsAnchored(kind, id)
+	if err != nil {
+		return "", err
+	}
+	if anchored {
+		return "记录已上链", nil
+	}
+	return "", nil
+}
+
+// rejectUndeletable 记录不能删除时返回错误信息，返回true表示已拒绝
+func rejectUndeletable(c *gin.Context, kind, id, action string) bool {
+	reason, err := checkDeletable(kind, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": action + "失败:" + err.Error()})
+		return true
+	}
+	if reason != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": action + "失败:" + reason + "，不能删除，请改为归档"})
+		return true
+	}
+	return false
+}
+
+// RestoreRecord 恢复被删除的记录
+func RestoreRecord(c *gin.Context) {
+	kind, id := c.Param("type"), c.Param("id")
+	if err := dbMod.RestoreRecord(kind, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "恢复记录成功"})
+}
+
+// ArchiveRecord 归档记录，归档后记录仍保留以便核验链上hash
+func ArchiveRecord(c *gin.Context) {
+	kind, id := c.Param("type"), c.Param("id")
+	if err := dbMod.ArchiveRecord(kind, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "归档记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "归档记录成功"})
+}
+
+// GetDeletedRecords 分页获取已删除的记录
+func GetDeletedRecords(c *gin.Context) {
+	//获取page和pageSize
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	records, err := dbMod.GetDeletedRecords(c.Param("type"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取已删除记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(records, page))
+}
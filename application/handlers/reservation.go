@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"community-governance/application/middleware"
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	"community-governance/application/waitlist"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarDays 未指定结束日期时日历查询的天数
+const calendarDays = 7
+
+// household 成员的户号，未登记户号时以成员ID计算配额，与链上一致
+func household(member *dbMod.Member) string {
+	if member.HouseholdID != "" {
+		return member.HouseholdID
+	}
+	return member.MemberID
+}
+
+func reservationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrInvalidReservation), errors.Is(err, dbMod.ErrNoSchedule):
+		return http.StatusBadRequest
+	case errors.Is(err, dbMod.ErrReservationDenied), errors.Is(err, dbMod.ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, dbMod.ErrReservationConflict), errors.Is(err, dbMod.ErrReservationState), errors.Is(err, dbMod.ErrSlotAvailable):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return chainErrorStatus(err)
+}
+
+// SetFacilitySchedule 设置设施开放时间与预约规则，同步上链
+func SetFacilitySchedule(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var scheduleReq models.FacilitySchedule
+	if err := c.ShouldBindJSON(&scheduleReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	days := make([]string, 0, len(scheduleReq.Days))
+	for _, day := range scheduleReq.Days {
+		days = append(days, strconv.Itoa(day))
+	}
+	userId := c.MustGet("userId").(string)
+	schedule := dbMod.FacilitySchedule{
+		FacilityID:  id,
+		Open:        scheduleReq.Open,
+		Close:       scheduleReq.Close,
+		Days:        strings.Join(days, ","),
+		SlotMinutes: scheduleReq.SlotMinutes,
+		CancelHours: scheduleReq.CancelHours,
+		Updater:     userId,
+		UpdateTime:  utils.GetNowTimeString(),
+	}
+	err := dbMod.SaveFacilitySchedule(&schedule, func() error {
+		_, err := fabric.SetFacilitySchedule(fabric.FacilitySchedule{
+			FacilityID:  schedule.FacilityID,
+			Open:        schedule.Open,
+			Close:       schedule.Close,
+			Days:        schedule.Days,
+			SlotMinutes: schedule.SlotMinutes,
+			CancelHours: schedule.CancelHours,
+		}, userId)
+		return err
+	})
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "设置开放时间失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": schedule})
+}
+
+func GetFacilitySchedule(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	schedule, err := dbMod.GetFacilitySchedule(id)
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "获取开放时间失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": schedule})
+}
+
+// ReserveFacility 预约设施时段，以预约人身份上链
+func ReserveFacility(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var reserveReq models.ReserveFacility
+	if err := c.ShouldBindJSON(&reserveReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	now := utils.GetNowTimeString()
+	reservation := dbMod.FacilityReservation{
+		ReservationID: uuid.New().String(),
+		FacilityID:    id,
+		Member:        userId,
+		Household:     household(member),
+		Start:         reserveReq.Start,
+		End:           reserveReq.End,
+		Purpose:       reserveReq.Purpose,
+		CreateTime:    now,
+	}
+	err = dbMod.CreateReservation(&reservation, now, func() error {
+		recorded, err := fabric.ReserveFacility(reservation.ReservationID, reservation.FacilityID, reservation.Start, reservation.End, userId)
+		if err != nil {
+			return err
+		}
+		reservation.TxID = recorded.TxID
+		return nil
+	})
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "预约设施失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": reservation})
+}
+
+// CancelFacilityReservation 取消预约，预约人须在取消截止时间之前取消，业委会成员可在结束前随时取消
+// 空出的时段由链上提供给候补，取消后同步候补状态
+func CancelFacilityReservation(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var cancelReq models.CancelReservation
+	if err := c.ShouldBindJSON(&cancelReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	override := member.Type == middleware.RoleCommittee || member.Type == middleware.RoleAdmin
+	reservation, err := dbMod.CancelReservation(id, userId, cancelReq.Reason, utils.GetNowTimeString(), override, func(reservation *dbMod.FacilityReservation) error {
+		//重试时链上可能已取消
+		recorded, err := fabric.GetReservation(id)
+		if err != nil || recorded.Status == dbMod.ReservationStatusCancelled {
+			return err
+		}
+		_, err = fabric.CancelReservation(id, cancelReq.Reason, userId)
+		return err
+	})
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "取消预约失败:" + err.Error()})
+		return
+	}
+	//链上已将空出的时段提供给候补
+	if err := waitlist.Sync(reservation.FacilityID, reservation.Date); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "预约已取消，同步候补失败:" + err.Error(), "reservation_id": id})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": reservation})
+}
+
+// GetReservationDetail 查询预约及链上记录
+func GetReservationDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	reservation, err := dbMod.GetReservationByID(id)
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "获取预约失败:" + err.Error()})
+		return
+	}
+	recorded, err := fabric.GetReservation(id)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上预约失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": reservation, "chain": recorded})
+}
+
+// GetFacilityCalendar 查询设施在from至to日期内的预约日历，默认为当天起7天，可按status筛选
+func GetFacilityCalendar(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	from := c.DefaultQuery("from", utils.GetNowTimeString()[:10])
+	to := c.Query("to")
+	if to == "" {
+		first, err := time.Parse(dbMod.ReservationDate, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "传入的日期不合法:" + from})
+			return
+		}
+		to = first.AddDate(0, 0, calendarDays-1).Format(dbMod.ReservationDate)
+	}
+	reservations, err := dbMod.GetFacilityCalendar(id, from, to, c.Query("status"))
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "获取预约日历失败:" + err.Error()})
+		return
+	}
+	schedule, err := dbMod.GetFacilitySchedule(id)
+	if err != nil && !errors.Is(err, dbMod.ErrNoSchedule) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取开放时间失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"from":         from,
+		"to":           to,
+		"schedule":     schedule,
+		"reservations": reservations,
+	}})
+}
+
+// GetMyReservations 分页查询当前用户的预约，可按status筛选
+func GetMyReservations(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	reservations, err := dbMod.GetReservationsWithPagination(c.MustGet("userId").(string), "", c.Query("status"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预约失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(reservations, page))
+}
+
+// GetReservationAllPage 分页查询预约，可按member、facility_id、status筛选
+func GetReservationAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	reservations, err := dbMod.GetReservationsWithPagination(c.Query("member"), c.Query("facility_id"), c.Query("status"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预约失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(reservations, page))
+}
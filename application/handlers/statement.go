@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"community-governance/application/report"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// noticeTypeStatement 发布收支报表hash的公告类型
+const noticeTypeStatement = "statement"
+
+// buildStatement 生成款项指定期间的收支报表及其hash，失败时已返回错误信息
+func buildStatement(c *gin.Context, id string) (*report.Statement, string, bool) {
+	period := c.Query("period")
+	if _, _, err := report.ParsePeriod(period); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的报表期间不合法:" + period})
+		return nil, "", false
+	}
+	fund, err := dbMod.GetFundByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取款项失败:" + err.Error()})
+		return nil, "", false
+	}
+	detail, err := fabric.GetFinancialDetail(id)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上收支记录失败:" + err.Error()})
+		return nil, "", false
+	}
+	statement, err := report.BuildStatement(*fund, detail, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成收支报表失败:" + err.Error()})
+		return nil, "", false
+	}
+	hash, err := utils.ComputeHash(statement)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算报表hash失败:" + err.Error()})
+		return nil, "", false
+	}
+	return statement, hash, true
+}
+
+// GetFundStatement 获取款项的月度或年度收支报表，format为json、csv或pdf
+func GetFundStatement(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	statement, hash, ok := buildStatement(c, id)
+	if !ok {
+		return
+	}
+	fileName := fmt.Sprintf("statement-%s-%s", id, statement.Period)
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, gin.H{"data": statement, "hash": hash})
+	case "csv":
+		data, err := statement.CSV(hash)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成CSV报表失败:" + err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".csv")
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	case "pdf":
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".pdf")
+		c.Data(http.StatusOK, "application/pdf", statement.PDF(hash))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的报表格式:" + c.Query("format")})
+	}
+}
+
+// PublishFundStatement 将收支报表的hash发布为公告并上链，居民可重新生成报表核对hash
+func PublishFundStatement(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	statement, hash, ok := buildStatement(c, id)
+	if !ok {
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	notice := dbMod.Notice{
+		NoticeID: uuid.New().String(),
+		Title:    fmt.Sprintf("%s %s 收支报表", statement.FundName, statement.Period),
+		Content: fmt.Sprintf("期初余额%s元，收入%s元，支出%s元，期末余额%s元，共%d笔链上收支记录。报表hash(SHA-256)：%s，"+
+			"可通过 /api/v1/fund/statement/%s?period=%s 重新生成报表核对。",
+			statement.OpeningBalance, statement.TotalIncome, statement.TotalExpense, statement.ClosingBalance,
+			len(statement.TxIDs), hash, id, statement.Period),
+		Type:        noticeTypeStatement,
+		Author:      userId,
+		PublishTime: utils.GetNowTimeString(),
+		Version:     1,
+		Status:      dbMod.NoticeStatusPublished,
+	}
+	if err := dbMod.CreateNotice(notice); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发布报表公告失败:" + err.Error()})
+		return
+	}
+	noticeHash, err := utils.ComputeHash(notice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算公告hash失败:" + err.Error()})
+		return
+	}
+	if err := fabric.CreateNotice(notice.NoticeID, noticeHash, userId); err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "报表公告上链失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"notice_id": notice.NoticeID, "hash": hash, "statement": statement}})
+}
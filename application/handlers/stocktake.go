@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func stocktakeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrStocktakeClosed):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return chainErrorStatus(err)
+}
+
+// assetCode 解析扫码内容，二维码内容为链接时取其中的id参数或最后一段路径，否则视为资产ID
+func assetCode(code string) string {
+	code = strings.TrimSpace(code)
+	link, err := url.Parse(code)
+	if err != nil || link.Scheme == "" {
+		return code
+	}
+	if id := link.Query().Get("id"); id != "" {
+		return id
+	}
+	return link.Path[strings.LastIndex(link.Path, "/")+1:]
+}
+
+// OpenStocktake 发起某一位置的资产盘点
+func OpenStocktake(c *gin.Context) {
+	var openReq models.OpenStocktake
+	if err := c.ShouldBindJSON(&openReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	stocktake := dbMod.Stocktake{
+		StocktakeID: uuid.New().String(),
+		Location:    openReq.Location,
+		Status:      dbMod.StocktakeStatusOpen,
+		Opener:      c.MustGet("userId").(string),
+		OpenTime:    utils.GetNowTimeString(),
+	}
+	if err := dbMod.CreateStocktake(&stocktake); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发起盘点失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": stocktake})
+}
+
+func GetStocktakeDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	stocktake, err := dbMod.GetStocktakeByID(id)
+	if err != nil {
+		c.JSON(stocktakeErrorStatus(err), gin.H{"error": "获取盘点失败:" + err.Error()})
+		return
+	}
+	scans, err := dbMod.GetStocktakeScans(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取清点记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": stocktake, "scans": scans})
+}
+
+// GetStocktakeAllPage 分页查询盘点，可按location、status筛选
+func GetStocktakeAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	stocktakes, err := dbMod.GetStocktakesWithPagination(c.Query("location"), c.Query("status"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取盘点失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(stocktakes, page))
+}
+
+// ScanStocktake 清点资产，可扫资产标签二维码或直接输入资产ID
+func ScanStocktake(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var scanReq models.ScanStocktake
+	if err := c.ShouldBindJSON(&scanReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	code := assetCode(scanReq.Code)
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无法识别的资产编码:" + scanReq.Code})
+		return
+	}
+	scan, err := dbMod.ScanStocktake(dbMod.StocktakeScan{
+		StocktakeID: id,
+		Code:        code,
+		Scanner:     c.MustGet("userId").(string),
+		ScanTime:    utils.GetNowTimeString(),
+		Note:        scanReq.Note,
+	})
+	if err != nil {
+		c.JSON(stocktakeErrorStatus(err), gin.H{"error": "清点失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scan, "registered": scan.AssetID != ""})
+}
+
+// GetStocktakeReport 盘点报告，列出盘亏、位置不符与未登记的物品
+func GetStocktakeReport(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	report, err := dbMod.GetStocktakeReport(id)
+	if err != nil {
+		c.JSON(stocktakeErrorStatus(err), gin.H{"error": "获取盘点报告失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// CloseStocktake 结束盘点，盘点报告的hash值与汇总数由结束人签名上链
+func CloseStocktake(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	operator := c.MustGet("userId").(string)
+	report, err := dbMod.CloseStocktake(id, operator, utils.GetNowTimeString(),
+		func(report *dbMod.StocktakeReport) (string, error) {
+			return utils.ComputeHash(report)
+		},
+		func(stocktake *dbMod.Stocktake) error {
+			_, err := fabric.CloseStocktake(fabric.Stocktake{
+				StocktakeID:  stocktake.StocktakeID,
+				Location:     stocktake.Location,
+				ReportHash:   stocktake.ReportHash,
+				Counted:      stocktake.Counted,
+				Missing:      stocktake.Missing,
+				Misplaced:    stocktake.Misplaced,
+				Unregistered: stocktake.Unregistered,
+			}, operator)
+			return err
+		})
+	if err != nil {
+		c.JSON(stocktakeErrorStatus(err), gin.H{"error": "结束盘点失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// VerifyStocktake 校验已结束盘点保存的报告与链上记录的hash值是否一致
+func VerifyStocktake(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	report, err := dbMod.GetStocktakeReport(id)
+	if err != nil {
+		c.JSON(stocktakeErrorStatus(err), gin.H{"error": "获取盘点报告失败:" + err.Error()})
+		return
+	}
+	if report.Stocktake.Status != dbMod.StocktakeStatusClosed {
+		c.JSON(http.StatusConflict, gin.H{"error": "盘点尚未结束"})
+		return
+	}
+	anchored, err := fabric.GetStocktake(id)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上盘点记录失败:" + err.Error()})
+		return
+	}
+	saved := report.Stocktake.ReportHash
+	report.Stocktake.ReportHash = ""
+	hash, err := utils.ComputeHash(report)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算盘点报告hash失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"verified":    hash == saved && hash == anchored.ReportHash,
+		"report_hash": hash,
+		"chain":       anchored,
+	}})
+}
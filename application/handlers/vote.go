@@ -8,7 +8,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
-	"strconv"
 	"strings"
 )
 
@@ -79,9 +78,10 @@ func AddVoteProject(c *gin.Context) {
 		return
 	}
 	//调用合约
-	err = fabric.CreatVote(voteId, hash, rule.RuleType, rule.RuleValue, strings.Join(optionsStr, ","))
+	userId := c.MustGet("userId").(string)
+	err = fabric.CreatVote(voteId, hash, rule.RuleType, rule.RuleValue, strings.Join(optionsStr, ","), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "调用合约失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "调用合约失败:" + err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "添加投票成功"})
@@ -128,7 +128,7 @@ func GetVoteDetail(c *gin.Context) {
 	detail.Options = options
 	chainDetail, err := fabric.GetVoteRecordDetail(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投票详情失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取投票详情失败:" + err.Error()})
 		return
 	}
 	detail.ChainVoteDetail = chainDetail
@@ -137,28 +137,24 @@ func GetVoteDetail(c *gin.Context) {
 
 func GetVoteAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	votes, err := dbMod.GetVoteAllWithPagination(iPage, iPageSize)
+	votes, err := dbMod.GetVoteAllWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投票失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": votes, "total": len(votes)})
+	c.JSON(http.StatusOK, pageResult(votes, page))
 }
 
 func DeleteVote(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
+	if rejectUndeletable(c, "vote", id, "删除投票") {
+		return
+	}
 	err := dbMod.DeleteVote(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除投票失败:" + err.Error()})
@@ -185,29 +181,22 @@ func UpdateVoteState(c *gin.Context) {
 
 func GetVoteByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	votes, err := dbMod.GetVoteAllWithConditions(conditions, iPage, iPageSize)
+	votes, err := dbMod.GetVoteAllWithConditions(query, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投票失败:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取投票失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": votes, "total": len(votes)})
+	c.JSON(http.StatusOK, pageResult(votes, page))
 }
 
 // VoteJoin 参与投票
@@ -224,7 +213,7 @@ func VoteJoin(c *gin.Context) {
 	userId := c.MustGet("userId").(string)
 	result, err := fabric.VoteJoin(id, userId, option)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "参与投票失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "参与投票失败:" + err.Error()})
 		return
 	}
 	//若不为空，则说明投票结束
@@ -247,7 +236,7 @@ func VoteEnd(c *gin.Context) {
 	id := c.Param("id")
 	result, err := fabric.EndVote(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "结束投票失败:" + err.Error()})
+		c.JSON(chainErrorStatus(err), gin.H{"error": "结束投票失败:" + err.Error()})
 		return
 	}
 	//更新投票状态
@@ -262,3 +251,19 @@ func VoteEnd(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"data": "结束投票成功"})
 }
+
+// GetVoteRecords 游标分页获取投票记录，游标为链上分页书签
+func GetVoteRecords(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	cursor, limit, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+	page, err := fabric.GetVoteRecordPage(id, cursor, limit)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取投票记录失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cursorResult(page.Records, page.NextCursor, page.HasNext, limit))
+}
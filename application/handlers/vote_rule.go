@@ -7,7 +7,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
-	"strconv"
 )
 
 func AddVoteRule(c *gin.Context) {
@@ -67,6 +66,9 @@ func UpdateVoteRule(c *gin.Context) {
 func DeleteVoteRule(c *gin.Context) {
 	//获取路径id值
 	id := c.Param("id")
+	if rejectUndeletable(c, "vote_rule", id, "删除投票规则") {
+		return
+	}
 	err := dbMod.DeleteVoteRule(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除投票规则失败:" + err.Error()})
@@ -77,55 +79,37 @@ func DeleteVoteRule(c *gin.Context) {
 
 func GetVoteRulesByConditions(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
-		return
-	}
-	var conditions map[string]interface{}
-	err = c.ShouldBind(&conditions)
+	var query dbMod.Query
+	err := c.ShouldBind(&query)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
 		return
 	}
-	voteRules, err := dbMod.GetVoteRulesAllWithConditions(conditions, iPage, iPageSize)
+	voteRules, err := dbMod.GetVoteRulesAllWithConditions(query, page)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投票规则失败:" + err.Error()})
+		c.JSON(queryErrorStatus(err), gin.H{"error": "获取投票规则失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": voteRules, "total": len(voteRules)})
+	c.JSON(http.StatusOK, pageResult(voteRules, page))
 
 }
 
 func GetVoteRulesAllPage(c *gin.Context) {
 	//获取page和pageSize
-	page, pageSize := c.Query("page"), c.Query("pageSize")
-	if page == "" || pageSize == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page和page size参数不合法"})
-		return
-	}
-	iPage, err := strconv.Atoi(page)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page参数不合法:" + err.Error()})
-		return
-	}
-	iPageSize, err := strconv.Atoi(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的page size参数不合法:" + err.Error()})
+	page, ok := parsePage(c)
+	if !ok {
 		return
 	}
-	voteRules, err := dbMod.GetVoteRulesAllWithPagination(iPage, iPageSize)
+	voteRules, err := dbMod.GetVoteRulesAllWithPagination(page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投票规则失败:" + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": voteRules, "total": len(voteRules)})
+	c.JSON(http.StatusOK, pageResult(voteRules, page))
 }
 func GetVoteNames(c *gin.Context) {
 	voteNames, err := dbMod.GetVoteRuleNames()
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"community-governance/application/middleware"
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	"community-governance/application/waitlist"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// SetFacilityPolicy 设置设施预约政策，同步上链
+func SetFacilityPolicy(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var policyReq models.FacilityPolicy
+	if err := c.ShouldBindJSON(&policyReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	policy := dbMod.FacilityPolicy{
+		FacilityID:     id,
+		MaxWeeklyHours: policyReq.MaxWeeklyHours,
+		AdvanceDays:    policyReq.AdvanceDays,
+		MaxActive:      policyReq.MaxActive,
+		ConfirmMinutes: policyReq.ConfirmMinutes,
+		Updater:        userId,
+		UpdateTime:     utils.GetNowTimeString(),
+	}
+	err := dbMod.SaveFacilityPolicy(&policy, func() error {
+		_, err := fabric.SetFacilityPolicy(fabric.FacilityPolicy{
+			FacilityID:     policy.FacilityID,
+			MaxWeeklyHours: policy.MaxWeeklyHours,
+			AdvanceDays:    policy.AdvanceDays,
+			MaxActive:      policy.MaxActive,
+			ConfirmMinutes: policy.ConfirmMinutes,
+		}, userId)
+		return err
+	})
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "设置预约政策失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": policy})
+}
+
+func GetFacilityPolicy(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	policy, err := dbMod.GetFacilityPolicy(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预约政策失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": policy})
+}
+
+// JoinFacilityWaitlist 候补已被预约的时段，以候补人身份上链
+func JoinFacilityWaitlist(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var joinReq models.ReserveFacility
+	if err := c.ShouldBindJSON(&joinReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	now := utils.GetNowTimeString()
+	entry := dbMod.FacilityWaitlist{
+		EntryID:    uuid.New().String(),
+		FacilityID: id,
+		Member:     userId,
+		Household:  household(member),
+		Start:      joinReq.Start,
+		End:        joinReq.End,
+		Purpose:    joinReq.Purpose,
+		CreateTime: now,
+	}
+	err = dbMod.JoinWaitlist(&entry, now, func() error {
+		recorded, err := fabric.JoinWaitlist(entry.EntryID, entry.FacilityID, entry.Start, entry.End, userId)
+		if err != nil {
+			return err
+		}
+		//排队顺序以链上加入时间为准
+		entry.CreateTime = recorded.CreateDate
+		return nil
+	})
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "加入候补失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entry})
+}
+
+// LeaveFacilityWaitlist 退出候补，业委会成员可代为退出，放弃的时段由链上提供给后续候补
+func LeaveFacilityWaitlist(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	userId := c.MustGet("userId").(string)
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	override := member.Type == middleware.RoleCommittee || member.Type == middleware.RoleAdmin
+	entry, err := dbMod.LeaveWaitlist(id, userId, override, func(entry *dbMod.FacilityWaitlist) error {
+		//重试时链上可能已退出
+		recorded, err := fabric.GetWaitEntry(id)
+		if err != nil || recorded.Status == dbMod.WaitStatusWithdrawn {
+			return err
+		}
+		_, err = fabric.LeaveWaitlist(id, userId)
+		return err
+	})
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "退出候补失败:" + err.Error()})
+		return
+	}
+	if err := waitlist.Sync(entry.FacilityID, entry.Date); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "已退出候补，同步候补失败:" + err.Error(), "entry_id": id})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entry})
+}
+
+// ConfirmFacilityWaitlist 候补人在确认时限内确认提供的时段，生成预约
+func ConfirmFacilityWaitlist(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	userId := c.MustGet("userId").(string)
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	now := utils.GetNowTimeString()
+	reservation := dbMod.FacilityReservation{
+		ReservationID: uuid.New().String(),
+		Member:        userId,
+		Household:     household(member),
+		CreateTime:    now,
+	}
+	entry, err := dbMod.ConfirmWaitOffer(id, userId, &reservation, now, func() error {
+		//重试时链上可能已确认，沿用链上生成的预约
+		recorded, err := fabric.GetWaitEntry(id)
+		if err != nil {
+			return err
+		}
+		if recorded.Status == dbMod.WaitStatusConfirmed {
+			reservation.ReservationID = recorded.ReservationID
+		} else if _, err := fabric.ConfirmWaitOffer(id, reservation.ReservationID, userId); err != nil {
+			return err
+		}
+		booked, err := fabric.GetReservation(reservation.ReservationID)
+		if err != nil {
+			return err
+		}
+		reservation.TxID = booked.TxID
+		return nil
+	})
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), gin.H{"error": "确认候补失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entry, "reservation": reservation})
+}
+
+// GetFacilityWaitlist 查询设施某天的候补，默认为当天，可按status筛选
+func GetFacilityWaitlist(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	date := c.DefaultQuery("date", utils.GetNowTimeString()[:10])
+	entries, err := dbMod.GetFacilityWaitlist(id, date, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取候补失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": entries})
+}
+
+// GetMyWaitlist 分页查询当前用户的候补，可按status筛选
+func GetMyWaitlist(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	entries, err := dbMod.GetWaitlistWithPagination(c.MustGet("userId").(string), "", c.Query("status"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取候补失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(entries, page))
+}
+
+// GetWaitlistAllPage 分页查询候补，可按member、facility_id、status筛选
+func GetWaitlistAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	entries, err := dbMod.GetWaitlistWithPagination(c.Query("member"), c.Query("facility_id"), c.Query("status"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取候补失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(entries, page))
+}
@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"community-governance/application/middleware"
+	"community-governance/application/models"
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// workflowClient 使用审批流程的业务，流程结束时在审批事务中执行对应操作
+type workflowClient struct {
+	steps    []dbMod.WorkflowStep // 未配置审批流程时使用的默认步骤
+	approved func(instance *dbMod.WorkflowInstance, operator string) error
+	rejected func(instance *dbMod.WorkflowInstance, operator string) error
+}
+
+var workflowClients = map[string]workflowClient{
+	dbMod.WorkflowKindAssetRequest: {
+		steps:    []dbMod.WorkflowStep{{Stage: 1, Name: "业委会审核", Role: middleware.RoleCommittee, Required: 1}},
+		approved: approveAssetRequest,
+		rejected: rejectAssetRequest,
+	},
+}
+
+// workflowRoles 可以作为审批角色的成员类型
+var workflowRoles = map[string]bool{
+	middleware.RoleAdmin:      true,
+	middleware.RoleTreasurer:  true,
+	middleware.RoleCommittee:  true,
+	middleware.RoleSupervisor: true,
+}
+
+func workflowErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbMod.ErrInvalidWorkflow), errors.Is(err, dbMod.ErrNoWorkflow):
+		return http.StatusBadRequest
+	case errors.Is(err, dbMod.ErrWorkflowDenied):
+		return http.StatusForbidden
+	case errors.Is(err, dbMod.ErrWorkflowState), errors.Is(err, dbMod.ErrWorkflowDecided):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return chainErrorStatus(err)
+}
+
+// startWorkflow 为审批对象发起审批流程，target不为nil时同时创建审批对象
+// 实例以operator身份在链上按链上的流程定义发起，链上步骤与本地不一致时发起失败
+func startWorkflow(kind, targetID, title, requester, operator string, target interface{}) (*dbMod.WorkflowInstance, error) {
+	instance := dbMod.WorkflowInstance{
+		InstanceID: uuid.New().String(),
+		Kind:       kind,
+		TargetID:   targetID,
+		Title:      title,
+		Requester:  requester,
+		CreateTime: utils.GetNowTimeString(),
+	}
+	err := dbMod.StartWorkflow(&instance, workflowClients[kind].steps, target, func(instance *dbMod.WorkflowInstance) error {
+		chain, err := fabric.StartWorkflowInstance(instance.InstanceID, instance.Kind, instance.TargetID, instance.Requester, operator)
+		if err != nil {
+			return err
+		}
+		return checkChainTasks(instance, chain)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// checkChainTasks 核对链上实例的步骤与本地一致，不一致说明链上流程定义未同步
+func checkChainTasks(instance *dbMod.WorkflowInstance, chain fabric.WorkflowInstance) error {
+	tasks := chainTasks(instance.Tasks)
+	if len(tasks) != len(chain.Tasks) {
+		return fmt.Errorf("workflow definition of %s on chain has %d steps, local has %d", instance.Kind, len(chain.Tasks), len(tasks))
+	}
+	for i := range tasks {
+		if tasks[i].TaskID != chain.Tasks[i].TaskID || tasks[i].Step != chain.Tasks[i].Step {
+			return fmt.Errorf("workflow definition of %s on chain differs from local at step %s", instance.Kind, tasks[i].TaskID)
+		}
+	}
+	return nil
+}
+
+// chainSteps 转换为链上的审批步骤定义
+func chainSteps(steps []dbMod.WorkflowStep) []fabric.WorkflowStep {
+	result := make([]fabric.WorkflowStep, len(steps))
+	for i, step := range steps {
+		result[i] = fabric.WorkflowStep{
+			Stage:         step.Stage,
+			Role:          step.Role,
+			Required:      step.Required,
+			DeadlineHours: step.DeadlineHours,
+			EscalateRole:  step.EscalateRole,
+		}
+	}
+	return result
+}
+
+// chainTasks 转换为链上的实例步骤
+func chainTasks(tasks []dbMod.WorkflowTask) []fabric.WorkflowTask {
+	result := make([]fabric.WorkflowTask, len(tasks))
+	for i, task := range tasks {
+		result[i] = fabric.WorkflowTask{
+			TaskID: task.TaskID,
+			Step: fabric.WorkflowStep{
+				Stage:         task.Stage,
+				Role:          task.Role,
+				Required:      task.Required,
+				DeadlineHours: task.Hours,
+				EscalateRole:  task.EscalateRole,
+			},
+			Approvals: task.Approvals,
+			Status:    task.Status,
+		}
+	}
+	return result
+}
+
+// canDecide 成员可审批的步骤，步骤升级后升级角色也可审批，管理员可审批所有步骤
+func canDecide(memberType string) func(*dbMod.WorkflowTask) (string, bool) {
+	return func(task *dbMod.WorkflowTask) (string, bool) {
+		if task.Role == memberType || (task.Escalated && task.EscalateRole == memberType) || memberType == middleware.RoleAdmin {
+			return memberType, true
+		}
+		return "", false
+	}
+}
+
+func commentHash(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(comment)))
+}
+
+// anchorDecision 以审批人身份将审批决定上链
+// 审批事务回滚后重试时链上可能已有该审批人对该步骤的决定，此时沿用链上记录
+func anchorDecision(instance *dbMod.WorkflowInstance, task *dbMod.WorkflowTask, decision *dbMod.WorkflowDecision) error {
+	recorded, err := fabric.GetWorkflowDecisions(instance.InstanceID)
+	if err != nil {
+		return err
+	}
+	for _, r := range recorded {
+		if r.TaskID != task.TaskID || r.Approver != decision.Approver {
+			continue
+		}
+		if r.Decision != decision.Decision || r.CommentHash != commentHash(decision.Comment) {
+			return fmt.Errorf("%w: %s", dbMod.ErrWorkflowDecided, task.TaskID)
+		}
+		decision.DecisionID = r.DecisionID
+		decision.TxID = r.TxID
+		return nil
+	}
+	r, err := fabric.RecordWorkflowDecision(fabric.WorkflowDecision{
+		DecisionID:  decision.DecisionID,
+		InstanceID:  instance.InstanceID,
+		TaskID:      task.TaskID,
+		Decision:    decision.Decision,
+		CommentHash: commentHash(decision.Comment),
+	}, decision.Approver)
+	if err != nil {
+		return err
+	}
+	decision.TxID = r.TxID
+	return nil
+}
+
+// decideWorkflow 审批人批准或驳回审批流程，流程结束时执行业务的后续操作
+func decideWorkflow(instanceID, taskID, approver, decision, comment string) (*dbMod.WorkflowInstance, error) {
+	member, err := dbMod.GetMemberByID(approver)
+	if err != nil {
+		return nil, err
+	}
+	record := dbMod.WorkflowDecision{
+		DecisionID: uuid.New().String(),
+		InstanceID: instanceID,
+		TaskID:     taskID,
+		Approver:   approver,
+		Decision:   decision,
+		Comment:    comment,
+		DecideTime: utils.GetNowTimeString(),
+	}
+	return dbMod.DecideWorkflowTask(&record, canDecide(member.Type), anchorDecision, func(instance *dbMod.WorkflowInstance) error {
+		client, ok := workflowClients[instance.Kind]
+		if !ok {
+			return nil
+		}
+		if instance.Status == dbMod.WorkflowStatusApproved {
+			return client.approved(instance, approver)
+		}
+		return client.rejected(instance, approver)
+	})
+}
+
+// checkWorkflowDefinition 校验审批流程的对象类型与审批角色
+func checkWorkflowDefinition(c *gin.Context, kind string, steps []dbMod.WorkflowStep) bool {
+	if !dbMod.WorkflowKinds[kind] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "审批对象类型不合法:" + kind})
+		return false
+	}
+	for _, step := range steps {
+		if !workflowRoles[step.Role] || (step.EscalateRole != "" && !workflowRoles[step.EscalateRole]) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "审批角色不合法:" + step.Name})
+			return false
+		}
+	}
+	if err := dbMod.CheckWorkflowSteps(steps); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "审批步骤不合法:" + err.Error()})
+		return false
+	}
+	return true
+}
+
+// GetWorkflowDefinitions 查询已配置的审批流程
+func GetWorkflowDefinitions(c *gin.Context) {
+	definitions, err := dbMod.GetWorkflowDefinitions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审批流程失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": definitions})
+}
+
+// GetWorkflowDefinition 查询对象类型的审批流程，未配置时返回默认流程
+func GetWorkflowDefinition(c *gin.Context) {
+	kind := c.Param("kind")
+	definition, err := dbMod.GetWorkflowDefinition(kind)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if client, ok := workflowClients[kind]; ok {
+			c.JSON(http.StatusOK, gin.H{"data": dbMod.WorkflowDefinition{Kind: kind, Steps: client.steps}, "default": true})
+			return
+		}
+	}
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "获取审批流程失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": definition, "default": false})
+}
+
+// SaveWorkflowDefinition 配置对象类型的审批流程并同步到链上，进行中的审批不受影响
+func SaveWorkflowDefinition(c *gin.Context) {
+	kind := c.Param("kind")
+	var definitionReq models.WorkflowDefinition
+	if err := c.ShouldBindJSON(&definitionReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	definition := dbMod.WorkflowDefinition{
+		Kind:       kind,
+		Name:       definitionReq.Name,
+		Updater:    c.MustGet("userId").(string),
+		UpdateTime: utils.GetNowTimeString(),
+	}
+	for _, step := range definitionReq.Steps {
+		definition.Steps = append(definition.Steps, dbMod.WorkflowStep{
+			Stage:         step.Stage,
+			Name:          step.Name,
+			Role:          step.Role,
+			Required:      step.Required,
+			DeadlineHours: step.DeadlineHours,
+			EscalateRole:  step.EscalateRole,
+		})
+	}
+	if !checkWorkflowDefinition(c, kind, definition.Steps) {
+		return
+	}
+	err := dbMod.SaveWorkflowDefinition(&definition, func(definition *dbMod.WorkflowDefinition) error {
+		return fabric.SetWorkflowDefinition(kind, chainSteps(definition.Steps), definition.Updater)
+	})
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "保存审批流程失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": definition})
+}
+
+// DeleteWorkflowDefinition 删除审批流程配置，之后发起的审批使用默认流程
+func DeleteWorkflowDefinition(c *gin.Context) {
+	kind := c.Param("kind")
+	err := dbMod.DeleteWorkflowDefinition(kind, func() error {
+		return fabric.SetWorkflowDefinition(kind, chainSteps(workflowClients[kind].steps), c.MustGet("userId").(string))
+	})
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "删除审批流程失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": "删除审批流程成功"})
+}
+
+func GetWorkflowInstanceDetail(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	instance, err := dbMod.GetWorkflowInstanceByID(id)
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "获取审批流程失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": instance})
+}
+
+// GetWorkflowInstanceAllPage 分页查询审批流程，可按kind、status、requester筛选
+func GetWorkflowInstanceAllPage(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	instances, err := dbMod.GetWorkflowInstancesWithPagination(c.Query("kind"), c.Query("status"), c.Query("requester"), page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审批流程失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(instances, page))
+}
+
+// GetWorkflowInstanceByTarget 查询审批对象最近一次的审批流程
+func GetWorkflowInstanceByTarget(c *gin.Context) {
+	instance, err := dbMod.GetWorkflowInstanceByTarget(c.Query("kind"), c.Query("target_id"))
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "获取审批流程失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": instance})
+}
+
+// GetMyWorkflowTasks 当前用户可审批的待审批步骤
+func GetMyWorkflowTasks(c *gin.Context) {
+	page, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	member, err := dbMod.GetMemberByID(c.MustGet("userId").(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	roles := []string{member.Type}
+	if member.Type == middleware.RoleAdmin {
+		roles = nil
+	}
+	tasks, err := dbMod.GetPendingWorkflowTasks(roles, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取待审批步骤失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pageResult(tasks, page))
+}
+
+// DecideWorkflow 批准或驳回审批步骤，决定以审批人身份上链
+func DecideWorkflow(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var decideReq models.DecideWorkflow
+	if err := c.ShouldBindJSON(&decideReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	instance, err := decideWorkflow(id, decideReq.TaskID, c.MustGet("userId").(string), decideReq.Decision, decideReq.Comment)
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "审批失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": instance})
+}
+
+// CommentWorkflow 发表审批意见，发起人、流程涉及角色的成员与管理员可以发表意见
+func CommentWorkflow(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	var commentReq models.WorkflowComment
+	if err := c.ShouldBindJSON(&commentReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "传入的参数不合法:" + err.Error()})
+		return
+	}
+	instance, err := dbMod.GetWorkflowInstanceByID(id)
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "获取审批流程失败:" + err.Error()})
+		return
+	}
+	userId := c.MustGet("userId").(string)
+	member, err := dbMod.GetMemberByID(userId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取成员信息失败:" + err.Error()})
+		return
+	}
+	allowed := userId == instance.Requester || member.Type == middleware.RoleAdmin
+	for _, task := range instance.Tasks {
+		allowed = allowed || task.Role == member.Type || task.EscalateRole == member.Type
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权对该审批发表意见"})
+		return
+	}
+	comment := dbMod.WorkflowDecision{
+		DecisionID: uuid.New().String(),
+		InstanceID: id,
+		Approver:   userId,
+		Comment:    commentReq.Comment,
+		DecideTime: utils.GetNowTimeString(),
+	}
+	if err := dbMod.AddWorkflowComment(&comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发表意见失败:" + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": comment})
+}
+
+// VerifyWorkflow 核对本地的审批决定与链上记录，审批意见按hash值核对
+func VerifyWorkflow(c *gin.Context) {
+	//获取路径id值
+	id := c.Param("id")
+	instance, err := dbMod.GetWorkflowInstanceByID(id)
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": "获取审批流程失败:" + err.Error()})
+		return
+	}
+	recorded, err := fabric.GetWorkflowDecisions(id)
+	if err != nil {
+		c.JSON(chainErrorStatus(err), gin.H{"error": "获取链上审批决定失败:" + err.Error()})
+		return
+	}
+	chain := make(map[string]fabric.WorkflowDecision, len(recorded))
+	for _, r := range recorded {
+		chain[r.DecisionID] = r
+	}
+	mismatched := make([]string, 0)
+	for _, decision := range instance.Decisions {
+		if decision.Decision == dbMod.DecisionComment {
+			continue
+		}
+		r, ok := chain[decision.DecisionID]
+		if !ok || r.TaskID != decision.TaskID || r.Approver != decision.Approver || r.Decision != decision.Decision ||
+			r.CommentHash != commentHash(decision.Comment) {
+			mismatched = append(mismatched, decision.DecisionID)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"verified":   len(mismatched) == 0,
+		"mismatched": mismatched,
+		"chain":      recorded,
+	}})
+}
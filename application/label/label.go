@@ -0,0 +1,136 @@
+package label
+
+import (
+	"community-governance/application/pdf"
+	"community-governance/application/qrcode"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+)
+
+// 标签对象类型，与维护对象类型一致
+const (
+	KindAsset    = "asset"
+	KindFacility = "facility"
+)
+
+// 未配置环境变量时使用的默认值
+const (
+	defaultBaseURL = "http://localhost:8080"
+	defaultSecret  = "community-label-key"
+)
+
+// ScanPath 公开扫码接口的路径
+const ScanPath = "/api/v1/scan"
+
+func baseURL() string {
+	if base := os.Getenv("LABEL_BASE_URL"); base != "" {
+		return base
+	}
+	return defaultBaseURL
+}
+
+func secret() []byte {
+	if key := os.Getenv("LABEL_SECRET"); key != "" {
+		return []byte(key)
+	}
+	return []byte(defaultSecret)
+}
+
+// Sign 对象类型与ID的签名，取HMAC-SHA256的前16字节，足以防止伪造标签且二维码不至于过密
+func Sign(kind, id string) string {
+	mac := hmac.New(sha256.New, secret())
+	mac.Write([]byte(kind + ":" + id))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Verify 校验标签链接中的签名
+func Verify(kind, id, sig string) bool {
+	expected, err := hex.DecodeString(Sign(kind, id))
+	if err != nil {
+		return false
+	}
+	actual, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, actual)
+}
+
+// Link 标签二维码中的深度链接，指向公开扫码接口
+func Link(kind, id string) string {
+	query := url.Values{}
+	query.Set("t", kind)
+	query.Set("id", id)
+	query.Set("sig", Sign(kind, id))
+	return baseURL() + ScanPath + "?" + query.Encode()
+}
+
+// Item 标签上打印的对象
+type Item struct {
+	Kind     string
+	ID       string
+	Name     string
+	Location string
+}
+
+// 标签纸的排版，A4纸3列8行，单位为点
+const (
+	sheetMargin  = 30.0
+	sheetColumns = 3
+	sheetRows    = 8
+	labelWidth   = (pdf.PageWidth - 2*sheetMargin) / sheetColumns
+	labelHeight  = (pdf.PageHeight - 2*sheetMargin) / sheetRows
+	qrSize       = 80.0
+)
+
+var kindNames = map[string]string{KindAsset: "资产", KindFacility: "公共设施"}
+
+// Sheet 生成A4标签纸，每个标签左侧为二维码，右侧为名称、位置与ID，超过一页时分页
+func Sheet(items []Item) ([]byte, error) {
+	doc := pdf.New()
+	for i, item := range items {
+		n := i % (sheetColumns * sheetRows)
+		if n == 0 {
+			doc.AddPage()
+		}
+		code, err := qrcode.Encode(Link(item.Kind, item.ID))
+		if err != nil {
+			return nil, err
+		}
+		x := sheetMargin + float64(n%sheetColumns)*labelWidth
+		y := sheetMargin + float64(n/sheetColumns)*labelHeight
+		top := y + (labelHeight-qrSize)/2
+		doc.Image(x+4, top, qrSize, qrSize, code.Image(1))
+		textX := x + qrSize + 8
+		textWidth := labelWidth - qrSize - 12
+		doc.Text(textX, top+14, 10, fit(item.Name, 10, textWidth))
+		doc.Text(textX, top+28, 7, fit(kindNames[item.Kind], 7, textWidth))
+		doc.Text(textX, top+40, 7, fit(item.Location, 7, textWidth))
+		// ID较长，按宽度折成多行
+		line := 0
+		for rest := item.ID; rest != "" && line < 3; line++ {
+			part := fit(rest, 7, textWidth)
+			if part == "" {
+				break
+			}
+			doc.Text(textX, top+56+float64(line)*9, 7, part)
+			rest = rest[len(part):]
+		}
+	}
+	return doc.Bytes(), nil
+}
+
+// fit 截取能放入宽度width的最长前缀
+func fit(text string, size, width float64) string {
+	var w float64
+	for i, r := range text {
+		w += pdf.TextWidth(size, string(r))
+		if w > width {
+			return text[:i]
+		}
+	}
+	return text
+}
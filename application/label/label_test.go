@@ -0,0 +1,27 @@
+package label
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestLinkVerify(t *testing.T) {
+	link, err := url.Parse(Link(KindFacility, "f-001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := link.Query()
+	if link.Path != ScanPath || query.Get("t") != KindFacility || query.Get("id") != "f-001" {
+		t.Fatalf("unexpected link %s", link)
+	}
+	if !Verify(KindFacility, "f-001", query.Get("sig")) {
+		t.Fatalf("signature of the link should verify")
+	}
+	// 签名与对象绑定，换成其他对象或类型都不能通过校验
+	if Verify(KindFacility, "f-002", query.Get("sig")) || Verify(KindAsset, "f-001", query.Get("sig")) {
+		t.Fatalf("signature should not verify for another item")
+	}
+	if Verify(KindFacility, "f-001", "not-hex") {
+		t.Fatalf("malformed signature should not verify")
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"community-governance/application/audit"
+	"community-governance/application/maintenance"
 	"community-governance/application/router"
+	"community-governance/application/waitlist"
+	"community-governance/application/workflow"
 	"community-governance/db"
+	dbMod "community-governance/db/models"
 	"log"
+	"time"
 )
 
 func main() {
@@ -12,6 +18,17 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to init db:%s", err.Error())
 	}
+	if err := dbMod.AutoMigrate(); err != nil {
+		log.Fatalf("failed to migrate db:%s", err.Error())
+	}
+	//定期将审计日志链头锚定到区块链
+	audit.StartAnchor(10 * time.Minute)
+	//定期为到期的维护计划生成工单
+	maintenance.StartSchedule(time.Hour)
+	//定期升级超过审批期限的审批步骤
+	workflow.StartEscalation(10 * time.Minute)
+	//定期将超过确认时限的候补时段提供给后续候补
+	waitlist.StartExpiry(time.Minute)
 	r := router.SetupRouter()
 
 	// 启动服务器
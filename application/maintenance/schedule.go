@@ -0,0 +1,32 @@
+package maintenance
+
+import (
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+// GenerateOnce 为截至当天已到期的维护计划生成工单
+func GenerateOnce() ([]dbMod.WorkOrder, error) {
+	now := utils.GetNowTimeString()
+	return dbMod.GenerateWorkOrders(now[:10], now, func() string { return uuid.New().String() })
+}
+
+// StartSchedule 定期为到期的维护计划生成工单
+func StartSchedule(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			orders, err := GenerateOnce()
+			if err != nil {
+				log.Printf("failed to generate work orders:%s", err.Error())
+				continue
+			}
+			if len(orders) > 0 {
+				log.Printf("generated %d work orders", len(orders))
+			}
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"community-governance/application/audit"
+	"community-governance/application/utils"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"strings"
+)
+
+// AuditLoader 根据ID加载操作对象，用于记录操作前后的数据
+type AuditLoader func(id string) (interface{}, error)
+
+// AuditLoad 将GetXxxByID形式的查询函数转换为AuditLoader
+func AuditLoad[T any](get func(string) (T, error)) AuditLoader {
+	return func(id string) (interface{}, error) {
+		return get(id)
+	}
+}
+
+// AuditMiddleware 审计中间件，记录操作人、操作、对象、前后数据差异、IP与时间
+// 路径中带有:id时通过loader读取操作前后的对象，否则以请求体作为操作后的数据
+// target为空时以路径中的:type作为操作对象
+func AuditMiddleware(action, target string, loader AuditLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetID := c.Param("id")
+		target := target
+		if target == "" {
+			target = c.Param("type")
+		}
+		var before, after interface{}
+		if targetID != "" && loader != nil {
+			if obj, err := loader(targetID); err == nil {
+				before = obj
+			}
+		}
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		c.Next()
+
+		if targetID != "" && loader != nil {
+			if obj, err := loader(targetID); err == nil {
+				after = obj
+			}
+		} else if json.Valid(body) {
+			after = json.RawMessage(body)
+		}
+		err := audit.Record(audit.Entry{
+			Actor:      auditActor(c),
+			Action:     action,
+			Target:     target,
+			TargetID:   targetID,
+			Before:     before,
+			After:      after,
+			IP:         c.ClientIP(),
+			StatusCode: c.Writer.Status(),
+		})
+		if err != nil {
+			log.Printf("failed to record audit log of %s:%s", action, err.Error())
+		}
+	}
+}
+
+// auditActor 获取操作人，未经过AuthMiddleware的路由尝试解析请求中的token
+func auditActor(c *gin.Context) string {
+	if userId := c.GetString("userId"); userId != "" {
+		return userId
+	}
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if tokenString == "" {
+		return "anonymous"
+	}
+	if claims, err := utils.ParseJWT(tokenString); err == nil {
+		return claims.UserId
+	}
+	return "anonymous"
+}
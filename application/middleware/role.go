@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// 成员角色，与成员类型(member.type)及链上证书的role属性取值一致
+const (
+	RoleAdmin      = "admin"      // 管理员
+	RoleTreasurer  = "treasurer"  // 财务负责人
+	RoleCommittee  = "committee"  // 业委会成员
+	RoleSupervisor = "supervisor" // 监事会成员
+)
+
+// RoleMiddleware 角色校验中间件，需在AuthMiddleware之后使用，管理员始终放行
+func RoleMiddleware(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.GetString("userId")
+		member, err := dbMod.GetMemberByID(userId)
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "获取用户角色失败:" + err.Error()})
+			c.Abort()
+			return
+		}
+		if member.Type != RoleAdmin && !containsRole(roles, member.Type) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "当前用户无权执行该操作"})
+			c.Abort()
+			return
+		}
+		c.Set("userRole", member.Type)
+		c.Next()
+	}
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
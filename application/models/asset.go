@@ -7,6 +7,16 @@ type CreateAsset struct {
 	Location     string `json:"location"`      // 资产位置
 	PurchaseDate string `json:"purchase_date"` // 购买日期
 	Owner        string `json:"owner"`         // 资产拥有者
+	Cost         string `json:"cost"`          // 购置成本
+	Residual     string `json:"residual"`      // 预计净残值
+	UsefulLife   int    `json:"useful_life"`   // 预计使用年限
+	Method       string `json:"method"`        // 折旧方法
+	FundID       string `json:"fund_id"`       // 购置款项
+}
+
+// RunDepreciation 计提年度折旧
+type RunDepreciation struct {
+	Year int `json:"year" binding:"required"` // 折旧年度
 }
 
 type CreateAssetRequest struct {
@@ -19,5 +29,30 @@ type CreateAssetRequest struct {
 	Location     string `json:"location"`       // 资产位置
 	RequestValue string ` json:"request_value"` // 申请传递得值
 	RequestType  string `json:"request_type"`   // 申请类型
-	Asset        string `json:"asset"`          // 资产
+	Asset        string `json:"asset"`          // 资产，处置、调拨与借用申请必填
+	Recipient    string `json:"recipient"`      // 调拨的新保管人
+	DueDate      string `json:"due_date"`       // 借用归还期限，格式为yyyy-MM-dd
+	Method       string `json:"method"`         // 处置方式
+	Proceeds     string `json:"proceeds"`       // 处置收入
+	FundID       string `json:"fund_id"`        // 处置收入入账的款项
+}
+
+// ReturnAsset 借出资产的归还验收
+type ReturnAsset struct {
+	Reason string `json:"reason"` // 验收说明
+}
+
+// ChangeAssetState 变更资产状态
+type ChangeAssetState struct {
+	State  string `json:"state" binding:"required"`  // 目标状态
+	Reason string `json:"reason" binding:"required"` // 变更原因
+}
+
+// AssetDepreciation 资产购置成本与折旧参数
+type AssetDepreciation struct {
+	Cost       string `json:"cost" binding:"required"` // 购置成本
+	Residual   string `json:"residual"`                // 预计净残值
+	UsefulLife int    `json:"useful_life"`             // 预计使用年限
+	Method     string `json:"method"`                  // 折旧方法，为空时不计提折旧
+	FundID     string `json:"fund_id"`                 // 购置款项
 }
@@ -0,0 +1,12 @@
+package models
+
+type ConfirmBankLine struct {
+	EntryID string `json:"entry_id"` //匹配的分录ID，为空时确认建议的匹配
+}
+
+type RecordBankLine struct {
+	Source   string `json:"source"`    //金额来源，为空时取对方户名
+	Explain  string `json:"explain"`   //说明，为空时取流水摘要
+	Category string `json:"category"`  //预算科目，为空时归入未分类
+	InfoHash string `json:"info_hash"` //证明文件SHA-256，需先上传附件
+}
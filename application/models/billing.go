@@ -0,0 +1,32 @@
+package models
+
+type Household struct {
+	Address string  `json:"address"` //房屋地址
+	Area    float64 `json:"area"`    //计费面积，单位平方米
+}
+
+type CreateFeeSchedule struct {
+	Name    string `json:"name"`     //名称，如物业费
+	FundID  string `json:"fund_id"`  //缴费计入的款项
+	Method  string `json:"method"`   //收费方式，flat-每户固定金额 area-按面积计费
+	Rate    string `json:"rate"`     //每户金额或每平方米金额
+	Cycle   string `json:"cycle"`    //计费周期，monthly、quarterly或yearly
+	DueDays int    `json:"due_days"` //出账后多少天到期
+}
+
+type UpdateFeeSchedule struct {
+	Name    string `json:"name"`     //名称
+	Rate    string `json:"rate"`     //费率，不传时不修改
+	DueDays *int   `json:"due_days"` //到期天数，不传时不修改
+	Status  string `json:"status"`   //状态
+}
+
+type GenerateInvoices struct {
+	Period    string `json:"period"`     //账期，月度2006-01，季度2006-Q1，年度2006
+	IssueDate string `json:"issue_date"` //出账日期，为空时取当天
+}
+
+type PayInvoice struct {
+	Amount    string `json:"amount"`    //缴费金额
+	Reference string `json:"reference"` //银行流水号
+}
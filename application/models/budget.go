@@ -0,0 +1,22 @@
+package models
+
+type BudgetLine struct {
+	Category    string `json:"category"`    //科目
+	Amount      string `json:"amount"`      //预算金额
+	Description string `json:"description"` //说明
+}
+
+type CreateBudget struct {
+	FundID string       `json:"fund_id"` //所属款项
+	Year   int          `json:"year"`    //预算年度
+	Name   string       `json:"name"`    //预算名称
+	Mode   string       `json:"mode"`    //超支处理方式，warn-提醒 block-拦截
+	Lines  []BudgetLine `json:"lines"`   //预算行
+}
+
+type UpdateBudget struct {
+	Name   string       `json:"name"`   //预算名称
+	Mode   string       `json:"mode"`   //超支处理方式
+	Status string       `json:"status"` //预算状态
+	Lines  []BudgetLine `json:"lines"`  //预算行，不传时保留原预算行
+}
@@ -6,3 +6,32 @@ type CreateFacility struct {
 	Location    string `json:"location"`    // 设施位置
 	Manager     string `json:"manager"`     // 负责人
 }
+
+// FacilitySchedule 设施开放时间与预约规则
+type FacilitySchedule struct {
+	Open        string `json:"open" binding:"required"`         // 每日开放时间，格式为HH:mm
+	Close       string `json:"close" binding:"required"`        // 每日关闭时间，格式为HH:mm
+	Days        []int  `json:"days" binding:"required,min=1"`   // 开放的星期，0为星期日
+	SlotMinutes int    `json:"slot_minutes" binding:"required"` // 预约时段长度，单位分钟
+	CancelHours int    `json:"cancel_hours"`                    // 开始前多少小时之前可以取消预约
+}
+
+// ReserveFacility 预约设施时段
+type ReserveFacility struct {
+	Start   string `json:"start" binding:"required"` // 开始时间，格式为yyyy-MM-dd HH:mm
+	End     string `json:"end" binding:"required"`   // 结束时间，与开始时间在同一天
+	Purpose string `json:"purpose"`                  // 用途
+}
+
+// CancelReservation 取消预约
+type CancelReservation struct {
+	Reason string `json:"reason"` // 取消原因
+}
+
+// FacilityPolicy 设施预约政策，各项为0表示不限制
+type FacilityPolicy struct {
+	MaxWeeklyHours int `json:"max_weekly_hours"` // 每户每周最多预约的小时数
+	AdvanceDays    int `json:"advance_days"`     // 最多可提前预约的天数
+	MaxActive      int `json:"max_active"`       // 每户同时持有的未结束预约数上限
+	ConfirmMinutes int `json:"confirm_minutes"`  // 候补确认时限，单位分钟，为0时默认30分钟
+}
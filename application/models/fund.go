@@ -11,8 +11,39 @@ type CreateFund struct {
 
 }
 type FinancialRecord struct {
-	Type    string `json:"type"`    //记录类型，0-收入 1-支出
-	Amount  string `json:"amount"`  //记录金额
-	Source  string `json:"source"`  //金额来源
+	Type      string `json:"type"`      //记录类型，0-收入 1-支出
+	Amount    string `json:"amount"`    //记录金额
+	Source    string `json:"source"`    //金额来源
+	Explain   string `json:"explain"`   //说明
+	InfoHash  string `json:"info_hash"` //证明文件SHA-256，需先上传附件
+	Category  string `json:"category"`  //预算科目，为空时归入未分类
+	Reference string `json:"reference"` //银行流水号，用于对账
+	Date      string `json:"date"`      //记录日期2006-01-02，为空时取当天，不能落在已结账期间
+}
+
+type CorrectRecord struct {
+	OriginalTxID string `json:"original_tx_id"` //被更正的原记录所在交易ID
+	FinancialRecord
+}
+
+type ClosePeriod struct {
+	Period string `json:"period"` //会计期间，月度为2006-01，年度为2006
+}
+
+// ApprovalPolicy 大额支出审批策略
+type ApprovalPolicy struct {
+	Threshold string `json:"threshold"` //审批阈值，支出金额超过该值需审批
+	Required  int    `json:"required"`  //需要的批准人数M，任意M名业委会成员批准即可，为0表示不启用审批
+}
+
+// RejectExpense 驳回待审批支出
+type RejectExpense struct {
+	Reason string `json:"reason"` //驳回原因
+}
+
+type FundTransfer struct {
+	From    string `json:"from"`    //转出款项ID
+	To      string `json:"to"`      //转入款项ID
+	Amount  string `json:"amount"`  //划转金额
 	Explain string `json:"explain"` //说明
 }
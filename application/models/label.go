@@ -0,0 +1,21 @@
+package models
+
+type LabelItem struct {
+	Kind string `json:"kind" binding:"required"` //对象类型，asset或facility
+	ID   string `json:"id" binding:"required"`   //资产ID或设施ID
+}
+
+type LabelSheet struct {
+	Items []LabelItem `json:"items" binding:"required,min=1,max=240,dive"` //打印的标签，每页24个
+}
+
+type ReportFault struct {
+	Kind        string `json:"t" binding:"required"`           //标签链接中的对象类型
+	ID          string `json:"id" binding:"required"`          //标签链接中的对象ID
+	Sig         string `json:"sig" binding:"required"`         //标签链接中的签名
+	Description string `json:"description" binding:"required"` //故障描述
+}
+
+type HandleFault struct {
+	Result string `json:"result" binding:"required"` //处理结果
+}
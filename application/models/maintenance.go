@@ -0,0 +1,35 @@
+package models
+
+type MaintenancePlan struct {
+	TargetType   string `json:"target_type"`   //维护对象类型，asset或facility，更新时不能修改
+	TargetID     string `json:"target_id"`     //资产ID或设施ID，更新时不能修改
+	Title        string `json:"title"`         //维护内容
+	Description  string `json:"description"`   //说明
+	Mode         string `json:"mode"`          //排期方式，interval-按间隔 calendar-按日历
+	IntervalDays int    `json:"interval_days"` //按间隔排期时的间隔天数
+	Cycle        string `json:"cycle"`         //按日历排期时的周期，monthly、quarterly或yearly
+	NextDue      string `json:"next_due"`      //下次到期日期
+	Assignee     string `json:"assignee"`      //默认负责人
+	FundID       string `json:"fund_id"`       //维护费用默认计入的款项
+	Status       string `json:"status"`        //计划状态，仅更新时使用
+}
+
+type GenerateWorkOrders struct {
+	AsOf string `json:"as_of"` //生成截至该日期已到期的工单，为空时取当天
+}
+
+type AssignWorkOrder struct {
+	Assignee string `json:"assignee" binding:"required"` //负责人
+}
+
+type CompleteWorkOrder struct {
+	Result   string `json:"result"`    //维护结果
+	Cost     string `json:"cost"`      //维护费用，填写时新记一笔支出，需财务负责人操作
+	FundID   string `json:"fund_id"`   //费用计入的款项，为空时取计划的默认款项
+	InfoHash string `json:"info_hash"` //发票等证明文件的SHA-256
+	EntryID  string `json:"entry_id"`  //关联已入账的支出分录，与cost二选一
+}
+
+type CancelWorkOrder struct {
+	Reason string `json:"reason" binding:"required"` //取消原因
+}
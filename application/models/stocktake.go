@@ -0,0 +1,10 @@
+package models
+
+type OpenStocktake struct {
+	Location string `json:"location" binding:"required"` //盘点范围的位置，与资产登记的位置一致
+}
+
+type ScanStocktake struct {
+	Code string `json:"code" binding:"required"` //资产ID或资产标签二维码的内容
+	Note string `json:"note"`                    //备注
+}
@@ -0,0 +1,25 @@
+package models
+
+type WorkflowStep struct {
+	Stage         int    `json:"stage" binding:"required"`    //阶段，从1开始，同一阶段的步骤并行审批
+	Name          string `json:"name" binding:"required"`     //步骤名称
+	Role          string `json:"role" binding:"required"`     //审批角色
+	Required      int    `json:"required" binding:"required"` //需要的批准人数
+	DeadlineHours int    `json:"deadline_hours"`              //审批期限，单位小时，0表示不限
+	EscalateRole  string `json:"escalate_role"`               //超期后可审批的角色，为空时不升级
+}
+
+type WorkflowDefinition struct {
+	Name  string         `json:"name" binding:"required"`             //流程名称
+	Steps []WorkflowStep `json:"steps" binding:"required,min=1,dive"` //审批步骤
+}
+
+type DecideWorkflow struct {
+	TaskID   string `json:"task_id"`                     //审批步骤ID，为空时审批当前可审批的第一个步骤
+	Decision string `json:"decision" binding:"required"` //approve或reject
+	Comment  string `json:"comment"`                     //审批意见
+}
+
+type WorkflowComment struct {
+	Comment string `json:"comment" binding:"required"` //意见
+}
@@ -0,0 +1,150 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// A4页面尺寸，单位为点(1/72英寸)
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document 简单的A4 PDF文档，文本使用阅读器内置的STSong-Light字体以支持中文
+// 坐标原点在页面左上角，y轴向下
+type Document struct {
+	pages  []*bytes.Buffer
+	images []string // 图像XObject对象，所有页面共用
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage 新增一页，之后的绘制都在该页上
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text 在(x, y)处绘制一行文本，y为文本基线
+func (d *Document) Text(x, y, size float64, text string) {
+	fmt.Fprintf(d.page(), "BT /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, PageHeight-y, encodeText(text))
+}
+
+// Line 绘制一条直线
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.page(), "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Rect 填充一个黑色矩形，(x, y)为左上角
+func (d *Document) Rect(x, y, w, h float64) {
+	fmt.Fprintf(d.page(), "%.2f %.2f %.2f %.2f re f\n", x, PageHeight-y-h, w, h)
+}
+
+// Image 以灰度图像填充(x, y)为左上角、宽w高h的区域，图像按原像素缩放不做插值，适合二维码等点阵图
+func (d *Document) Image(x, y, w, h float64, img image.Image) {
+	bounds := img.Bounds()
+	var data bytes.Buffer
+	zw := zlib.NewWriter(&data)
+	row := make([]byte, bounds.Dx())
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			row[px-bounds.Min.X] = color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y
+		}
+		zw.Write(row)
+	}
+	zw.Close()
+	d.images = append(d.images, fmt.Sprintf(
+		"<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Interpolate false /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+		bounds.Dx(), bounds.Dy(), data.Len(), data.String()))
+	fmt.Fprintf(d.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, PageHeight-y-h, len(d.images))
+}
+
+// TextWidth 估算文本宽度，ASCII字符为半角，其余为全角
+func TextWidth(size float64, text string) float64 {
+	var width float64
+	for _, r := range text {
+		if r < 0x80 {
+			width += size / 2
+		} else {
+			width += size
+		}
+	}
+	return width
+}
+
+// encodeText 以UCS-2编码文本，超出基本多文种平面的字符以?代替
+func encodeText(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if r > 0xFFFF {
+			r = '?'
+		}
+		fmt.Fprintf(&sb, "%04X", r)
+	}
+	return sb.String()
+}
+
+// Bytes 输出PDF文件内容
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	const firstPage = 6
+	objects := []string{
+		"",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // 页面树，页面对象编号确定后填写
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+	}
+	firstImage := firstPage + 2*len(d.pages)
+	resources := "/Font << /F1 3 0 R >>"
+	if len(d.images) > 0 {
+		names := make([]string, len(d.images))
+		for i := range d.images {
+			names[i] = fmt.Sprintf("/Im%d %d 0 R", i+1, firstImage+i)
+		}
+		resources += " /XObject << " + strings.Join(names, " ") + " >>"
+	}
+	kids := make([]string, len(d.pages))
+	for i, content := range d.pages {
+		pageObj := firstPage + 2*i
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents %d 0 R >>",
+				PageWidth, PageHeight, resources, pageObj+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+	objects = append(objects, d.images...)
+	objects[2] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	offsets := make([]int, len(objects))
+	for i := 1; i < len(objects); i++ {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i, objects[i])
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects))
+	for i := 1; i < len(objects); i++ {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offsets[i])
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects), xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,54 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDocumentXref(t *testing.T) {
+	doc := New()
+	doc.Text(50, 50, 12, "收支报表 2026-03")
+	doc.AddPage()
+	doc.Line(50, 60, 500, 60, 1)
+	data := doc.Bytes()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) {
+		t.Fatalf("missing PDF header")
+	}
+	// xref中的偏移量必须指向对应的对象
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data, -1)
+	if len(entries) != 9 {
+		t.Fatalf("xref entries = %d, want 9", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := fmt.Sprintf("%d 0 obj", i+1)
+		if !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points to %q", i+1, data[offset:offset+10])
+		}
+	}
+	if !bytes.Contains(data, []byte("<6536652F62A58868")) {
+		t.Fatalf("text is not encoded as UCS-2")
+	}
+}
+
+func TestDocumentImage(t *testing.T) {
+	doc := New()
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.SetGray(1, 1, color.Gray{Y: 0xFF})
+	doc.Image(50, 50, 30, 20, img)
+	data := doc.Bytes()
+	if !bytes.Contains(data, []byte("/XObject << /Im1 8 0 R >>")) {
+		t.Fatalf("page resources do not reference the image")
+	}
+	if !bytes.Contains(data, []byte("q 30.00 0 0 20.00 50.00 771.89 cm /Im1 Do Q")) {
+		t.Fatalf("image is not drawn on the page")
+	}
+	if !bytes.Contains(data, []byte("8 0 obj\n<< /Type /XObject /Subtype /Image /Width 3 /Height 2")) {
+		t.Fatalf("image object is missing")
+	}
+}
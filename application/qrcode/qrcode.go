@@ -0,0 +1,452 @@
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code 二维码，以字节模式编码，纠错等级为M(约15%)，支持版本1至10，最多213字节
+type Code struct {
+	Version int
+	Size    int
+	modules [][]bool
+}
+
+// Black 判断第x列第y行的模块是否为深色
+func (c *Code) Black(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// version 纠错等级M下各版本的分块与对齐图案位置
+type version struct {
+	ecPerBlock int    // 每块纠错码字数
+	blocks     [2]int // 两组分块的块数
+	data       [2]int // 两组分块每块的数据码字数
+	align      []int  // 对齐图案中心坐标
+}
+
+var versions = []version{
+	{},
+	{10, [2]int{1, 0}, [2]int{16, 0}, nil},
+	{16, [2]int{1, 0}, [2]int{28, 0}, []int{6, 18}},
+	{26, [2]int{1, 0}, [2]int{44, 0}, []int{6, 22}},
+	{18, [2]int{2, 0}, [2]int{32, 0}, []int{6, 26}},
+	{24, [2]int{2, 0}, [2]int{43, 0}, []int{6, 30}},
+	{16, [2]int{4, 0}, [2]int{27, 0}, []int{6, 34}},
+	{18, [2]int{4, 0}, [2]int{31, 0}, []int{6, 22, 38}},
+	{22, [2]int{2, 2}, [2]int{38, 39}, []int{6, 24, 42}},
+	{22, [2]int{3, 2}, [2]int{36, 37}, []int{6, 26, 46}},
+	{26, [2]int{4, 1}, [2]int{43, 44}, []int{6, 28, 50}},
+}
+
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// dataCapacity 版本的数据码字总数
+func (v version) dataCapacity() int {
+	return v.blocks[0]*v.data[0] + v.blocks[1]*v.data[1]
+}
+
+// Encode 将文本编码为二维码，选择能容纳数据的最小版本
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	for ver := 1; ver < len(versions); ver++ {
+		countBits := 8
+		if ver >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > 8*versions[ver].dataCapacity() {
+			continue
+		}
+		codewords := encodeData(data, countBits, versions[ver].dataCapacity())
+		return build(ver, interleave(versions[ver], codewords)), nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(data))
+}
+
+// bitBuffer 按位写入的缓冲区
+type bitBuffer []bool
+
+func (b *bitBuffer) write(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 == 1)
+	}
+}
+
+// encodeData 生成字节模式的数据码字，不足容量时补终止符与填充字节
+func encodeData(data []byte, countBits, capacity int) []byte {
+	var bits bitBuffer
+	bits.write(0x4, 4)
+	bits.write(len(data), countBits)
+	for _, c := range data {
+		bits.write(int(c), 8)
+	}
+	bits.write(0, min(4, capacity*8-len(bits)))
+	bits.write(0, (8-len(bits)%8)%8)
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var c byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				c |= 0x80 >> j
+			}
+		}
+		codewords = append(codewords, c)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// interleave 分块计算纠错码字后交错排列数据码字与纠错码字
+func interleave(v version, codewords []byte) []byte {
+	var blocks, ecBlocks [][]byte
+	for group := 0; group < 2; group++ {
+		for i := 0; i < v.blocks[group]; i++ {
+			block := codewords[:v.data[group]]
+			codewords = codewords[v.data[group]:]
+			blocks = append(blocks, block)
+			ecBlocks = append(ecBlocks, reedSolomon(block, v.ecPerBlock))
+		}
+	}
+	var result []byte
+	for i := 0; i < max(v.data[0], v.data[1]); i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// gfExp、gfLog GF(256)的指数表与对数表，本原多项式为x^8+x^4+x^3+x^2+1
+var gfExp, gfLog = func() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// reedSolomon 计算数据块的n个纠错码字
+func reedSolomon(data []byte, n int) []byte {
+	// 生成多项式(x-α^0)(x-α^1)...(x-α^(n-1))，系数从高次到低次，省略最高次项
+	generator := make([]byte, n)
+	generator[n-1] = 1
+	root := byte(1)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			generator[j] = gfMul(generator[j], root)
+			if j+1 < n {
+				generator[j] ^= generator[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	remainder := make([]byte, n)
+	for _, c := range data {
+		factor := c ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[n-1] = 0
+		for j := range remainder {
+			remainder[j] ^= gfMul(generator[j], factor)
+		}
+	}
+	return remainder
+}
+
+// matrix 绘制中的二维码，function标记功能图案所在的模块
+type matrix struct {
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func newMatrix(size int) *matrix {
+	m := &matrix{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range m.modules {
+		m.modules[i] = make([]bool, size)
+		m.function[i] = make([]bool, size)
+	}
+	return m
+}
+
+func (m *matrix) setFunction(x, y int, black bool) {
+	m.modules[y][x] = black
+	m.function[y][x] = true
+}
+
+// build 绘制功能图案、放置码字并选择罚分最低的掩码
+func build(ver int, codewords []byte) *Code {
+	size := ver*4 + 17
+	m := newMatrix(size)
+	m.drawFunctionPatterns(ver)
+	m.placeCodewords(codewords)
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		m.applyMask(mask)
+		m.drawFormatBits(mask)
+		if penalty := m.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		m.applyMask(mask)
+	}
+	m.applyMask(best)
+	m.drawFormatBits(best)
+	return &Code{Version: ver, Size: size, modules: m.modules}
+}
+
+func (m *matrix) drawFunctionPatterns(ver int) {
+	for i := 0; i < m.size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+	m.drawFinder(3, 3)
+	m.drawFinder(m.size-4, 3)
+	m.drawFinder(3, m.size-4)
+	align := versions[ver].align
+	for i, x := range align {
+		for j, y := range align {
+			// 与定位图案重叠的位置不绘制对齐图案
+			last := len(align) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			m.drawAlignment(x, y)
+		}
+	}
+	// 预留格式信息的位置，掩码选定后再写入
+	m.drawFormatBits(0)
+	m.drawVersion(ver)
+}
+
+// drawFinder 以(cx, cy)为中心绘制定位图案及其分隔符
+func (m *matrix) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= m.size || y < 0 || y >= m.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			m.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment 以(cx, cy)为中心绘制对齐图案
+func (m *matrix) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			m.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits 写入纠错等级M与掩码的格式信息，两处各一份
+func (m *matrix) drawFormatBits(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	for i := 0; i <= 5; i++ {
+		m.setFunction(8, i, bit(i))
+	}
+	m.setFunction(8, 7, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		m.setFunction(m.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, m.size-15+i, bit(i))
+	}
+	m.setFunction(8, m.size-8, true)
+}
+
+// formatBits 格式信息，纠错等级M的指示符为00，BCH(15,5)编码后与0x5412异或
+func formatBits(mask int) int {
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawVersion 版本7及以上写入版本信息
+func (m *matrix) drawVersion(ver int) {
+	if ver < 7 {
+		return
+	}
+	bits := versionBits(ver)
+	for i := 0; i < 18; i++ {
+		black := bits>>i&1 == 1
+		a, b := m.size-11+i%3, i/3
+		m.setFunction(a, b, black)
+		m.setFunction(b, a, black)
+	}
+}
+
+// versionBits 版本信息，BCH(18,6)编码
+func versionBits(ver int) int {
+	rem := ver
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return ver<<12 | rem
+}
+
+// placeCodewords 从右下角起按两列一组上下往返放置码字，跳过功能图案与竖直定时图案
+func (m *matrix) placeCodewords(codewords []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < m.size; vert++ {
+			y := vert
+			if upward {
+				y = m.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if m.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				m.modules[y][x] = codewords[i>>3]>>(7-i&7)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask 对数据模块异或掩码，再次调用可撤销
+func (m *matrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if m.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				m.modules[y][x] = !m.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty 按标准的四条规则计算掩码罚分
+func (m *matrix) penalty() int {
+	n := m.size
+	at := func(x, y int, horizontal bool) bool {
+		if horizontal {
+			return m.modules[y][x]
+		}
+		return m.modules[x][y]
+	}
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	score := 0
+	for _, horizontal := range []bool{true, false} {
+		for y := 0; y < n; y++ {
+			// 规则1：同色模块连续5个及以上
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, horizontal) == at(x-1, y, horizontal) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			// 规则3：类似定位图案的1:1:3:1:1序列
+			for x := 0; x+11 <= n; x++ {
+				for _, pattern := range finderLike {
+					match := true
+					for k, black := range pattern {
+						if at(x+k, y, horizontal) != black {
+							match = false
+							break
+						}
+					}
+					if match {
+						score += 40
+					}
+				}
+			}
+		}
+	}
+	// 规则2：2x2同色块
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if m.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := m.modules[y][x]
+				if c == m.modules[y][x+1] && c == m.modules[y+1][x] && c == m.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	// 规则4：深色模块比例偏离50%
+	score += abs(dark*100/(n*n)-50) / 5 * 10
+	return score
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
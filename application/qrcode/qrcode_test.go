@@ -0,0 +1,45 @@
+package qrcode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// 版本1-M的"HELLO WORLD"数据码字及其纠错码字
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomon(data, 10); !bytes.Equal(got, want) {
+		t.Fatalf("reedSolomon = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	formats := map[int]int{0: 0x5412, 5: 0x40CE, 7: 0x4AA0}
+	for mask, want := range formats {
+		if got := formatBits(mask); got != want {
+			t.Fatalf("formatBits(%d) = %015b, want %015b", mask, got, want)
+		}
+	}
+	if got := versionBits(7); got != 0x07C94 {
+		t.Fatalf("versionBits(7) = %X, want 07C94", got)
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	cases := []struct {
+		length, version int
+	}{{14, 1}, {15, 2}, {213, 10}}
+	for _, tc := range cases {
+		code, err := Encode(string(bytes.Repeat([]byte("a"), tc.length)))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", tc.length, err)
+		}
+		if code.Version != tc.version || code.Size != tc.version*4+17 {
+			t.Fatalf("Encode(%d bytes) version = %d size = %d, want version %d", tc.length, code.Version, code.Size, tc.version)
+		}
+	}
+	if _, err := Encode(string(bytes.Repeat([]byte("a"), 214))); err == nil {
+		t.Fatalf("Encode(214 bytes) should fail")
+	}
+}
@@ -0,0 +1,36 @@
+package report
+
+import (
+	"bytes"
+	dbMod "community-governance/db/models"
+	"encoding/csv"
+	"strconv"
+)
+
+// AssetRegisterCSV 以CSV格式输出资产台账，带UTF-8 BOM以便表格软件识别中文
+func AssetRegisterCSV(date string, register []dbMod.AssetRegisterLine) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(&buf)
+	rows := [][]string{
+		{"资产台账", date},
+		{},
+		{"资产ID", "名称", "类型", "位置", "状态", "购买日期", "购置款项", "折旧方法", "使用年限", "购置成本", "净残值", "已计提月数", "累计折旧", "账面价值"},
+	}
+	var cost, accumulated, bookValue dbMod.Money
+	for _, line := range register {
+		rows = append(rows, []string{
+			line.AssetID, line.Name, line.Type, line.Location, line.Status, line.PurchaseDate, line.FundID,
+			line.Method, strconv.Itoa(line.UsefulLife), line.Cost.String(), line.Residual.String(),
+			strconv.Itoa(line.Months), line.Accumulated.String(), line.BookValue.String(),
+		})
+		cost += line.Cost
+		accumulated += line.Accumulated
+		bookValue += line.BookValue
+	}
+	rows = append(rows, []string{"合计", "", "", "", "", "", "", "", "", cost.String(), "", "", accumulated.String(), bookValue.String()})
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,258 @@
+package report
+
+import (
+	"bytes"
+	"community-governance/application/pdf"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 记录类型，与链上收支记录一致
+const (
+	recordTypeIncome  = "0"
+	recordTypeExpense = "1"
+)
+
+// unknownSource 未填写来源的记录归入的来源
+const unknownSource = "未注明"
+
+// SourceLine 按来源汇总的收入或支出
+type SourceLine struct {
+	Source string      `json:"source"`
+	Amount dbMod.Money `json:"amount"`
+	TxIDs  []string    `json:"tx_ids"` // 汇总的链上交易ID
+}
+
+// Statement 款项收支报表，全部数据取自链上收支记录，相同期间重复生成的内容与hash不变
+type Statement struct {
+	FundID         string       `json:"fund_id"`
+	FundName       string       `json:"fund_name"`
+	Period         string       `json:"period"` // 报表期间，月报为2006-01，年报为2006
+	Start          string       `json:"start"`  // 期间开始时间(含)
+	End            string       `json:"end"`    // 期间结束时间(不含)
+	OpeningBalance dbMod.Money  `json:"opening_balance"`
+	Income         []SourceLine `json:"income"`
+	TotalIncome    dbMod.Money  `json:"total_income"`
+	Expense        []SourceLine `json:"expense"`
+	TotalExpense   dbMod.Money  `json:"total_expense"`
+	ClosingBalance dbMod.Money  `json:"closing_balance"`
+	TxIDs          []string     `json:"tx_ids"` // 本期全部收支记录的链上交易ID
+}
+
+// ParsePeriod 解析报表期间，支持月报2006-01与年报2006，按服务器本地时区划分
+func ParsePeriod(period string) (time.Time, time.Time, error) {
+	if start, err := time.ParseInLocation("2006-01", period, time.Local); err == nil {
+		return start, start.AddDate(0, 1, 0), nil
+	}
+	if start, err := time.ParseInLocation("2006", period, time.Local); err == nil {
+		return start, start.AddDate(1, 0, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid period: %s, expect 2006-01 or 2006", period)
+}
+
+// BuildStatement 根据链上收支记录生成款项在指定期间的收支报表
+// 期初资金由链上汇总推算：余额 - 累计收入 + 累计支出
+func BuildStatement(fund dbMod.Fund, detail fabric.ChainFundDetail, period string) (*Statement, error) {
+	start, end, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := dbMod.ParseMoney(detail.Totals.Balance)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain balance: %v", err)
+	}
+	income, err := dbMod.ParseMoney(detail.Totals.Income)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain income: %v", err)
+	}
+	expense, err := dbMod.ParseMoney(detail.Totals.Expense)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain expense: %v", err)
+	}
+	statement := &Statement{
+		FundID:         fund.FundID,
+		FundName:       fund.Name,
+		Period:         period,
+		Start:          start.Format("2006-01-02 15:04:05"),
+		End:            end.Format("2006-01-02 15:04:05"),
+		OpeningBalance: balance - income + expense,
+		Income:         []SourceLine{},
+		Expense:        []SourceLine{},
+		TxIDs:          []string{},
+	}
+	incomeLines := make(map[string]*SourceLine)
+	expenseLines := make(map[string]*SourceLine)
+	for _, tx := range detail.Transactions {
+		txTime, err := recordTime(tx)
+		if err != nil {
+			return nil, err
+		}
+		if !txTime.Before(end) {
+			continue
+		}
+		amount, err := dbMod.ParseMoney(tx.Record.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount of %s: %v", tx.TxID, err)
+		}
+		if tx.Record.Type == recordTypeExpense {
+			amount = -amount
+		} else if tx.Record.Type != recordTypeIncome {
+			return nil, fmt.Errorf("invalid record type of %s: %s", tx.TxID, tx.Record.Type)
+		}
+		//期间开始前的记录计入期初余额
+		if txTime.Before(start) {
+			statement.OpeningBalance += amount
+			continue
+		}
+		lines := incomeLines
+		if amount < 0 {
+			lines = expenseLines
+			amount = -amount
+			statement.TotalExpense += amount
+		} else {
+			statement.TotalIncome += amount
+		}
+		source := tx.Record.Source
+		if source == "" {
+			source = unknownSource
+		}
+		line, ok := lines[source]
+		if !ok {
+			line = &SourceLine{Source: source}
+			lines[source] = line
+		}
+		line.Amount += amount
+		line.TxIDs = append(line.TxIDs, tx.TxID)
+		statement.TxIDs = append(statement.TxIDs, tx.TxID)
+	}
+	statement.Income = sortedLines(incomeLines)
+	statement.Expense = sortedLines(expenseLines)
+	statement.ClosingBalance = statement.OpeningBalance + statement.TotalIncome - statement.TotalExpense
+	return statement, nil
+}
+
+// recordTime 记录所属的时间，有记录日期时按记录日期归入期间，否则按交易时间
+func recordTime(tx fabric.FinancialTransaction) (time.Time, error) {
+	if tx.Record.Date != "" {
+		date, err := time.ParseInLocation("2006-01-02", tx.Record.Date, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid record date of %s: %v", tx.TxID, err)
+		}
+		return date, nil
+	}
+	txTime, err := time.Parse(time.RFC3339, tx.Timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp of %s: %v", tx.TxID, err)
+	}
+	return txTime, nil
+}
+
+func sortedLines(lines map[string]*SourceLine) []SourceLine {
+	result := make([]SourceLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, *line)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Source < result[j].Source })
+	return result
+}
+
+// CSV 以CSV格式输出报表，带UTF-8 BOM以便表格软件识别中文
+func (s *Statement) CSV(hash string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(&buf)
+	rows := [][]string{
+		{"款项", s.FundName, s.FundID},
+		{"期间", s.Period, s.Start + " ~ " + s.End},
+		{"报表hash", hash},
+		{},
+		{"项目", "来源", "金额", "链上交易ID"},
+		{"期初余额", "", s.OpeningBalance.String(), ""},
+	}
+	for _, line := range s.Income {
+		for i, txID := range line.TxIDs {
+			row := []string{"", "", "", txID}
+			if i == 0 {
+				row = []string{"收入", line.Source, line.Amount.String(), txID}
+			}
+			rows = append(rows, row)
+		}
+	}
+	rows = append(rows, []string{"收入合计", "", s.TotalIncome.String(), ""})
+	for _, line := range s.Expense {
+		for i, txID := range line.TxIDs {
+			row := []string{"", "", "", txID}
+			if i == 0 {
+				row = []string{"支出", line.Source, line.Amount.String(), txID}
+			}
+			rows = append(rows, row)
+		}
+	}
+	rows = append(rows,
+		[]string{"支出合计", "", s.TotalExpense.String(), ""},
+		[]string{"期末余额", "", s.ClosingBalance.String(), ""},
+	)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PDF 以PDF格式输出报表，末尾附本期全部链上交易ID
+func (s *Statement) PDF(hash string) []byte {
+	const (
+		left   = 50.0
+		right  = pdf.PageWidth - 50
+		top    = 60.0
+		bottom = pdf.PageHeight - 50
+	)
+	doc := pdf.New()
+	doc.AddPage()
+	y := top
+	line := func(size float64, cells ...string) {
+		if y > bottom {
+			doc.AddPage()
+			y = top
+		}
+		//第一列左对齐，第二列居中，最后一列金额右对齐
+		for i, cell := range cells {
+			switch {
+			case i == 0:
+				doc.Text(left, y, size, cell)
+			case i == len(cells)-1:
+				doc.Text(right-pdf.TextWidth(size, cell), y, size, cell)
+			default:
+				doc.Text(left+180, y, size, cell)
+			}
+		}
+		y += size * 1.8
+	}
+	line(18, s.FundName+" 收支报表")
+	line(10, "期间："+s.Period+"（"+s.Start+" ~ "+s.End+"）")
+	line(10, "款项ID："+s.FundID)
+	line(8, "报表hash："+hash)
+	doc.Line(left, y-8, right, y-8, 0.5)
+	line(12, "期初余额", s.OpeningBalance.String())
+	line(12, "收入")
+	for _, l := range s.Income {
+		line(10, "", l.Source, l.Amount.String())
+	}
+	line(12, "收入合计", s.TotalIncome.String())
+	line(12, "支出")
+	for _, l := range s.Expense {
+		line(10, "", l.Source, l.Amount.String())
+	}
+	line(12, "支出合计", s.TotalExpense.String())
+	doc.Line(left, y-8, right, y-8, 0.5)
+	line(12, "期末余额", s.ClosingBalance.String())
+	y += 10
+	line(12, "链上交易ID")
+	for _, txID := range s.TxIDs {
+		line(8, txID)
+	}
+	return doc.Bytes()
+}
@@ -0,0 +1,86 @@
+package report
+
+import (
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"strings"
+	"testing"
+	"time"
+)
+
+func chainTx(txID, typ, source, amount string, at time.Time) fabric.FinancialTransaction {
+	return fabric.FinancialTransaction{
+		TxID:      txID,
+		Timestamp: at.Format(time.RFC3339),
+		Record:    fabric.FinancialRecord{Type: typ, Source: source, Amount: amount},
+	}
+}
+
+func TestBuildStatement(t *testing.T) {
+	month := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 12, 0, 0, 0, time.Local) }
+	// 期初资金1000，2月收入200支出50，3月收入300与100、支出400
+	detail := fabric.ChainFundDetail{
+		Totals: fabric.FinancialTotals{Income: "600.00", Expense: "450.00", Balance: "1150.00"},
+		Transactions: []fabric.FinancialTransaction{
+			chainTx("t1", "0", "物业费", "200.00", month(2, 10)),
+			chainTx("t2", "1", "维修", "50.00", month(2, 20)),
+			chainTx("t3", "0", "物业费", "300.00", month(3, 1)),
+			chainTx("t4", "0", "", "100.00", month(3, 15)),
+			chainTx("t5", "1", "维修", "400.00", month(3, 31)),
+		},
+	}
+	statement, err := BuildStatement(dbMod.Fund{FundID: "f1", Name: "公共维修金"}, detail, "2026-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.OpeningBalance != 115000 {
+		t.Fatalf("OpeningBalance = %s, want 1150.00", statement.OpeningBalance)
+	}
+	if statement.TotalIncome != 40000 || statement.TotalExpense != 40000 {
+		t.Fatalf("TotalIncome = %s, TotalExpense = %s", statement.TotalIncome, statement.TotalExpense)
+	}
+	if statement.ClosingBalance != 115000 {
+		t.Fatalf("ClosingBalance = %s, want 1150.00", statement.ClosingBalance)
+	}
+	if len(statement.Income) != 2 || statement.Income[0].Source != unknownSource && statement.Income[1].Source != unknownSource {
+		t.Fatalf("Income = %+v", statement.Income)
+	}
+	if strings.Join(statement.TxIDs, ",") != "t3,t4,t5" {
+		t.Fatalf("TxIDs = %v", statement.TxIDs)
+	}
+
+	annual, err := BuildStatement(dbMod.Fund{FundID: "f1"}, detail, "2026")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if annual.OpeningBalance != 100000 || annual.ClosingBalance != 115000 || len(annual.TxIDs) != 5 {
+		t.Fatalf("annual = %+v", annual)
+	}
+	if _, err := BuildStatement(dbMod.Fund{}, detail, "2026-13"); err == nil {
+		t.Fatalf("expected invalid period error")
+	}
+}
+
+func TestBuildStatementRecordDate(t *testing.T) {
+	// 4月补记的3月收入按记录日期计入3月
+	backdated := chainTx("t1", "0", "物业费", "80.00", time.Date(2026, 4, 2, 9, 0, 0, 0, time.Local))
+	backdated.Record.Date = "2026-03-28"
+	detail := fabric.ChainFundDetail{
+		Totals:       fabric.FinancialTotals{Income: "80.00", Expense: "0.00", Balance: "80.00"},
+		Transactions: []fabric.FinancialTransaction{backdated},
+	}
+	march, err := BuildStatement(dbMod.Fund{FundID: "f1"}, detail, "2026-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if march.TotalIncome != 8000 || march.OpeningBalance != 0 {
+		t.Fatalf("march = %+v", march)
+	}
+	april, err := BuildStatement(dbMod.Fund{FundID: "f1"}, detail, "2026-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if april.TotalIncome != 0 || april.OpeningBalance != 8000 {
+		t.Fatalf("april = %+v", april)
+	}
+}
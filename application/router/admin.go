@@ -0,0 +1,20 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminRoutes 注册管理员路由，:type为member、fund、notice、asset、asset_request、facility、vote、vote_option、vote_rule、budget、fee_schedule、maintenance_plan
+func RegisterAdminRoutes(r *gin.Engine) {
+	adminGroup := r.Group("/api/v1/admin")
+	adminGroup.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleAdmin))
+	{
+		adminGroup.GET("/deleted/:type", handlers.GetDeletedRecords)                                                             // 查询已删除记录
+		adminGroup.GET("/restore/:type/:id", middleware.AuditMiddleware("restore", "", nil), handlers.RestoreRecord)             // 恢复已删除记录
+		adminGroup.GET("/archive/:type/:id", middleware.AuditMiddleware("archive", "", nil), handlers.ArchiveRecord)             // 归档记录
+		adminGroup.POST("/migrate/fund-totals", middleware.AuditMiddleware("migrate", "fund", nil), handlers.MigrateFundTotals)  // 迁移链上款项收支汇总
+		adminGroup.POST("/migrate/workflows", middleware.AuditMiddleware("migrate", "workflow", nil), handlers.MigrateWorkflows) // 同步链上审批流程
+	}
+}
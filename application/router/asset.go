@@ -3,27 +3,46 @@ package router
 import (
 	"community-governance/application/handlers"
 	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterAssetRoutes(r *gin.Engine) {
 	assetGroup := r.Group("/api/v1/asset")
+	assetAudit := middleware.AuditLoad(dbMod.GetAssetByID)
+	requestAudit := middleware.AuditLoad(dbMod.GetAssetRequestByID)
 
 	{
 		assetGroup.GET("/query/:id", handlers.GetAssetDetail)  // 获取资产信息详细信息
 		assetGroup.GET("/query/all", handlers.GetAssetAllPage) // 获取所有资产信息
-		assetGroup.GET("/delete/:id", handlers.DeleteAsset)    // 删除资产
+		assetGroup.GET("/delete/:id", middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleCommittee),
+			middleware.AuditMiddleware("delete", "asset", assetAudit), handlers.DeleteAsset) // 删除资产
 		assetGroup.POST("/query/conditions", handlers.GetAssetByConditions)
+		assetGroup.GET("/timeline/:id", handlers.GetAssetTimeline) // 资产状态时间线
+		assetGroup.POST("/state/:id", middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleCommittee),
+			middleware.AuditMiddleware("update_state", "asset", assetAudit), handlers.ChangeAssetState) // 变更资产状态
+		assetGroup.GET("/book-value/:id", handlers.GetAssetBookValue) // 资产账面价值与折旧记录
+		assetGroup.GET("/register", handlers.ExportAssetRegister)     // 资产台账
+		assetGroup.POST("/depreciation/update/:id", middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleTreasurer),
+			middleware.AuditMiddleware("update_depreciation", "asset", assetAudit), handlers.SetAssetDepreciation) // 设置折旧参数
+		assetGroup.POST("/depreciation/run", middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleTreasurer),
+			middleware.AuditMiddleware("depreciate", "asset", nil), handlers.RunDepreciation) // 计提年度折旧
 
 		recordsGroup := assetGroup.Group("/records") // 修改分组路径
 		recordsGroup.Use(middleware.AuthMiddleware())
 		{
 			recordsGroup.POST("/add", handlers.AddAssetRequestRecord) // 添加记录
 			recordsGroup.GET("/query/all", handlers.GetAssetRequestAllPage)
-			recordsGroup.GET("/delete/:id", handlers.DeleteAssetRequest)
-			recordsGroup.GET("/audit/:id", handlers.AuditRequestRecord)
+			recordsGroup.GET("/delete/:id", middleware.AuditMiddleware("delete", "asset_request", requestAudit), handlers.DeleteAssetRequest)
+			recordsGroup.GET("/audit/:id", middleware.AuditMiddleware("audit", "asset_request", requestAudit), handlers.AuditRequestRecord)
 			recordsGroup.POST("/query/conditions", handlers.GetAssetRequestByConditions)
 			recordsGroup.GET("/query/person", handlers.GetAssetRequestByPerson)
+			recordsGroup.POST("/accept/:id", middleware.AuditMiddleware("accept", "asset_request", requestAudit), handlers.AcceptAssetTransfer)    // 接收调拨
+			recordsGroup.POST("/decline/:id", middleware.AuditMiddleware("decline", "asset_request", requestAudit), handlers.DeclineAssetTransfer) // 拒绝调拨
+			recordsGroup.POST("/return/:id", middleware.AuditMiddleware("return", "asset_request", requestAudit), handlers.ReturnLentAsset)        // 借用归还验收
+			recordsGroup.POST("/credit/:id", middleware.RoleMiddleware(middleware.RoleTreasurer),
+				middleware.AuditMiddleware("credit", "asset_request", requestAudit), handlers.CreditDisposalProceeds) // 处置收入入账
+			recordsGroup.GET("/overdue", middleware.RoleMiddleware(middleware.RoleCommittee), handlers.GetOverdueLoanPage) // 逾期未归还的借用
 		}
 	}
 }
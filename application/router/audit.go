@@ -0,0 +1,20 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAuditRoutes 注册审计日志路由，仅监事会与业委会可查询
+func RegisterAuditRoutes(r *gin.Engine) {
+	auditGroup := r.Group("/api/v1/audit")
+	auditGroup.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleSupervisor, middleware.RoleCommittee))
+	{
+		auditGroup.GET("/query/all", handlers.GetAuditLogs)      // 查询审计日志
+		auditGroup.GET("/query/:id", handlers.GetAuditLogDetail) // 获取审计日志详情
+		auditGroup.GET("/verify", handlers.VerifyAuditChain)     // 校验审计日志hash链
+		auditGroup.GET("/anchor/all", handlers.GetAuditAnchors)  // 查询锚定记录
+		auditGroup.GET("/anchor", handlers.AnchorAuditHead)      // 立即锚定链头
+	}
+}
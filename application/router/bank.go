@@ -0,0 +1,23 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterBankRoutes(r *gin.Engine) {
+	bankGroup := r.Group("/api/v1/bank")
+	bankGroup.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleTreasurer))
+	lineAudit := middleware.AuditLoad(dbMod.GetBankLineByID)
+	{
+		bankGroup.POST("/import/:id", middleware.AuditMiddleware("import", "bank", nil), handlers.ImportBankStatement)               // 导入款项的银行流水并匹配分录
+		bankGroup.GET("/query/:id", handlers.GetBankImportDetail)                                                                    // 获取导入批次及流水明细
+		bankGroup.GET("/query/all", handlers.GetBankImportAllPage)                                                                   // 分页查询导入批次
+		bankGroup.GET("/unreconciled/:id", handlers.GetUnreconciledEntries)                                                          // 查询没有银行流水对应的分录
+		bankGroup.POST("/line/confirm/:id", middleware.AuditMiddleware("confirm", "bank_line", lineAudit), handlers.ConfirmBankLine) // 确认流水与分录匹配
+		bankGroup.POST("/line/record/:id", middleware.AuditMiddleware("record", "bank_line", lineAudit), handlers.RecordBankLine)    // 为流水创建收支记录
+		bankGroup.POST("/line/ignore/:id", middleware.AuditMiddleware("ignore", "bank_line", lineAudit), handlers.IgnoreBankLine)    // 忽略流水
+	}
+}
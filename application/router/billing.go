@@ -0,0 +1,34 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterBillingRoutes(r *gin.Engine) {
+	billingGroup := r.Group("/api/v1/billing")
+	billingGroup.Use(middleware.AuthMiddleware())
+	scheduleAudit := middleware.AuditLoad(dbMod.GetFeeScheduleByID)
+	invoiceAudit := middleware.AuditLoad(dbMod.GetInvoiceByID)
+	treasurer := middleware.RoleMiddleware(middleware.RoleTreasurer)
+	viewer := middleware.RoleMiddleware(middleware.RoleTreasurer, middleware.RoleCommittee, middleware.RoleSupervisor)
+	{
+		billingGroup.POST("/household/update/:id", treasurer, middleware.AuditMiddleware("update", "household", middleware.AuditLoad(dbMod.GetHouseholdByID)), handlers.SaveHousehold) // 登记房屋面积
+		billingGroup.GET("/household/query/all", viewer, handlers.GetHouseholdAllPage)                                                                                                 // 分页查询房屋信息
+		billingGroup.POST("/schedule/add", treasurer, middleware.AuditMiddleware("add", "fee_schedule", nil), handlers.AddFeeSchedule)                                                 // 创建收费标准
+		billingGroup.GET("/schedule/query/:id", handlers.GetFeeScheduleDetail)                                                                                                         // 获取收费标准
+		billingGroup.GET("/schedule/query/all", handlers.GetFeeScheduleAllPage)                                                                                                        // 分页查询收费标准
+		billingGroup.POST("/schedule/update/:id", treasurer, middleware.AuditMiddleware("update", "fee_schedule", scheduleAudit), handlers.UpdateFeeSchedule)                          // 更新收费标准
+		billingGroup.GET("/schedule/delete/:id", treasurer, middleware.AuditMiddleware("delete", "fee_schedule", scheduleAudit), handlers.DeleteFeeSchedule)                           // 删除收费标准
+		billingGroup.POST("/invoice/generate/:id", treasurer, middleware.AuditMiddleware("generate_invoice", "fee_schedule", nil), handlers.GenerateInvoices)                          // 按收费标准生成账期账单
+		billingGroup.GET("/invoice/query/:id", viewer, handlers.GetInvoiceDetail)                                                                                                      // 获取账单
+		billingGroup.GET("/invoice/query/all", viewer, handlers.GetInvoiceAllPage)                                                                                                     // 分页查询账单
+		billingGroup.POST("/invoice/pay/:id", treasurer, middleware.AuditMiddleware("pay", "invoice", invoiceAudit), handlers.PayInvoice)                                              // 登记缴费
+		billingGroup.GET("/invoice/void/:id", treasurer, middleware.AuditMiddleware("void", "invoice", invoiceAudit), handlers.VoidInvoice)                                            // 作废账单
+		billingGroup.GET("/arrears", viewer, handlers.GetArrearsReport)                                                                                                                // 欠费账龄
+		billingGroup.GET("/statement/my", handlers.GetMyStatement)                                                                                                                     // 住户查看本户账单
+		billingGroup.GET("/statement/:id", viewer, handlers.GetHouseholdStatement)                                                                                                     // 查看指定户号的账单
+	}
+}
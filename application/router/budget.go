@@ -0,0 +1,23 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterBudgetRoutes(r *gin.Engine) {
+	budgetGroup := r.Group("/api/v1/budget")
+	budgetGroup.Use(middleware.AuthMiddleware())
+	budgetAudit := middleware.AuditLoad(dbMod.GetBudgetByID)
+	manager := middleware.RoleMiddleware(middleware.RoleTreasurer, middleware.RoleCommittee)
+	{
+		budgetGroup.POST("/add", manager, middleware.AuditMiddleware("add", "budget", nil), handlers.AddBudget)                      // 创建预算
+		budgetGroup.GET("/query/:id", handlers.GetBudgetDetail)                                                                      // 获取预算及预算行
+		budgetGroup.GET("/query/all", handlers.GetBudgetAllPage)                                                                     // 分页查询预算
+		budgetGroup.GET("/report/:id", handlers.GetBudgetReport)                                                                     // 按月对比预算与实际支出
+		budgetGroup.POST("/update/:id", manager, middleware.AuditMiddleware("update", "budget", budgetAudit), handlers.UpdateBudget) // 更新预算
+		budgetGroup.GET("/delete/:id", manager, middleware.AuditMiddleware("delete", "budget", budgetAudit), handlers.DeleteBudget)  // 删除预算
+	}
+}
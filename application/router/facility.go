@@ -3,20 +3,46 @@ package router
 import (
 	"community-governance/application/handlers"
 	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterFacilityRoutes(r *gin.Engine) {
 	faclitiesGroup := r.Group("/api/v1/facilities")
 	faclitiesGroup.Use(middleware.AuthMiddleware())
+	facilityAudit := middleware.AuditLoad(dbMod.GetFacilityByID)
+	reservationAudit := middleware.AuditLoad(dbMod.GetReservationByID)
+	waitlistAudit := middleware.AuditLoad(dbMod.GetWaitlistEntryByID)
 	{
-		faclitiesGroup.GET("/query/:id", handlers.GetFacilityDetail)               // 获取设备信息详细信息
-		faclitiesGroup.POST("/add", handlers.AddFacility)                          // 创建新公共设施
-		faclitiesGroup.GET("/query/all", handlers.GetFacilityAllPage)              // 获取所有公共设施
-		faclitiesGroup.POST("/update/:id", handlers.UpdateFacility)                // 更新设备信息
-		faclitiesGroup.GET("/delete/:id", handlers.DeleteFacility)                 // 删除设备
-		faclitiesGroup.POST("/query/conditions", handlers.GetFacilityByConditions) // 根据条件获取公共设施
-		faclitiesGroup.GET("/request/:id", handlers.RequestFacility)               // 请求公共设施
-		faclitiesGroup.GET("/release/:id", handlers.ReleaseFacility)               // 释放公共设施
+		faclitiesGroup.GET("/query/:id", handlers.GetFacilityDetail)                                                                 // 获取设备信息详细信息
+		faclitiesGroup.POST("/add", middleware.AuditMiddleware("add", "facility", nil), handlers.AddFacility)                        // 创建新公共设施
+		faclitiesGroup.GET("/query/all", handlers.GetFacilityAllPage)                                                                // 获取所有公共设施
+		faclitiesGroup.POST("/update/:id", middleware.AuditMiddleware("update", "facility", facilityAudit), handlers.UpdateFacility) // 更新设备信息
+		faclitiesGroup.GET("/delete/:id", middleware.AuditMiddleware("delete", "facility", facilityAudit), handlers.DeleteFacility)  // 删除设备
+		faclitiesGroup.POST("/query/conditions", handlers.GetFacilityByConditions)                                                   // 根据条件获取公共设施
+		faclitiesGroup.GET("/release/:id", handlers.ReleaseFacility)                                                                 // 释放公共设施
+
+		faclitiesGroup.GET("/schedule/:id", handlers.GetFacilitySchedule) // 开放时间与预约规则
+		faclitiesGroup.POST("/schedule/:id", middleware.RoleMiddleware(middleware.RoleCommittee),
+			middleware.AuditMiddleware("schedule", "facility", facilityAudit), handlers.SetFacilitySchedule) // 设置开放时间
+		faclitiesGroup.GET("/calendar/:id", handlers.GetFacilityCalendar)                                                           // 预约日历
+		faclitiesGroup.POST("/reserve/:id", handlers.ReserveFacility)                                                               // 预约时段
+		faclitiesGroup.GET("/reservation/query/:id", handlers.GetReservationDetail)                                                 // 预约详情
+		faclitiesGroup.GET("/reservation/my", handlers.GetMyReservations)                                                           // 我的预约
+		faclitiesGroup.GET("/reservation/all", middleware.RoleMiddleware(middleware.RoleCommittee), handlers.GetReservationAllPage) // 分页查询预约
+		faclitiesGroup.POST("/reservation/cancel/:id", middleware.AuditMiddleware("cancel", "facility_reservation", reservationAudit),
+			handlers.CancelFacilityReservation) // 取消预约
+
+		faclitiesGroup.GET("/policy/:id", handlers.GetFacilityPolicy) // 预约政策
+		faclitiesGroup.POST("/policy/:id", middleware.RoleMiddleware(middleware.RoleCommittee),
+			middleware.AuditMiddleware("policy", "facility", facilityAudit), handlers.SetFacilityPolicy) // 设置预约政策
+		faclitiesGroup.POST("/waitlist/join/:id", handlers.JoinFacilityWaitlist) // 候补已被预约的时段
+		faclitiesGroup.POST("/waitlist/leave/:id", middleware.AuditMiddleware("leave", "facility_waitlist", waitlistAudit),
+			handlers.LeaveFacilityWaitlist) // 退出候补
+		faclitiesGroup.POST("/waitlist/confirm/:id", middleware.AuditMiddleware("confirm", "facility_waitlist", waitlistAudit),
+			handlers.ConfirmFacilityWaitlist) // 确认候补提供的时段
+		faclitiesGroup.GET("/waitlist/facility/:id", handlers.GetFacilityWaitlist)                                            // 设施某天的候补
+		faclitiesGroup.GET("/waitlist/my", handlers.GetMyWaitlist)                                                            // 我的候补
+		faclitiesGroup.GET("/waitlist/all", middleware.RoleMiddleware(middleware.RoleCommittee), handlers.GetWaitlistAllPage) // 分页查询候补
 	}
 }
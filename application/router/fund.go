@@ -3,20 +3,42 @@ package router
 import (
 	"community-governance/application/handlers"
 	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterFundRoutes(r *gin.Engine) {
 	noticeGroup := r.Group("/api/v1/fund")
 	noticeGroup.Use(middleware.AuthMiddleware())
+	fundAudit := middleware.AuditLoad(dbMod.GetFundByID)
+	pendingAudit := middleware.AuditLoad(dbMod.GetPendingExpenseByID)
+	committee := middleware.RoleMiddleware(middleware.RoleCommittee)
 	{
-		noticeGroup.POST("/add", handlers.AddFund)             // 创建新财务款项
-		noticeGroup.GET("/query/:id", handlers.GetFundDetail)  // 获取财务款项详细信息
-		noticeGroup.GET("/query/all", handlers.GetFundAllPage) // 获取所有财务款项信息
+		noticeGroup.POST("/add", middleware.AuditMiddleware("add", "fund", nil), handlers.AddFund) // 创建新财务款项
+		noticeGroup.GET("/query/:id", handlers.GetFundDetail)                                      // 获取财务款项详细信息
+		noticeGroup.GET("/query/all", handlers.GetFundAllPage)                                     // 获取所有财务款项信息
 		noticeGroup.POST("/query/conditions", handlers.GetFundByConditions)
-		noticeGroup.POST("/update/:id", handlers.UpdateFund)        // 更新财务款项信息
-		noticeGroup.GET("/delete/:id", handlers.DeleteFund)         // 删除财务款项告信息
-		noticeGroup.POST("/add/record/:id", handlers.AddFundRecord) //添加记录
+		noticeGroup.GET("/query/records/:id", handlers.GetFundRecords)                                                                                                                               // 游标分页查询收支记录
+		noticeGroup.GET("/query/journal/:id", handlers.GetFundJournal)                                                                                                                               // 分页查询款项分录
+		noticeGroup.POST("/update/:id", middleware.AuditMiddleware("update", "fund", fundAudit), handlers.UpdateFund)                                                                                // 更新财务款项信息
+		noticeGroup.GET("/delete/:id", middleware.AuditMiddleware("delete", "fund", fundAudit), handlers.DeleteFund)                                                                                 // 删除财务款项告信息
+		noticeGroup.POST("/add/record/:id", middleware.AuditMiddleware("add_record", "fund", fundAudit), handlers.AddFundRecord)                                                                     //添加记录
+		noticeGroup.POST("/attachment/upload", handlers.UploadAttachment)                                                                                                                            // 上传证明文件
+		noticeGroup.GET("/attachment/download/:hash", handlers.DownloadAttachment)                                                                                                                   // 下载证明文件
+		noticeGroup.POST("/attachment/verify/:id", handlers.VerifyAttachment)                                                                                                                        // 校验证明文件与链上记录是否一致
+		noticeGroup.GET("/statement/:id", handlers.GetFundStatement)                                                                                                                                 // 生成月度或年度收支报表
+		noticeGroup.POST("/statement/publish/:id", middleware.RoleMiddleware(middleware.RoleTreasurer), middleware.AuditMiddleware("publish_statement", "fund", nil), handlers.PublishFundStatement) // 发布收支报表hash公告
+		noticeGroup.POST("/transfer", middleware.RoleMiddleware(middleware.RoleTreasurer), middleware.AuditMiddleware("transfer", "fund", nil), handlers.TransferFund)                               // 款项间划转
+		noticeGroup.GET("/query/transfer/:id", handlers.GetFundTransfer)                                                                                                                             // 查询款项间划转
+		noticeGroup.POST("/period/close", middleware.RoleMiddleware(middleware.RoleTreasurer), middleware.AuditMiddleware("close_period", "fund", nil), handlers.ClosePeriod)                        // 会计期间结账
+		noticeGroup.GET("/period/all", handlers.GetClosedPeriods)                                                                                                                                    // 查询已结账的会计期间
+		noticeGroup.POST("/correct/record/:id", middleware.AuditMiddleware("correct_record", "fund", fundAudit), handlers.CorrectFundRecord)                                                         // 添加更正记录
+		noticeGroup.GET("/policy", handlers.GetApprovalPolicy)                                                                                                                                       // 查询大额支出审批策略
+		noticeGroup.POST("/policy", middleware.RoleMiddleware(middleware.RoleAdmin), middleware.AuditMiddleware("set_policy", "approval_policy", nil), handlers.SetApprovalPolicy)                   // 设置大额支出审批策略
+		noticeGroup.GET("/pending/all", handlers.GetPendingExpenses)                                                                                                                                 // 分页查询待审批支出
+		noticeGroup.GET("/query/pending/:id", handlers.GetChainPendingExpenses)                                                                                                                      // 查询款项链上待审批支出
+		noticeGroup.POST("/pending/approve/:id", committee, middleware.AuditMiddleware("approve", "pending_expense", pendingAudit), handlers.ApproveFundExpense)                                     // 批准待审批支出
+		noticeGroup.POST("/pending/reject/:id", committee, middleware.AuditMiddleware("reject", "pending_expense", pendingAudit), handlers.RejectFundExpense)                                        // 驳回待审批支出
 	}
 
 }
@@ -0,0 +1,29 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/label"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterLabelRoutes(r *gin.Engine) {
+	labelGroup := r.Group("/api/v1/label")
+	labelGroup.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleCommittee))
+	{
+		labelGroup.GET("/:kind/:id", handlers.GetLabel)   // 单个二维码标签
+		labelGroup.POST("/sheet", handlers.GetLabelSheet) // 批量生成A4标签纸
+	}
+
+	scanGroup := r.Group(label.ScanPath)
+	faultAudit := middleware.AuditLoad(dbMod.GetFaultReportByID)
+	{
+		scanGroup.GET("", handlers.ScanLabel)                                       // 扫码查看，无需登录
+		scanGroup.POST("/fault", middleware.AuthMiddleware(), handlers.ReportFault) // 扫码报告故障
+		scanGroup.GET("/fault/all", middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleCommittee),
+			handlers.GetFaultReportAllPage) // 分页查询故障报告
+		scanGroup.POST("/fault/handle/:id", middleware.AuthMiddleware(), middleware.RoleMiddleware(middleware.RoleCommittee),
+			middleware.AuditMiddleware("handle", "fault_report", faultAudit), handlers.HandleFaultReport) // 处理故障报告
+	}
+}
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterMaintenanceRoutes(r *gin.Engine) {
+	maintenanceGroup := r.Group("/api/v1/maintenance")
+	maintenanceGroup.Use(middleware.AuthMiddleware())
+	planAudit := middleware.AuditLoad(dbMod.GetMaintenancePlanByID)
+	orderAudit := middleware.AuditLoad(dbMod.GetWorkOrderByID)
+	committee := middleware.RoleMiddleware(middleware.RoleCommittee)
+	{
+		maintenanceGroup.POST("/plan/add", committee, middleware.AuditMiddleware("add", "maintenance_plan", nil), handlers.AddMaintenancePlan)                    // 创建维护计划
+		maintenanceGroup.GET("/plan/query/:id", handlers.GetMaintenancePlanDetail)                                                                                // 获取维护计划
+		maintenanceGroup.GET("/plan/query/all", handlers.GetMaintenancePlanAllPage)                                                                               // 分页查询维护计划
+		maintenanceGroup.POST("/plan/update/:id", committee, middleware.AuditMiddleware("update", "maintenance_plan", planAudit), handlers.UpdateMaintenancePlan) // 更新维护计划
+		maintenanceGroup.GET("/plan/delete/:id", committee, middleware.AuditMiddleware("delete", "maintenance_plan", planAudit), handlers.DeleteMaintenancePlan)  // 删除维护计划
+		maintenanceGroup.POST("/order/generate", committee, middleware.AuditMiddleware("generate_order", "maintenance_plan", nil), handlers.GenerateWorkOrders)   // 为到期计划生成工单
+		maintenanceGroup.GET("/order/query/:id", handlers.GetWorkOrderDetail)                                                                                     // 获取维护工单
+		maintenanceGroup.GET("/order/query/all", handlers.GetWorkOrderAllPage)                                                                                    // 分页查询维护工单
+		maintenanceGroup.GET("/order/my", handlers.GetMyWorkOrders)                                                                                               // 指派给当前用户的工单
+		maintenanceGroup.POST("/order/assign/:id", committee, middleware.AuditMiddleware("assign", "work_order", orderAudit), handlers.AssignWorkOrder)           // 指派负责人
+		maintenanceGroup.GET("/order/start/:id", committee, middleware.AuditMiddleware("start", "work_order", orderAudit), handlers.StartWorkOrder)               // 开工
+		maintenanceGroup.POST("/order/complete/:id", middleware.AuditMiddleware("complete", "work_order", orderAudit), handlers.CompleteWorkOrder)                // 完成工单
+		maintenanceGroup.POST("/order/cancel/:id", committee, middleware.AuditMiddleware("cancel", "work_order", orderAudit), handlers.CancelWorkOrder)           // 取消工单
+	}
+}
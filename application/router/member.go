@@ -2,19 +2,27 @@ package router
 
 import (
 	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterMemberRoutes 注册成员路由
 func RegisterMemberRoutes(r *gin.Engine) {
 	memberGroup := r.Group("/api/v1/members")
+	memberGroup.Use(middleware.AuthMiddleware())
+	//成员可以查看自己的信息，管理员可以查看所有成员，在处理函数中校验
+	memberGroup.GET("query/:id", handlers.GetMember) // 获取用户信息
+	//成员登记会签发带角色属性的证书，仅管理员可以管理成员
+	adminGroup := memberGroup.Group("", middleware.RoleMiddleware(middleware.RoleAdmin))
+	memberAudit := middleware.AuditLoad(dbMod.GetMemberByID)
 	{
-		memberGroup.GET("query/:id", handlers.GetMember)                 // 获取用户信息
-		memberGroup.POST("/add", handlers.AddMember)                     // 创建新用户
-		memberGroup.GET("/query/all", handlers.GetMemberAllPage)         // 获取所有用户
-		memberGroup.POST("/update/:id", handlers.UpdateMember)           // 更新用户信息
-		memberGroup.GET("/delete/:id", handlers.DeleteMember)            // 删除用户
-		memberGroup.GET("/update/state/:id", handlers.UpdateMemberState) // 更新用户状态
-		memberGroup.POST("/query/conditions", handlers.GetMemberByConditions)
+		adminGroup.POST("/add", middleware.AuditMiddleware("add", "member", nil), handlers.AddMember)                                      // 创建新用户
+		adminGroup.GET("/query/all", handlers.GetMemberAllPage)                                                                            // 获取所有用户
+		adminGroup.POST("/update/:id", middleware.AuditMiddleware("update", "member", memberAudit), handlers.UpdateMember)                 // 更新用户信息
+		adminGroup.GET("/delete/:id", middleware.AuditMiddleware("delete", "member", memberAudit), handlers.DeleteMember)                  // 删除用户
+		adminGroup.GET("/update/state/:id", middleware.AuditMiddleware("update_state", "member", memberAudit), handlers.UpdateMemberState) // 更新用户状态
+		adminGroup.POST("/query/conditions", handlers.GetMemberByConditions)
+		adminGroup.GET("/enroll/:id", middleware.AuditMiddleware("enroll", "member", memberAudit), handlers.EnrollMember) // 登记成员链上身份
 	}
 }
@@ -3,18 +3,20 @@ package router
 import (
 	"community-governance/application/handlers"
 	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterNoticeRoutes(r *gin.Engine) {
 	noticeGroup := r.Group("/api/v1/notice")
 	noticeGroup.Use(middleware.AuthMiddleware())
+	noticeAudit := middleware.AuditLoad(dbMod.GetNoticeByID)
 	{
-		noticeGroup.POST("/add", handlers.AddNotice)             // 创建新公告
-		noticeGroup.GET("/query/:id", handlers.GetNoticeDetail)  // 获取公告详细信息
-		noticeGroup.GET("/query/all", handlers.GetNoticeAllPage) // 获取所有公告信息
-		noticeGroup.POST("/update/:id", handlers.UpdateNotice)   // 更新公告信息
-		noticeGroup.GET("/delete/:id", handlers.DeleteNotice)    // 删除公告信息
+		noticeGroup.POST("/add", middleware.AuditMiddleware("add", "notice", nil), handlers.AddNotice)                      // 创建新公告
+		noticeGroup.GET("/query/:id", handlers.GetNoticeDetail)                                                             // 获取公告详细信息
+		noticeGroup.GET("/query/all", handlers.GetNoticeAllPage)                                                            // 获取所有公告信息
+		noticeGroup.POST("/update/:id", middleware.AuditMiddleware("update", "notice", noticeAudit), handlers.UpdateNotice) // 更新公告信息
+		noticeGroup.GET("/delete/:id", middleware.AuditMiddleware("delete", "notice", noticeAudit), handlers.DeleteNotice)  // 删除公告信息
 		noticeGroup.POST("/query/conditions", handlers.GetNoticeByConditions)
 	}
 
@@ -15,5 +15,14 @@ func SetupRouter() *gin.Engine {
 	RegisterMemberRoutes(r)
 	RegisterVoteRoutes(r)
 	RegisterFacilityRoutes(r)
+	RegisterAuditRoutes(r)
+	RegisterAdminRoutes(r)
+	RegisterBudgetRoutes(r)
+	RegisterBankRoutes(r)
+	RegisterBillingRoutes(r)
+	RegisterMaintenanceRoutes(r)
+	RegisterStocktakeRoutes(r)
+	RegisterLabelRoutes(r)
+	RegisterWorkflowRoutes(r)
 	return r
 }
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterStocktakeRoutes(r *gin.Engine) {
+	stocktakeGroup := r.Group("/api/v1/stocktake")
+	stocktakeGroup.Use(middleware.AuthMiddleware())
+	stocktakeAudit := middleware.AuditLoad(dbMod.GetStocktakeByID)
+	admin := middleware.RoleMiddleware()
+	{
+		stocktakeGroup.POST("/open", admin, middleware.AuditMiddleware("add", "stocktake", nil), handlers.OpenStocktake)                   // 发起盘点
+		stocktakeGroup.GET("/query/:id", handlers.GetStocktakeDetail)                                                                      // 获取盘点及清点记录
+		stocktakeGroup.GET("/query/all", handlers.GetStocktakeAllPage)                                                                     // 分页查询盘点
+		stocktakeGroup.POST("/scan/:id", handlers.ScanStocktake)                                                                           // 清点资产
+		stocktakeGroup.GET("/report/:id", handlers.GetStocktakeReport)                                                                     // 盘点报告
+		stocktakeGroup.GET("/close/:id", admin, middleware.AuditMiddleware("close", "stocktake", stocktakeAudit), handlers.CloseStocktake) // 结束盘点并上链
+		stocktakeGroup.GET("/verify/:id", handlers.VerifyStocktake)                                                                        // 校验盘点报告
+	}
+}
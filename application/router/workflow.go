@@ -0,0 +1,31 @@
+package router
+
+import (
+	"community-governance/application/handlers"
+	"community-governance/application/middleware"
+	dbMod "community-governance/db/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterWorkflowRoutes(r *gin.Engine) {
+	workflowGroup := r.Group("/api/v1/workflow")
+	workflowGroup.Use(middleware.AuthMiddleware())
+	instanceAudit := middleware.AuditLoad(dbMod.GetWorkflowInstanceByID)
+	{
+		workflowGroup.GET("/definition/all", handlers.GetWorkflowDefinitions)        // 已配置的审批流程
+		workflowGroup.GET("/definition/query/:kind", handlers.GetWorkflowDefinition) // 对象类型的审批流程
+		workflowGroup.POST("/definition/save/:kind", middleware.RoleMiddleware(),
+			middleware.AuditMiddleware("save", "workflow_definition", nil), handlers.SaveWorkflowDefinition) // 配置审批流程
+		workflowGroup.GET("/definition/delete/:kind", middleware.RoleMiddleware(),
+			middleware.AuditMiddleware("delete", "workflow_definition", nil), handlers.DeleteWorkflowDefinition) // 恢复默认审批流程
+
+		workflowGroup.GET("/query/all", handlers.GetWorkflowInstanceAllPage)     // 分页查询审批流程
+		workflowGroup.GET("/query/target", handlers.GetWorkflowInstanceByTarget) // 审批对象的审批流程
+		workflowGroup.GET("/query/:id", handlers.GetWorkflowInstanceDetail)      // 审批流程详情
+		workflowGroup.GET("/task/my", handlers.GetMyWorkflowTasks)               // 我的待审批步骤
+		workflowGroup.POST("/decide/:id", middleware.AuditMiddleware("decide", "workflow", instanceAudit),
+			handlers.DecideWorkflow) // 批准或驳回
+		workflowGroup.POST("/comment/:id", handlers.CommentWorkflow) // 发表意见
+		workflowGroup.GET("/verify/:id", handlers.VerifyWorkflow)    // 核对链上审批决定
+	}
+}
@@ -0,0 +1,62 @@
+package waitlist
+
+import (
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"community-governance/fabric"
+	"log"
+	"time"
+)
+
+// Sync 按链上状态同步设施某天的候补，取消预约或候补变化后链上可能已将时段提供给后续候补
+func Sync(facilityID, date string) error {
+	recorded, err := fabric.GetWaitlist(facilityID, date)
+	if err != nil {
+		return err
+	}
+	entries := make([]dbMod.FacilityWaitlist, 0, len(recorded))
+	for _, e := range recorded {
+		entries = append(entries, dbMod.FacilityWaitlist{
+			EntryID:       e.EntryID,
+			Status:        e.Status,
+			OfferTime:     e.OfferDate,
+			Deadline:      e.Deadline,
+			ReservationID: e.ReservationID,
+		})
+	}
+	return dbMod.SyncWaitlist(entries)
+}
+
+// ExpireOnce 将超过确认时限的候补置为过期，并将空出的时段提供给后续候补，返回处理的设施日期数
+func ExpireOnce() (int, error) {
+	days, err := dbMod.GetLapsedWaitlistDays(utils.GetNowTimeString())
+	if err != nil {
+		return 0, err
+	}
+	for _, day := range days {
+		if _, err := fabric.ExpireWaitOffers(day.FacilityID, day.Date); err != nil {
+			return 0, err
+		}
+		if err := Sync(day.FacilityID, day.Date); err != nil {
+			return 0, err
+		}
+	}
+	return len(days), nil
+}
+
+// StartExpiry 定期处理超过确认时限的候补
+func StartExpiry(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			expired, err := ExpireOnce()
+			if err != nil {
+				log.Printf("failed to expire waitlist offers:%s", err.Error())
+				continue
+			}
+			if expired > 0 {
+				log.Printf("processed waitlist offers of %d facility days", expired)
+			}
+		}
+	}()
+}
@@ -0,0 +1,25 @@
+package workflow
+
+import (
+	"community-governance/application/utils"
+	dbMod "community-governance/db/models"
+	"log"
+	"time"
+)
+
+// StartEscalation 定期将超过审批期限的步骤升级，升级后升级角色也可审批
+func StartEscalation(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			escalated, err := dbMod.EscalateWorkflowTasks(utils.GetNowTimeString())
+			if err != nil {
+				log.Printf("failed to escalate workflow tasks:%s", err.Error())
+				continue
+			}
+			if escalated > 0 {
+				log.Printf("escalated %d workflow tasks", escalated)
+			}
+		}
+	}()
+}
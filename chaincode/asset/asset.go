@@ -1,6 +1,7 @@
 package main
 
 import (
+	"community-governance/chaincode/common"
 	"encoding/json"
 	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
@@ -15,16 +16,31 @@ type Asset struct {
 	Owner      string `json:"owner"`     //资产拥有者
 	Recorder   string `json:"recorder"`
 	RecordDate string `json:"recordDate"`
+	Status     string `json:"status"`               //资产状态
+	Reason     string `json:"reason,omitempty"`     //最近一次状态变更的原因
+	WorkOrder  string `json:"workOrder,omitempty"`  //维修中资产的维护工单ID
+	Assignee   string `json:"assignee,omitempty"`   //维护工单的负责人ID
+	Request    string `json:"request,omitempty"`    //最近一次处置、调拨或借用的资产申请ID
+	Transferee string `json:"transferee,omitempty"` //调拨中待接收的保管人ID
+	Borrower   string `json:"borrower,omitempty"`   //借用人ID
+	DueDate    string `json:"dueDate,omitempty"`    //借用归还期限
+	Disposal   string `json:"disposal,omitempty"`   //处置方式
+	Proceeds   string `json:"proceeds,omitempty"`   //处置收入
+	Fund       string `json:"fund,omitempty"`       //处置收入入账的款项
 }
 
 const (
-	stateInit        = "active"  // 资产状态 - 初始化
-	stateMaintenance = "repair"  // 资产状态 - 维修中
-	stateExpired     = "disposa" // 资产状态 - 失效
+	stateRegistered  = "registered" // 资产状态 - 已登记
+	stateInit        = "active"     // 资产状态 - 使用中
+	stateMaintenance = "repair"     // 资产状态 - 维修中
+	stateExpired     = "disposed"   // 资产状态 - 已处置
 )
 
 // CreateAsset 创建资产
-func (a *AssetContract) CreateAsset(ctx contractapi.TransactionContextInterface, assetID, asserHash, owner, recorder string) error {
+func (a *AssetContract) CreateAsset(ctx contractapi.TransactionContextInterface, assetID, asserHash, owner string) error {
+	if err := common.RequireRole(ctx, "CreateAsset", common.RoleCommittee); err != nil {
+		return err
+	}
 	state, err := ctx.GetStub().GetState(assetID)
 	if err != nil {
 		return fmt.Errorf("failed get state:%s", err.Error())
@@ -32,6 +48,10 @@ func (a *AssetContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 	if state != nil {
 		return fmt.Errorf("%s already exist", assetID)
 	}
+	recorder, err := common.GetActor(ctx)
+	if err != nil {
+		return err
+	}
 	nowTime, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return fmt.Errorf("failed to get tx timestamp:%s", err.Error())
@@ -41,6 +61,7 @@ func (a *AssetContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 		Owner:      owner,
 		Recorder:   recorder,
 		RecordDate: nowTime.AsTime().Format("2006-01-02 15:04:05"),
+		Status:     stateRegistered,
 	}
 	assetBytes, err := json.Marshal(asset)
 	if err != nil {
@@ -70,12 +91,28 @@ func (a *AssetContract) GetAsset(ctx contractapi.TransactionContextInterface, as
 
 // ExchangeOwner 更改资产owner
 func (a *AssetContract) ExchangeOwner(ctx contractapi.TransactionContextInterface, assetID string, newOwner string) error {
+	if err := common.RequireRole(ctx, "ExchangeOwner", common.RoleCommittee); err != nil {
+		return err
+	}
 	//判断assetID是否存在
 	asset, err := a.GetAsset(ctx, assetID)
 	if err != nil {
 		return err
 	}
+	if assetState(asset) == stateExpired {
+		return fmt.Errorf("asset %s is disposed", assetID)
+	}
+	recorder, err := common.GetActor(ctx)
+	if err != nil {
+		return err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
 	asset.Owner = newOwner
+	asset.Recorder = recorder
+	asset.RecordDate = nowTime.AsTime().Format("2006-01-02 15:04:05")
 	assetBytes, err := json.Marshal(asset)
 	if err != nil {
 		return fmt.Errorf("failed to marashl:%s", err.Error())
@@ -110,11 +147,21 @@ func (a *AssetContract) GetAssetHistory(ctx contractapi.TransactionContextInterf
 }
 
 // UpdateAsset 更改资产信息
-func (a *AssetContract) UpdateAsset(ctx contractapi.TransactionContextInterface, assetID, asserHash, owner, recorder string) error {
+func (a *AssetContract) UpdateAsset(ctx contractapi.TransactionContextInterface, assetID, asserHash, owner string) error {
+	if err := common.RequireRole(ctx, "UpdateAsset", common.RoleCommittee); err != nil {
+		return err
+	}
 	asset, err := a.GetAsset(ctx, assetID)
 	if err != nil {
 		return err
 	}
+	if assetState(asset) == stateExpired {
+		return fmt.Errorf("asset %s is disposed", assetID)
+	}
+	recorder, err := common.GetActor(ctx)
+	if err != nil {
+		return err
+	}
 	nowTime, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return fmt.Errorf("failed to get tx timestamp:%s", err.Error())
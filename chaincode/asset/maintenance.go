@@ -0,0 +1,69 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// StartAssetMaintenance 按维护工单将资产转入维修中，记录工单与负责人
+func (a *AssetContract) StartAssetMaintenance(ctx contractapi.TransactionContextInterface, assetID, workOrderID, assignee, reason string) (Asset, error) {
+	if err := common.RequireRole(ctx, "StartAssetMaintenance", common.RoleCommittee); err != nil {
+		return Asset{}, err
+	}
+	if workOrderID == "" || assignee == "" {
+		return Asset{}, fmt.Errorf("work order and assignee are required")
+	}
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	from := assetState(asset)
+	if !canTransit(from, stateMaintenance) {
+		return Asset{}, fmt.Errorf("illegal state transition of %s: %s -> %s", assetID, from, stateMaintenance)
+	}
+	asset.Status = stateMaintenance
+	asset.Reason = reason
+	asset.WorkOrder = workOrderID
+	asset.Assignee = assignee
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
+
+// EndAssetMaintenance 维护工单结束后资产恢复使用，只有工单负责人或业委会成员可以操作
+func (a *AssetContract) EndAssetMaintenance(ctx contractapi.TransactionContextInterface, assetID, workOrderID, reason string) (Asset, error) {
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	if asset.Status != stateMaintenance || asset.WorkOrder != workOrderID {
+		return Asset{}, fmt.Errorf("asset %s is not under work order %s", assetID, workOrderID)
+	}
+	if err := common.RequireOwnerOrRole(ctx, "EndAssetMaintenance", asset.Assignee, common.RoleCommittee); err != nil {
+		return Asset{}, err
+	}
+	asset.Status = stateInit
+	asset.Reason = reason
+	asset.WorkOrder = ""
+	asset.Assignee = ""
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
+
+// putAsset 记录操作人与时间后写入资产
+func (a *AssetContract) putAsset(ctx contractapi.TransactionContextInterface, assetID string, asset *Asset) error {
+	recorder, err := common.GetActor(ctx)
+	if err != nil {
+		return err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	asset.Recorder = recorder
+	asset.RecordDate = nowTime.AsTime().Format("2006-01-02 15:04:05")
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return fmt.Errorf("failed marshal asset:%s", err.Error())
+	}
+	return ctx.GetStub().PutState(assetID, assetBytes)
+}
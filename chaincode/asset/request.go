@@ -0,0 +1,179 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"time"
+)
+
+// 资产处置方式，出售必须有处置收入，捐赠不能有处置收入
+const (
+	disposalSale    = "sale"    // 出售
+	disposalScrap   = "scrap"   // 报废
+	disposalDonate  = "donate"  // 捐赠
+	disposalRecycle = "recycle" // 回收
+)
+
+var disposalMethods = map[string]bool{disposalSale: true, disposalScrap: true, disposalDonate: true, disposalRecycle: true}
+
+// checkIdle 资产已处置、调拨中或借出时不能再处置、调拨或借出
+func checkIdle(assetID string, asset Asset) error {
+	if assetState(asset) == stateExpired {
+		return fmt.Errorf("asset %s is disposed", assetID)
+	}
+	if asset.Transferee != "" {
+		return fmt.Errorf("asset %s is being transferred to %s", assetID, asset.Transferee)
+	}
+	if asset.Borrower != "" {
+		return fmt.Errorf("asset %s is lent to %s", assetID, asset.Borrower)
+	}
+	return nil
+}
+
+// DisposeAsset 按资产申请处置资产，记录处置方式与处置收入，有处置收入时必须指定入账款项
+func (a *AssetContract) DisposeAsset(ctx contractapi.TransactionContextInterface, assetID, requestID, method, proceeds, fundID, reason string) (Asset, error) {
+	if err := common.RequireRole(ctx, "DisposeAsset", common.RoleCommittee); err != nil {
+		return Asset{}, err
+	}
+	if !disposalMethods[method] {
+		return Asset{}, fmt.Errorf("illegal disposal method:%s", method)
+	}
+	cents, err := common.ParseAmount(proceeds)
+	if err != nil {
+		return Asset{}, err
+	}
+	if method == disposalSale && cents == 0 {
+		return Asset{}, fmt.Errorf("proceeds are required for sale")
+	}
+	if method == disposalDonate && cents > 0 {
+		return Asset{}, fmt.Errorf("donation must not have proceeds")
+	}
+	if cents > 0 && fundID == "" {
+		return Asset{}, fmt.Errorf("fund is required for proceeds")
+	}
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	if err := checkIdle(assetID, asset); err != nil {
+		return Asset{}, err
+	}
+	from := assetState(asset)
+	if !canTransit(from, stateExpired) {
+		return Asset{}, fmt.Errorf("illegal state transition of %s: %s -> %s", assetID, from, stateExpired)
+	}
+	asset.Status = stateExpired
+	asset.Reason = reason
+	asset.WorkOrder = ""
+	asset.Assignee = ""
+	asset.Request = requestID
+	asset.Disposal = method
+	asset.Proceeds = common.FormatAmount(cents)
+	asset.Fund = fundID
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
+
+// StartTransfer 按资产申请将资产调拨给新的保管人，新保管人接收后保管人才变更
+func (a *AssetContract) StartTransfer(ctx contractapi.TransactionContextInterface, assetID, requestID, transferee string) (Asset, error) {
+	if err := common.RequireRole(ctx, "StartTransfer", common.RoleCommittee); err != nil {
+		return Asset{}, err
+	}
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	if err := checkIdle(assetID, asset); err != nil {
+		return Asset{}, err
+	}
+	if transferee == "" || transferee == asset.Owner {
+		return Asset{}, fmt.Errorf("illegal transferee:%s", transferee)
+	}
+	asset.Request = requestID
+	asset.Transferee = transferee
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
+
+// AcceptTransfer 新保管人接收调拨的资产，只有待接收的保管人本人可以操作
+func (a *AssetContract) AcceptTransfer(ctx contractapi.TransactionContextInterface, assetID string) (Asset, error) {
+	return a.finishTransfer(ctx, "AcceptTransfer", assetID, true)
+}
+
+// DeclineTransfer 新保管人拒绝接收调拨的资产，资产仍由原保管人保管
+func (a *AssetContract) DeclineTransfer(ctx contractapi.TransactionContextInterface, assetID string) (Asset, error) {
+	return a.finishTransfer(ctx, "DeclineTransfer", assetID, false)
+}
+
+func (a *AssetContract) finishTransfer(ctx contractapi.TransactionContextInterface, function, assetID string, accept bool) (Asset, error) {
+	actor, err := common.RequireMember(ctx, function)
+	if err != nil {
+		return Asset{}, err
+	}
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	if asset.Transferee == "" {
+		return Asset{}, fmt.Errorf("asset %s is not being transferred", assetID)
+	}
+	if actor != asset.Transferee {
+		return Asset{}, &common.AccessError{Code: common.CodeNotOwner, Function: function, Message: fmt.Sprintf("%s is not the transferee", actor)}
+	}
+	if accept {
+		asset.Owner = asset.Transferee
+	}
+	asset.Transferee = ""
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
+
+// LendAsset 按资产申请将资产借给借用人，归还期限为yyyy-MM-dd且不能早于当天
+func (a *AssetContract) LendAsset(ctx contractapi.TransactionContextInterface, assetID, requestID, borrower, dueDate string) (Asset, error) {
+	if err := common.RequireRole(ctx, "LendAsset", common.RoleCommittee); err != nil {
+		return Asset{}, err
+	}
+	if borrower == "" {
+		return Asset{}, fmt.Errorf("borrower is required")
+	}
+	if _, err := time.Parse("2006-01-02", dueDate); err != nil {
+		return Asset{}, fmt.Errorf("illegal due date:%s", dueDate)
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return Asset{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	if dueDate < nowTime.AsTime().Format("2006-01-02") {
+		return Asset{}, fmt.Errorf("due date %s has passed", dueDate)
+	}
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	if err := checkIdle(assetID, asset); err != nil {
+		return Asset{}, err
+	}
+	if assetState(asset) == stateMaintenance {
+		return Asset{}, fmt.Errorf("asset %s is under maintenance", assetID)
+	}
+	asset.Request = requestID
+	asset.Borrower = borrower
+	asset.DueDate = dueDate
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
+
+// ReturnAsset 借出的资产归还入库，由资产保管人或业委会成员验收
+func (a *AssetContract) ReturnAsset(ctx contractapi.TransactionContextInterface, assetID, reason string) (Asset, error) {
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	if asset.Borrower == "" {
+		return Asset{}, fmt.Errorf("asset %s is not lent", assetID)
+	}
+	if err := common.RequireOwnerOrRole(ctx, "ReturnAsset", asset.Owner, common.RoleCommittee); err != nil {
+		return Asset{}, err
+	}
+	asset.Reason = reason
+	asset.Borrower = ""
+	asset.DueDate = ""
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+type mockIdentity struct {
+	cn    string
+	attrs map[string]string
+}
+
+func (m *mockIdentity) GetID() (string, error)    { return m.cn, nil }
+func (m *mockIdentity) GetMSPID() (string, error) { return "Org1MSP", nil }
+func (m *mockIdentity) GetAttributeValue(name string) (string, bool, error) {
+	v, ok := m.attrs[name]
+	return v, ok, nil
+}
+func (m *mockIdentity) AssertAttributeValue(name, value string) error { return nil }
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{Subject: pkix.Name{CommonName: m.cn}}, nil
+}
+
+func newCtx(cn string, attrs map[string]string) *contractapi.TransactionContext {
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetClientIdentity(&mockIdentity{cn: cn, attrs: attrs})
+	return ctx
+}
+
+func TestCheckIdle(t *testing.T) {
+	cases := []struct {
+		name  string
+		asset Asset
+		err   string
+	}{
+		{"active", Asset{Status: stateInit}, ""},
+		{"legacy without status", Asset{}, ""},
+		{"under maintenance", Asset{Status: stateMaintenance}, ""},
+		{"disposed", Asset{Status: stateExpired}, "is disposed"},
+		{"being transferred", Asset{Status: stateInit, Transferee: "m2"}, "being transferred to m2"},
+		{"lent", Asset{Status: stateInit, Borrower: "m3"}, "lent to m3"},
+	}
+	for _, c := range cases {
+		err := checkIdle("a1", c.asset)
+		if c.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: got %v, want error containing %q", c.name, err, c.err)
+		}
+	}
+}
+
+// 处置参数在读取资产之前校验
+func TestDisposeAssetArguments(t *testing.T) {
+	committee := map[string]string{common.RoleAttribute: common.RoleCommittee}
+	cases := []struct {
+		name     string
+		attrs    map[string]string
+		method   string
+		proceeds string
+		fund     string
+		err      string
+	}{
+		{"not committee", map[string]string{common.RoleAttribute: "resident"}, disposalScrap, "0", "", "resident"},
+		{"unknown method", committee, "burn", "0", "", "illegal disposal method"},
+		{"illegal proceeds", committee, disposalSale, "1.234", "f1", "1.234"},
+		{"sale without proceeds", committee, disposalSale, "0", "f1", "proceeds are required for sale"},
+		{"donation with proceeds", committee, disposalDonate, "10.00", "f1", "donation must not have proceeds"},
+		{"proceeds without fund", committee, disposalRecycle, "10.00", "", "fund is required for proceeds"},
+	}
+	a := new(AssetContract)
+	for _, c := range cases {
+		_, err := a.DisposeAsset(newCtx("m1", c.attrs), "a1", "req1", c.method, c.proceeds, c.fund, "reason")
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: got %v, want error containing %q", c.name, err, c.err)
+		}
+	}
+}
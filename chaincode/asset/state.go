@@ -0,0 +1,113 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// assetTransitions 资产状态机，键为当前状态，值为允许转入的状态
+// 已登记的资产启用后进入使用中，使用中的资产可送修或处置，维修完成后恢复使用，已处置为终态
+var assetTransitions = map[string][]string{
+	stateRegistered:  {stateInit, stateExpired},
+	stateInit:        {stateMaintenance, stateExpired},
+	stateMaintenance: {stateInit, stateExpired},
+}
+
+// AssetStateChange 资产状态时间线中的一次状态变更
+type AssetStateChange struct {
+	TxID   string `json:"txId"`
+	From   string `json:"from"`   //变更前状态，登记时为空
+	State  string `json:"state"`  //变更后状态
+	Actor  string `json:"actor"`  //操作人ID
+	Reason string `json:"reason"` //变更原因
+	Date   string `json:"date"`   //变更时间
+}
+
+// assetState 资产当前状态，状态机上线前登记的资产没有状态，视为使用中
+func assetState(asset Asset) string {
+	if asset.Status == "" {
+		return stateInit
+	}
+	return asset.Status
+}
+
+// canTransit 判断资产能否从from状态转入to状态
+func canTransit(from, to string) bool {
+	for _, state := range assetTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeAssetState 变更资产状态，校验状态机并记录操作人与原因
+func (a *AssetContract) ChangeAssetState(ctx contractapi.TransactionContextInterface, assetID, state, reason string) (Asset, error) {
+	if err := common.RequireRole(ctx, "ChangeAssetState", common.RoleCommittee); err != nil {
+		return Asset{}, err
+	}
+	if reason == "" {
+		return Asset{}, fmt.Errorf("reason is required")
+	}
+	asset, err := a.GetAsset(ctx, assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	//处置前资产需已归还且不在调拨中
+	if state == stateExpired {
+		if err := checkIdle(assetID, asset); err != nil {
+			return Asset{}, err
+		}
+	}
+	from := assetState(asset)
+	if !canTransit(from, state) {
+		return Asset{}, fmt.Errorf("illegal state transition of %s: %s -> %s", assetID, from, state)
+	}
+	asset.Status = state
+	asset.Reason = reason
+	asset.WorkOrder = ""
+	asset.Assignee = ""
+	return asset, a.putAsset(ctx, assetID, &asset)
+}
+
+// GetAssetTimeline 查询资产的状态时间线，只返回状态发生变化的历史记录
+func (a *AssetContract) GetAssetTimeline(ctx contractapi.TransactionContextInterface, assetID string) ([]AssetStateChange, error) {
+	state, err := ctx.GetStub().GetHistoryForKey(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history record for %s: %v", assetID, err)
+	}
+	defer state.Close()
+
+	timeline := make([]AssetStateChange, 0)
+	from := ""
+	for state.HasNext() {
+		next, err := state.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next history record: %v", err)
+		}
+		if next.IsDelete {
+			continue
+		}
+		var asset Asset
+		if err := json.Unmarshal(next.Value, &asset); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset data: %v", err)
+		}
+		current := assetState(asset)
+		if current == from {
+			continue
+		}
+		change := AssetStateChange{
+			TxID:   next.TxId,
+			From:   from,
+			State:  current,
+			Actor:  asset.Recorder,
+			Reason: asset.Reason,
+			Date:   asset.RecordDate,
+		}
+		timeline = append(timeline, change)
+		from = current
+	}
+	return timeline, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestCanTransit(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     bool
+	}{
+		{stateRegistered, stateInit, true},
+		{stateRegistered, stateExpired, true},
+		{stateRegistered, stateMaintenance, false},
+		{stateInit, stateMaintenance, true},
+		{stateInit, stateExpired, true},
+		{stateInit, stateRegistered, false},
+		{stateInit, stateInit, false},
+		{stateMaintenance, stateInit, true},
+		{stateMaintenance, stateExpired, true},
+		{stateExpired, stateInit, false},
+		{stateExpired, stateMaintenance, false},
+		{"unknown", stateInit, false},
+	}
+	for _, c := range cases {
+		if got := canTransit(c.from, c.to); got != c.want {
+			t.Errorf("%s -> %s: got %v, want %v", c.from, c.to, got, c.want)
+		}
+	}
+}
+
+func TestAssetState(t *testing.T) {
+	cases := []struct {
+		status string
+		want   string
+	}{
+		{"", stateInit}, //状态机上线前登记的资产
+		{stateRegistered, stateRegistered},
+		{stateMaintenance, stateMaintenance},
+		{stateExpired, stateExpired},
+	}
+	for _, c := range cases {
+		if got := assetState(Asset{Status: c.status}); got != c.want {
+			t.Errorf("status %q: got %s, want %s", c.status, got, c.want)
+		}
+	}
+}
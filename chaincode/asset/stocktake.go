@@ -0,0 +1,88 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Stocktake 已结束的资产盘点，盘点报告的hash由结束盘点的管理员签名提交
+type Stocktake struct {
+	StocktakeID  string `json:"stocktakeId"`
+	Location     string `json:"location"`     //盘点范围的位置
+	ReportHash   string `json:"reportHash"`   //盘点报告hash值
+	Counted      int    `json:"counted"`      //已清点的资产数
+	Missing      int    `json:"missing"`      //盘亏的资产数
+	Misplaced    int    `json:"misplaced"`    //位置不符的资产数
+	Unregistered int    `json:"unregistered"` //未登记的物品数
+	Closer       string `json:"closer"`       //结束盘点的管理员ID
+	CloseDate    string `json:"closeDate"`    //结束时间
+}
+
+const stocktakeIndex = "stocktake~id" //盘点记录的组合键前缀
+
+// CloseStocktake 记录结束的资产盘点，每次盘点只能记录一次
+func (a *AssetContract) CloseStocktake(ctx contractapi.TransactionContextInterface, stocktakeID, location, reportHash string, counted, missing, misplaced, unregistered int) (Stocktake, error) {
+	if err := common.RequireRole(ctx, "CloseStocktake"); err != nil {
+		return Stocktake{}, err
+	}
+	if reportHash == "" {
+		return Stocktake{}, fmt.Errorf("report hash is required")
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(stocktakeIndex, []string{stocktakeID})
+	if err != nil {
+		return Stocktake{}, err
+	}
+	state, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return Stocktake{}, fmt.Errorf("failed to get state:%s", err.Error())
+	}
+	if state != nil {
+		return Stocktake{}, fmt.Errorf("stocktake %s is already closed", stocktakeID)
+	}
+	closer, err := common.GetActor(ctx)
+	if err != nil {
+		return Stocktake{}, err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return Stocktake{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	stocktake := Stocktake{
+		StocktakeID:  stocktakeID,
+		Location:     location,
+		ReportHash:   reportHash,
+		Counted:      counted,
+		Missing:      missing,
+		Misplaced:    misplaced,
+		Unregistered: unregistered,
+		Closer:       closer,
+		CloseDate:    nowTime.AsTime().Format("2006-01-02 15:04:05"),
+	}
+	data, err := json.Marshal(stocktake)
+	if err != nil {
+		return Stocktake{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	return stocktake, ctx.GetStub().PutState(key, data)
+}
+
+// GetStocktake 查询链上的盘点记录
+func (a *AssetContract) GetStocktake(ctx contractapi.TransactionContextInterface, stocktakeID string) (Stocktake, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(stocktakeIndex, []string{stocktakeID})
+	if err != nil {
+		return Stocktake{}, err
+	}
+	state, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return Stocktake{}, fmt.Errorf("failed to get state:%s", err.Error())
+	}
+	if state == nil {
+		return Stocktake{}, fmt.Errorf("stocktake %s is not exist", stocktakeID)
+	}
+	var stocktake Stocktake
+	if err := json.Unmarshal(state, &stocktake); err != nil {
+		return Stocktake{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return stocktake, nil
+}
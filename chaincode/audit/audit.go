@@ -0,0 +1,117 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"strconv"
+)
+
+// AuditContract 审计日志锚定合约，定期记录链下审计日志hash链的链头
+type AuditContract struct {
+	contractapi.Contract
+}
+
+// Anchor 审计链头
+type Anchor struct {
+	LastLogID  uint64 `json:"last_log_id"` //链头日志ID
+	HeadHash   string `json:"head_hash"`   //链头hash
+	Anchorer   string `json:"anchorer"`    //锚定人
+	AnchorTime string `json:"anchor_time"` //锚定时间
+}
+
+type AnchorRecord struct {
+	Tx     string `json:"tx"`
+	Anchor Anchor `json:"anchor"`
+}
+
+const headKey = "audit-head"
+
+// AnchorHead 锚定审计链头，链头日志ID必须单调递增，返回交易ID
+// 仅审计锚定身份与管理员可以锚定，避免成员锚定伪造的链头使之后的锚定全部被拒绝
+func (a *AuditContract) AnchorHead(ctx contractapi.TransactionContextInterface, lastLogID, headHash string) (string, error) {
+	if err := common.RequireRole(ctx, "AnchorHead", common.RoleAuditor); err != nil {
+		return "", err
+	}
+	anchorer, err := common.GetActor(ctx)
+	if err != nil {
+		return "", err
+	}
+	logID, err := strconv.ParseUint(lastLogID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid last log id:%s", err.Error())
+	}
+	if headHash == "" {
+		return "", fmt.Errorf("head hash = ''")
+	}
+	state, err := ctx.GetStub().GetState(headKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get state:%s", err.Error())
+	}
+	if state != nil {
+		var head Anchor
+		if err := json.Unmarshal(state, &head); err != nil {
+			return "", fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+		if logID <= head.LastLogID {
+			return "", fmt.Errorf("log id %d is not after anchored head %d", logID, head.LastLogID)
+		}
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	anchor := Anchor{
+		LastLogID:  logID,
+		HeadHash:   headHash,
+		Anchorer:   anchorer,
+		AnchorTime: nowTime.AsTime().Format("2006-01-02 15:04:05"),
+	}
+	data, err := json.Marshal(anchor)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	if err := ctx.GetStub().PutState(headKey, data); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// GetHead 查询当前链头
+func (a *AuditContract) GetHead(ctx contractapi.TransactionContextInterface) (Anchor, error) {
+	state, err := ctx.GetStub().GetState(headKey)
+	if err != nil {
+		return Anchor{}, err
+	}
+	if state == nil {
+		return Anchor{}, fmt.Errorf("audit head not anchored")
+	}
+	var anchor Anchor
+	if err := json.Unmarshal(state, &anchor); err != nil {
+		return Anchor{}, err
+	}
+	return anchor, nil
+}
+
+// GetHeadHistory 查询全部锚定记录
+func (a *AuditContract) GetHeadHistory(ctx contractapi.TransactionContextInterface) ([]AnchorRecord, error) {
+	iterator, err := ctx.GetStub().GetHistoryForKey(headKey)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	var records []AnchorRecord
+	for iterator.HasNext() {
+		next, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		record := AnchorRecord{Tx: next.TxId}
+		if err := json.Unmarshal(next.Value, &record.Anchor); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
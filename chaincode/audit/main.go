@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"log"
+)
+
+func main() {
+	auditContract, err := contractapi.NewChaincode(&AuditContract{})
+	if err != nil {
+		log.Panicf("error create audit contract:%s", err.Error())
+	}
+	if err := auditContract.Start(); err != nil {
+		log.Panicf("failed start chaincode:%s", err.Error())
+	}
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// 证书属性名与角色取值，成员登记时由CA写入证书
+const (
+	RoleAttribute      = "role"
+	HouseholdAttribute = "household" // 成员所属户号
+	RoleAdmin          = "admin"     // 管理员，可执行所有操作
+	RoleTreasurer      = "treasurer" // 财务负责人
+	RoleCommittee      = "committee" // 业委会成员
+	RoleAuditor        = "auditor"   // 审计锚定身份，仅用于锚定审计链头
+)
+
+// 访问控制错误码
+const (
+	CodeNoIdentity  = "ACCESS_NO_IDENTITY"  // 无法解析调用者身份
+	CodeNoRole      = "ACCESS_NO_ROLE"      // 调用者证书缺少role属性
+	CodeRoleDenied  = "ACCESS_ROLE_DENIED"  // 调用者角色无权执行该操作
+	CodeNotOwner    = "ACCESS_NOT_OWNER"    // 调用者不是记录的所有人
+	CodeAttrInvalid = "ACCESS_ATTR_INVALID" // 读取证书属性失败
+)
+
+// AccessError 访问控制错误，Code用于应用端区分拒绝原因
+type AccessError struct {
+	Code     string
+	Function string
+	Message  string
+}
+
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("[%s] %s: %s", e.Code, e.Function, e.Message)
+}
+
+// GetRole 读取调用者证书中的role属性
+func GetRole(ctx contractapi.TransactionContextInterface) (string, bool, error) {
+	return ctx.GetClientIdentity().GetAttributeValue(RoleAttribute)
+}
+
+// RequireRole 要求调用者拥有指定角色之一，管理员始终放行
+func RequireRole(ctx contractapi.TransactionContextInterface, function string, roles ...string) error {
+	role, found, err := GetRole(ctx)
+	if err != nil {
+		return &AccessError{Code: CodeAttrInvalid, Function: function, Message: err.Error()}
+	}
+	if !found || role == "" {
+		return &AccessError{Code: CodeNoRole, Function: function, Message: "client certificate has no role attribute"}
+	}
+	if role == RoleAdmin {
+		return nil
+	}
+	for _, r := range roles {
+		if role == r {
+			return nil
+		}
+	}
+	return &AccessError{Code: CodeRoleDenied, Function: function, Message: fmt.Sprintf("role %s is not allowed, require one of %v", role, roles)}
+}
+
+// RequireMember 要求调用者为已登记的成员身份
+func RequireMember(ctx contractapi.TransactionContextInterface, function string) (string, error) {
+	actor, err := GetActor(ctx)
+	if err != nil {
+		return "", &AccessError{Code: CodeNoIdentity, Function: function, Message: err.Error()}
+	}
+	return actor, nil
+}
+
+// RequireOwnerOrRole 要求调用者为记录所有人，或拥有指定角色之一
+func RequireOwnerOrRole(ctx contractapi.TransactionContextInterface, function, owner string, roles ...string) error {
+	actor, err := RequireMember(ctx, function)
+	if err != nil {
+		return err
+	}
+	if actor == owner {
+		return nil
+	}
+	if err := RequireRole(ctx, function, roles...); err != nil {
+		return &AccessError{Code: CodeNotOwner, Function: function, Message: fmt.Sprintf("%s is not the owner and %s", actor, err.Error())}
+	}
+	return nil
+}
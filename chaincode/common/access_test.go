@@ -0,0 +1,67 @@
+package common
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"testing"
+)
+
+type mockIdentity struct {
+	cn    string
+	attrs map[string]string
+}
+
+func (m *mockIdentity) GetID() (string, error)    { return m.cn, nil }
+func (m *mockIdentity) GetMSPID() (string, error) { return "Org1MSP", nil }
+func (m *mockIdentity) GetAttributeValue(name string) (string, bool, error) {
+	v, ok := m.attrs[name]
+	return v, ok, nil
+}
+func (m *mockIdentity) AssertAttributeValue(name, value string) error { return nil }
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{Subject: pkix.Name{CommonName: m.cn}}, nil
+}
+
+func newCtx(cn string, attrs map[string]string) *contractapi.TransactionContext {
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetClientIdentity(&mockIdentity{cn: cn, attrs: attrs})
+	return ctx
+}
+
+func accessCode(err error) string {
+	var accessErr *AccessError
+	if errors.As(err, &accessErr) {
+		return accessErr.Code
+	}
+	return ""
+}
+
+func TestRequireRole(t *testing.T) {
+	cases := []struct {
+		attrs map[string]string
+		code  string
+	}{
+		{map[string]string{RoleAttribute: RoleTreasurer}, ""},
+		{map[string]string{RoleAttribute: RoleAdmin}, ""},
+		{map[string]string{RoleAttribute: "resident"}, CodeRoleDenied},
+		{map[string]string{}, CodeNoRole},
+	}
+	for _, c := range cases {
+		err := RequireRole(newCtx("m1", c.attrs), "AddRecord", RoleTreasurer)
+		if accessCode(err) != c.code {
+			t.Errorf("attrs %v: got %v, want code %q", c.attrs, err, c.code)
+		}
+	}
+}
+
+func TestRequireOwnerOrRole(t *testing.T) {
+	if err := RequireOwnerOrRole(newCtx("m1", nil), "ReleaseFacility", "m1", RoleCommittee); err != nil {
+		t.Errorf("owner should pass: %v", err)
+	}
+	err := RequireOwnerOrRole(newCtx("m2", map[string]string{RoleAttribute: "resident"}), "ReleaseFacility", "m1", RoleCommittee)
+	if accessCode(err) != CodeNotOwner {
+		t.Errorf("got %v, want %s", err, CodeNotOwner)
+	}
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxAmountDigits 金额整数部分最多位数，保证换算为分后不会溢出
+const maxAmountDigits = 16
+
+// ParseAmount 将"123.45"形式的金额解析为以分为单位的整数
+// 只接受非负的十进制数字与最多两位小数，不使用浮点运算，保证各背书节点结果一致
+func ParseAmount(amount string) (int64, error) {
+	intPart, fracPart, hasDot := strings.Cut(amount, ".")
+	if intPart == "" || len(intPart) > maxAmountDigits || (hasDot && (fracPart == "" || len(fracPart) > 2)) {
+		return 0, fmt.Errorf("invalid amount:%q", amount)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, ch := range part {
+			if ch < '0' || ch > '9' {
+				return 0, fmt.Errorf("invalid amount:%q", amount)
+			}
+		}
+	}
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount:%q", amount)
+	}
+	var cents int64
+	if fracPart != "" {
+		cents, _ = strconv.ParseInt((fracPart + "0")[:2], 10, 64)
+	}
+	return yuan*100 + cents, nil
+}
+
+// FormatAmount 将以分为单位的整数格式化为保留两位小数的金额
+func FormatAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// AddAmount 金额相加，溢出时返回错误
+func AddAmount(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, fmt.Errorf("amount overflow")
+	}
+	return a + b, nil
+}
@@ -0,0 +1,43 @@
+package common
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		amount string
+		cents  int64
+		ok     bool
+	}{
+		{"0", 0, true},
+		{"12", 1200, true},
+		{"12.5", 1250, true},
+		{"12.05", 1205, true},
+		{"9999999999999999.99", 999999999999999999, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"+1", 0, false},
+		{"1.234", 0, false},
+		{"1.", 0, false},
+		{".5", 0, false},
+		{"1e3", 0, false},
+		{" 1", 0, false},
+		{"12345678901234567", 0, false},
+	}
+	for _, c := range cases {
+		cents, err := ParseAmount(c.amount)
+		if c.ok && (err != nil || cents != c.cents) {
+			t.Errorf("ParseAmount(%q) = %d, %v, want %d", c.amount, cents, err, c.cents)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("ParseAmount(%q) = %d, want error", c.amount, cents)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	for cents, want := range map[int64]string{0: "0.00", 5: "0.05", 1250: "12.50", -1205: "-12.05"} {
+		if got := FormatAmount(cents); got != want {
+			t.Errorf("FormatAmount(%d) = %s, want %s", cents, got, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// GetActor 获取交易提交者的成员ID
+// 成员通过CA登记时以成员ID作为登记ID，因此证书的CN即为成员ID
+func GetActor(ctx contractapi.TransactionContextInterface) (string, error) {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client certificate:%s", err.Error())
+	}
+	if cert == nil || cert.Subject.CommonName == "" {
+		return "", fmt.Errorf("client identity has no common name")
+	}
+	return cert.Subject.CommonName, nil
+}
+
+// GetHousehold 获取交易提交者所属的户号，读取证书中的household属性
+// 户号属性上线前登记的证书没有该属性，此时以成员ID作为户号
+func GetHousehold(ctx contractapi.TransactionContextInterface) (string, error) {
+	household, found, err := ctx.GetClientIdentity().GetAttributeValue(HouseholdAttribute)
+	if err != nil {
+		return "", fmt.Errorf("failed to get household attribute:%s", err.Error())
+	}
+	if found && household != "" {
+		return household, nil
+	}
+	return GetActor(ctx)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"community-governance/chaincode/common"
 	"encoding/json"
 	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
@@ -12,9 +13,11 @@ type FacilityContract struct {
 }
 
 type Facility struct {
-	MessageHash string `json:"message"`     //信息hash值
-	UpdateDate  string `json:"update_date"` //上次修改时间
-	State       string `json:"state"`       //状态
+	MessageHash string `json:"message"`              //信息hash值
+	UpdateDate  string `json:"update_date"`          //上次修改时间
+	State       string `json:"state"`                //状态
+	WorkOrder   string `json:"work_order,omitempty"` //维护中设施的维护工单ID
+	Assignee    string `json:"assignee,omitempty"`   //维护工单的负责人ID
 }
 
 // UsageRecord 使用记录结构体
@@ -34,12 +37,18 @@ const (
 	operationReturn = "return"
 	stateAva        = "available"
 	//stateRun  = "run"
-	stateStop = "stop"
-	recordFix = "record_"
+	stateStop           = "stop"
+	stateMaintenance    = "maintenance"
+	operationMaintain   = "maintain"
+	operationMaintained = "maintained"
+	recordFix           = "record_"
 )
 
 // RegisterFacility 注册新的公共设施
 func (f *FacilityContract) RegisterFacility(ctx contractapi.TransactionContextInterface, facilityID, messageHash string) error {
+	if err := common.RequireRole(ctx, "RegisterFacility", common.RoleCommittee); err != nil {
+		return err
+	}
 	//判断记录是否已经存在
 	state, err := ctx.GetStub().GetState(facilityID)
 	if err != nil {
@@ -81,50 +90,26 @@ func (f *FacilityContract) GetFacility(ctx contractapi.TransactionContextInterfa
 	return facility, nil
 }
 
-// RequestFacility 申请使用公共设施
-func (f *FacilityContract) RequestFacility(ctx contractapi.TransactionContextInterface, facilityID, user string) error {
-	//获取facility信息
+// ReleaseFacility 释放公共设施，用于归还旧版独占借用中的设施，借用已由时段预约取代
+func (f *FacilityContract) ReleaseFacility(ctx contractapi.TransactionContextInterface, facilityID string) error {
 	facility, err := f.GetFacility(ctx, facilityID)
 	if err != nil {
 		return err
 	}
-	//获取record信息
-	id := recordFix + facilityID
-
-	//判断用户状态是否可用
-	if facility.State != stateAva {
-		return fmt.Errorf("facility %s is currently %s", facilityID, facility.State)
-	}
-	//更新状态
-	facility.State = stateStop
-	updatedFacilityJSON, err := json.Marshal(facility)
-	if err != nil {
-		return fmt.Errorf("failed to marshal updated facility: %s", err.Error())
-	}
-	if err := ctx.GetStub().PutState(facilityID, updatedFacilityJSON); err != nil {
-		return fmt.Errorf("failed to update facility state: %v", err)
+	//维护中的设施只能通过结束维护恢复可用
+	if facility.State == stateMaintenance {
+		return fmt.Errorf("facility %s is under maintenance", facilityID)
 	}
-	stamp, err := ctx.GetStub().GetTxTimestamp()
+	//只有借用人本人或业委会成员可以归还设施
+	id := recordFix + facilityID
+	lastRecord, err := f.getLastUsageRecord(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to get time stamp:%s", err.Error())
-	}
-
-	// 记录使用信息
-	usageRecord := UsageRecord{
-		User:      user,
-		OperaTime: stamp.AsTime().Format("2006-01-02 15:04:05"),
-		Operation: operationBorrow,
+		return err
 	}
-	recordJSON, err := json.Marshal(usageRecord)
-	if err != nil {
-		return fmt.Errorf("failed to marshal usage record: %v", err)
+	if err := common.RequireOwnerOrRole(ctx, "ReleaseFacility", lastRecord.User, common.RoleCommittee); err != nil {
+		return err
 	}
-	return ctx.GetStub().PutState(id, recordJSON)
-}
-
-// ReleaseFacility 释放公共设施
-func (f *FacilityContract) ReleaseFacility(ctx contractapi.TransactionContextInterface, facilityID, user string) error {
-	facility, err := f.GetFacility(ctx, facilityID)
+	user, err := common.GetActor(ctx)
 	if err != nil {
 		return err
 	}
@@ -137,8 +122,6 @@ func (f *FacilityContract) ReleaseFacility(ctx contractapi.TransactionContextInt
 	if err := ctx.GetStub().PutState(facilityID, updatedFacilityJSON); err != nil {
 		return fmt.Errorf("failed to update facility state: %v", err)
 	}
-	//获取record信息
-	id := recordFix + facilityID
 	stamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return fmt.Errorf("failed to get time stamp:%s", err.Error())
@@ -147,7 +130,7 @@ func (f *FacilityContract) ReleaseFacility(ctx contractapi.TransactionContextInt
 	usageRecord := UsageRecord{
 		User:      user,
 		OperaTime: stamp.AsTime().Format("2006-01-02 15:04:05"),
-		Operation: operationBorrow,
+		Operation: operationReturn,
 	}
 	usageRecordJSON, err := json.Marshal(usageRecord)
 	if err != nil {
@@ -156,6 +139,22 @@ func (f *FacilityContract) ReleaseFacility(ctx contractapi.TransactionContextInt
 	return ctx.GetStub().PutState(id, usageRecordJSON)
 }
 
+// getLastUsageRecord 获取设施最近一次的使用记录
+func (f *FacilityContract) getLastUsageRecord(ctx contractapi.TransactionContextInterface, id string) (UsageRecord, error) {
+	state, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return UsageRecord{}, fmt.Errorf("failed to get usage record:%s", err.Error())
+	}
+	if state == nil {
+		return UsageRecord{}, fmt.Errorf("%s has no usage record", id)
+	}
+	var record UsageRecord
+	if err := json.Unmarshal(state, &record); err != nil {
+		return UsageRecord{}, fmt.Errorf("failed to unmarshal usage record:%s", err.Error())
+	}
+	return record, nil
+}
+
 // GetFacilityUsageHistory 查询设施的使用记录
 func (f *FacilityContract) GetFacilityUsageHistory(ctx contractapi.TransactionContextInterface, facilityID string) ([]UsageRecord, error) {
 	id := recordFix + facilityID
@@ -181,6 +180,9 @@ func (f *FacilityContract) GetFacilityUsageHistory(ctx contractapi.TransactionCo
 }
 
 func (f *FacilityContract) UpdateFacility(ctx contractapi.TransactionContextInterface, facilityID, messageHash, state string) error {
+	if err := common.RequireRole(ctx, "UpdateFacility", common.RoleCommittee); err != nil {
+		return err
+	}
 	facility, err := f.GetFacility(ctx, facilityID)
 	if err != nil {
 		return err
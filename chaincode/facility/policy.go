@@ -0,0 +1,152 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"time"
+)
+
+// Policy 设施的预约政策，各项为0表示不限制
+type Policy struct {
+	FacilityID     string `json:"facility_id"`
+	MaxWeeklyHours int    `json:"max_weekly_hours"` //每户每周（周一起算）最多预约的小时数
+	AdvanceDays    int    `json:"advance_days"`     //最多可提前预约的天数
+	MaxActive      int    `json:"max_active"`       //每户同时持有的未结束预约数上限
+	ConfirmMinutes int    `json:"confirm_minutes"`  //候补收到空出时段后确认的时限，为0时使用默认时限
+	UpdateDate     string `json:"update_date"`
+}
+
+const (
+	policyIndex = "policy~facility" //预约政策的组合键前缀
+
+	defaultConfirmMinutes = 30 //候补确认的默认时限
+)
+
+// confirmWindow 候补确认的时限
+func (p Policy) confirmWindow() time.Duration {
+	if p.ConfirmMinutes > 0 {
+		return time.Duration(p.ConfirmMinutes) * time.Minute
+	}
+	return defaultConfirmMinutes * time.Minute
+}
+
+// weekStart 返回t所在周的周一零点
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// SetPolicy 设置设施的预约政策，已有预约不受影响
+func (f *FacilityContract) SetPolicy(ctx contractapi.TransactionContextInterface, facilityID string, maxWeeklyHours, advanceDays, maxActive, confirmMinutes int) (Policy, error) {
+	if err := common.RequireRole(ctx, "SetPolicy", common.RoleCommittee); err != nil {
+		return Policy{}, err
+	}
+	if _, err := f.GetFacility(ctx, facilityID); err != nil {
+		return Policy{}, err
+	}
+	if maxWeeklyHours < 0 || advanceDays < 0 || maxActive < 0 || confirmMinutes < 0 {
+		return Policy{}, fmt.Errorf("policy values must not be negative")
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return Policy{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	policy := Policy{
+		FacilityID:     facilityID,
+		MaxWeeklyHours: maxWeeklyHours,
+		AdvanceDays:    advanceDays,
+		MaxActive:      maxActive,
+		ConfirmMinutes: confirmMinutes,
+		UpdateDate:     nowTime.AsTime().Format("2006-01-02 15:04:05"),
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(policyIndex, []string{facilityID})
+	if err != nil {
+		return Policy{}, err
+	}
+	return policy, putJSON(ctx, key, policy)
+}
+
+// GetPolicy 查询设施的预约政策，未设置时返回不限制的政策
+func (f *FacilityContract) GetPolicy(ctx contractapi.TransactionContextInterface, facilityID string) (Policy, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(policyIndex, []string{facilityID})
+	if err != nil {
+		return Policy{}, err
+	}
+	policy := Policy{FacilityID: facilityID}
+	if _, err := getJSON(ctx, key, &policy); err != nil {
+		return Policy{}, err
+	}
+	return policy, nil
+}
+
+// checkQuota 校验预约是否符合提前预约天数、同时持有预约数与每周时长的限制
+func (f *FacilityContract) checkQuota(ctx contractapi.TransactionContextInterface, policy Policy, household string, start, end, now time.Time) error {
+	if policy.AdvanceDays > 0 {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, calendarZone)
+		if !start.Before(today.AddDate(0, 0, policy.AdvanceDays+1)) {
+			return fmt.Errorf("reservation can be made at most %d days in advance", policy.AdvanceDays)
+		}
+	}
+	if policy.MaxActive == 0 && policy.MaxWeeklyHours == 0 {
+		return nil
+	}
+	reservations, err := f.getHouseholdReservations(ctx, policy.FacilityID, household)
+	if err != nil {
+		return err
+	}
+	week := weekStart(start)
+	active, used := 0, end.Sub(start)
+	for _, r := range reservations {
+		if r.Status != reservationBooked {
+			continue
+		}
+		from, err := time.ParseInLocation(minuteLayout, r.Start, calendarZone)
+		if err != nil {
+			return err
+		}
+		to, err := time.ParseInLocation(minuteLayout, r.End, calendarZone)
+		if err != nil {
+			return err
+		}
+		if to.After(now) {
+			active++
+		}
+		if weekStart(from).Equal(week) {
+			used += to.Sub(from)
+		}
+	}
+	if policy.MaxActive > 0 && active >= policy.MaxActive {
+		return fmt.Errorf("household %s already holds %d active reservations", household, active)
+	}
+	if policy.MaxWeeklyHours > 0 && used > time.Duration(policy.MaxWeeklyHours)*time.Hour {
+		return fmt.Errorf("household %s exceeds %d hours in the week of %s", household, policy.MaxWeeklyHours, week.Format(dateLayout))
+	}
+	return nil
+}
+
+// getHouseholdReservations 通过户号索引读取某户在设施的预约
+func (f *FacilityContract) getHouseholdReservations(ctx contractapi.TransactionContextInterface, facilityID, household string) ([]Reservation, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(householdIndex, []string{facilityID, household})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var result []Reservation
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		reservation, err := f.GetReservation(ctx, parts[2])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, reservation)
+	}
+	return result, nil
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 设施的开放时间与预约规则
+type Schedule struct {
+	FacilityID  string `json:"facility_id"`
+	Open        string `json:"open"`         //每日开放时间，格式为HH:mm
+	Close       string `json:"close"`        //每日关闭时间，格式为HH:mm
+	Days        string `json:"days"`         //开放的星期，0为星期日，逗号分隔
+	SlotMinutes int    `json:"slot_minutes"` //预约时段长度，预约的起止时间须与时段对齐
+	CancelHours int    `json:"cancel_hours"` //开始前多少小时之前可以取消预约
+	UpdateDate  string `json:"update_date"`
+}
+
+// Reservation 设施预约
+type Reservation struct {
+	ReservationID string `json:"reservation_id"`
+	FacilityID    string `json:"facility_id"`
+	Member        string `json:"member"`    //预约人ID
+	Household     string `json:"household"` //预约人户号，用于预约配额
+	Start         string `json:"start"`     //开始时间，格式为yyyy-MM-dd HH:mm
+	End           string `json:"end"`       //结束时间，与开始时间在同一天
+	Status        string `json:"status"`
+	CreateDate    string `json:"create_date"`
+	Canceller     string `json:"canceller,omitempty"`
+	CancelReason  string `json:"cancel_reason,omitempty"`
+	CancelDate    string `json:"cancel_date,omitempty"`
+	TxID          string `json:"tx_id"`
+}
+
+const (
+	scheduleIndex    = "schedule~facility"      //开放时间的组合键前缀
+	reservationIndex = "reservation~id"         //预约的组合键前缀
+	calendarIndex    = "calendar~facility~date" //按设施与日期索引预约的组合键前缀
+	householdIndex   = "household~facility"     //按设施与户号索引预约的组合键前缀
+
+	reservationBooked    = "booked"
+	reservationCancelled = "cancelled"
+
+	minuteLayout = "2006-01-02 15:04"
+	dateLayout   = "2006-01-02"
+
+	maxCalendarDays = 62 //日历查询最多的天数
+)
+
+// calendarZone 预约时间按小区所在时区解释，与应用服务器一致
+var calendarZone = time.FixedZone("UTC+8", 8*60*60)
+
+// parseClock 解析HH:mm，返回当天的分钟数
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("illegal time:%s", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// openOn 判断设施在星期weekday是否开放
+func (s Schedule) openOn(weekday time.Weekday) bool {
+	for _, day := range strings.Split(s.Days, ",") {
+		if day == strconv.Itoa(int(weekday)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSlot 校验预约时段在开放时间内且与预约时段对齐
+func (s Schedule) checkSlot(start, end time.Time) error {
+	if start.Format(dateLayout) != end.Format(dateLayout) || !start.Before(end) {
+		return fmt.Errorf("reservation must start before it ends on the same day")
+	}
+	if !s.openOn(start.Weekday()) {
+		return fmt.Errorf("facility %s is closed on %s", s.FacilityID, start.Weekday())
+	}
+	open, err := parseClock(s.Open)
+	if err != nil {
+		return err
+	}
+	closing, err := parseClock(s.Close)
+	if err != nil {
+		return err
+	}
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from < open || to > closing {
+		return fmt.Errorf("reservation is outside opening hours %s-%s", s.Open, s.Close)
+	}
+	if (from-open)%s.SlotMinutes != 0 || (to-open)%s.SlotMinutes != 0 {
+		return fmt.Errorf("reservation must align to %d minute slots", s.SlotMinutes)
+	}
+	return nil
+}
+
+// SetSchedule 设置设施的开放时间与预约规则，已有预约不受影响
+func (f *FacilityContract) SetSchedule(ctx contractapi.TransactionContextInterface, facilityID, open, closing, days string, slotMinutes, cancelHours int) (Schedule, error) {
+	if err := common.RequireRole(ctx, "SetSchedule", common.RoleCommittee); err != nil {
+		return Schedule{}, err
+	}
+	if _, err := f.GetFacility(ctx, facilityID); err != nil {
+		return Schedule{}, err
+	}
+	from, err := parseClock(open)
+	if err != nil {
+		return Schedule{}, err
+	}
+	to, err := parseClock(closing)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if from >= to || slotMinutes <= 0 || (to-from)%slotMinutes != 0 || cancelHours < 0 {
+		return Schedule{}, fmt.Errorf("illegal schedule %s-%s with %d minute slots", open, closing, slotMinutes)
+	}
+	for _, day := range strings.Split(days, ",") {
+		if n, err := strconv.Atoi(day); err != nil || n < 0 || n > 6 {
+			return Schedule{}, fmt.Errorf("illegal days:%s", days)
+		}
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return Schedule{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	schedule := Schedule{
+		FacilityID:  facilityID,
+		Open:        open,
+		Close:       closing,
+		Days:        days,
+		SlotMinutes: slotMinutes,
+		CancelHours: cancelHours,
+		UpdateDate:  nowTime.AsTime().Format("2006-01-02 15:04:05"),
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(scheduleIndex, []string{facilityID})
+	if err != nil {
+		return Schedule{}, err
+	}
+	return schedule, putJSON(ctx, key, schedule)
+}
+
+// GetSchedule 查询设施的开放时间与预约规则
+func (f *FacilityContract) GetSchedule(ctx contractapi.TransactionContextInterface, facilityID string) (Schedule, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(scheduleIndex, []string{facilityID})
+	if err != nil {
+		return Schedule{}, err
+	}
+	var schedule Schedule
+	found, err := getJSON(ctx, key, &schedule)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if !found {
+		return Schedule{}, fmt.Errorf("facility %s has no schedule", facilityID)
+	}
+	return schedule, nil
+}
+
+// Reserve 预约设施的一个时段，预约人与户号取自交易提交者身份
+// 时段须在开放时间内、与预约时段对齐、晚于当前时间，不能与已有预约或候补保留的时段重叠，且符合设施的预约政策
+func (f *FacilityContract) Reserve(ctx contractapi.TransactionContextInterface, reservationID, facilityID, start, end string) (Reservation, error) {
+	return f.reserve(ctx, "Reserve", reservationID, facilityID, start, end, "")
+}
+
+// reserve 创建预约，holder为确认候补时该候补的ID，其保留的时段不视为冲突
+func (f *FacilityContract) reserve(ctx contractapi.TransactionContextInterface, function, reservationID, facilityID, start, end, holder string) (Reservation, error) {
+	member, err := common.RequireMember(ctx, function)
+	if err != nil {
+		return Reservation{}, err
+	}
+	household, err := common.GetHousehold(ctx)
+	if err != nil {
+		return Reservation{}, err
+	}
+	from, to, err := f.checkReservable(ctx, facilityID, start, end)
+	if err != nil {
+		return Reservation{}, err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	now := nowTime.AsTime().In(calendarZone)
+	key, err := ctx.GetStub().CreateCompositeKey(reservationIndex, []string{reservationID})
+	if err != nil {
+		return Reservation{}, err
+	}
+	if state, err := ctx.GetStub().GetState(key); err != nil || state != nil {
+		return Reservation{}, fmt.Errorf("reservation %s already exist", reservationID)
+	}
+	date := from.Format(dateLayout)
+	if err := f.checkConflict(ctx, facilityID, date, start, end, holder, now); err != nil {
+		return Reservation{}, err
+	}
+	policy, err := f.GetPolicy(ctx, facilityID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if err := f.checkQuota(ctx, policy, household, from, to, now); err != nil {
+		return Reservation{}, err
+	}
+	reservation := Reservation{
+		ReservationID: reservationID,
+		FacilityID:    facilityID,
+		Member:        member,
+		Household:     household,
+		Start:         start,
+		End:           end,
+		Status:        reservationBooked,
+		CreateDate:    now.Format("2006-01-02 15:04:05"),
+		TxID:          ctx.GetStub().GetTxID(),
+	}
+	if err := putJSON(ctx, key, reservation); err != nil {
+		return Reservation{}, err
+	}
+	calendarKey, err := ctx.GetStub().CreateCompositeKey(calendarIndex, []string{facilityID, date, reservationID})
+	if err != nil {
+		return Reservation{}, err
+	}
+	if err := ctx.GetStub().PutState(calendarKey, []byte{0}); err != nil {
+		return Reservation{}, err
+	}
+	householdKey, err := ctx.GetStub().CreateCompositeKey(householdIndex, []string{facilityID, household, reservationID})
+	if err != nil {
+		return Reservation{}, err
+	}
+	return reservation, ctx.GetStub().PutState(householdKey, []byte{0})
+}
+
+// checkReservable 校验设施可预约且时段在开放时间内、晚于当前时间，返回时段的起止时间
+func (f *FacilityContract) checkReservable(ctx contractapi.TransactionContextInterface, facilityID, start, end string) (time.Time, time.Time, error) {
+	facility, err := f.GetFacility(ctx, facilityID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if facility.State == stateMaintenance {
+		return time.Time{}, time.Time{}, fmt.Errorf("facility %s is under maintenance", facilityID)
+	}
+	//旧版借用中的设施须先归还才能预约
+	if facility.State != stateAva {
+		return time.Time{}, time.Time{}, fmt.Errorf("facility %s is currently %s", facilityID, facility.State)
+	}
+	schedule, err := f.GetSchedule(ctx, facilityID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	from, err := time.ParseInLocation(minuteLayout, start, calendarZone)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("illegal start:%s", start)
+	}
+	to, err := time.ParseInLocation(minuteLayout, end, calendarZone)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("illegal end:%s", end)
+	}
+	if err := schedule.checkSlot(from, to); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	if !from.After(nowTime.AsTime()) {
+		return time.Time{}, time.Time{}, fmt.Errorf("reservation must start in the future")
+	}
+	return from, to, nil
+}
+
+// checkConflict 时段不能与已有预约或其他候补保留中的时段重叠
+func (f *FacilityContract) checkConflict(ctx contractapi.TransactionContextInterface, facilityID, date, start, end, holder string, now time.Time) error {
+	booked, err := f.getReservationsOn(ctx, facilityID, date)
+	if err != nil {
+		return err
+	}
+	for _, r := range booked {
+		if r.Status == reservationBooked && r.Start < end && r.End > start {
+			return fmt.Errorf("reservation conflicts with %s (%s - %s)", r.ReservationID, r.Start, r.End)
+		}
+	}
+	entries, err := f.getWaitlistOn(ctx, facilityID, date)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.EntryID != holder && e.holds(now) && e.Start < end && e.End > start {
+			return fmt.Errorf("slot is held for waitlist entry %s until %s", e.EntryID, e.Deadline)
+		}
+	}
+	return nil
+}
+
+// CancelReservation 取消预约，预约人须在开始前cancel_hours小时之前取消，业委会成员可在结束前随时取消
+// 取消后空出的时段提供给最早加入且不再冲突的候补
+func (f *FacilityContract) CancelReservation(ctx contractapi.TransactionContextInterface, reservationID, reason string) (Reservation, error) {
+	reservation, err := f.GetReservation(ctx, reservationID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if reservation.Status != reservationBooked {
+		return Reservation{}, fmt.Errorf("reservation %s is %s", reservationID, reservation.Status)
+	}
+	actor, err := common.RequireMember(ctx, "CancelReservation")
+	if err != nil {
+		return Reservation{}, err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return Reservation{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	now := nowTime.AsTime()
+	start, err := time.ParseInLocation(minuteLayout, reservation.Start, calendarZone)
+	if err != nil {
+		return Reservation{}, err
+	}
+	end, err := time.ParseInLocation(minuteLayout, reservation.End, calendarZone)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if !now.Before(end) {
+		return Reservation{}, fmt.Errorf("reservation %s has ended", reservationID)
+	}
+	//预约人超过取消期限后只能由业委会成员取消
+	schedule, err := f.GetSchedule(ctx, reservation.FacilityID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	deadline := start.Add(-time.Duration(schedule.CancelHours) * time.Hour)
+	if actor != reservation.Member || now.After(deadline) {
+		if err := common.RequireRole(ctx, "CancelReservation", common.RoleCommittee); err != nil {
+			return Reservation{}, err
+		}
+	}
+	reservation.Status = reservationCancelled
+	reservation.Canceller = actor
+	reservation.CancelReason = reason
+	reservation.CancelDate = now.In(calendarZone).Format("2006-01-02 15:04:05")
+	key, err := ctx.GetStub().CreateCompositeKey(reservationIndex, []string{reservationID})
+	if err != nil {
+		return Reservation{}, err
+	}
+	if err := putJSON(ctx, key, reservation); err != nil {
+		return Reservation{}, err
+	}
+	//空出的时段依次提供给候补
+	_, err = f.offerNext(ctx, reservation.FacilityID, start.Format(dateLayout), map[string]bool{reservationID: true}, now)
+	return reservation, err
+}
+
+// GetReservation 查询预约
+func (f *FacilityContract) GetReservation(ctx contractapi.TransactionContextInterface, reservationID string) (Reservation, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(reservationIndex, []string{reservationID})
+	if err != nil {
+		return Reservation{}, err
+	}
+	var reservation Reservation
+	found, err := getJSON(ctx, key, &reservation)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if !found {
+		return Reservation{}, fmt.Errorf("reservation %s not exist", reservationID)
+	}
+	return reservation, nil
+}
+
+// GetReservations 查询设施在from至to日期内的预约日历，包含已取消的预约
+func (f *FacilityContract) GetReservations(ctx contractapi.TransactionContextInterface, facilityID, from, to string) ([]Reservation, error) {
+	first, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("illegal date:%s", from)
+	}
+	last, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("illegal date:%s", to)
+	}
+	if last.Before(first) || last.Sub(first) > maxCalendarDays*24*time.Hour {
+		return nil, fmt.Errorf("date range must be within %d days", maxCalendarDays)
+	}
+	result := make([]Reservation, 0)
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		reservations, err := f.getReservationsOn(ctx, facilityID, day.Format(dateLayout))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, reservations...)
+	}
+	return result, nil
+}
+
+// getReservationsOn 通过日历索引读取设施某天的预约
+func (f *FacilityContract) getReservationsOn(ctx contractapi.TransactionContextInterface, facilityID, date string) ([]Reservation, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(calendarIndex, []string{facilityID, date})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var result []Reservation
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		reservation, err := f.GetReservation(ctx, parts[2])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, reservation)
+	}
+	return result, nil
+}
+
+func putJSON(ctx contractapi.TransactionContextInterface, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+func getJSON(ctx contractapi.TransactionContextInterface, key string, value interface{}) (bool, error) {
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to get state:%s", err.Error())
+	}
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return true, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// 交易时间为2024-06-05（星期三）09:00，所在周从2024-06-03开始
+const testNow = "2024-06-05 09:00"
+
+func booked(id, household, start, end string) Reservation {
+	return Reservation{ReservationID: id, FacilityID: "f1", Household: household, Start: start, End: end, Status: reservationBooked}
+}
+
+func TestCheckQuota(t *testing.T) {
+	now := clock(t, testNow)
+	ctx, _ := newStubCtx(now)
+	f := new(FacilityContract)
+	for _, r := range []Reservation{
+		booked("r1", "h1", "2024-06-03 08:00", "2024-06-03 10:00"), //本周已结束，计入时长不计入持有数
+		booked("r2", "h1", "2024-06-06 10:00", "2024-06-06 12:00"), //本周未结束
+		booked("r3", "h1", "2024-05-31 10:00", "2024-05-31 14:00"), //上周
+		booked("r5", "h1", "2024-06-11 10:00", "2024-06-11 11:00"), //下周
+		{ReservationID: "r4", FacilityID: "f1", Household: "h1", Start: "2024-06-07 10:00", End: "2024-06-07 14:00", Status: reservationCancelled},
+	} {
+		putReservation(t, ctx, r)
+	}
+	cases := []struct {
+		name       string
+		policy     Policy
+		household  string
+		start, end string
+		err        string
+	}{
+		{"no limits", Policy{}, "h1", "2024-06-07 08:00", "2024-06-07 20:00", ""},
+		{"weekly hours reached exactly", Policy{MaxWeeklyHours: 6}, "h1", "2024-06-07 10:00", "2024-06-07 12:00", ""},
+		{"weekly hours exceeded", Policy{MaxWeeklyHours: 6}, "h1", "2024-06-07 10:00", "2024-06-07 13:00", "exceeds 6 hours in the week of 2024-06-03"},
+		{"next week counted separately", Policy{MaxWeeklyHours: 3}, "h1", "2024-06-11 14:00", "2024-06-11 16:00", ""},
+		{"sunday counts in the week from monday", Policy{MaxWeeklyHours: 3}, "h1", "2024-06-09 14:00", "2024-06-09 16:00", "exceeds 3 hours"},
+		{"below max active", Policy{MaxActive: 3}, "h1", "2024-06-07 10:00", "2024-06-07 11:00", ""},
+		{"max active reached", Policy{MaxActive: 2}, "h1", "2024-06-07 10:00", "2024-06-07 11:00", "already holds 2 active reservations"},
+		{"other household", Policy{MaxActive: 1, MaxWeeklyHours: 2}, "h2", "2024-06-07 10:00", "2024-06-07 12:00", ""},
+		{"within advance days", Policy{AdvanceDays: 2}, "h1", "2024-06-07 23:00", "2024-06-07 23:30", ""},
+		{"beyond advance days", Policy{AdvanceDays: 2}, "h1", "2024-06-08 08:00", "2024-06-08 09:00", "at most 2 days in advance"},
+	}
+	for _, c := range cases {
+		c.policy.FacilityID = "f1"
+		err := f.checkQuota(ctx, c.policy, c.household, clock(t, c.start), clock(t, c.end), now)
+		if c.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: got %v, want error containing %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestCheckConflict(t *testing.T) {
+	now := clock(t, testNow)
+	ctx, _ := newStubCtx(now)
+	f := new(FacilityContract)
+	putReservation(t, ctx, booked("r1", "h1", "2024-06-06 10:00", "2024-06-06 12:00"))
+	putReservation(t, ctx, Reservation{ReservationID: "r2", FacilityID: "f1", Household: "h1",
+		Start: "2024-06-06 13:00", End: "2024-06-06 14:00", Status: reservationCancelled})
+	for _, e := range []WaitEntry{
+		{EntryID: "w1", FacilityID: "f1", Start: "2024-06-06 14:00", End: "2024-06-06 15:00", Status: waitOffered, Deadline: "2024-06-05 09:30:00"},
+		{EntryID: "w2", FacilityID: "f1", Start: "2024-06-06 16:00", End: "2024-06-06 17:00", Status: waitOffered, Deadline: "2024-06-05 08:00:00"},
+		{EntryID: "w3", FacilityID: "f1", Start: "2024-06-06 18:00", End: "2024-06-06 19:00", Status: waitWaiting},
+	} {
+		putWait(t, f, ctx, e)
+	}
+	cases := []struct {
+		name       string
+		start, end string
+		holder     string
+		err        string
+	}{
+		{"overlaps booking", "2024-06-06 11:00", "2024-06-06 13:00", "", "conflicts with r1"},
+		{"covers booking", "2024-06-06 09:00", "2024-06-06 13:00", "", "conflicts with r1"},
+		{"adjacent after", "2024-06-06 12:00", "2024-06-06 13:00", "", ""},
+		{"adjacent before", "2024-06-06 09:00", "2024-06-06 10:00", "", ""},
+		{"cancelled booking", "2024-06-06 13:00", "2024-06-06 14:00", "", ""},
+		{"held for waitlist", "2024-06-06 14:30", "2024-06-06 15:30", "", "held for waitlist entry w1"},
+		{"holder confirms own offer", "2024-06-06 14:00", "2024-06-06 15:00", "w1", ""},
+		{"other holder", "2024-06-06 14:00", "2024-06-06 15:00", "w3", "held for waitlist entry w1"},
+		{"expired offer", "2024-06-06 16:00", "2024-06-06 17:00", "", ""},
+		{"waiting entry holds nothing", "2024-06-06 18:00", "2024-06-06 19:00", "", ""},
+	}
+	for _, c := range cases {
+		err := f.checkConflict(ctx, "f1", "2024-06-06", c.start, c.end, c.holder, now)
+		if c.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: got %v, want error containing %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestOfferNext(t *testing.T) {
+	waiting := func(id, created, start, end string) WaitEntry {
+		return WaitEntry{EntryID: id, FacilityID: "f1", Start: start, End: end, Status: waitWaiting, CreateDate: created}
+	}
+	cases := []struct {
+		name         string
+		date         string
+		policy       *Policy
+		reservations []Reservation
+		entries      []WaitEntry
+		released     map[string]bool
+		want         map[string]string //状态变化的候补ID及新状态，offered附带确认截止时间
+	}{
+		{
+			name:         "earliest entry gets the released slot",
+			date:         "2024-06-06",
+			reservations: []Reservation{booked("r1", "h1", "2024-06-06 10:00", "2024-06-06 12:00")},
+			entries: []WaitEntry{
+				waiting("wa", "2024-06-01 08:00:00", "2024-06-06 10:00", "2024-06-06 11:00"),
+				waiting("wb", "2024-06-01 07:00:00", "2024-06-06 10:30", "2024-06-06 11:30"),
+				waiting("wc", "2024-06-01 09:00:00", "2024-06-06 11:30", "2024-06-06 12:00"),
+			},
+			released: map[string]bool{"r1": true},
+			want:     map[string]string{"wb": "offered 2024-06-05 09:30:00", "wc": "offered 2024-06-05 09:30:00"},
+		},
+		{
+			name:         "slot still booked",
+			date:         "2024-06-06",
+			reservations: []Reservation{booked("r1", "h1", "2024-06-06 10:00", "2024-06-06 12:00")},
+			entries:      []WaitEntry{waiting("wa", "2024-06-01 08:00:00", "2024-06-06 10:00", "2024-06-06 11:00")},
+			want:         map[string]string{},
+		},
+		{
+			name: "expired offer passes to next entry",
+			date: "2024-06-06",
+			entries: []WaitEntry{
+				{EntryID: "wx", FacilityID: "f1", Start: "2024-06-06 10:00", End: "2024-06-06 11:00", Status: waitOffered,
+					CreateDate: "2024-06-01 07:00:00", Deadline: "2024-06-05 08:59:00"},
+				waiting("wy", "2024-06-01 08:00:00", "2024-06-06 10:00", "2024-06-06 11:00"),
+			},
+			want: map[string]string{"wx": "expired", "wy": "offered 2024-06-05 09:30:00"},
+		},
+		{
+			name: "held offer blocks later entries",
+			date: "2024-06-06",
+			entries: []WaitEntry{
+				{EntryID: "wx", FacilityID: "f1", Start: "2024-06-06 10:00", End: "2024-06-06 11:00", Status: waitOffered,
+					CreateDate: "2024-06-01 07:00:00", Deadline: "2024-06-05 09:10:00"},
+				waiting("wy", "2024-06-01 08:00:00", "2024-06-06 10:00", "2024-06-06 11:00"),
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "released entry frees its slot",
+			date: "2024-06-06",
+			entries: []WaitEntry{
+				{EntryID: "wx", FacilityID: "f1", Start: "2024-06-06 10:00", End: "2024-06-06 11:00", Status: waitOffered,
+					CreateDate: "2024-06-01 07:00:00", Deadline: "2024-06-05 09:10:00"},
+				waiting("wy", "2024-06-01 08:00:00", "2024-06-06 10:00", "2024-06-06 11:00"),
+			},
+			released: map[string]bool{"wx": true},
+			want:     map[string]string{"wy": "offered 2024-06-05 09:30:00"},
+		},
+		{
+			name:    "started slot expires",
+			date:    "2024-06-05",
+			entries: []WaitEntry{waiting("wa", "2024-06-01 08:00:00", "2024-06-05 09:00", "2024-06-05 10:00")},
+			want:    map[string]string{"wa": "expired"},
+		},
+		{
+			name:    "deadline capped at slot start",
+			date:    "2024-06-05",
+			policy:  &Policy{FacilityID: "f1", ConfirmMinutes: 120},
+			entries: []WaitEntry{waiting("wa", "2024-06-01 08:00:00", "2024-06-05 10:00", "2024-06-05 11:00")},
+			want:    map[string]string{"wa": "offered 2024-06-05 10:00:00"},
+		},
+	}
+	for _, c := range cases {
+		now := clock(t, testNow)
+		ctx, _ := newStubCtx(now)
+		f := new(FacilityContract)
+		if c.policy != nil {
+			key, _ := ctx.GetStub().CreateCompositeKey(policyIndex, []string{"f1"})
+			if err := putJSON(ctx, key, c.policy); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, r := range c.reservations {
+			putReservation(t, ctx, r)
+		}
+		for _, e := range c.entries {
+			putWait(t, f, ctx, e)
+		}
+		changed, err := f.offerNext(ctx, "f1", c.date, c.released, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		got := map[string]string{}
+		for _, e := range changed {
+			got[e.EntryID] = e.Status
+			if e.Status == waitOffered {
+				got[e.EntryID] += " " + e.Deadline
+			}
+			stored, err := f.GetWaitEntry(ctx, e.EntryID)
+			if err != nil || stored.Status != e.Status {
+				t.Errorf("%s: entry %s stored as %v (%v), want %s", c.name, e.EntryID, stored.Status, err, e.Status)
+			}
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: changed %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for id, status := range c.want {
+			if got[id] != status {
+				t.Errorf("%s: entry %s is %q, want %q", c.name, id, got[id], status)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// memStub 测试用的内存账本，只实现合约用到的读写与组合键查询，写入立即可见
+type memStub struct {
+	shim.ChaincodeStubInterface
+	state map[string][]byte
+	now   time.Time
+}
+
+func (s *memStub) GetState(key string) ([]byte, error) { return s.state[key], nil }
+func (s *memStub) PutState(key string, value []byte) error {
+	s.state[key] = value
+	return nil
+}
+func (s *memStub) DelState(key string) error {
+	delete(s.state, key)
+	return nil
+}
+func (s *memStub) GetTxID() string { return "tx1" }
+func (s *memStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.now), nil
+}
+func (s *memStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+func (s *memStub) SplitCompositeKey(key string) (string, []string, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "\x00"), "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+func (s *memStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	iter := &memIterator{}
+	for key, value := range s.state {
+		if strings.HasPrefix(key, prefix) {
+			iter.kvs = append(iter.kvs, &queryresult.KV{Key: key, Value: value})
+		}
+	}
+	sort.Slice(iter.kvs, func(i, j int) bool { return iter.kvs[i].Key < iter.kvs[j].Key })
+	return iter, nil
+}
+
+type memIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (it *memIterator) HasNext() bool { return len(it.kvs) > 0 }
+func (it *memIterator) Close() error  { return nil }
+func (it *memIterator) Next() (*queryresult.KV, error) {
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+// newStubCtx 创建使用内存账本的交易上下文，交易时间为now
+func newStubCtx(now time.Time) (*contractapi.TransactionContext, *memStub) {
+	stub := &memStub{state: map[string][]byte{}, now: now}
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	return ctx, stub
+}
+
+// clock 按小区时区解析yyyy-MM-dd HH:mm
+func clock(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.ParseInLocation(minuteLayout, value, calendarZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// putReservation 直接写入预约及其日历与户号索引
+func putReservation(t *testing.T, ctx contractapi.TransactionContextInterface, r Reservation) {
+	t.Helper()
+	stub := ctx.GetStub()
+	key, _ := stub.CreateCompositeKey(reservationIndex, []string{r.ReservationID})
+	if err := putJSON(ctx, key, r); err != nil {
+		t.Fatal(err)
+	}
+	calendarKey, _ := stub.CreateCompositeKey(calendarIndex, []string{r.FacilityID, r.Start[:10], r.ReservationID})
+	householdKey, _ := stub.CreateCompositeKey(householdIndex, []string{r.FacilityID, r.Household, r.ReservationID})
+	_ = stub.PutState(calendarKey, []byte{0})
+	_ = stub.PutState(householdKey, []byte{0})
+}
+
+// putWait 直接写入候补及其日历索引
+func putWait(t *testing.T, f *FacilityContract, ctx contractapi.TransactionContextInterface, e WaitEntry) {
+	t.Helper()
+	if err := f.putWaitEntry(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := ctx.GetStub().CreateCompositeKey(waitCalendarIndex, []string{e.FacilityID, e.Start[:10], e.EntryID})
+	_ = ctx.GetStub().PutState(key, []byte{0})
+}
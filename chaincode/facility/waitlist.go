@@ -0,0 +1,331 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"sort"
+	"time"
+)
+
+// WaitEntry 已被预约时段的候补，时段空出后按加入顺序提供给候补，候补须在时限内确认
+type WaitEntry struct {
+	EntryID       string `json:"entry_id"`
+	FacilityID    string `json:"facility_id"`
+	Member        string `json:"member"`
+	Household     string `json:"household"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Status        string `json:"status"`
+	CreateDate    string `json:"create_date"`
+	OfferDate     string `json:"offer_date,omitempty"`     //时段提供给候补的时间
+	Deadline      string `json:"deadline,omitempty"`       //确认截止时间，截止前时段为该候补保留
+	ReservationID string `json:"reservation_id,omitempty"` //确认后生成的预约ID
+}
+
+const (
+	waitIndex         = "wait~id"            //候补的组合键前缀
+	waitCalendarIndex = "wait~facility~date" //按设施与日期索引候补的组合键前缀
+
+	waitWaiting   = "waiting"
+	waitOffered   = "offered"
+	waitConfirmed = "confirmed"
+	waitExpired   = "expired"
+	waitWithdrawn = "withdrawn"
+
+	secondLayout = "2006-01-02 15:04:05"
+)
+
+// holds 候补是否仍在确认时限内保留时段
+func (e WaitEntry) holds(now time.Time) bool {
+	if e.Status != waitOffered {
+		return false
+	}
+	deadline, err := time.ParseInLocation(secondLayout, e.Deadline, calendarZone)
+	return err == nil && now.Before(deadline)
+}
+
+// JoinWaitlist 候补已被预约或保留的时段，空闲的时段应直接预约
+func (f *FacilityContract) JoinWaitlist(ctx contractapi.TransactionContextInterface, entryID, facilityID, start, end string) (WaitEntry, error) {
+	member, err := common.RequireMember(ctx, "JoinWaitlist")
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	household, err := common.GetHousehold(ctx)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	from, _, err := f.checkReservable(ctx, facilityID, start, end)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return WaitEntry{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	now := nowTime.AsTime().In(calendarZone)
+	key, err := ctx.GetStub().CreateCompositeKey(waitIndex, []string{entryID})
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	if state, err := ctx.GetStub().GetState(key); err != nil || state != nil {
+		return WaitEntry{}, fmt.Errorf("waitlist entry %s already exist", entryID)
+	}
+	date := from.Format(dateLayout)
+	if err := f.checkConflict(ctx, facilityID, date, start, end, "", now); err == nil {
+		return WaitEntry{}, fmt.Errorf("slot %s - %s is available, reserve it directly", start, end)
+	}
+	entries, err := f.getWaitlistOn(ctx, facilityID, date)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	for _, e := range entries {
+		if e.Member == member && e.Start == start && e.End == end && (e.Status == waitWaiting || e.Status == waitOffered) {
+			return WaitEntry{}, fmt.Errorf("member %s is already on the waitlist as %s", member, e.EntryID)
+		}
+	}
+	entry := WaitEntry{
+		EntryID:    entryID,
+		FacilityID: facilityID,
+		Member:     member,
+		Household:  household,
+		Start:      start,
+		End:        end,
+		Status:     waitWaiting,
+		CreateDate: now.Format(secondLayout),
+	}
+	if err := putJSON(ctx, key, entry); err != nil {
+		return WaitEntry{}, err
+	}
+	calendarKey, err := ctx.GetStub().CreateCompositeKey(waitCalendarIndex, []string{facilityID, date, entryID})
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	return entry, ctx.GetStub().PutState(calendarKey, []byte{0})
+}
+
+// LeaveWaitlist 退出候补，放弃已提供的时段时该时段继续提供给后续候补
+func (f *FacilityContract) LeaveWaitlist(ctx contractapi.TransactionContextInterface, entryID string) (WaitEntry, error) {
+	entry, err := f.GetWaitEntry(ctx, entryID)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	if err := common.RequireOwnerOrRole(ctx, "LeaveWaitlist", entry.Member, common.RoleCommittee); err != nil {
+		return WaitEntry{}, err
+	}
+	if entry.Status != waitWaiting && entry.Status != waitOffered {
+		return WaitEntry{}, fmt.Errorf("waitlist entry %s is %s", entryID, entry.Status)
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return WaitEntry{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	offered := entry.Status == waitOffered
+	entry.Status = waitWithdrawn
+	if err := f.putWaitEntry(ctx, entry); err != nil {
+		return WaitEntry{}, err
+	}
+	if offered {
+		if _, err := f.offerNext(ctx, entry.FacilityID, entry.Start[:len(dateLayout)], map[string]bool{entryID: true}, nowTime.AsTime()); err != nil {
+			return WaitEntry{}, err
+		}
+	}
+	return entry, nil
+}
+
+// ConfirmOffer 候补在确认时限内确认提供的时段，生成预约，预约仍须符合设施的预约政策
+func (f *FacilityContract) ConfirmOffer(ctx contractapi.TransactionContextInterface, entryID, reservationID string) (WaitEntry, error) {
+	entry, err := f.GetWaitEntry(ctx, entryID)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	actor, err := common.RequireMember(ctx, "ConfirmOffer")
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	if actor != entry.Member {
+		return WaitEntry{}, &common.AccessError{Code: common.CodeNotOwner, Function: "ConfirmOffer", Message: fmt.Sprintf("%s is not the waitlist member", actor)}
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return WaitEntry{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	if !entry.holds(nowTime.AsTime()) {
+		return WaitEntry{}, fmt.Errorf("waitlist entry %s has no pending offer", entryID)
+	}
+	if _, err := f.reserve(ctx, "ConfirmOffer", reservationID, entry.FacilityID, entry.Start, entry.End, entryID); err != nil {
+		return WaitEntry{}, err
+	}
+	entry.Status = waitConfirmed
+	entry.ReservationID = reservationID
+	return entry, f.putWaitEntry(ctx, entry)
+}
+
+// ExpireOffers 将设施某天超过确认时限的候补置为过期，并将空出的时段提供给后续候补，返回状态变化的候补
+func (f *FacilityContract) ExpireOffers(ctx contractapi.TransactionContextInterface, facilityID, date string) ([]WaitEntry, error) {
+	if _, err := common.RequireMember(ctx, "ExpireOffers"); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return nil, fmt.Errorf("illegal date:%s", date)
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	return f.offerNext(ctx, facilityID, date, nil, nowTime.AsTime())
+}
+
+// offerNext 将设施某天空出的时段按加入顺序提供给候补，released为本交易中已取消的预约或已结束的候补
+// 同一交易内的写入对读取不可见，因此已释放的记录通过released排除
+func (f *FacilityContract) offerNext(ctx contractapi.TransactionContextInterface, facilityID, date string, released map[string]bool, now time.Time) ([]WaitEntry, error) {
+	entries, err := f.getWaitlistOn(ctx, facilityID, date)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	booked, err := f.getReservationsOn(ctx, facilityID, date)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := f.GetPolicy(ctx, facilityID)
+	if err != nil {
+		return nil, err
+	}
+	now = now.In(calendarZone)
+	var taken [][2]string
+	for _, r := range booked {
+		if r.Status == reservationBooked && !released[r.ReservationID] {
+			taken = append(taken, [2]string{r.Start, r.End})
+		}
+	}
+	var changed, waiting []WaitEntry
+	for _, e := range entries {
+		if released[e.EntryID] {
+			continue
+		}
+		switch {
+		case e.holds(now):
+			taken = append(taken, [2]string{e.Start, e.End})
+		case e.Status == waitOffered:
+			e.Status = waitExpired
+			changed = append(changed, e)
+		case e.Status == waitWaiting:
+			waiting = append(waiting, e)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		if waiting[i].CreateDate != waiting[j].CreateDate {
+			return waiting[i].CreateDate < waiting[j].CreateDate
+		}
+		return waiting[i].EntryID < waiting[j].EntryID
+	})
+	for _, e := range waiting {
+		start, err := time.ParseInLocation(minuteLayout, e.Start, calendarZone)
+		if err != nil {
+			return nil, err
+		}
+		//时段已开始的候补不再提供
+		if !start.After(now) {
+			e.Status = waitExpired
+			changed = append(changed, e)
+			continue
+		}
+		free := true
+		for _, t := range taken {
+			if t[0] < e.End && t[1] > e.Start {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		deadline := now.Add(policy.confirmWindow())
+		if deadline.After(start) {
+			deadline = start
+		}
+		e.Status = waitOffered
+		e.OfferDate = now.Format(secondLayout)
+		e.Deadline = deadline.Format(secondLayout)
+		taken = append(taken, [2]string{e.Start, e.End})
+		changed = append(changed, e)
+	}
+	for _, e := range changed {
+		if err := f.putWaitEntry(ctx, e); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+// GetWaitEntry 查询候补
+func (f *FacilityContract) GetWaitEntry(ctx contractapi.TransactionContextInterface, entryID string) (WaitEntry, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(waitIndex, []string{entryID})
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	var entry WaitEntry
+	found, err := getJSON(ctx, key, &entry)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	if !found {
+		return WaitEntry{}, fmt.Errorf("waitlist entry %s not exist", entryID)
+	}
+	return entry, nil
+}
+
+// GetWaitlist 查询设施某天的候补，按加入顺序排列
+func (f *FacilityContract) GetWaitlist(ctx contractapi.TransactionContextInterface, facilityID, date string) ([]WaitEntry, error) {
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return nil, fmt.Errorf("illegal date:%s", date)
+	}
+	entries, err := f.getWaitlistOn(ctx, facilityID, date)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].CreateDate != entries[j].CreateDate {
+			return entries[i].CreateDate < entries[j].CreateDate
+		}
+		return entries[i].EntryID < entries[j].EntryID
+	})
+	return append(make([]WaitEntry, 0, len(entries)), entries...), nil
+}
+
+func (f *FacilityContract) putWaitEntry(ctx contractapi.TransactionContextInterface, entry WaitEntry) error {
+	key, err := ctx.GetStub().CreateCompositeKey(waitIndex, []string{entry.EntryID})
+	if err != nil {
+		return err
+	}
+	return putJSON(ctx, key, entry)
+}
+
+// getWaitlistOn 通过日历索引读取设施某天的候补
+func (f *FacilityContract) getWaitlistOn(ctx contractapi.TransactionContextInterface, facilityID, date string) ([]WaitEntry, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(waitCalendarIndex, []string{facilityID, date})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	var result []WaitEntry
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, parts, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := f.GetWaitEntry(ctx, parts[2])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
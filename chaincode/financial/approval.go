@@ -0,0 +1,285 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ApprovalPolicy 大额支出审批策略，超过阈值的支出需任意M名不同的业委会成员批准后入账
+// 链上没有业委会名册，批准人只按证书的committee角色校验，不限定成员总数
+type ApprovalPolicy struct {
+	Threshold int64 `json:"threshold"` //审批阈值，单位分，支出金额超过该值需审批
+	Required  int   `json:"required"`  //需要的批准人数M，为0表示不启用审批
+}
+
+// PendingExpense 待审批的支出
+type PendingExpense struct {
+	ID          string   `json:"id"`           //待审批支出ID，取提交时的交易ID
+	FundID      string   `json:"fund_id"`      //款项ID
+	Amount      string   `json:"amount"`       //支出金额
+	Source      string   `json:"source"`       //金额去向
+	Category    string   `json:"category"`     //预算科目
+	InfoHash    string   `json:"info_hash"`    //证明文件的SHA-256
+	Explain     string   `json:"explain"`      //说明
+	Proposer    string   `json:"proposer"`     //提交人ID
+	ProposeDate string   `json:"propose_date"` //提交时间
+	Required    int      `json:"required"`     //提交时策略要求的批准人数
+	Approvers   []string `json:"approvers"`    //已批准的成员ID
+	Rejecter    string   `json:"rejecter"`     //驳回人ID
+	Reason      string   `json:"reason"`       //驳回原因
+	Status      string   `json:"status"`       //审批状态
+	UpdateDate  string   `json:"update_date"`  //上次修改时间
+}
+
+const (
+	approvalPolicyKey = "approval-policy" //审批策略的状态key
+	pendingIndex      = "pending~fund"    //待审批支出的组合键前缀
+
+	pendingStatusPending  = "pending"  //审批中
+	pendingStatusPosted   = "posted"   //已批准并入账
+	pendingStatusRejected = "rejected" //已驳回
+)
+
+// requiresApproval 判断支出金额是否需要审批
+func (p ApprovalPolicy) requiresApproval(cents int64) bool {
+	return p.Required > 0 && cents > p.Threshold
+}
+
+// SetApprovalPolicy 设置大额支出审批策略，required为0时关闭审批，仅管理员可调用
+func (f *FinancialContract) SetApprovalPolicy(ctx contractapi.TransactionContextInterface, threshold string, required int) error {
+	if err := common.RequireRole(ctx, "SetApprovalPolicy"); err != nil {
+		return err
+	}
+	cents, err := common.ParseAmount(threshold)
+	if err != nil {
+		return err
+	}
+	if required < 0 {
+		return fmt.Errorf("illegal approval policy: require %d approvals", required)
+	}
+	data, err := json.Marshal(ApprovalPolicy{Threshold: cents, Required: required})
+	if err != nil {
+		return fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	return ctx.GetStub().PutState(approvalPolicyKey, data)
+}
+
+// GetApprovalPolicy 查询大额支出审批策略，未设置时返回不启用审批的策略
+func (f *FinancialContract) GetApprovalPolicy(ctx contractapi.TransactionContextInterface) (ApprovalPolicy, error) {
+	state, err := ctx.GetStub().GetState(approvalPolicyKey)
+	if err != nil {
+		return ApprovalPolicy{}, err
+	}
+	var policy ApprovalPolicy
+	if state == nil {
+		return policy, nil
+	}
+	if err := json.Unmarshal(state, &policy); err != nil {
+		return ApprovalPolicy{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return policy, nil
+}
+
+// ProposeExpense 提交超过审批阈值的支出，批准人数达到要求后才计入款项
+func (f *FinancialContract) ProposeExpense(ctx contractapi.TransactionContextInterface, id, source, explain, amount, infoHash, category string) (PendingExpense, error) {
+	if err := common.RequireRole(ctx, "ProposeExpense", common.RoleTreasurer); err != nil {
+		return PendingExpense{}, err
+	}
+	if err := checkInfoHash(infoHash); err != nil {
+		return PendingExpense{}, err
+	}
+	financial, err := f.GetFinancial(ctx, id)
+	if err != nil {
+		return PendingExpense{}, fmt.Errorf("failed to get financial:%s", err.Error())
+	}
+	if financial.State == stateClose {
+		return PendingExpense{}, fmt.Errorf("%s is close", id)
+	}
+	cents, err := common.ParseAmount(amount)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	policy, err := f.GetApprovalPolicy(ctx)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	if !policy.requiresApproval(cents) {
+		return PendingExpense{}, fmt.Errorf("expense %s does not require approval, submit it with AddRecord", common.FormatAmount(cents))
+	}
+	if err := financial.checkVersion(id); err != nil {
+		return PendingExpense{}, err
+	}
+	//提交时先校验余额，入账时会再次校验
+	if cents > financial.Balance {
+		return PendingExpense{}, fmt.Errorf("insufficient balance: balance %s, expense %s",
+			common.FormatAmount(financial.Balance), common.FormatAmount(cents))
+	}
+	proposer, err := common.GetActor(ctx)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	pending := PendingExpense{
+		ID:          ctx.GetStub().GetTxID(),
+		FundID:      id,
+		Amount:      common.FormatAmount(cents),
+		Source:      source,
+		Category:    category,
+		InfoHash:    infoHash,
+		Explain:     explain,
+		Proposer:    proposer,
+		ProposeDate: now,
+		Required:    policy.Required,
+		Approvers:   []string{},
+		Status:      pendingStatusPending,
+		UpdateDate:  now,
+	}
+	return pending, f.putPendingExpense(ctx, pending)
+}
+
+// ApproveExpense 业委会成员批准待审批支出，批准人数达到要求时自动入账
+func (f *FinancialContract) ApproveExpense(ctx contractapi.TransactionContextInterface, id, pendingID string) (PendingExpense, error) {
+	if err := common.RequireRole(ctx, "ApproveExpense", common.RoleCommittee); err != nil {
+		return PendingExpense{}, err
+	}
+	pending, err := f.GetPendingExpense(ctx, id, pendingID)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	if pending.Status != pendingStatusPending {
+		return PendingExpense{}, fmt.Errorf("pending expense %s is %s", pendingID, pending.Status)
+	}
+	approver, err := common.GetActor(ctx)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	//提交人不能批准自己提交的支出
+	if approver == pending.Proposer {
+		return PendingExpense{}, fmt.Errorf("proposer %s cannot approve own expense", approver)
+	}
+	for _, a := range pending.Approvers {
+		if a == approver {
+			return PendingExpense{}, fmt.Errorf("%s already approved %s", approver, pendingID)
+		}
+	}
+	pending.Approvers = append(pending.Approvers, approver)
+	if pending.UpdateDate, err = txTime(ctx); err != nil {
+		return PendingExpense{}, err
+	}
+	if len(pending.Approvers) >= pending.Required {
+		financial, err := f.GetFinancial(ctx, id)
+		if err != nil {
+			return PendingExpense{}, fmt.Errorf("failed to get financial:%s", err.Error())
+		}
+		cents, err := common.ParseAmount(pending.Amount)
+		if err != nil {
+			return PendingExpense{}, err
+		}
+		//记录人为提交人，审批人记录在待审批支出中
+		if _, err := f.postRecord(ctx, id, &financial, FinancialRecord{
+			Type:       typeOut,
+			Source:     pending.Source,
+			Category:   pending.Category,
+			InfoHash:   pending.InfoHash,
+			Explain:    pending.Explain,
+			RecorderID: pending.Proposer,
+		}, cents); err != nil {
+			return PendingExpense{}, err
+		}
+		pending.Status = pendingStatusPosted
+	}
+	return pending, f.putPendingExpense(ctx, pending)
+}
+
+// RejectExpense 业委会成员驳回待审批支出，驳回后不再入账
+func (f *FinancialContract) RejectExpense(ctx contractapi.TransactionContextInterface, id, pendingID, reason string) (PendingExpense, error) {
+	if err := common.RequireRole(ctx, "RejectExpense", common.RoleCommittee); err != nil {
+		return PendingExpense{}, err
+	}
+	pending, err := f.GetPendingExpense(ctx, id, pendingID)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	if pending.Status != pendingStatusPending {
+		return PendingExpense{}, fmt.Errorf("pending expense %s is %s", pendingID, pending.Status)
+	}
+	if pending.Rejecter, err = common.GetActor(ctx); err != nil {
+		return PendingExpense{}, err
+	}
+	if pending.UpdateDate, err = txTime(ctx); err != nil {
+		return PendingExpense{}, err
+	}
+	pending.Reason = reason
+	pending.Status = pendingStatusRejected
+	return pending, f.putPendingExpense(ctx, pending)
+}
+
+// GetPendingExpense 查询指定的待审批支出
+func (f *FinancialContract) GetPendingExpense(ctx contractapi.TransactionContextInterface, id, pendingID string) (PendingExpense, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(pendingIndex, []string{id, pendingID})
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	state, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	if state == nil {
+		return PendingExpense{}, fmt.Errorf("pending expense %s not exist", pendingID)
+	}
+	var pending PendingExpense
+	if err := json.Unmarshal(state, &pending); err != nil {
+		return PendingExpense{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return pending, nil
+}
+
+// GetPendingExpenses 查询款项的全部待审批支出，status为空时返回所有状态
+func (f *FinancialContract) GetPendingExpenses(ctx contractapi.TransactionContextInterface, id, status string) ([]PendingExpense, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(pendingIndex, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	result := make([]PendingExpense, 0)
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		var pending PendingExpense
+		if err := json.Unmarshal(kv.Value, &pending); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+		if status == "" || pending.Status == status {
+			result = append(result, pending)
+		}
+	}
+	return result, nil
+}
+
+func (f *FinancialContract) putPendingExpense(ctx contractapi.TransactionContextInterface, pending PendingExpense) error {
+	key, err := ctx.GetStub().CreateCompositeKey(pendingIndex, []string{pending.FundID, pending.ID})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// txTime 获取交易时间
+func txTime(ctx contractapi.TransactionContextInterface) (string, error) {
+	notTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	return notTime.AsTime().Format("2006-01-02 15:04:05"), nil
+}
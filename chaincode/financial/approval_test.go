@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"community-governance/chaincode/common"
+)
+
+func TestSetApprovalPolicy(t *testing.T) {
+	cases := []struct {
+		name      string
+		threshold string
+		required  int
+		role      string
+		err       string
+	}{
+		{"require two", "500.00", 2, common.RoleAdmin, ""},
+		{"disable approval", "500.00", 0, common.RoleAdmin, ""},
+		{"negative approvals", "500.00", -1, common.RoleAdmin, "illegal approval policy"},
+		{"treasurer cannot set policy", "500.00", 2, common.RoleTreasurer, common.CodeRoleDenied},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := new(FinancialContract)
+			stub := newStub(day(t, "2024-06-15"))
+			err := f.SetApprovalPolicy(asRole(stub, "a1", c.role), c.threshold, c.required)
+			if !errContains(err, c.err) {
+				t.Fatalf("got %v, want %q", err, c.err)
+			}
+			if c.err != "" {
+				return
+			}
+			policy, err := f.GetApprovalPolicy(asRole(stub, "a1", c.role))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if policy.Threshold != 50000 || policy.Required != c.required {
+				t.Errorf("policy %+v", policy)
+			}
+		})
+	}
+}
+
+// 审批只要求M名不同的业委会成员批准，不限定业委会成员总数
+func TestApproveExpense(t *testing.T) {
+	f := new(FinancialContract)
+	stub := newStub(day(t, "2024-06-15"))
+	createFund(t, f, stub, "f1", "1000.00")
+	if err := f.SetApprovalPolicy(asRole(stub, "a1", common.RoleAdmin), "500.00", 2); err != nil {
+		t.Fatal(err)
+	}
+	stub.txID = "tx-pending"
+	pending, err := f.ProposeExpense(asRole(stub, "t1", common.RoleTreasurer), "f1", "物业公司", "电梯维修", "800.00", "", "维修")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name, cn, role string
+		err            string
+		status         string
+	}{
+		{"treasurer cannot approve", "t2", common.RoleTreasurer, common.CodeRoleDenied, ""},
+		{"first committee member", "c1", common.RoleCommittee, "", pendingStatusPending},
+		{"same member twice", "c1", common.RoleCommittee, "already approved", ""},
+		{"second committee member posts the expense", "c7", common.RoleCommittee, "", pendingStatusPosted},
+		{"posted expense", "c8", common.RoleCommittee, "is posted", ""},
+	}
+	for _, s := range steps {
+		approved, err := f.ApproveExpense(asRole(stub, s.cn, s.role), "f1", pending.ID)
+		if !errContains(err, s.err) {
+			t.Fatalf("%s: got %v, want %q", s.name, err, s.err)
+		}
+		if s.err == "" && approved.Status != s.status {
+			t.Errorf("%s: status %s, want %s", s.name, approved.Status, s.status)
+		}
+	}
+	totals, err := f.GetFinancialTotals(asRole(stub, "t1", common.RoleTreasurer), "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.Balance != "200.00" {
+		t.Errorf("balance %s, want 200.00", totals.Balance)
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"community-governance/chaincode/common"
 	"encoding/json"
 	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"time"
 )
 
 // FinancialContract 财务管理合约
@@ -12,15 +14,39 @@ type FinancialContract struct {
 }
 type Financial struct {
 	MessageHash string `json:"message"`     //信息hash值
+	Principal   string `json:"principal"`   //负责人ID
 	UpdateDate  string `json:"update_date"` //上次修改时间
 	State       string `json:"state"`       //款项状态
+	Income      int64  `json:"income"`      //累计收入，单位分
+	Expense     int64  `json:"expense"`     //累计支出，单位分
+	Balance     int64  `json:"balance"`     //当前余额，单位分，含期初资金
+	Version     int    `json:"version"`     //收支汇总版本，汇总上线前创建的款项为0，须经MigrateTotals迁移
+}
+
+// FinancialTotals 款项收支汇总，金额为保留两位小数的字符串
+type FinancialTotals struct {
+	Income  string `json:"income"`
+	Expense string `json:"expense"`
+	Balance string `json:"balance"`
 }
 type FinancialRecord struct {
-	Type       string `json:"type"`      //记录类型，0-收入 1-支出
-	Amount     string `json:"amount"`    //记录金额
-	Source     string `json:"source"`    //金额来源
-	Explain    string `json:"explain"`   //说明
-	RecorderID string `json:"record_id"` //记录人ID
+	Type       string `json:"type"`                  //记录类型，0-收入 1-支出
+	Amount     string `json:"amount"`                //记录金额
+	Source     string `json:"source"`                //金额来源
+	Category   string `json:"category"`              //预算科目
+	InfoHash   string `json:"info_hash"`             //证明文件的SHA-256
+	Explain    string `json:"explain"`               //说明
+	RecorderID string `json:"record_id"`             //记录人ID
+	TransferID string `json:"transfer_id,omitempty"` //款项间划转ID，非划转记录为空
+	Date       string `json:"date,omitempty"`        //记录日期，2006-01-02
+	Corrects   string `json:"corrects,omitempty"`    //更正的原记录所在交易ID，非更正记录为空
+}
+
+// FinancialTransaction 收支记录及其所在交易
+type FinancialTransaction struct {
+	TxID      string          `json:"tx_id"`     //记录所在交易ID
+	Timestamp string          `json:"timestamp"` //交易时间，RFC3339格式
+	Record    FinancialRecord `json:"record"`
 }
 
 const (
@@ -28,10 +54,16 @@ const (
 	typeOut    = "1" //记录类型-支出
 	stateInit  = "0" //项目状态-运行中
 	stateClose = "1" //项目已关闭
+
+	totalsVersion = 1 //当前收支汇总版本
 )
 
 // CreateFinancial  创建资产项目
-func (f *FinancialContract) CreateFinancial(ctx contractapi.TransactionContextInterface, id, hash string) error {
+// opening为期初资金，计入余额但不计入累计收入
+func (f *FinancialContract) CreateFinancial(ctx contractapi.TransactionContextInterface, id, hash, opening string) error {
+	if err := common.RequireRole(ctx, "CreateFinancial", common.RoleTreasurer); err != nil {
+		return err
+	}
 	if id == "" || hash == "" {
 		return fmt.Errorf("id = ''  or hash = ''")
 	}
@@ -43,14 +75,25 @@ func (f *FinancialContract) CreateFinancial(ctx contractapi.TransactionContextIn
 	if exist {
 		return fmt.Errorf("%s already existed", id)
 	}
+	openingCents, err := common.ParseAmount(opening)
+	if err != nil {
+		return err
+	}
+	principal, err := common.GetActor(ctx)
+	if err != nil {
+		return err
+	}
 	notTime, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return fmt.Errorf("failed to get tx timestamp:%s", err.Error())
 	}
 	fin := Financial{
 		MessageHash: hash,
+		Principal:   principal,
 		UpdateDate:  notTime.AsTime().Format("2006-01-02 15:04:05"),
 		State:       stateInit,
+		Balance:     openingCents,
+		Version:     totalsVersion,
 	}
 	marshal, err := json.Marshal(fin)
 	if err != nil {
@@ -59,6 +102,9 @@ func (f *FinancialContract) CreateFinancial(ctx contractapi.TransactionContextIn
 	return ctx.GetStub().PutState(id, marshal)
 }
 func (f *FinancialContract) UpdateFinancial(ctx contractapi.TransactionContextInterface, id, hash string) error {
+	if err := common.RequireRole(ctx, "UpdateFinancial", common.RoleTreasurer); err != nil {
+		return err
+	}
 	if id == "" || hash == "" {
 		return fmt.Errorf("id = '' or  hash = ''")
 	}
@@ -84,33 +130,214 @@ func (f *FinancialContract) UpdateFinancial(ctx contractapi.TransactionContextIn
 
 }
 
-// AddRecord 添加款项变动记录
-func (f *FinancialContract) AddRecord(ctx contractapi.TransactionContextInterface, id, finType, source, explain, recorder, amount string) error {
-	recordId := fmt.Sprintf("%s-%s", "record", id)
+// AddRecord 添加款项变动记录，更新款项的收支汇总，支出超过余额时拒绝
+// 设置了审批策略时，超过阈值的支出需经ProposeExpense提交审批，infoHash为证明文件的SHA-256，可为空
+// category为记录所属的预算科目，date为记录日期，为空时取交易日期，已结账期间内的日期被拒绝
+func (f *FinancialContract) AddRecord(ctx contractapi.TransactionContextInterface, id, finType, source, explain, amount, infoHash, category, date string) (FinancialTotals, error) {
+	if err := common.RequireRole(ctx, "AddRecord", common.RoleTreasurer); err != nil {
+		return FinancialTotals{}, err
+	}
+	return f.addRecord(ctx, id, FinancialRecord{
+		Type:     finType,
+		Source:   source,
+		Category: category,
+		InfoHash: infoHash,
+		Explain:  explain,
+		Date:     date,
+	}, amount)
+}
+
+// addRecord 校验证明文件与审批阈值后写入收支记录，记录人取自交易提交者身份
+func (f *FinancialContract) addRecord(ctx contractapi.TransactionContextInterface, id string, record FinancialRecord, amount string) (FinancialTotals, error) {
+	if err := checkInfoHash(record.InfoHash); err != nil {
+		return FinancialTotals{}, err
+	}
 	financial, err := f.GetFinancial(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to get financial:%s", err.Error())
+		return FinancialTotals{}, fmt.Errorf("failed to get financial:%s", err.Error())
+	}
+	cents, err := common.ParseAmount(amount)
+	if err != nil {
+		return FinancialTotals{}, err
 	}
+	if record.Type == typeOut {
+		policy, err := f.GetApprovalPolicy(ctx)
+		if err != nil {
+			return FinancialTotals{}, err
+		}
+		if policy.requiresApproval(cents) {
+			return FinancialTotals{}, fmt.Errorf("expense %s exceeds approval threshold %s, submit it with ProposeExpense",
+				common.FormatAmount(cents), common.FormatAmount(policy.Threshold))
+		}
+	}
+	if record.RecorderID, err = common.GetActor(ctx); err != nil {
+		return FinancialTotals{}, err
+	}
+	return f.postRecord(ctx, id, &financial, record, cents)
+}
+
+// postRecord 校验金额与记录日期并写入收支记录，同时更新款项的收支汇总
+func (f *FinancialContract) postRecord(ctx contractapi.TransactionContextInterface, id string, financial *Financial, record FinancialRecord, cents int64) (FinancialTotals, error) {
 	//判断项目状态，如果是关闭状态，则不能再执行修改的操作
 	if financial.State == stateClose {
-		return fmt.Errorf("%s is close", id)
+		return FinancialTotals{}, fmt.Errorf("%s is close", id)
 	}
-	record := FinancialRecord{
-		Type:       finType,
-		Source:     source,
-		Amount:     amount,
-		Explain:    explain,
-		RecorderID: recorder,
+	if err := financial.checkVersion(id); err != nil {
+		return FinancialTotals{}, err
 	}
+	if err := f.checkRecordDate(ctx, &record); err != nil {
+		return FinancialTotals{}, err
+	}
+	if cents == 0 {
+		return FinancialTotals{}, fmt.Errorf("amount must be positive")
+	}
+	var err error
+	switch record.Type {
+	case typeIn:
+		if financial.Income, err = common.AddAmount(financial.Income, cents); err != nil {
+			return FinancialTotals{}, err
+		}
+		if financial.Balance, err = common.AddAmount(financial.Balance, cents); err != nil {
+			return FinancialTotals{}, err
+		}
+	case typeOut:
+		if cents > financial.Balance {
+			return FinancialTotals{}, fmt.Errorf("insufficient balance: balance %s, expense %s",
+				common.FormatAmount(financial.Balance), common.FormatAmount(cents))
+		}
+		if financial.Expense, err = common.AddAmount(financial.Expense, cents); err != nil {
+			return FinancialTotals{}, err
+		}
+		financial.Balance -= cents
+	default:
+		return FinancialTotals{}, fmt.Errorf("illegal record type:%s", record.Type)
+	}
+	record.Amount = common.FormatAmount(cents)
 	data, err := json.Marshal(record)
 	if err != nil {
-		return fmt.Errorf("failed to marshal:%s", err.Error())
+		return FinancialTotals{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	recordId := fmt.Sprintf("%s-%s", "record", id)
+	if err := ctx.GetStub().PutState(recordId, data); err != nil {
+		return FinancialTotals{}, err
+	}
+	data, err = json.Marshal(financial)
+	if err != nil {
+		return FinancialTotals{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	if err := ctx.GetStub().PutState(id, data); err != nil {
+		return FinancialTotals{}, err
+	}
+	return financial.totals(), nil
+}
+
+// checkVersion 汇总上线前创建的款项余额未知，迁移前不能记账
+func (fin Financial) checkVersion(id string) error {
+	if fin.Version < totalsVersion {
+		return fmt.Errorf("totals of %s are not migrated, run MigrateTotals first", id)
+	}
+	return nil
+}
+
+// MigrateTotals 迁移汇总上线前创建的款项，仅管理员可执行且每个款项只能执行一次
+// 累计收入与支出由链上收支记录的历史重新汇总，balance为应用端已迁移账本中资金账户的当前余额
+func (f *FinancialContract) MigrateTotals(ctx contractapi.TransactionContextInterface, id, balance string) (FinancialTotals, error) {
+	if err := common.RequireRole(ctx, "MigrateTotals"); err != nil {
+		return FinancialTotals{}, err
+	}
+	financial, err := f.GetFinancial(ctx, id)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
+	if financial.Version >= totalsVersion {
+		return FinancialTotals{}, fmt.Errorf("totals of %s are already migrated", id)
+	}
+	balanceCents, err := common.ParseAmount(balance)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
+	income, expense, err := f.sumRecordHistory(ctx, id)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return FinancialTotals{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	financial.Income = income
+	financial.Expense = expense
+	financial.Balance = balanceCents
+	financial.Version = totalsVersion
+	financial.UpdateDate = nowTime.AsTime().Format("2006-01-02 15:04:05")
+	data, err := json.Marshal(financial)
+	if err != nil {
+		return FinancialTotals{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	if err := ctx.GetStub().PutState(id, data); err != nil {
+		return FinancialTotals{}, err
+	}
+	return financial.totals(), nil
+}
+
+// sumRecordHistory 汇总款项全部收支记录的收入与支出
+func (f *FinancialContract) sumRecordHistory(ctx contractapi.TransactionContextInterface, id string) (int64, int64, error) {
+	iter, err := ctx.GetStub().GetHistoryForKey(fmt.Sprintf("%s-%s", "record", id))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get history:%s", err.Error())
+	}
+	defer iter.Close()
+	var income, expense int64
+	for iter.HasNext() {
+		modification, err := iter.Next()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get history:%s", err.Error())
+		}
+		if modification.IsDelete {
+			continue
+		}
+		var record FinancialRecord
+		if err := json.Unmarshal(modification.Value, &record); err != nil {
+			return 0, 0, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+		cents, err := common.ParseAmount(record.Amount)
+		if err != nil {
+			return 0, 0, fmt.Errorf("record %s of %s:%s", modification.TxId, id, err.Error())
+		}
+		switch record.Type {
+		case typeIn:
+			income, err = common.AddAmount(income, cents)
+		case typeOut:
+			expense, err = common.AddAmount(expense, cents)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return income, expense, nil
+}
+
+// GetFinancialTotals 获取款项收支汇总
+func (f *FinancialContract) GetFinancialTotals(ctx contractapi.TransactionContextInterface, id string) (FinancialTotals, error) {
+	financial, err := f.GetFinancial(ctx, id)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
+	return financial.totals(), nil
+}
+
+func (fin Financial) totals() FinancialTotals {
+	return FinancialTotals{
+		Income:  common.FormatAmount(fin.Income),
+		Expense: common.FormatAmount(fin.Expense),
+		Balance: common.FormatAmount(fin.Balance),
 	}
-	return ctx.GetStub().PutState(recordId, data)
 }
 
 // ExchangeState 更改资产项目状态
 func (f *FinancialContract) ExchangeState(ctx contractapi.TransactionContextInterface, id, state string) error {
+	if err := common.RequireRole(ctx, "ExchangeState", common.RoleTreasurer); err != nil {
+		return err
+	}
 	financial, err := f.GetFinancial(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get financial:%s", err.Error())
@@ -216,3 +443,67 @@ func (f *FinancialContract) GetFinancialRecordHistory(ctx contractapi.Transactio
 	}
 	return records, nil
 }
+
+// VerifyAttachment 查找记录了指定证明文件hash的收支记录
+func (f *FinancialContract) VerifyAttachment(ctx contractapi.TransactionContextInterface, id, infoHash string) ([]FinancialTransaction, error) {
+	if err := checkInfoHash(infoHash); err != nil || infoHash == "" {
+		return nil, fmt.Errorf("illegal info hash:%s", infoHash)
+	}
+	return f.recordTransactions(ctx, id, func(record FinancialRecord) bool {
+		return record.InfoHash == infoHash
+	})
+}
+
+// GetFinancialRecordTransactions 获取款项的全部收支记录及其交易ID与交易时间
+func (f *FinancialContract) GetFinancialRecordTransactions(ctx contractapi.TransactionContextInterface, id string) ([]FinancialTransaction, error) {
+	return f.recordTransactions(ctx, id, func(FinancialRecord) bool { return true })
+}
+
+// recordTransactions 按交易顺序读取款项的收支记录历史，match为false的记录被跳过
+func (f *FinancialContract) recordTransactions(ctx contractapi.TransactionContextInterface, id string, match func(FinancialRecord) bool) ([]FinancialTransaction, error) {
+	recordId := fmt.Sprintf("%s-%s", "record", id)
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(recordId)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+	transactions := make([]FinancialTransaction, 0)
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if modification.IsDelete {
+			continue
+		}
+		var record FinancialRecord
+		if err := json.Unmarshal(modification.Value, &record); err != nil {
+			return nil, err
+		}
+		if !match(record) {
+			continue
+		}
+		transactions = append(transactions, FinancialTransaction{
+			TxID:      modification.TxId,
+			Timestamp: modification.Timestamp.AsTime().Format(time.RFC3339),
+			Record:    record,
+		})
+	}
+	return transactions, nil
+}
+
+// checkInfoHash 证明文件hash为空或64位小写十六进制的SHA-256
+func checkInfoHash(infoHash string) error {
+	if infoHash == "" {
+		return nil
+	}
+	if len(infoHash) != 64 {
+		return fmt.Errorf("illegal info hash:%s", infoHash)
+	}
+	for _, c := range infoHash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return fmt.Errorf("illegal info hash:%s", infoHash)
+		}
+	}
+	return nil
+}
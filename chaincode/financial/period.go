@@ -0,0 +1,160 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"time"
+)
+
+// ClosedPeriod 已结账的会计期间，期间内日期的收支记录不能再添加，只能在未结账期间记更正记录
+type ClosedPeriod struct {
+	Period    string `json:"period"`     //会计期间，月度为2006-01，年度为2006
+	Closer    string `json:"closer"`     //结账人ID
+	CloseDate string `json:"close_date"` //结账时间
+}
+
+const periodIndex = "period~closed" //已结账期间的组合键前缀
+
+// periodEnd 会计期间的结束日期(不含)，期间格式不合法时返回错误
+func periodEnd(period string) (string, error) {
+	if start, err := time.Parse("2006-01", period); err == nil {
+		return start.AddDate(0, 1, 0).Format("2006-01-02"), nil
+	}
+	if start, err := time.Parse("2006", period); err == nil {
+		return start.AddDate(1, 0, 0).Format("2006-01-02"), nil
+	}
+	return "", fmt.Errorf("illegal period:%s, expect 2006-01 or 2006", period)
+}
+
+// ClosePeriod 结账，只能结已经结束的期间，年度结账同时锁定该年各月
+func (f *FinancialContract) ClosePeriod(ctx contractapi.TransactionContextInterface, period string) (ClosedPeriod, error) {
+	if err := common.RequireRole(ctx, "ClosePeriod", common.RoleTreasurer); err != nil {
+		return ClosedPeriod{}, err
+	}
+	end, err := periodEnd(period)
+	if err != nil {
+		return ClosedPeriod{}, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return ClosedPeriod{}, err
+	}
+	if now[:10] < end {
+		return ClosedPeriod{}, fmt.Errorf("period %s has not ended", period)
+	}
+	closed, err := f.getClosedPeriod(ctx, period)
+	if err != nil {
+		return ClosedPeriod{}, err
+	}
+	if closed != nil {
+		return ClosedPeriod{}, fmt.Errorf("fiscal period %s is already closed", period)
+	}
+	closer, err := common.GetActor(ctx)
+	if err != nil {
+		return ClosedPeriod{}, err
+	}
+	result := ClosedPeriod{Period: period, Closer: closer, CloseDate: now}
+	key, err := ctx.GetStub().CreateCompositeKey(periodIndex, []string{period})
+	if err != nil {
+		return ClosedPeriod{}, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return ClosedPeriod{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	return result, ctx.GetStub().PutState(key, data)
+}
+
+// GetClosedPeriods 查询全部已结账的期间
+func (f *FinancialContract) GetClosedPeriods(ctx contractapi.TransactionContextInterface) ([]ClosedPeriod, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(periodIndex, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	result := make([]ClosedPeriod, 0)
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		var period ClosedPeriod
+		if err := json.Unmarshal(kv.Value, &period); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+		result = append(result, period)
+	}
+	return result, nil
+}
+
+func (f *FinancialContract) getClosedPeriod(ctx contractapi.TransactionContextInterface, period string) (*ClosedPeriod, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(periodIndex, []string{period})
+	if err != nil {
+		return nil, err
+	}
+	state, err := ctx.GetStub().GetState(key)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	var closed ClosedPeriod
+	if err := json.Unmarshal(state, &closed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return &closed, nil
+}
+
+// checkRecordDate 记录日期为空时取交易日期，日期所在的月度或年度已结账时拒绝
+func (f *FinancialContract) checkRecordDate(ctx contractapi.TransactionContextInterface, record *FinancialRecord) error {
+	if record.Date == "" {
+		now, err := txTime(ctx)
+		if err != nil {
+			return err
+		}
+		record.Date = now[:10]
+	}
+	if _, err := time.Parse("2006-01-02", record.Date); err != nil {
+		return fmt.Errorf("illegal record date:%s", record.Date)
+	}
+	for _, period := range []string{record.Date[:7], record.Date[:4]} {
+		closed, err := f.getClosedPeriod(ctx, period)
+		if err != nil {
+			return err
+		}
+		if closed != nil {
+			return fmt.Errorf("fiscal period %s is closed, record a correcting entry in an open period instead", period)
+		}
+	}
+	return nil
+}
+
+// CorrectRecord 在当前期间记一笔更正记录，关联被更正的原记录所在交易，原记录须属于该款项
+// 更正记录按普通收支记录校验余额与审批阈值，如冲销原记录时记相反类型的同额记录
+func (f *FinancialContract) CorrectRecord(ctx contractapi.TransactionContextInterface, id, originalTxID, finType, source, explain, amount, infoHash, category string) (FinancialTotals, error) {
+	if err := common.RequireRole(ctx, "CorrectRecord", common.RoleTreasurer); err != nil {
+		return FinancialTotals{}, err
+	}
+	found := false
+	transactions, err := f.GetFinancialRecordTransactions(ctx, id)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
+	for _, tx := range transactions {
+		if tx.TxID == originalTxID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return FinancialTotals{}, fmt.Errorf("record %s not found in %s", originalTxID, id)
+	}
+	return f.addRecord(ctx, id, FinancialRecord{
+		Type:     finType,
+		Source:   source,
+		Category: category,
+		InfoHash: infoHash,
+		Explain:  explain,
+		Corrects: originalTxID,
+	}, amount)
+}
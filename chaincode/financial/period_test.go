@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+
+	"community-governance/chaincode/common"
+)
+
+func TestClosePeriod(t *testing.T) {
+	cases := []struct {
+		name   string
+		period string
+		today  string
+		role   string
+		err    string //为空时要求成功
+	}{
+		{"ended month", "2024-05", "2024-06-01", common.RoleTreasurer, ""},
+		{"unfinished month", "2024-06", "2024-06-30", common.RoleTreasurer, "has not ended"},
+		{"ended year", "2023", "2024-01-01", common.RoleTreasurer, ""},
+		{"unfinished year", "2024", "2024-12-31", common.RoleTreasurer, "has not ended"},
+		{"future month", "2025-01", "2024-06-15", common.RoleTreasurer, "has not ended"},
+		{"illegal month", "2024-13", "2025-06-15", common.RoleTreasurer, "illegal period"},
+		{"illegal format", "24-05", "2025-06-15", common.RoleTreasurer, "illegal period"},
+		{"resident cannot close", "2024-05", "2024-06-01", "resident", common.CodeRoleDenied},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := new(FinancialContract)
+			stub := newStub(day(t, c.today))
+			closed, err := f.ClosePeriod(asRole(stub, "t1", c.role), c.period)
+			if !errContains(err, c.err) {
+				t.Fatalf("got %v, want %q", err, c.err)
+			}
+			if c.err != "" {
+				return
+			}
+			if closed.Period != c.period || closed.Closer != "t1" {
+				t.Errorf("closed %+v", closed)
+			}
+			if _, err := f.ClosePeriod(asRole(stub, "t1", c.role), c.period); !errContains(err, "already closed") {
+				t.Errorf("closing twice: got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckRecordDate(t *testing.T) {
+	f := new(FinancialContract)
+	stub := newStub(day(t, "2024-06-15"))
+	createFund(t, f, stub, "f1", "1000.00")
+	treasurer := asRole(stub, "t1", common.RoleTreasurer)
+	for _, period := range []string{"2024-05", "2023"} {
+		if _, err := f.ClosePeriod(treasurer, period); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		name string
+		date string
+		err  string
+	}{
+		{"date in open month", "2024-06-01", ""},
+		{"empty date takes tx date", "", ""},
+		{"last day of closed month", "2024-05-31", "fiscal period 2024-05 is closed"},
+		{"first day of closed month", "2024-05-01", "fiscal period 2024-05 is closed"},
+		{"month of closed year", "2023-07-01", "fiscal period 2023 is closed"},
+		{"open month before closed one", "2024-04-30", ""},
+		{"illegal date", "2024-02-30", "illegal record date"},
+		{"illegal format", "2024/06/01", "illegal record date"},
+	}
+	for _, c := range cases {
+		_, err := f.AddRecord(treasurer, "f1", typeIn, "物业费", c.name, "10.00", "", "", c.date)
+		if !errContains(err, c.err) {
+			t.Errorf("%s: got %v, want %q", c.name, err, c.err)
+		}
+	}
+	transactions, err := f.GetFinancialRecordTransactions(treasurer, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates := map[string]bool{}
+	for _, tx := range transactions {
+		dates[tx.Record.Date] = true
+	}
+	if len(transactions) != 3 || !dates["2024-06-15"] || !dates["2024-04-30"] {
+		t.Errorf("records %+v, want 3 records in open periods with the tx date for the empty one", transactions)
+	}
+}
+
+func TestCorrectRecord(t *testing.T) {
+	f := new(FinancialContract)
+	stub := newStub(day(t, "2024-05-10"))
+	createFund(t, f, stub, "f1", "1000.00")
+	createFund(t, f, stub, "f2", "1000.00")
+	treasurer := asRole(stub, "t1", common.RoleTreasurer)
+	stub.txID = "tx-f1"
+	if _, err := f.AddRecord(treasurer, "f1", typeOut, "维修", "电梯维修", "300.00", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	stub.txID = "tx-f2"
+	if _, err := f.AddRecord(treasurer, "f2", typeOut, "绿化", "补种", "100.00", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	//原记录所在的5月已结账，更正记录记在6月
+	stub.now = day(t, "2024-06-15")
+	if _, err := f.ClosePeriod(treasurer, "2024-05"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name       string
+		role       string
+		originalTx string
+		err        string
+	}{
+		{"record of another fund", common.RoleTreasurer, "tx-f2", "record tx-f2 not found in f1"},
+		{"unknown record", common.RoleTreasurer, "tx-none", "record tx-none not found in f1"},
+		{"fund creation is not a record", common.RoleTreasurer, "tx0", "record tx0 not found in f1"},
+		{"committee cannot correct", common.RoleCommittee, "tx-f1", common.CodeRoleDenied},
+		{"reverse record of closed month", common.RoleTreasurer, "tx-f1", ""},
+	}
+	for _, c := range cases {
+		stub.txID = "tx-" + c.name
+		_, err := f.CorrectRecord(asRole(stub, "t1", c.role), "f1", c.originalTx, typeIn, "维修", "冲销", "300.00", "", "")
+		if !errContains(err, c.err) {
+			t.Errorf("%s: got %v, want %q", c.name, err, c.err)
+		}
+	}
+	totals, err := f.GetFinancialTotals(treasurer, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.Balance != "1000.00" || totals.Income != "300.00" || totals.Expense != "300.00" {
+		t.Errorf("totals %+v after reversing the expense", totals)
+	}
+	transactions, err := f.GetFinancialRecordTransactions(treasurer, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := transactions[len(transactions)-1].Record
+	if last.Corrects != "tx-f1" || last.Date != "2024-06-15" {
+		t.Errorf("correcting record %+v, want corrects tx-f1 dated 2024-06-15", last)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"community-governance/chaincode/common"
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// memStub 测试用的内存账本，只实现合约用到的读写、组合键查询与键历史，写入立即可见
+// 合约返回错误时真实账本会丢弃整个交易的写入，内存账本不会，失败用例不应再核对余额
+type memStub struct {
+	shim.ChaincodeStubInterface
+	state   map[string][]byte
+	history map[string][]*queryresult.KeyModification
+	txID    string
+	now     time.Time
+}
+
+func (s *memStub) GetState(key string) ([]byte, error) { return s.state[key], nil }
+func (s *memStub) PutState(key string, value []byte) error {
+	s.state[key] = value
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.txID, Value: value, Timestamp: timestamppb.New(s.now)})
+	return nil
+}
+func (s *memStub) GetTxID() string { return s.txID }
+func (s *memStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.now), nil
+}
+func (s *memStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+func (s *memStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	iter := &memIterator{}
+	for key, value := range s.state {
+		if strings.HasPrefix(key, prefix) {
+			iter.kvs = append(iter.kvs, &queryresult.KV{Key: key, Value: value})
+		}
+	}
+	sort.Slice(iter.kvs, func(i, j int) bool { return iter.kvs[i].Key < iter.kvs[j].Key })
+	return iter, nil
+}
+func (s *memStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &memHistoryIterator{modifications: s.history[key]}, nil
+}
+
+type memIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (it *memIterator) HasNext() bool { return len(it.kvs) > 0 }
+func (it *memIterator) Close() error  { return nil }
+func (it *memIterator) Next() (*queryresult.KV, error) {
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+type memHistoryIterator struct {
+	modifications []*queryresult.KeyModification
+}
+
+func (it *memHistoryIterator) HasNext() bool { return len(it.modifications) > 0 }
+func (it *memHistoryIterator) Close() error  { return nil }
+func (it *memHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	modification := it.modifications[0]
+	it.modifications = it.modifications[1:]
+	return modification, nil
+}
+
+type mockIdentity struct {
+	cn    string
+	attrs map[string]string
+}
+
+func (m *mockIdentity) GetID() (string, error)    { return m.cn, nil }
+func (m *mockIdentity) GetMSPID() (string, error) { return "Org1MSP", nil }
+func (m *mockIdentity) GetAttributeValue(name string) (string, bool, error) {
+	v, ok := m.attrs[name]
+	return v, ok, nil
+}
+func (m *mockIdentity) AssertAttributeValue(name, value string) error { return nil }
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{Subject: pkix.Name{CommonName: m.cn}}, nil
+}
+
+func newStub(now time.Time) *memStub {
+	return &memStub{state: map[string][]byte{}, history: map[string][]*queryresult.KeyModification{}, txID: "tx0", now: now}
+}
+
+// asRole 以指定角色的成员cn身份调用合约，role为空时证书没有role属性
+func asRole(stub *memStub, cn, role string) *contractapi.TransactionContext {
+	attrs := map[string]string{}
+	if role != "" {
+		attrs[common.RoleAttribute] = role
+	}
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&mockIdentity{cn: cn, attrs: attrs})
+	return ctx
+}
+
+// day 解析yyyy-MM-dd为当天中午的UTC时间
+func day(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Add(12 * time.Hour)
+}
+
+// createFund 由财务负责人t1创建款项并记期初资金
+func createFund(t *testing.T, f *FinancialContract, stub *memStub, id, opening string) {
+	t.Helper()
+	if err := f.CreateFinancial(asRole(stub, "t1", common.RoleTreasurer), id, "hash-"+id, opening); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// errContains err为nil时要求want为空，否则要求错误信息包含want
+func errContains(err error, want string) bool {
+	if want == "" {
+		return err == nil
+	}
+	return err != nil && strings.Contains(err.Error(), want)
+}
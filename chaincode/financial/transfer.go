@@ -0,0 +1,116 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// FundTransfer 款项间划转，在同一交易中记转出款项的支出与转入款项的收入
+type FundTransfer struct {
+	ID         string          `json:"id"`          //划转ID，两条收支记录共用
+	From       string          `json:"from"`        //转出款项ID
+	To         string          `json:"to"`          //转入款项ID
+	Amount     string          `json:"amount"`      //划转金额
+	Explain    string          `json:"explain"`     //说明
+	Operator   string          `json:"operator"`    //操作人ID
+	Date       string          `json:"date"`        //划转时间
+	FromTotals FinancialTotals `json:"from_totals"` //划转后转出款项的收支汇总
+	ToTotals   FinancialTotals `json:"to_totals"`   //划转后转入款项的收支汇总
+}
+
+// transferKey 划转记录的状态key
+func transferKey(transferID string) string {
+	return fmt.Sprintf("%s-%s", "transfer", transferID)
+}
+
+// Transfer 从from款项划转资金到to款项，两个款项的记录在同一交易中写入，任一失败时整体不生效
+// transferID由调用方生成，与链下分录共用，不能重复；转出款项余额不足或任一款项已关闭时拒绝
+func (f *FinancialContract) Transfer(ctx contractapi.TransactionContextInterface, transferID, from, to, amount, explain string) (FundTransfer, error) {
+	if err := common.RequireRole(ctx, "Transfer", common.RoleTreasurer); err != nil {
+		return FundTransfer{}, err
+	}
+	if transferID == "" {
+		return FundTransfer{}, fmt.Errorf("transfer id = ''")
+	}
+	state, err := ctx.GetStub().GetState(transferKey(transferID))
+	if err != nil {
+		return FundTransfer{}, err
+	}
+	if state != nil {
+		return FundTransfer{}, fmt.Errorf("transfer %s already existed", transferID)
+	}
+	if from == to {
+		return FundTransfer{}, fmt.Errorf("cannot transfer within the same fund %s", from)
+	}
+	cents, err := common.ParseAmount(amount)
+	if err != nil {
+		return FundTransfer{}, err
+	}
+	fromFin, err := f.GetFinancial(ctx, from)
+	if err != nil {
+		return FundTransfer{}, fmt.Errorf("failed to get financial:%s", err.Error())
+	}
+	toFin, err := f.GetFinancial(ctx, to)
+	if err != nil {
+		return FundTransfer{}, fmt.Errorf("failed to get financial:%s", err.Error())
+	}
+	operator, err := common.GetActor(ctx)
+	if err != nil {
+		return FundTransfer{}, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return FundTransfer{}, err
+	}
+	transfer := FundTransfer{
+		ID:       transferID,
+		From:     from,
+		To:       to,
+		Amount:   common.FormatAmount(cents),
+		Explain:  explain,
+		Operator: operator,
+		Date:     now,
+	}
+	//转出记录的来源为转入款项，转入记录的来源为转出款项
+	if transfer.FromTotals, err = f.postRecord(ctx, from, &fromFin, FinancialRecord{
+		Type:       typeOut,
+		Source:     to,
+		Explain:    explain,
+		RecorderID: operator,
+		TransferID: transfer.ID,
+	}, cents); err != nil {
+		return FundTransfer{}, err
+	}
+	if transfer.ToTotals, err = f.postRecord(ctx, to, &toFin, FinancialRecord{
+		Type:       typeIn,
+		Source:     from,
+		Explain:    explain,
+		RecorderID: operator,
+		TransferID: transfer.ID,
+	}, cents); err != nil {
+		return FundTransfer{}, err
+	}
+	data, err := json.Marshal(transfer)
+	if err != nil {
+		return FundTransfer{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	return transfer, ctx.GetStub().PutState(transferKey(transfer.ID), data)
+}
+
+// GetTransfer 查询划转记录
+func (f *FinancialContract) GetTransfer(ctx contractapi.TransactionContextInterface, transferID string) (FundTransfer, error) {
+	state, err := ctx.GetStub().GetState(transferKey(transferID))
+	if err != nil {
+		return FundTransfer{}, err
+	}
+	if state == nil {
+		return FundTransfer{}, fmt.Errorf("transfer %s not exist", transferID)
+	}
+	var transfer FundTransfer
+	if err := json.Unmarshal(state, &transfer); err != nil {
+		return FundTransfer{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return transfer, nil
+}
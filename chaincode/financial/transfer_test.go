@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"community-governance/chaincode/common"
+)
+
+func TestTransfer(t *testing.T) {
+	cases := []struct {
+		name     string
+		id       string
+		from, to string
+		amount   string
+		closed   string //划转前关闭的款项
+		role     string
+		err      string    //为空时要求成功
+		balances [2]string //划转后转出与转入款项的余额
+	}{
+		{"transfer", "t1", "f1", "f2", "400.00", "", common.RoleTreasurer, "", [2]string{"600.00", "400.00"}},
+		{"whole balance", "t1", "f1", "f2", "1000.00", "", common.RoleTreasurer, "", [2]string{"0.00", "1000.00"}},
+		{"insufficient balance", "t1", "f1", "f2", "1000.01", "", common.RoleTreasurer, "insufficient balance", [2]string{}},
+		{"target balance is not available", "t1", "f2", "f1", "0.01", "", common.RoleTreasurer, "insufficient balance", [2]string{}},
+		{"closed source", "t1", "f1", "f2", "400.00", "f1", common.RoleTreasurer, "f1 is close", [2]string{}},
+		{"closed target", "t1", "f1", "f2", "400.00", "f2", common.RoleTreasurer, "f2 is close", [2]string{}},
+		{"same fund", "t1", "f1", "f1", "400.00", "", common.RoleTreasurer, "within the same fund", [2]string{}},
+		{"unknown fund", "t1", "f1", "f3", "400.00", "", common.RoleTreasurer, "f3 not exist", [2]string{}},
+		{"zero amount", "t1", "f1", "f2", "0", "", common.RoleTreasurer, "amount must be positive", [2]string{}},
+		{"empty id", "", "f1", "f2", "400.00", "", common.RoleTreasurer, "transfer id = ''", [2]string{}},
+		{"committee cannot transfer", "t1", "f1", "f2", "400.00", "", common.RoleCommittee, common.CodeRoleDenied, [2]string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := new(FinancialContract)
+			stub := newStub(day(t, "2024-06-15"))
+			createFund(t, f, stub, "f1", "1000.00")
+			createFund(t, f, stub, "f2", "0")
+			treasurer := asRole(stub, "t1", common.RoleTreasurer)
+			if c.closed != "" {
+				if err := f.ExchangeState(treasurer, c.closed, stateClose); err != nil {
+					t.Fatal(err)
+				}
+			}
+			stub.txID = "tx-transfer"
+			transfer, err := f.Transfer(asRole(stub, "t1", c.role), c.id, c.from, c.to, c.amount, "划拨")
+			if !errContains(err, c.err) {
+				t.Fatalf("got %v, want %q", err, c.err)
+			}
+			if c.err != "" {
+				return
+			}
+			if transfer.FromTotals.Balance != c.balances[0] || transfer.ToTotals.Balance != c.balances[1] {
+				t.Errorf("balances %s and %s, want %v", transfer.FromTotals.Balance, transfer.ToTotals.Balance, c.balances)
+			}
+			for i, id := range []string{c.from, c.to} {
+				totals, err := f.GetFinancialTotals(treasurer, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if totals.Balance != c.balances[i] {
+					t.Errorf("%s balance %s, want %s", id, totals.Balance, c.balances[i])
+				}
+				transactions, err := f.GetFinancialRecordTransactions(treasurer, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(transactions) != 1 || transactions[0].Record.TransferID != c.id || transactions[0].TxID != "tx-transfer" {
+					t.Errorf("%s records %+v, want one record of transfer %s", id, transactions, c.id)
+				}
+			}
+			saved, err := f.GetTransfer(treasurer, c.id)
+			if err != nil || saved.Amount != c.amount || saved.Operator != "t1" {
+				t.Errorf("saved transfer %+v, %v", saved, err)
+			}
+		})
+	}
+}
+
+func TestTransferReusedID(t *testing.T) {
+	f := new(FinancialContract)
+	stub := newStub(day(t, "2024-06-15"))
+	createFund(t, f, stub, "f1", "1000.00")
+	createFund(t, f, stub, "f2", "0")
+	treasurer := asRole(stub, "t1", common.RoleTreasurer)
+	if _, err := f.Transfer(treasurer, "t1", "f1", "f2", "100.00", "划拨"); err != nil {
+		t.Fatal(err)
+	}
+	//划转ID与链下分录共用，重复提交或反向划转都不能复用
+	for _, pair := range [][2]string{{"f1", "f2"}, {"f2", "f1"}} {
+		if _, err := f.Transfer(treasurer, "t1", pair[0], pair[1], "100.00", "划拨"); !errContains(err, "transfer t1 already existed") {
+			t.Errorf("%s to %s: got %v, want reused id error", pair[0], pair[1], err)
+		}
+	}
+	totals, err := f.GetFinancialTotals(treasurer, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.Balance != "900.00" {
+		t.Errorf("f1 balance %s after rejected transfers, want 900.00", totals.Balance)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"community-governance/chaincode/common"
 	"encoding/json"
 	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
@@ -19,7 +20,10 @@ type Notice struct {
 }
 
 // CreateNotice 上传公告信息
-func (n *NoticeContract) CreateNotice(ctx contractapi.TransactionContextInterface, id, hash, publisher string) error {
+func (n *NoticeContract) CreateNotice(ctx contractapi.TransactionContextInterface, id, hash string) error {
+	if err := common.RequireRole(ctx, "CreateNotice", common.RoleCommittee); err != nil {
+		return err
+	}
 	//检查id是否已经存在
 	state, err := ctx.GetStub().GetState(id)
 	if err != nil {
@@ -28,6 +32,11 @@ func (n *NoticeContract) CreateNotice(ctx contractapi.TransactionContextInterfac
 	if state != nil {
 		return fmt.Errorf("%s already exist", id)
 	}
+	//发布人取自交易提交者身份
+	publisher, err := common.GetActor(ctx)
+	if err != nil {
+		return err
+	}
 	nowStamp, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
 		return err
@@ -45,7 +54,10 @@ func (n *NoticeContract) CreateNotice(ctx contractapi.TransactionContextInterfac
 }
 
 // UpdateNotice  更新公告信息
-func (n *NoticeContract) UpdateNotice(ctx contractapi.TransactionContextInterface, id, hash, publisher string) error {
+func (n *NoticeContract) UpdateNotice(ctx contractapi.TransactionContextInterface, id, hash string) error {
+	if err := common.RequireRole(ctx, "UpdateNotice", common.RoleCommittee); err != nil {
+		return err
+	}
 	state, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return err
@@ -53,6 +65,10 @@ func (n *NoticeContract) UpdateNotice(ctx contractapi.TransactionContextInterfac
 	if state == nil {
 		return fmt.Errorf("%s not exist", id)
 	}
+	publisher, err := common.GetActor(ctx)
+	if err != nil {
+		return err
+	}
 	var notice Notice
 	if err := json.Unmarshal(state, &notice); err != nil {
 		return err
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// memStub 测试用的内存账本，只实现合约用到的读写与组合键分页查询，写入立即可见
+type memStub struct {
+	shim.ChaincodeStubInterface
+	state map[string][]byte
+	txID  string
+	now   time.Time
+}
+
+func (s *memStub) GetState(key string) ([]byte, error) { return s.state[key], nil }
+func (s *memStub) PutState(key string, value []byte) error {
+	s.state[key] = value
+	return nil
+}
+func (s *memStub) GetTxID() string { return s.txID }
+func (s *memStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.now), nil
+}
+func (s *memStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+// GetStateByPartialCompositeKeyWithPagination 与LevelDB一致，书签为下一页第一个键，没有下一页时为空
+func (s *memStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	var matched []string
+	for key := range s.state {
+		if strings.HasPrefix(key, prefix) && key >= bookmark {
+			matched = append(matched, key)
+		}
+	}
+	sort.Strings(matched)
+	iter := &memIterator{}
+	metadata := &peer.QueryResponseMetadata{}
+	for i, key := range matched {
+		if i == int(pageSize) {
+			metadata.Bookmark = key
+			break
+		}
+		iter.kvs = append(iter.kvs, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	metadata.FetchedRecordsCount = int32(len(iter.kvs))
+	return iter, metadata, nil
+}
+
+type memIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (it *memIterator) HasNext() bool { return len(it.kvs) > 0 }
+func (it *memIterator) Close() error  { return nil }
+func (it *memIterator) Next() (*queryresult.KV, error) {
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+type mockIdentity struct {
+	cn    string
+	attrs map[string]string
+}
+
+func (m *mockIdentity) GetID() (string, error)    { return m.cn, nil }
+func (m *mockIdentity) GetMSPID() (string, error) { return "Org1MSP", nil }
+func (m *mockIdentity) GetAttributeValue(name string) (string, bool, error) {
+	v, ok := m.attrs[name]
+	return v, ok, nil
+}
+func (m *mockIdentity) AssertAttributeValue(name, value string) error { return nil }
+func (m *mockIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return &x509.Certificate{Subject: pkix.Name{CommonName: m.cn}}, nil
+}
+
+// newStubCtx 创建使用内存账本的交易上下文，调用者为cn
+func newStubCtx(stub *memStub, cn string, attrs map[string]string) *contractapi.TransactionContext {
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(&mockIdentity{cn: cn, attrs: attrs})
+	return ctx
+}
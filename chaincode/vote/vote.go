@@ -1,6 +1,7 @@
 package main
 
 import (
+	"community-governance/chaincode/common"
 	"encoding/json"
 	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
@@ -15,6 +16,12 @@ const (
 	RuleTypeTopN      = "top_n"
 )
 
+// ballotIndex 每张选票以组合键(ballot, 投票ID, 交易时间, 交易ID)单独保存，按投票ID前缀分页读取
+const ballotIndex = "ballot"
+
+// ballotTimeLayout 组合键中的交易时间，定长UTC时间使键的字典序与投票顺序一致
+const ballotTimeLayout = "20060102150405.000000000"
+
 type VoteContract struct {
 	contractapi.Contract
 }
@@ -32,7 +39,17 @@ type VoteRecord struct {
 	VoteTime string `json:"vote_time"` //投票时间
 }
 
+// VoteRecordPage 分页返回的投票记录
+type VoteRecordPage struct {
+	Records    []VoteRecord `json:"records"`
+	NextCursor string       `json:"next_cursor"` //下一页游标，即账本返回的分页书签
+	HasNext    bool         `json:"has_next"`    //是否还有下一页
+}
+
 func (v *VoteContract) CreatVote(ctx contractapi.TransactionContextInterface, id, base, ruleType, ruleValue, options string) error {
+	if err := common.RequireRole(ctx, "CreatVote", common.RoleCommittee); err != nil {
+		return err
+	}
 	state, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return err
@@ -61,12 +78,17 @@ func (v *VoteContract) CreatVote(ctx contractapi.TransactionContextInterface, id
 }
 
 // VoteJoin 投票
-func (v *VoteContract) VoteJoin(ctx contractapi.TransactionContextInterface, id, voter, option string) (string, error) {
+func (v *VoteContract) VoteJoin(ctx contractapi.TransactionContextInterface, id, option string) (string, error) {
 	//获取投票信息
 	vote, err := v.GetVote(ctx, id)
 	if err != nil {
 		return "", err
 	}
+	//投票人取自交易提交者身份，防止代替他人投票
+	voter, err := common.RequireMember(ctx, "VoteJoin")
+	if err != nil {
+		return "", err
+	}
 	//判断投票项目是否已经结束
 	if vote.IsEnd {
 		return "", fmt.Errorf("vote is end")
@@ -111,6 +133,13 @@ func (v *VoteContract) VoteJoin(ctx contractapi.TransactionContextInterface, id,
 	if err != nil {
 		return "", fmt.Errorf("failed to put vote record state:%s", err.Error())
 	}
+	ballotKey, err := ctx.GetStub().CreateCompositeKey(ballotIndex, []string{id, notTime.AsTime().UTC().Format(ballotTimeLayout), ctx.GetStub().GetTxID()})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(ballotKey, data); err != nil {
+		return "", fmt.Errorf("failed to put ballot state:%s", err.Error())
+	}
 
 	return result, nil
 }
@@ -161,6 +190,37 @@ func (v *VoteContract) GetVoteRecordHistory(ctx contractapi.TransactionContextIn
 	return records, nil
 }
 
+// GetVoteRecordPage 按投票顺序分页获取投票记录，cursor为上一页返回的书签，为空时从第一张选票开始
+// 每张选票单独保存在组合键下，账本按书签直接定位，翻页代价与页数无关；只包含以组合键保存的选票
+func (v *VoteContract) GetVoteRecordPage(ctx contractapi.TransactionContextInterface, id, cursor string, limit int) (VoteRecordPage, error) {
+	if limit <= 0 {
+		return VoteRecordPage{}, fmt.Errorf("limit must be positive")
+	}
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(ballotIndex, []string{id}, int32(limit), cursor)
+	if err != nil {
+		return VoteRecordPage{}, err
+	}
+	defer resultsIterator.Close()
+	result := VoteRecordPage{Records: []VoteRecord{}}
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return VoteRecordPage{}, err
+		}
+		var record VoteRecord
+		if err := json.Unmarshal(queryResult.Value, &record); err != nil {
+			return VoteRecordPage{}, err
+		}
+		result.Records = append(result.Records, record)
+	}
+	//读完最后一张选票时书签为空，CouchDB恰好取满最后一页时仍返回书签，下一页为空页
+	if len(result.Records) == limit && metadata.GetBookmark() != "" {
+		result.NextCursor = metadata.GetBookmark()
+		result.HasNext = true
+	}
+	return result, nil
+}
+
 // EndVote 结束投票，获取投票结果
 func (v *VoteContract) EndVote(ctx contractapi.TransactionContextInterface, id string) ([]string, error) {
 	vote, err := v.GetVote(ctx, id)
@@ -227,6 +287,9 @@ func processTopN(options map[string]int, n int) []string {
 
 // CloseVote 异常管理投票
 func (v *VoteContract) CloseVote(ctx contractapi.TransactionContextInterface, id string) error {
+	if err := common.RequireRole(ctx, "CloseVote", common.RoleCommittee); err != nil {
+		return err
+	}
 	vote, err := v.GetVote(ctx, id)
 	if err != nil {
 		return err
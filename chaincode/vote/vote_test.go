@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"community-governance/chaincode/common"
+)
+
+func TestGetVoteRecordPage(t *testing.T) {
+	v := new(VoteContract)
+	stub := &memStub{state: map[string][]byte{}, txID: "tx-create", now: time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)}
+	committee := map[string]string{common.RoleAttribute: common.RoleCommittee}
+	for _, id := range []string{"v1", "v2"} {
+		if err := v.CreatVote(newStubCtx(stub, "c1", committee), id, "hash", RuleTypeMajority, "", "yes,no"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//交易ID与投票顺序相反，分页应按交易时间排序
+	for i := 0; i < 5; i++ {
+		stub.txID = fmt.Sprintf("tx%d", 9-i)
+		stub.now = stub.now.Add(time.Minute)
+		if _, err := v.VoteJoin(newStubCtx(stub, fmt.Sprintf("m%d", i), nil), "v1", "yes"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := v.VoteJoin(newStubCtx(stub, "m9", nil), "v2", "no"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		limit int
+		pages [][]string
+	}{
+		{2, [][]string{{"m0", "m1"}, {"m2", "m3"}, {"m4"}}},
+		{5, [][]string{{"m0", "m1", "m2", "m3", "m4"}}},
+		{10, [][]string{{"m0", "m1", "m2", "m3", "m4"}}},
+	}
+	for _, c := range cases {
+		ctx := newStubCtx(stub, "m0", nil)
+		cursor := ""
+		for i, want := range c.pages {
+			page, err := v.GetVoteRecordPage(ctx, "v1", cursor, c.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			voters := make([]string, len(page.Records))
+			for j, record := range page.Records {
+				voters[j] = record.Voter
+			}
+			if fmt.Sprint(voters) != fmt.Sprint(want) {
+				t.Errorf("limit %d page %d: got %v, want %v", c.limit, i+1, voters, want)
+			}
+			if last := i == len(c.pages)-1; page.HasNext == last || (page.NextCursor == "") != last {
+				t.Errorf("limit %d page %d: hasNext %v with cursor %q", c.limit, i+1, page.HasNext, page.NextCursor)
+			}
+			cursor = page.NextCursor
+		}
+	}
+	if _, err := v.GetVoteRecordPage(newStubCtx(stub, "m0", nil), "v1", "", 0); err == nil {
+		t.Error("want error for limit 0")
+	}
+}
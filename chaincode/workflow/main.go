@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"log"
+)
+
+func main() {
+	workflowContract, err := contractapi.NewChaincode(&WorkflowContract{})
+	if err != nil {
+		log.Panicf("error create workflow contract:%s", err.Error())
+	}
+	if err := workflowContract.Start(); err != nil {
+		log.Panicf("failed start chaincode:%s", err.Error())
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"community-governance/chaincode/common"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// WorkflowContract 审批流程合约，保存审批流程定义并按定义推进审批流程实例，记录每一次审批决定
+// 审批角色、阶段与批准人数均取自链上定义，不信任调用者传入的角色
+type WorkflowContract struct {
+	contractapi.Contract
+}
+
+// Step 审批步骤定义
+type Step struct {
+	Stage         int    `json:"stage"`          //阶段，从1开始
+	Role          string `json:"role"`           //审批角色
+	Required      int    `json:"required"`       //需要的批准人数
+	DeadlineHours int    `json:"deadline_hours"` //审批期限，0表示不限
+	EscalateRole  string `json:"escalate_role"`  //超期后可审批的角色，为空时不升级
+}
+
+// Definition 审批流程定义，步骤按阶段排序，同一阶段的步骤并行审批
+type Definition struct {
+	Kind       string `json:"kind"`        //审批对象类型
+	Steps      []Step `json:"steps"`       //审批步骤
+	UpdateDate string `json:"update_date"` //上次修改时间
+}
+
+// Task 审批流程实例的步骤，发起时复制流程定义的步骤
+type Task struct {
+	TaskID    string `json:"task_id"`   //步骤ID，实例ID-步骤序号
+	Step      Step   `json:"step"`      //步骤定义
+	Approvals int    `json:"approvals"` //已批准人数
+	Status    string `json:"status"`    //步骤状态
+}
+
+// Instance 审批流程实例
+type Instance struct {
+	InstanceID string `json:"instance_id"` //实例ID
+	Kind       string `json:"kind"`        //审批对象类型
+	TargetID   string `json:"target_id"`   //审批对象ID
+	Requester  string `json:"requester"`   //发起人ID
+	Stage      int    `json:"stage"`       //当前阶段
+	StageStart string `json:"stage_start"` //当前阶段开始时间
+	Status     string `json:"status"`      //实例状态
+	Tasks      []Task `json:"tasks"`       //审批步骤
+}
+
+// Decision 审批决定
+type Decision struct {
+	DecisionID  string `json:"decision_id"`  //决定ID
+	InstanceID  string `json:"instance_id"`  //审批流程实例ID
+	Kind        string `json:"kind"`         //审批对象类型
+	TargetID    string `json:"target_id"`    //审批对象ID
+	TaskID      string `json:"task_id"`      //审批步骤ID
+	Stage       int    `json:"stage"`        //审批阶段
+	Role        string `json:"role"`         //审批人以该角色审批
+	Decision    string `json:"decision"`     //approve或reject
+	CommentHash string `json:"comment_hash"` //审批意见的SHA-256，没有意见时为空
+	Approver    string `json:"approver"`     //审批人ID
+	DecideDate  string `json:"decide_date"`  //审批时间
+	TxID        string `json:"tx_id"`
+}
+
+const (
+	definitionIndex = "definition~kind"   //审批流程定义的组合键前缀
+	instanceIndex   = "instance~id"       //审批流程实例的组合键前缀
+	decisionIndex   = "decision~instance" //审批决定的组合键前缀
+	approverIndex   = "approver~stage"    //审批人已审批阶段的组合键前缀
+
+	decisionApprove = "approve"
+	decisionReject  = "reject"
+
+	statusPending  = "pending"
+	statusApproved = "approved"
+	statusRejected = "rejected"
+	statusWaiting  = "waiting"
+
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+// SetDefinition 设置对象类型的审批流程，steps为空数组时删除该类型的定义，仅管理员可调用
+// 进行中的实例已复制发起时的步骤，不受影响
+func (w *WorkflowContract) SetDefinition(ctx contractapi.TransactionContextInterface, kind, steps string) error {
+	if err := common.RequireRole(ctx, "SetDefinition"); err != nil {
+		return err
+	}
+	var parsed []Step
+	if err := json.Unmarshal([]byte(steps), &parsed); err != nil {
+		return fmt.Errorf("failed to unmarshal steps:%s", err.Error())
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(definitionIndex, []string{kind})
+	if err != nil {
+		return err
+	}
+	if len(parsed) == 0 {
+		return ctx.GetStub().DelState(key)
+	}
+	if err := checkSteps(parsed); err != nil {
+		return err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	return putJSON(ctx, key, Definition{Kind: kind, Steps: parsed, UpdateDate: now.Format(timeLayout)})
+}
+
+// checkSteps 校验审批步骤并按阶段稳定排序，阶段从1开始连续编号
+func checkSteps(steps []Step) error {
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Stage < steps[j].Stage })
+	for i, step := range steps {
+		if step.Role == "" || step.Required < 1 || step.DeadlineHours < 0 {
+			return fmt.Errorf("illegal step %d: role is required and required must be positive", i+1)
+		}
+		if step.Stage < 1 || (i == 0 && step.Stage != 1) || (i > 0 && step.Stage > steps[i-1].Stage+1) {
+			return fmt.Errorf("illegal stage %d: stages must start from 1 and be consecutive", step.Stage)
+		}
+	}
+	return nil
+}
+
+// GetDefinition 查询对象类型的审批流程
+func (w *WorkflowContract) GetDefinition(ctx contractapi.TransactionContextInterface, kind string) (Definition, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(definitionIndex, []string{kind})
+	if err != nil {
+		return Definition{}, err
+	}
+	var definition Definition
+	found, err := getJSON(ctx, key, &definition)
+	if err != nil {
+		return Definition{}, err
+	}
+	if !found {
+		return Definition{}, fmt.Errorf("no workflow is defined for %s", kind)
+	}
+	return definition, nil
+}
+
+// StartInstance 按链上的审批流程定义发起审批流程实例，发起人本人或可审批该流程的角色可以发起
+func (w *WorkflowContract) StartInstance(ctx contractapi.TransactionContextInterface, instanceID, kind, targetID, requester string) (Instance, error) {
+	definition, err := w.GetDefinition(ctx, kind)
+	if err != nil {
+		return Instance{}, err
+	}
+	roles := make([]string, 0, len(definition.Steps))
+	for _, step := range definition.Steps {
+		roles = append(roles, step.Role)
+	}
+	if err := common.RequireOwnerOrRole(ctx, "StartInstance", requester, roles...); err != nil {
+		return Instance{}, err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(instanceIndex, []string{instanceID})
+	if err != nil {
+		return Instance{}, err
+	}
+	if state, err := ctx.GetStub().GetState(key); err != nil || state != nil {
+		return Instance{}, fmt.Errorf("instance %s already exist", instanceID)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return Instance{}, err
+	}
+	instance := Instance{
+		InstanceID: instanceID,
+		Kind:       kind,
+		TargetID:   targetID,
+		Requester:  requester,
+		Stage:      1,
+		StageStart: now.Format(timeLayout),
+		Status:     statusPending,
+		Tasks:      make([]Task, len(definition.Steps)),
+	}
+	for i, step := range definition.Steps {
+		instance.Tasks[i] = Task{TaskID: instanceID + "-" + strconv.Itoa(i+1), Step: step, Status: statusWaiting}
+		if step.Stage == 1 {
+			instance.Tasks[i].Status = statusPending
+		}
+	}
+	return instance, putJSON(ctx, key, instance)
+}
+
+// RestoreInstance 恢复链上审批流程上线前发起、仍在审批中的实例，仅管理员可调用
+// 实例的步骤、进度与已审批的成员取自链下记录，decided为各审批人已决定的阶段
+func (w *WorkflowContract) RestoreInstance(ctx contractapi.TransactionContextInterface, instance, decided string) (Instance, error) {
+	if err := common.RequireRole(ctx, "RestoreInstance"); err != nil {
+		return Instance{}, err
+	}
+	var restored Instance
+	if err := json.Unmarshal([]byte(instance), &restored); err != nil {
+		return Instance{}, fmt.Errorf("failed to unmarshal instance:%s", err.Error())
+	}
+	var stages map[string][]int
+	if err := json.Unmarshal([]byte(decided), &stages); err != nil {
+		return Instance{}, fmt.Errorf("failed to unmarshal decided stages:%s", err.Error())
+	}
+	steps := make([]Step, len(restored.Tasks))
+	for i, task := range restored.Tasks {
+		steps[i] = task.Step
+	}
+	if err := checkSteps(steps); err != nil {
+		return Instance{}, err
+	}
+	if restored.Status != statusPending || restored.Stage < 1 {
+		return Instance{}, fmt.Errorf("instance %s is not under approval", restored.InstanceID)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(instanceIndex, []string{restored.InstanceID})
+	if err != nil {
+		return Instance{}, err
+	}
+	if state, err := ctx.GetStub().GetState(key); err != nil || state != nil {
+		return Instance{}, fmt.Errorf("instance %s already exist", restored.InstanceID)
+	}
+	for approver, list := range stages {
+		for _, stage := range list {
+			approverKey, err := ctx.GetStub().CreateCompositeKey(approverIndex, []string{restored.InstanceID, strconv.Itoa(stage), approver})
+			if err != nil {
+				return Instance{}, err
+			}
+			if err := ctx.GetStub().PutState(approverKey, []byte{0}); err != nil {
+				return Instance{}, err
+			}
+		}
+	}
+	return restored, putJSON(ctx, key, restored)
+}
+
+// GetInstance 查询审批流程实例
+func (w *WorkflowContract) GetInstance(ctx contractapi.TransactionContextInterface, instanceID string) (Instance, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(instanceIndex, []string{instanceID})
+	if err != nil {
+		return Instance{}, err
+	}
+	var instance Instance
+	found, err := getJSON(ctx, key, &instance)
+	if err != nil {
+		return Instance{}, err
+	}
+	if !found {
+		return Instance{}, fmt.Errorf("instance %s not exist", instanceID)
+	}
+	return instance, nil
+}
+
+// RecordDecision 记录审批决定并推进审批流程实例
+// 审批人需持有链上步骤定义的角色，步骤超期后升级角色也可审批；发起人不能审批，同一审批人在同一阶段只能决定一个步骤
+func (w *WorkflowContract) RecordDecision(ctx contractapi.TransactionContextInterface, decisionID, instanceID, taskID, decision, commentHash string) (Decision, error) {
+	if decision != decisionApprove && decision != decisionReject {
+		return Decision{}, fmt.Errorf("illegal decision:%s", decision)
+	}
+	instance, err := w.GetInstance(ctx, instanceID)
+	if err != nil {
+		return Decision{}, err
+	}
+	if instance.Status != statusPending {
+		return Decision{}, fmt.Errorf("instance %s is %s", instanceID, instance.Status)
+	}
+	index := -1
+	for i := range instance.Tasks {
+		if instance.Tasks[i].TaskID == taskID {
+			index = i
+		}
+	}
+	if index < 0 {
+		return Decision{}, fmt.Errorf("task %s not exist in instance %s", taskID, instanceID)
+	}
+	task := &instance.Tasks[index]
+	if task.Status != statusPending || task.Step.Stage != instance.Stage {
+		return Decision{}, fmt.Errorf("task %s is %s", taskID, task.Status)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return Decision{}, err
+	}
+	roles := []string{task.Step.Role}
+	if escalated, err := instance.escalated(task.Step, now); err != nil {
+		return Decision{}, err
+	} else if escalated {
+		roles = append(roles, task.Step.EscalateRole)
+	}
+	if err := common.RequireRole(ctx, "RecordDecision", roles...); err != nil {
+		return Decision{}, err
+	}
+	approver, err := common.GetActor(ctx)
+	if err != nil {
+		return Decision{}, err
+	}
+	if approver == instance.Requester {
+		return Decision{}, fmt.Errorf("requester %s cannot decide own workflow", approver)
+	}
+	role, _, err := common.GetRole(ctx)
+	if err != nil {
+		return Decision{}, err
+	}
+	approverKey, err := ctx.GetStub().CreateCompositeKey(approverIndex, []string{instanceID, strconv.Itoa(instance.Stage), approver})
+	if err != nil {
+		return Decision{}, err
+	}
+	state, err := ctx.GetStub().GetState(approverKey)
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to get state:%s", err.Error())
+	}
+	if state != nil {
+		return Decision{}, fmt.Errorf("%s already decided stage %d of instance %s", approver, instance.Stage, instanceID)
+	}
+	result := Decision{
+		DecisionID:  decisionID,
+		InstanceID:  instanceID,
+		Kind:        instance.Kind,
+		TargetID:    instance.TargetID,
+		TaskID:      taskID,
+		Stage:       instance.Stage,
+		Role:        role,
+		Decision:    decision,
+		CommentHash: commentHash,
+		Approver:    approver,
+		DecideDate:  now.Format(timeLayout),
+		TxID:        ctx.GetStub().GetTxID(),
+	}
+	instance.decide(index, decision, now)
+	key, err := ctx.GetStub().CreateCompositeKey(decisionIndex, []string{instanceID, decisionID})
+	if err != nil {
+		return Decision{}, err
+	}
+	if err := putJSON(ctx, key, result); err != nil {
+		return Decision{}, err
+	}
+	instanceKey, err := ctx.GetStub().CreateCompositeKey(instanceIndex, []string{instanceID})
+	if err != nil {
+		return Decision{}, err
+	}
+	if err := putJSON(ctx, instanceKey, instance); err != nil {
+		return Decision{}, err
+	}
+	return result, ctx.GetStub().PutState(approverKey, []byte(decisionID))
+}
+
+// escalated 步骤是否已超过期限并可由升级角色审批，期限从当前阶段开始时计算
+func (i *Instance) escalated(step Step, now time.Time) (bool, error) {
+	if step.EscalateRole == "" || step.DeadlineHours == 0 {
+		return false, nil
+	}
+	start, err := time.ParseInLocation(timeLayout, i.StageStart, time.UTC)
+	if err != nil {
+		return false, fmt.Errorf("illegal stage start:%s", i.StageStart)
+	}
+	return now.After(start.Add(time.Duration(step.DeadlineHours) * time.Hour)), nil
+}
+
+// decide 按审批决定推进实例：驳回时整个流程驳回；步骤批准人数达到要求后完成该步骤，
+// 阶段内步骤全部完成后进入下一阶段，没有下一阶段时流程通过
+func (i *Instance) decide(index int, decision string, now time.Time) {
+	task := &i.Tasks[index]
+	if decision == decisionReject {
+		task.Status = statusRejected
+		i.Status = statusRejected
+		return
+	}
+	task.Approvals++
+	if task.Approvals < task.Step.Required {
+		return
+	}
+	task.Status = statusApproved
+	for _, t := range i.Tasks {
+		if t.Step.Stage == i.Stage && t.Status == statusPending {
+			return
+		}
+	}
+	i.Stage++
+	i.StageStart = now.Format(timeLayout)
+	started := false
+	for k := range i.Tasks {
+		if i.Tasks[k].Step.Stage == i.Stage {
+			i.Tasks[k].Status = statusPending
+			started = true
+		}
+	}
+	if !started {
+		i.Status = statusApproved
+	}
+}
+
+// GetDecisions 查询审批流程实例的全部审批决定
+func (w *WorkflowContract) GetDecisions(ctx contractapi.TransactionContextInterface, instanceID string) ([]Decision, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(decisionIndex, []string{instanceID})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	result := make([]Decision, 0)
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		var decision Decision
+		if err := json.Unmarshal(kv.Value, &decision); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+		result = append(result, decision)
+	}
+	return result, nil
+}
+
+// txTime 获取交易时间，统一按UTC记录
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	nowTime, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get tx timestamp:%s", err.Error())
+	}
+	return nowTime.AsTime().UTC(), nil
+}
+
+func putJSON(ctx contractapi.TransactionContextInterface, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+func getJSON(ctx contractapi.TransactionContextInterface, key string, value interface{}) (bool, error) {
+	state, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to get state:%s", err.Error())
+	}
+	if state == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(state, value); err != nil {
+		return false, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return true, nil
+}
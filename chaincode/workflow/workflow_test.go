@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newInstance 按步骤创建审批中的实例，与StartInstance一致
+func newInstance(steps []Step, start time.Time) Instance {
+	instance := Instance{InstanceID: "i1", Stage: 1, StageStart: start.Format(timeLayout), Status: statusPending, Tasks: make([]Task, len(steps))}
+	for i, step := range steps {
+		instance.Tasks[i] = Task{TaskID: "i1-" + strconv.Itoa(i+1), Step: step, Status: statusWaiting}
+		if step.Stage == 1 {
+			instance.Tasks[i].Status = statusPending
+		}
+	}
+	return instance
+}
+
+func TestDecide(t *testing.T) {
+	//第1阶段为两个并行步骤，第2阶段为一个步骤
+	steps := []Step{
+		{Stage: 1, Role: "committee", Required: 2},
+		{Stage: 1, Role: "treasurer", Required: 1},
+		{Stage: 2, Role: "admin", Required: 1},
+	}
+	type decision struct {
+		task     int
+		decision string
+	}
+	cases := []struct {
+		name      string
+		decisions []decision
+		stage     int
+		status    string
+		tasks     []string
+	}{
+		{"one parallel step approved", []decision{{1, decisionApprove}},
+			1, statusPending, []string{statusPending, statusApproved, statusWaiting}},
+		{"required approvals not reached", []decision{{0, decisionApprove}, {1, decisionApprove}},
+			1, statusPending, []string{statusPending, statusApproved, statusWaiting}},
+		{"all parallel steps approved", []decision{{0, decisionApprove}, {1, decisionApprove}, {0, decisionApprove}},
+			2, statusPending, []string{statusApproved, statusApproved, statusPending}},
+		{"last stage approved", []decision{{1, decisionApprove}, {0, decisionApprove}, {0, decisionApprove}, {2, decisionApprove}},
+			3, statusApproved, []string{statusApproved, statusApproved, statusApproved}},
+		{"reject in parallel step", []decision{{0, decisionApprove}, {1, decisionReject}},
+			1, statusRejected, []string{statusPending, statusRejected, statusWaiting}},
+		{"reject in last stage", []decision{{1, decisionApprove}, {0, decisionApprove}, {0, decisionApprove}, {2, decisionReject}},
+			2, statusRejected, []string{statusApproved, statusApproved, statusRejected}},
+	}
+	start := time.Date(2024, 6, 5, 1, 0, 0, 0, time.UTC)
+	for _, c := range cases {
+		instance := newInstance(steps, start)
+		now := start
+		for _, d := range c.decisions {
+			now = now.Add(time.Hour)
+			instance.decide(d.task, d.decision, now)
+		}
+		if instance.Stage != c.stage || instance.Status != c.status {
+			t.Errorf("%s: stage %d status %s, want stage %d status %s", c.name, instance.Stage, instance.Status, c.stage, c.status)
+		}
+		for i, task := range instance.Tasks {
+			if task.Status != c.tasks[i] {
+				t.Errorf("%s: task %d is %s, want %s", c.name, i, task.Status, c.tasks[i])
+			}
+		}
+		if c.stage == 2 && c.status == statusPending && instance.StageStart != now.Format(timeLayout) {
+			t.Errorf("%s: stage start %s, want %s", c.name, instance.StageStart, now.Format(timeLayout))
+		}
+	}
+}
+
+func TestCheckSteps(t *testing.T) {
+	cases := []struct {
+		name   string
+		steps  []Step
+		err    string
+		stages []int
+	}{
+		{"single step", []Step{{Stage: 1, Role: "committee", Required: 1}}, "", []int{1}},
+		{"sorted by stage", []Step{{Stage: 2, Role: "admin", Required: 1}, {Stage: 1, Role: "committee", Required: 2}, {Stage: 1, Role: "treasurer", Required: 1}},
+			"", []int{1, 1, 2}},
+		{"missing role", []Step{{Stage: 1, Required: 1}}, "illegal step 1", nil},
+		{"zero required", []Step{{Stage: 1, Role: "committee"}}, "illegal step 1", nil},
+		{"negative deadline", []Step{{Stage: 1, Role: "committee", Required: 1, DeadlineHours: -1}}, "illegal step 1", nil},
+		{"first stage not 1", []Step{{Stage: 2, Role: "committee", Required: 1}}, "illegal stage 2", nil},
+		{"stage gap", []Step{{Stage: 1, Role: "committee", Required: 1}, {Stage: 3, Role: "admin", Required: 1}}, "illegal stage 3", nil},
+	}
+	for _, c := range cases {
+		err := checkSteps(c.steps)
+		if c.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: got %v, want error containing %q", c.name, err, c.err)
+			}
+			continue
+		}
+		for i, step := range c.steps {
+			if step.Stage != c.stages[i] {
+				t.Errorf("%s: step %d at stage %d, want %d", c.name, i, step.Stage, c.stages[i])
+			}
+		}
+	}
+}
+
+func TestEscalated(t *testing.T) {
+	start := time.Date(2024, 6, 5, 1, 0, 0, 0, time.UTC)
+	instance := Instance{StageStart: start.Format(timeLayout)}
+	cases := []struct {
+		name  string
+		step  Step
+		after time.Duration
+		want  bool
+	}{
+		{"no escalate role", Step{DeadlineHours: 1}, 2 * time.Hour, false},
+		{"no deadline", Step{EscalateRole: "admin"}, 2 * time.Hour, false},
+		{"before deadline", Step{DeadlineHours: 24, EscalateRole: "admin"}, 24 * time.Hour, false},
+		{"after deadline", Step{DeadlineHours: 24, EscalateRole: "admin"}, 24*time.Hour + time.Second, true},
+	}
+	for _, c := range cases {
+		got, err := instance.escalated(c.step, start.Add(c.after))
+		if err != nil || got != c.want {
+			t.Errorf("%s: got %v (%v), want %v", c.name, got, err, c.want)
+		}
+	}
+}
@@ -3,23 +3,42 @@ package models
 import (
 	"community-governance/db"
 	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 资产状态，与链上资产状态机一致，以链上为准
+const (
+	AssetStatusRegistered = "registered" // 已登记
+	AssetStatusActive     = "active"     // 使用中
+	AssetStatusRepair     = "repair"     // 维修中
+	AssetStatusDisposed   = "disposed"   // 已处置
 )
 
 type Asset struct {
-	AssetID      string `gorm:"primaryKey;type:varchar(64);not null" json:"asset_id"` // 资产ID
-	Name         string `gorm:"type:varchar(20);not null" json:"name"`                // 资产名称
-	Type         string `gorm:"type:varchar(20);not null" json:"type"`                // 资产类型
-	Description  string `gorm:"type:varchar(200)" json:"description"`                 // 资产说明
-	Status       string `gorm:"type:varchar(10);not null" json:"status"`              // 资产状态
-	Location     string `gorm:"type:varchar(100);not null" json:"location"`           // 资产位置
-	PurchaseDate string `gorm:"type:varchar(26);not null" json:"purchase_date"`       // 购买日期
-	Owner        string `gorm:"type:varchar(64);not null" json:"owner"`               // 资产拥有者
+	AssetID      string         `gorm:"primaryKey;type:varchar(64);not null" json:"asset_id"`  // 资产ID
+	Name         string         `gorm:"type:varchar(20);not null" json:"name"`                 // 资产名称
+	Type         string         `gorm:"type:varchar(20);not null" json:"type"`                 // 资产类型
+	Description  string         `gorm:"type:varchar(200)" json:"description"`                  // 资产说明
+	Status       string         `gorm:"type:varchar(10);not null" json:"status"`               // 资产状态
+	Location     string         `gorm:"type:varchar(100);not null" json:"location"`            // 资产位置
+	PurchaseDate string         `gorm:"type:varchar(26);not null" json:"purchase_date"`        // 购买日期
+	Owner        string         `gorm:"type:varchar(64);not null" json:"owner"`                // 资产拥有者
+	Cost         Money          `gorm:"type:decimal(20,2);not null;default:0" json:"cost"`     // 购置成本
+	Residual     Money          `gorm:"type:decimal(20,2);not null;default:0" json:"residual"` // 预计净残值
+	UsefulLife   int            `gorm:"not null;default:0" json:"useful_life"`                 // 预计使用年限
+	Method       string         `gorm:"type:varchar(20);not null;default:''" json:"method"`    // 折旧方法，为空时不计提折旧
+	FundID       string         `gorm:"type:varchar(64);not null;default:''" json:"fund_id"`   // 购置款项，折旧计入该款项
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`                                        // 删除时间
 }
 
 func (Asset) TableName() string {
 	return "asset"
 }
 
+// assetQueryFields 允许条件查询的列
+var assetQueryFields = NewQueryFields("asset_id", "name", "type", "description", "status", "location", "purchase_date", "owner", "method", "fund_id")
+
 func GetAssetByID(id string) (*Asset, error) {
 	var asset Asset
 	err := db.DB.First(&asset, "asset_id = ?", id).Error
@@ -46,23 +65,38 @@ func DeleteAsset(id string) error {
 	return nil
 }
 
-func GetAllAssetWithPagination(page, pageSize int) ([]Asset, error) {
-	var asset []Asset
-	err := db.DB.Offset((page - 1) * pageSize).Limit(pageSize).Find(&asset).Error
-	return asset, err
+func GetAllAssetWithPagination(page *Page) ([]Asset, error) {
+	var assets []Asset
+	err := paginate(db.DB.Model(&Asset{}), page, &assets)
+	return assets, err
 }
 
-func GetAllAssetWithConditions(conditions map[string]interface{}, page, pageSize int) ([]Asset, error) {
-	var asset []Asset
-
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
+func GetAllAssetWithConditions(query Query, page *Page) ([]Asset, error) {
+	var assets []Asset
 
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&asset).Error
-	return asset, err
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&Asset{}), assetQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	err = paginate(tx, page, &assets)
+	return assets, err
 }
 
 func CreateAsset(asset *Asset) error {
 	return db.DB.Create(asset).Error
 }
+
+// ChangeAssetStatus 在事务中更新资产状态并上链，链上拒绝状态转换时回滚
+func ChangeAssetStatus(id, status string, anchor func() error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var asset Asset
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&asset, "asset_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&asset).Update("status", status).Error; err != nil {
+			return err
+		}
+		return anchor()
+	})
+}
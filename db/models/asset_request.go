@@ -2,29 +2,79 @@ package models
 
 import (
 	"community-governance/db"
+	"errors"
 	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// 资产申请状态，审批由审批流程完成
+const (
+	AssetRequestStatusActive    = "active"    // 审批中
+	AssetRequestStatusPass      = "pass"      // 已通过，调拨申请为新保管人已接收
+	AssetRequestStatusReject    = "reject"    // 已驳回
+	AssetRequestStatusAwaiting  = "awaiting"  // 调拨已通过，待新保管人接收
+	AssetRequestStatusDeclined  = "declined"  // 新保管人拒绝接收
+	AssetRequestStatusLent      = "lent"      // 借用已通过，资产借出中
+	AssetRequestStatusReturned  = "returned"  // 借出的资产已归还
+	AssetRequestStatusCrediting = "crediting" // 处置已通过，处置收入待入账
+)
+
+// 资产申请类型
+const (
+	AssetRequestTypeAdd      = "add"      // 新增资产
+	AssetRequestTypeUpdate   = "update"   // 变更资产所有人，已由调拨取代，仅用于处理存量申请
+	AssetRequestTypeDisposal = "disposal" // 处置资产
+	AssetRequestTypeTransfer = "transfer" // 调拨给新的保管人
+	AssetRequestTypeLoan     = "loan"     // 临时借用
+)
+
+// 资产处置方式，与链上一致
+const (
+	DisposalSale    = "sale"    // 出售，必须有处置收入
+	DisposalScrap   = "scrap"   // 报废
+	DisposalDonate  = "donate"  // 捐赠，不能有处置收入
+	DisposalRecycle = "recycle" // 回收
+)
+
+var DisposalMethods = map[string]bool{DisposalSale: true, DisposalScrap: true, DisposalDonate: true, DisposalRecycle: true}
+
+// DisposalCategory 处置收入入账的科目
+const DisposalCategory = "资产处置收入"
+
+var ErrAssetRequestState = errors.New("illegal asset request state")
+
 type AssetRequest struct {
-	RequestID    string `gorm:"primaryKey;type:varchar(64);not null" json:"request_id"` // 资产申请ID
-	Name         string `gorm:"type:varchar(20);not null" json:"name"`                  // 资产名称
-	RequestDate  string `gorm:"type:varchar(26);not null" json:"request_date"`          // 申请时间
-	Description  string `gorm:"type:varchar(200)" json:"description"`                   // 申请说明
-	Status       string `gorm:"type:varchar(10);not null" json:"status"`                // 申请状态
-	PurchaseTime string `gorm:"type:varchar(26);not null" json:"purchase_time"`         // 购买日期
-	ProcessDate  string `gorm:"type:varchar(26);not null" json:"process_date"`          // 处理日期
-	Type         string `gorm:"type:varchar(20);not null" json:"type"`                  // 资产类型
-	Location     string `gorm:"type:varchar(100);not null" json:"location"`             // 资产位置
-	RequestValue string `gorm:"type:varchar(100);not null" json:"request_value"`        // 申请金额
-	Requester    string `gorm:"type:varchar(100);not null" json:"requester"`            // 申请人
-	RequestType  string `gorm:"type:varchar(100);not null" json:"request_type"`         // 申请类型
-	Asset        string `gorm:"type:varchar(64);not null" json:"asset"`                 // 资产ID
+	RequestID    string         `gorm:"primaryKey;type:varchar(64);not null" json:"request_id"`    // 资产申请ID
+	Name         string         `gorm:"type:varchar(20);not null" json:"name"`                     // 资产名称
+	RequestDate  string         `gorm:"type:varchar(26);not null" json:"request_date"`             // 申请时间
+	Description  string         `gorm:"type:varchar(200)" json:"description"`                      // 申请说明
+	Status       string         `gorm:"type:varchar(10);not null" json:"status"`                   // 申请状态
+	PurchaseTime string         `gorm:"type:varchar(26);not null" json:"purchase_time"`            // 购买日期
+	ProcessDate  string         `gorm:"type:varchar(26);not null" json:"process_date"`             // 处理日期
+	Type         string         `gorm:"type:varchar(20);not null" json:"type"`                     // 资产类型
+	Location     string         `gorm:"type:varchar(100);not null" json:"location"`                // 资产位置
+	RequestValue string         `gorm:"type:varchar(100);not null" json:"request_value"`           // 申请金额
+	Requester    string         `gorm:"type:varchar(100);not null" json:"requester"`               // 申请人
+	RequestType  string         `gorm:"type:varchar(100);not null" json:"request_type"`            // 申请类型
+	Asset        string         `gorm:"type:varchar(64);not null" json:"asset"`                    // 资产ID
+	Recipient    string         `gorm:"type:varchar(64);not null;default:''" json:"recipient"`     // 调拨的新保管人
+	DueDate      string         `gorm:"type:varchar(26);not null;default:''" json:"due_date"`      // 借用归还期限
+	Method       string         `gorm:"type:varchar(20);not null;default:''" json:"method"`        // 处置方式
+	Proceeds     Money          `gorm:"type:decimal(20,2);not null;default:0" json:"proceeds"`     // 处置收入
+	FundID       string         `gorm:"type:varchar(64);not null;default:''" json:"fund_id"`       // 处置收入入账的款项
+	EntryID      string         `gorm:"type:varchar(64);not null;default:''" json:"entry_id"`      // 处置收入的分录ID
+	CompleteDate string         `gorm:"type:varchar(26);not null;default:''" json:"complete_date"` // 调拨接收或借用归还的时间
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`                                            // 删除时间
 }
 
 func (AssetRequest) TableName() string {
 	return "asset_request"
 }
 
+// assetRequestQueryFields 允许条件查询的列
+var assetRequestQueryFields = NewQueryFields("request_id", "name", "request_date", "description", "status", "purchase_time", "process_date", "type", "location", "request_value", "requester", "request_type", "asset", "recipient", "due_date", "method", "fund_id", "complete_date")
+
 // CreateAssetRequest 创建资产申请
 func CreateAssetRequest(request AssetRequest) error {
 	return db.DB.Create(&request).Error
@@ -61,19 +111,110 @@ func DeleteAssetRequest(requestID string) error {
 	return nil
 }
 
-func GetAllAsseRequestWithConditions(page, pageSize int, conditions map[string]interface{}) ([]AssetRequest, error) {
-	var asset []AssetRequest
+func GetAllAsseRequestWithConditions(page *Page, query Query) ([]AssetRequest, error) {
+	var requests []AssetRequest
+
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&AssetRequest{}), assetRequestQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	err = paginate(tx, page, &requests)
+	return requests, err
+}
+
+func GetAllAsseRequestWithPagination(page *Page) ([]AssetRequest, error) {
+	var requests []AssetRequest
+	err := paginate(db.DB.Model(&AssetRequest{}), page, &requests)
+	return requests, err
+}
+
+// DisposeAssetByRequest 按处置申请处置资产，有处置收入时申请进入待入账，由财务负责人确认收款后入账
+// anchor在写库后执行，用于将处置上链
+func DisposeAssetByRequest(request *AssetRequest, processDate string, anchor func() error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Asset{}).Where("asset_id = ?", request.Asset).Update("status", AssetStatusDisposed).Error; err != nil {
+			return err
+		}
+		request.Status = AssetRequestStatusPass
+		if request.Proceeds > 0 {
+			request.Status = AssetRequestStatusCrediting
+		}
+		request.ProcessDate = processDate
+		if err := tx.Model(request).Select("status", "process_date").Updates(request).Error; err != nil {
+			return err
+		}
+		return anchor()
+	})
+}
+
+// CreditDisposalProceeds 处置收入入账，记一笔收入分录，分录ID由申请ID生成
+func CreditDisposalProceeds(requestID, recorder, entryTime string, anchor func(*AssetRequest, *JournalEntry) error) (*AssetRequest, error) {
+	entry := JournalEntry{
+		EntryID:   "disposal:" + requestID,
+		Type:      EntryTypeIncome,
+		Source:    "资产处置",
+		Category:  DisposalCategory,
+		Recorder:  recorder,
+		EntryTime: entryTime,
+	}
+	return completeAssetRequest(requestID, AssetRequestStatusCrediting, AssetRequestStatusPass, entryTime, func(request *AssetRequest) error {
+		return anchor(request, &entry)
+	}, func(tx *gorm.DB, request *AssetRequest) error {
+		entry.FundID = request.FundID
+		entry.Explain = request.Name + "处置收入"
+		if _, err := postFundRecord(tx, &entry, request.Proceeds); err != nil {
+			return err
+		}
+		request.EntryID = entry.EntryID
+		return tx.Model(request).Update("entry_id", entry.EntryID).Error
+	})
+}
 
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
+// completeAssetRequest 锁定申请并校验状态，执行complete并更新状态后执行anchor上链，用于调拨接收、借用归还与处置收入入账
+func completeAssetRequest(requestID, from, to, completeDate string, anchor func(*AssetRequest) error, complete func(*gorm.DB, *AssetRequest) error) (*AssetRequest, error) {
+	var request AssetRequest
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, "request_id = ?", requestID).Error; err != nil {
+			return err
+		}
+		if request.Status != from {
+			return fmt.Errorf("%w: request %s is %s", ErrAssetRequestState, requestID, request.Status)
+		}
+		if complete != nil {
+			if err := complete(tx, &request); err != nil {
+				return err
+			}
+		}
+		request.Status = to
+		request.CompleteDate = completeDate
+		if err := tx.Model(&request).Select("status", "complete_date").Updates(&request).Error; err != nil {
+			return err
+		}
+		return anchor(&request)
+	})
+	return &request, err
+}
+
+// FinishAssetTransfer 新保管人接收或拒绝调拨，接收时资产保管人变更为新保管人
+func FinishAssetTransfer(requestID string, accept bool, completeDate string, anchor func(*AssetRequest) error) (*AssetRequest, error) {
+	if !accept {
+		return completeAssetRequest(requestID, AssetRequestStatusAwaiting, AssetRequestStatusDeclined, completeDate, anchor, nil)
+	}
+	return completeAssetRequest(requestID, AssetRequestStatusAwaiting, AssetRequestStatusPass, completeDate, anchor, func(tx *gorm.DB, request *AssetRequest) error {
+		return tx.Model(&Asset{}).Where("asset_id = ?", request.Asset).Update("owner", request.Recipient).Error
+	})
+}
 
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&asset).Error
-	return asset, err
+// ReturnLentAsset 借出的资产归还入库
+func ReturnLentAsset(requestID, completeDate string, anchor func(*AssetRequest) error) (*AssetRequest, error) {
+	return completeAssetRequest(requestID, AssetRequestStatusLent, AssetRequestStatusReturned, completeDate, anchor, nil)
 }
 
-func GetAllAsseRequestWithPagination(page, pageSize int) ([]AssetRequest, error) {
-	var asset []AssetRequest
-	err := db.DB.Offset((page - 1) * pageSize).Limit(pageSize).Find(&asset).Error
-	return asset, err
+// GetOverdueLoans 分页查询超过归还期限仍未归还的借用申请
+func GetOverdueLoans(today string, page *Page) ([]AssetRequest, error) {
+	var requests []AssetRequest
+	tx := db.DB.Model(&AssetRequest{}).Where("status = ? AND due_date < ?", AssetRequestStatusLent, today)
+	err := paginate(tx.Order("due_date"), page, &requests)
+	return requests, err
 }
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"testing"
+)
+
+// createTestAssetRequest 登记资产f1购置的a1并创建处于status状态的申请r1
+func createTestAssetRequest(t *testing.T, requestType, status string, request AssetRequest) {
+	t.Helper()
+	asset := Asset{AssetID: "a1", Name: "投影仪", Type: "设备", Status: AssetStatusActive, Location: "活动室", PurchaseDate: "2024-01-01", Owner: "o1", FundID: "f1"}
+	if err := CreateAsset(&asset); err != nil {
+		t.Fatal(err)
+	}
+	request.RequestID = "r1"
+	request.Name = asset.Name
+	request.Asset = asset.AssetID
+	request.RequestType = requestType
+	request.Status = status
+	if err := CreateAssetRequest(request); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getTestAsset(t *testing.T) Asset {
+	t.Helper()
+	var asset Asset
+	if err := db.DB.First(&asset, "asset_id = ?", "a1").Error; err != nil {
+		t.Fatal(err)
+	}
+	return asset
+}
+
+func getTestAssetRequest(t *testing.T) AssetRequest {
+	t.Helper()
+	var request AssetRequest
+	if err := db.DB.First(&request, "request_id = ?", "r1").Error; err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func TestCompleteAssetRequest(t *testing.T) {
+	cases := []struct {
+		name        string
+		requestType string
+		status      string
+		complete    func(anchor func(*AssetRequest) error) (*AssetRequest, error)
+		anchorErr   error
+		err         error
+		want        string //申请完成后的状态
+		owner       string
+	}{
+		{"accept transfer", AssetRequestTypeTransfer, AssetRequestStatusAwaiting, func(anchor func(*AssetRequest) error) (*AssetRequest, error) {
+			return FinishAssetTransfer("r1", true, "2024-06-02", anchor)
+		}, nil, nil, AssetRequestStatusPass, "o2"},
+		{"decline transfer", AssetRequestTypeTransfer, AssetRequestStatusAwaiting, func(anchor func(*AssetRequest) error) (*AssetRequest, error) {
+			return FinishAssetTransfer("r1", false, "2024-06-02", anchor)
+		}, nil, nil, AssetRequestStatusDeclined, "o1"},
+		{"transfer not approved yet", AssetRequestTypeTransfer, AssetRequestStatusActive, func(anchor func(*AssetRequest) error) (*AssetRequest, error) {
+			return FinishAssetTransfer("r1", true, "2024-06-02", anchor)
+		}, nil, ErrAssetRequestState, AssetRequestStatusActive, "o1"},
+		{"accept rolled back when anchor fails", AssetRequestTypeTransfer, AssetRequestStatusAwaiting, func(anchor func(*AssetRequest) error) (*AssetRequest, error) {
+			return FinishAssetTransfer("r1", true, "2024-06-02", anchor)
+		}, errAnchor, errAnchor, AssetRequestStatusAwaiting, "o1"},
+		{"return loan", AssetRequestTypeLoan, AssetRequestStatusLent, func(anchor func(*AssetRequest) error) (*AssetRequest, error) {
+			return ReturnLentAsset("r1", "2024-06-02", anchor)
+		}, nil, nil, AssetRequestStatusReturned, "o1"},
+		{"loan returned twice", AssetRequestTypeLoan, AssetRequestStatusReturned, func(anchor func(*AssetRequest) error) (*AssetRequest, error) {
+			return ReturnLentAsset("r1", "2024-06-02", anchor)
+		}, nil, ErrAssetRequestState, AssetRequestStatusReturned, "o1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			createTestAssetRequest(t, c.requestType, c.status, AssetRequest{Recipient: "o2", DueDate: "2024-06-10"})
+			_, err := c.complete(func(*AssetRequest) error { return c.anchorErr })
+			if c.err == nil && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			request := getTestAssetRequest(t)
+			if request.Status != c.want {
+				t.Errorf("request is %s, want %s", request.Status, c.want)
+			}
+			if c.err == nil && request.CompleteDate != "2024-06-02" {
+				t.Errorf("complete date %q", request.CompleteDate)
+			}
+			if owner := getTestAsset(t).Owner; owner != c.owner {
+				t.Errorf("owner %s, want %s", owner, c.owner)
+			}
+		})
+	}
+}
+
+func TestDisposeAssetByRequest(t *testing.T) {
+	cases := []struct {
+		name      string
+		proceeds  Money
+		anchorErr error
+		status    string //处置后申请的状态
+		asset     string //处置后资产的状态
+	}{
+		{"scrap", 0, nil, AssetRequestStatusPass, AssetStatusDisposed},
+		{"sale waits for proceeds", 3000, nil, AssetRequestStatusCrediting, AssetStatusDisposed},
+		{"rolled back when anchor fails", 3000, errAnchor, AssetRequestStatusActive, AssetStatusActive},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			createTestAssetRequest(t, AssetRequestTypeDisposal, AssetRequestStatusActive, AssetRequest{Method: DisposalSale, Proceeds: c.proceeds, FundID: "f1"})
+			request := getTestAssetRequest(t)
+			err := DisposeAssetByRequest(&request, "2024-06-02", func() error { return c.anchorErr })
+			if !errors.Is(err, c.anchorErr) {
+				t.Fatalf("got %v, want %v", err, c.anchorErr)
+			}
+			if status := getTestAssetRequest(t).Status; status != c.status {
+				t.Errorf("request is %s, want %s", status, c.status)
+			}
+			if status := getTestAsset(t).Status; status != c.asset {
+				t.Errorf("asset is %s, want %s", status, c.asset)
+			}
+		})
+	}
+}
+
+func TestCreditDisposalProceeds(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    string
+		anchorErr error
+		err       error
+		want      string
+		balance   Money
+	}{
+		{"credit proceeds", AssetRequestStatusCrediting, nil, nil, AssetRequestStatusPass, 13000},
+		{"proceeds credited twice", AssetRequestStatusPass, nil, ErrAssetRequestState, AssetRequestStatusPass, 10000},
+		{"rolled back when anchor fails", AssetRequestStatusCrediting, errAnchor, errAnchor, AssetRequestStatusCrediting, 10000},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			openTestFund(t, "f1", 10000)
+			createTestAssetRequest(t, AssetRequestTypeDisposal, c.status, AssetRequest{Method: DisposalSale, Proceeds: 3000, FundID: "f1"})
+			_, err := CreditDisposalProceeds("r1", "u1", "2024-06-03 10:00:00", func(*AssetRequest, *JournalEntry) error { return c.anchorErr })
+			if c.err == nil && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			request := getTestAssetRequest(t)
+			if request.Status != c.want {
+				t.Errorf("request is %s, want %s", request.Status, c.want)
+			}
+			if balance := fundBalance(t, "f1"); balance != c.balance {
+				t.Errorf("balance %s, want %s", balance, c.balance)
+			}
+			if c.err == nil && request.EntryID != "disposal:r1" {
+				t.Errorf("entry id %q, want disposal:r1", request.EntryID)
+			}
+		})
+	}
+}
+
+func TestGetOverdueLoans(t *testing.T) {
+	useTestDB(t)
+	loans := []AssetRequest{
+		{RequestID: "r1", Status: AssetRequestStatusLent, DueDate: "2024-06-05"},
+		{RequestID: "r2", Status: AssetRequestStatusLent, DueDate: "2024-06-01"},
+		{RequestID: "r3", Status: AssetRequestStatusLent, DueDate: "2024-06-10"},
+		{RequestID: "r4", Status: AssetRequestStatusReturned, DueDate: "2024-06-01"},
+		{RequestID: "r5", Status: AssetRequestStatusActive, DueDate: "2024-06-01"},
+	}
+	for _, loan := range loans {
+		loan.RequestType = AssetRequestTypeLoan
+		if err := CreateAssetRequest(loan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page := &Page{Page: 1, PageSize: 10}
+	overdue, err := GetOverdueLoans("2024-06-06", page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(overdue) != 2 || overdue[0].RequestID != "r2" || overdue[1].RequestID != "r1" {
+		t.Errorf("got %d of %d overdue loans %+v, want r2 and r1", len(overdue), page.Total, overdue)
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"community-governance/db"
+	"gorm.io/gorm/clause"
+)
+
+// Attachment 附件表，文件按内容的SHA-256存储，同一文件只保存一条记录
+type Attachment struct {
+	Hash        string `gorm:"primaryKey;type:varchar(64);not null" json:"hash"` // 文件SHA-256
+	FileName    string `gorm:"type:varchar(200);not null" json:"file_name"`      // 首次上传时的文件名
+	ContentType string `gorm:"type:varchar(100);not null" json:"content_type"`   // 文件类型
+	Size        int64  `gorm:"not null" json:"size"`                             // 文件大小，单位字节
+	Uploader    string `gorm:"type:varchar(64);not null" json:"uploader"`        // 上传人
+	UploadTime  string `gorm:"type:varchar(26);not null" json:"upload_time"`     // 上传时间
+}
+
+func (Attachment) TableName() string {
+	return "attachment"
+}
+
+// CreateAttachment 保存附件信息，文件已存在时保留原记录
+func CreateAttachment(attachment *Attachment) error {
+	if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(attachment).Error; err != nil {
+		return err
+	}
+	return db.DB.First(attachment, "hash = ?", attachment.Hash).Error
+}
+
+// GetAttachmentByHash 查询附件信息
+func GetAttachmentByHash(hash string) (*Attachment, error) {
+	var attachment Attachment
+	err := db.DB.First(&attachment, "hash = ?", hash).Error
+	return &attachment, err
+}
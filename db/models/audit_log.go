@@ -0,0 +1,118 @@
+package models
+
+import (
+	"community-governance/db"
+	"crypto/sha256"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// AuditLog 管理操作审计日志，每条记录通过PrevHash与上一条记录组成hash链
+type AuditLog struct {
+	LogID      uint64 `gorm:"primaryKey;autoIncrement" json:"log_id"`                 // 日志ID
+	Actor      string `gorm:"type:varchar(64);not null;index" json:"actor"`           // 操作人ID
+	Action     string `gorm:"type:varchar(64);not null;index" json:"action"`          // 操作类型
+	Target     string `gorm:"type:varchar(32);not null" json:"target"`                // 操作对象类型
+	TargetID   string `gorm:"type:varchar(64);index" json:"target_id"`                // 操作对象ID
+	Before     string `gorm:"type:text" json:"before"`                                // 操作前数据
+	After      string `gorm:"type:text" json:"after"`                                 // 操作后数据
+	Diff       string `gorm:"type:text" json:"diff"`                                  // 变更字段
+	IP         string `gorm:"type:varchar(64)" json:"ip"`                             // 操作来源IP
+	StatusCode int    `gorm:"not null" json:"status_code"`                            // 接口返回状态码
+	CreateTime string `gorm:"type:varchar(26);not null;index" json:"create_time"`     // 操作时间
+	PrevHash   string `gorm:"type:varchar(64);not null;uniqueIndex" json:"prev_hash"` // 上一条记录的hash
+	Hash       string `gorm:"type:varchar(64);not null;uniqueIndex" json:"hash"`      // 本条记录的hash
+}
+
+func (AuditLog) TableName() string {
+	return "audit_log"
+}
+
+// ComputeAuditHash 计算审计日志hash，覆盖除LogID与Hash之外的全部字段
+func ComputeAuditHash(log *AuditLog) string {
+	content := strings.Join([]string{
+		log.PrevHash, log.Actor, log.Action, log.Target, log.TargetID,
+		log.Before, log.After, log.Diff, log.IP, fmt.Sprint(log.StatusCode), log.CreateTime,
+	}, "|")
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
+
+// AppendAuditLog 追加审计日志，在事务中锁定链头保证hash链不分叉
+func AppendAuditLog(log *AuditLog) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var last AuditLog
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("log_id desc").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+		log.PrevHash = last.Hash
+		log.Hash = ComputeAuditHash(log)
+		return tx.Create(log).Error
+	})
+}
+
+// GetLastAuditLog 获取链头日志
+func GetLastAuditLog() (*AuditLog, error) {
+	var log AuditLog
+	err := db.DB.Order("log_id desc").First(&log).Error
+	return &log, err
+}
+
+// GetAuditLogsAfter 按顺序获取指定ID之后的日志，用于校验hash链
+func GetAuditLogsAfter(logID uint64, limit int) ([]AuditLog, error) {
+	var logs []AuditLog
+	err := db.DB.Where("log_id > ?", logID).Order("log_id asc").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+func GetAuditLogByID(logID uint64) (*AuditLog, error) {
+	var log AuditLog
+	err := db.DB.First(&log, "log_id = ?", logID).Error
+	return &log, err
+}
+
+// GetAuditLogsWithConditions 按条件分页查询审计日志
+func GetAuditLogsWithConditions(conditions map[string]interface{}, startTime, endTime string, page *Page) ([]AuditLog, error) {
+	var logs []AuditLog
+	query := db.DB.Model(&AuditLog{}).Where(conditions)
+	if startTime != "" {
+		query = query.Where("create_time >= ?", startTime)
+	}
+	if endTime != "" {
+		query = query.Where("create_time <= ?", endTime)
+	}
+	err := paginate(query.Order("log_id desc"), page, &logs)
+	return logs, err
+}
+
+// AuditAnchor 审计链头上链记录
+type AuditAnchor struct {
+	AnchorID   uint64 `gorm:"primaryKey;autoIncrement" json:"anchor_id"`    // 锚定ID
+	LastLogID  uint64 `gorm:"not null" json:"last_log_id"`                  // 锚定时的链头日志ID
+	HeadHash   string `gorm:"type:varchar(64);not null" json:"head_hash"`   // 锚定时的链头hash
+	TxID       string `gorm:"type:varchar(64);not null" json:"tx_id"`       // 上链交易ID
+	AnchorTime string `gorm:"type:varchar(26);not null" json:"anchor_time"` // 锚定时间
+}
+
+func (AuditAnchor) TableName() string {
+	return "audit_anchor"
+}
+
+func CreateAuditAnchor(anchor *AuditAnchor) error {
+	return db.DB.Create(anchor).Error
+}
+
+// GetLastAuditAnchor 获取最近一次锚定记录，不存在时返回空记录
+func GetLastAuditAnchor() (*AuditAnchor, error) {
+	var anchor AuditAnchor
+	err := db.DB.Order("anchor_id desc").Limit(1).Find(&anchor).Error
+	return &anchor, err
+}
+
+func GetAuditAnchorsWithPagination(page *Page) ([]AuditAnchor, error) {
+	var anchors []AuditAnchor
+	err := paginate(db.DB.Model(&AuditAnchor{}).Order("anchor_id desc"), page, &anchors)
+	return anchors, err
+}
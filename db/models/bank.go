@@ -0,0 +1,257 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 银行流水的对账状态
+const (
+	BankLineUnmatched = "unmatched" // 未找到对应记录
+	BankLineSuggested = "suggested" // 已找到建议匹配的记录，待确认
+	BankLineMatched   = "matched"   // 已确认与记录匹配
+	BankLineRecorded  = "recorded"  // 已据此创建收支记录
+	BankLineIgnored   = "ignored"   // 已忽略，如账户间划转
+	BankLineDuplicate = "duplicate" // 与已导入的流水重复，不参与对账
+)
+
+var (
+	ErrBankLineResolved = errors.New("bank line already resolved")
+	ErrEntryReconciled  = errors.New("entry already reconciled")
+)
+
+// BankImport 银行流水导入批次
+type BankImport struct {
+	ImportID   string     `gorm:"primaryKey;type:varchar(64);not null" json:"import_id"` // 导入批次ID
+	FundID     string     `gorm:"type:varchar(64);not null;index" json:"fund_id"`        // 所属款项
+	FileName   string     `gorm:"type:varchar(200);not null" json:"file_name"`           // 导入的文件名
+	Format     string     `gorm:"type:varchar(10);not null" json:"format"`               // 文件格式，csv或camt053
+	StartDate  string     `gorm:"type:varchar(10)" json:"start_date"`                    // 流水最早日期
+	EndDate    string     `gorm:"type:varchar(10)" json:"end_date"`                      // 流水最晚日期
+	Importer   string     `gorm:"type:varchar(64);not null" json:"importer"`             // 导入人
+	ImportTime string     `gorm:"type:varchar(26);not null" json:"import_time"`          // 导入时间
+	Lines      []BankLine `gorm:"foreignKey:ImportID" json:"lines"`                      // 流水明细
+}
+
+func (BankImport) TableName() string {
+	return "bank_import"
+}
+
+// BankLine 银行流水明细，收入金额为正，支出金额为负
+type BankLine struct {
+	LineID       uint64 `gorm:"primaryKey;autoIncrement" json:"line_id"`          // 流水明细ID
+	ImportID     string `gorm:"type:varchar(64);not null;index" json:"import_id"` // 导入批次ID
+	FundID       string `gorm:"type:varchar(64);not null;index" json:"fund_id"`   // 所属款项
+	LineDate     string `gorm:"type:varchar(10);not null" json:"line_date"`       // 记账日期
+	Amount       Money  `gorm:"type:decimal(20,2);not null" json:"amount"`        // 金额
+	Reference    string `gorm:"type:varchar(100)" json:"reference"`               // 银行流水号
+	Counterparty string `gorm:"type:varchar(100)" json:"counterparty"`            // 对方户名
+	Description  string `gorm:"type:varchar(200)" json:"description"`             // 摘要
+	Status       string `gorm:"type:varchar(10);not null;index" json:"status"`    // 对账状态
+	EntryID      string `gorm:"type:varchar(64);index" json:"entry_id"`           // 匹配或创建的分录ID
+	Score        int    `json:"score"`                                            // 建议匹配的得分
+}
+
+func (BankLine) TableName() string {
+	return "bank_line"
+}
+
+// ReconcileEntry 参与对账的分录，金额为资金账户的变动，收入为正、支出为负
+type ReconcileEntry struct {
+	EntryID   string `json:"entry_id"`
+	Type      string `json:"type"`
+	EntryTime string `json:"entry_time"`
+	Amount    Money  `json:"amount"`
+	Reference string `json:"reference"`
+	Source    string `json:"source"`
+	Explain   string `json:"explain"`
+}
+
+// CreateBankImport 保存导入批次及流水明细
+func CreateBankImport(bankImport *BankImport) error {
+	return db.DB.Create(bankImport).Error
+}
+
+// bankLineKey 判断流水是否重复的键，款项、记账日期、金额与银行流水号均相同视为同一笔流水
+type bankLineKey struct {
+	FundID    string
+	LineDate  string
+	Amount    Money
+	Reference string
+}
+
+func (l BankLine) key() bankLineKey {
+	return bankLineKey{FundID: l.FundID, LineDate: l.LineDate, Amount: l.Amount, Reference: l.Reference}
+}
+
+// FlagDuplicateBankLines 将已导入过的流水标记为重复，返回重复的条数，lines须属于同一款项
+// 导入的对账单日期范围重叠时，重复的流水不再参与匹配与入账
+func FlagDuplicateBankLines(fundID string, lines []BankLine) (int, error) {
+	if len(lines) == 0 {
+		return 0, nil
+	}
+	start, end := lines[0].LineDate, lines[0].LineDate
+	for _, line := range lines {
+		start, end = min(start, line.LineDate), max(end, line.LineDate)
+	}
+	var existing []BankLine
+	err := db.DB.Where("fund_id = ? AND line_date BETWEEN ? AND ? AND status <> ?", fundID, start, end, BankLineDuplicate).
+		Find(&existing).Error
+	if err != nil {
+		return 0, err
+	}
+	return flagDuplicates(existing, lines), nil
+}
+
+// flagDuplicates 将lines中与existing重复的流水标记为重复，返回重复的条数
+func flagDuplicates(existing, lines []BankLine) int {
+	seen := make(map[bankLineKey]bool, len(existing))
+	for _, line := range existing {
+		seen[line.key()] = true
+	}
+	count := 0
+	for i := range lines {
+		if seen[lines[i].key()] {
+			lines[i].Status = BankLineDuplicate
+			lines[i].EntryID = ""
+			lines[i].Score = 0
+			count++
+		}
+	}
+	return count
+}
+
+// GetBankImportByID 查询导入批次及流水明细
+func GetBankImportByID(id string) (*BankImport, error) {
+	var bankImport BankImport
+	err := db.DB.Preload("Lines", func(tx *gorm.DB) *gorm.DB { return tx.Order("line_id") }).
+		First(&bankImport, "import_id = ?", id).Error
+	return &bankImport, err
+}
+
+// GetBankImportsWithPagination 分页查询导入批次，fundID为空时不筛选
+func GetBankImportsWithPagination(fundID string, page *Page) ([]BankImport, error) {
+	var imports []BankImport
+	tx := db.DB.Model(&BankImport{})
+	if fundID != "" {
+		tx = tx.Where("fund_id = ?", fundID)
+	}
+	err := paginate(tx.Order("import_time desc"), page, &imports)
+	return imports, err
+}
+
+// GetBankLineByID 查询流水明细
+func GetBankLineByID(id string) (*BankLine, error) {
+	var line BankLine
+	err := db.DB.First(&line, "line_id = ?", id).Error
+	return &line, err
+}
+
+// reconcileEntries 查询款项在日期范围内尚未与银行流水确认匹配的收支分录，start、end为空时不限制
+func reconcileEntries(tx *gorm.DB, fundID, start, end string) *gorm.DB {
+	query := tx.Table("journal_entry AS e").
+		Joins("JOIN posting AS p ON p.entry_id = e.entry_id AND p.account_id = ?", FundAccountID(fundID, AccountTypeAsset)).
+		Where("e.fund_id = ? AND e.type IN ?", fundID, []string{EntryTypeIncome, EntryTypeExpense}).
+		Where("NOT EXISTS (SELECT 1 FROM bank_line AS b WHERE b.entry_id = e.entry_id AND b.status IN ?)",
+			[]string{BankLineMatched, BankLineRecorded}).
+		Select("e.entry_id, e.type, e.entry_time, p.amount, e.reference, e.source, e.explain")
+	if start != "" {
+		query = query.Where("e.entry_time >= ?", start)
+	}
+	if end != "" {
+		//end为日期，包含当天
+		query = query.Where("e.entry_time < ?", end+"~")
+	}
+	return query
+}
+
+// GetReconcileCandidates 查询可与银行流水匹配的分录
+func GetReconcileCandidates(fundID, start, end string) ([]ReconcileEntry, error) {
+	var entries []ReconcileEntry
+	err := reconcileEntries(db.DB, fundID, start, end).Order("e.entry_time").Scan(&entries).Error
+	return entries, err
+}
+
+// GetUnreconciledEntries 分页查询没有银行流水对应的分录
+func GetUnreconciledEntries(fundID, start, end string, page *Page) ([]ReconcileEntry, error) {
+	var entries []ReconcileEntry
+	err := paginate(reconcileEntries(db.DB, fundID, start, end).Order("e.entry_time"), page, &entries)
+	return entries, err
+}
+
+// resolveBankLine 锁定待处理的流水明细后执行update
+func resolveBankLine(id string, update func(tx *gorm.DB, line *BankLine) error) (*BankLine, error) {
+	var line BankLine
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&line, "line_id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		if line.Status != BankLineUnmatched && line.Status != BankLineSuggested {
+			return fmt.Errorf("%w: line %s is %s", ErrBankLineResolved, id, line.Status)
+		}
+		if err := update(tx, &line); err != nil {
+			return err
+		}
+		return tx.Save(&line).Error
+	})
+	return &line, err
+}
+
+// ConfirmBankLine 确认流水与分录匹配，entryID为空时确认建议的匹配
+// 分录须属于同一款项、金额一致，且未与其他流水确认匹配
+func ConfirmBankLine(id, entryID string) (*BankLine, error) {
+	return resolveBankLine(id, func(tx *gorm.DB, line *BankLine) error {
+		if entryID == "" {
+			entryID = line.EntryID
+		}
+		if entryID == "" {
+			return fmt.Errorf("line %s has no suggested entry", id)
+		}
+		var entries []ReconcileEntry
+		if err := reconcileEntries(tx, line.FundID, "", "").Where("e.entry_id = ?", entryID).Scan(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("%w: %s", ErrEntryReconciled, entryID)
+		}
+		if entries[0].Amount != line.Amount {
+			return fmt.Errorf("amount of entry %s is %s, bank line is %s", entryID, entries[0].Amount, line.Amount)
+		}
+		line.EntryID = entryID
+		line.Status = BankLineMatched
+		return nil
+	})
+}
+
+// IgnoreBankLine 忽略流水，不参与对账
+func IgnoreBankLine(id string) (*BankLine, error) {
+	return resolveBankLine(id, func(tx *gorm.DB, line *BankLine) error {
+		line.EntryID = ""
+		line.Status = BankLineIgnored
+		return nil
+	})
+}
+
+// RecordBankLine 据流水创建收支记录，build根据流水生成分录，分录与流水状态在同一事务中写入
+// anchor在分录写入后、事务提交前执行，用于同步上链，上链失败时分录与流水状态一并回滚
+func RecordBankLine(id string, build func(line *BankLine) *JournalEntry, anchor func(entry *JournalEntry, amount Money) error) (*BankLine, *BudgetWarning, error) {
+	var warning *BudgetWarning
+	line, err := resolveBankLine(id, func(tx *gorm.DB, line *BankLine) error {
+		entry := build(line)
+		var err error
+		if warning, err = postFundRecord(tx, entry, line.Amount.Abs()); err != nil {
+			return err
+		}
+		if err := anchor(entry, line.Amount.Abs()); err != nil {
+			return err
+		}
+		line.EntryID = entry.EntryID
+		line.Status = BankLineRecorded
+		return nil
+	})
+	return line, warning, err
+}
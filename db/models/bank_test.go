@@ -0,0 +1,61 @@
+package models
+
+import "testing"
+
+func TestFlagDuplicateBankLines(t *testing.T) {
+	useTestDB(t)
+	existing := BankImport{ImportID: "i1", FundID: "f1", FileName: "6月.csv", Format: "csv", Importer: "u1", ImportTime: "2024-07-01 10:00:00",
+		Lines: []BankLine{
+			{FundID: "f1", LineDate: "2024-06-10", Amount: 5000, Reference: "B001", Status: BankLineMatched, EntryID: "e1"},
+			{FundID: "f1", LineDate: "2024-06-20", Amount: -3000, Reference: "B002", Status: BankLineUnmatched},
+			{FundID: "f1", LineDate: "2024-06-25", Amount: 800, Reference: "B003", Status: BankLineDuplicate},
+			{FundID: "f2", LineDate: "2024-06-15", Amount: 1000, Reference: "B004", Status: BankLineUnmatched},
+		}}
+	if err := CreateBankImport(&existing); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		line      BankLine
+		duplicate bool
+	}{
+		{"same line", BankLine{LineDate: "2024-06-10", Amount: 5000, Reference: "B001"}, true},
+		{"expense line", BankLine{LineDate: "2024-06-20", Amount: -3000, Reference: "B002"}, true},
+		{"different reference", BankLine{LineDate: "2024-06-10", Amount: 5000, Reference: "B009"}, false},
+		{"different amount", BankLine{LineDate: "2024-06-10", Amount: 5001, Reference: "B001"}, false},
+		{"different date", BankLine{LineDate: "2024-06-11", Amount: 5000, Reference: "B001"}, false},
+		{"line flagged duplicate before", BankLine{LineDate: "2024-06-25", Amount: 800, Reference: "B003"}, false},
+		{"line of another fund", BankLine{LineDate: "2024-06-15", Amount: 1000, Reference: "B004"}, false},
+	}
+	lines := make([]BankLine, len(cases))
+	want := 0
+	for i, c := range cases {
+		lines[i] = c.line
+		lines[i].FundID = "f1"
+		lines[i].Status = BankLineSuggested
+		lines[i].EntryID = "e9"
+		lines[i].Score = 80
+		if c.duplicate {
+			want++
+		}
+	}
+	count, err := FlagDuplicateBankLines("f1", lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != want {
+		t.Errorf("%d duplicates, want %d", count, want)
+	}
+	for i, c := range cases {
+		line := lines[i]
+		if duplicate := line.Status == BankLineDuplicate; duplicate != c.duplicate {
+			t.Errorf("%s: status %s, want duplicate %v", c.name, line.Status, c.duplicate)
+		}
+		if c.duplicate && (line.EntryID != "" || line.Score != 0) {
+			t.Errorf("%s: duplicate keeps suggestion %s with score %d", c.name, line.EntryID, line.Score)
+		}
+	}
+	if count, err := FlagDuplicateBankLines("f1", nil); err != nil || count != 0 {
+		t.Errorf("no lines: got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,510 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"sort"
+	"time"
+)
+
+// 收费方式
+const (
+	FeeMethodFlat = "flat" // 每户固定金额
+	FeeMethodArea = "area" // 按面积计费，费率为每平方米金额
+)
+
+// 计费周期
+const (
+	FeeCycleMonthly   = "monthly"   // 月度，账期格式2006-01
+	FeeCycleQuarterly = "quarterly" // 季度，账期格式2006-Q1
+	FeeCycleYearly    = "yearly"    // 年度，账期格式2006
+)
+
+// FeeScheduleStatusActive 使用中的收费标准，归档后不能再生成账单
+const FeeScheduleStatusActive = "active"
+
+// 账单状态
+const (
+	InvoiceStatusUnpaid  = "unpaid"  // 未缴
+	InvoiceStatusPartial = "partial" // 部分缴纳
+	InvoiceStatusPaid    = "paid"    // 已缴清
+	InvoiceStatusVoid    = "void"    // 已作废
+)
+
+var (
+	ErrInvalidFee     = errors.New("invalid fee schedule")
+	ErrInvalidPeriod  = errors.New("invalid billing period")
+	ErrInvoiceClosed  = errors.New("invoice is closed")
+	ErrOverpayment    = errors.New("payment exceeds outstanding amount")
+	ErrInvoiceHasPaid = errors.New("invoice has payments")
+)
+
+// Household 房屋信息，按面积计费时使用，户号与成员表的household_id一致
+type Household struct {
+	HouseholdID string  `gorm:"primaryKey;type:varchar(64);not null" json:"household_id"` // 户号
+	Address     string  `gorm:"type:varchar(100)" json:"address"`                         // 房屋地址
+	Area        float64 `gorm:"type:decimal(10,2);not null" json:"area"`                  // 计费面积，单位平方米
+	UpdateTime  string  `gorm:"type:varchar(26)" json:"update_time"`                      // 上次修改时间
+}
+
+func (Household) TableName() string {
+	return "household"
+}
+
+// FeeSchedule 收费标准，按周期为每户生成账单，缴费计入指定款项
+type FeeSchedule struct {
+	ScheduleID string         `gorm:"primaryKey;type:varchar(64);not null" json:"schedule_id"` // 收费标准ID
+	Name       string         `gorm:"type:varchar(100);not null" json:"name"`                  // 名称，如物业费
+	FundID     string         `gorm:"type:varchar(64);not null;index" json:"fund_id"`          // 缴费计入的款项
+	Method     string         `gorm:"type:varchar(10);not null" json:"method"`                 // 收费方式
+	Rate       Money          `gorm:"type:decimal(20,2);not null" json:"rate"`                 // 每户金额或每平方米金额
+	Cycle      string         `gorm:"type:varchar(10);not null" json:"cycle"`                  // 计费周期
+	DueDays    int            `gorm:"not null" json:"due_days"`                                // 出账后多少天到期
+	Status     string         `gorm:"type:varchar(10);not null" json:"status"`                 // 状态
+	Creator    string         `gorm:"type:varchar(64);not null" json:"creator"`                // 创建人
+	CreateTime string         `gorm:"type:varchar(26);not null" json:"create_time"`            // 创建时间
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (FeeSchedule) TableName() string {
+	return "fee_schedule"
+}
+
+// Invoice 账单，同一收费标准同一账期每户一张
+type Invoice struct {
+	InvoiceID   string `gorm:"primaryKey;type:varchar(64);not null" json:"invoice_id"`                             // 账单ID
+	ScheduleID  string `gorm:"type:varchar(64);not null;uniqueIndex:idx_invoice_period" json:"schedule_id"`        // 收费标准ID
+	HouseholdID string `gorm:"type:varchar(64);not null;uniqueIndex:idx_invoice_period;index" json:"household_id"` // 户号
+	Period      string `gorm:"type:varchar(10);not null;uniqueIndex:idx_invoice_period" json:"period"`             // 账期
+	FundID      string `gorm:"type:varchar(64);not null;index" json:"fund_id"`                                     // 缴费计入的款项
+	Name        string `gorm:"type:varchar(100);not null" json:"name"`                                             // 收费名称
+	Amount      Money  `gorm:"type:decimal(20,2);not null" json:"amount"`                                          // 应缴金额
+	Paid        Money  `gorm:"type:decimal(20,2);not null" json:"paid"`                                            // 已缴金额
+	Status      string `gorm:"type:varchar(10);not null;index" json:"status"`                                      // 账单状态
+	IssueDate   string `gorm:"type:varchar(10);not null" json:"issue_date"`                                        // 出账日期
+	DueDate     string `gorm:"type:varchar(10);not null;index" json:"due_date"`                                    // 到期日期
+	CreateTime  string `gorm:"type:varchar(26);not null" json:"create_time"`                                       // 创建时间
+}
+
+func (Invoice) TableName() string {
+	return "invoice"
+}
+
+// Outstanding 未缴金额
+func (i Invoice) Outstanding() Money {
+	if i.Status == InvoiceStatusVoid {
+		return 0
+	}
+	return i.Amount - i.Paid
+}
+
+// Payment 缴费记录，每笔缴费对应款项中的一笔收入分录
+type Payment struct {
+	PaymentID   string `gorm:"primaryKey;type:varchar(64);not null" json:"payment_id"` // 缴费ID，与分录ID一致
+	InvoiceID   string `gorm:"type:varchar(64);not null;index" json:"invoice_id"`      // 账单ID
+	HouseholdID string `gorm:"type:varchar(64);not null;index" json:"household_id"`    // 户号
+	FundID      string `gorm:"type:varchar(64);not null" json:"fund_id"`               // 计入的款项
+	Amount      Money  `gorm:"type:decimal(20,2);not null" json:"amount"`              // 缴费金额
+	Reference   string `gorm:"type:varchar(100)" json:"reference"`                     // 银行流水号
+	Recorder    string `gorm:"type:varchar(64);not null" json:"recorder"`              // 登记人
+	PayTime     string `gorm:"type:varchar(26);not null" json:"pay_time"`              // 缴费时间
+}
+
+func (Payment) TableName() string {
+	return "payment"
+}
+
+// ArrearsAging 一户的欠费账龄，按逾期天数分段
+type ArrearsAging struct {
+	HouseholdID string `json:"household_id"`
+	Current     Money  `json:"current"`     // 未到期
+	Days30      Money  `json:"days_30"`     // 逾期1-30天
+	Days60      Money  `json:"days_60"`     // 逾期31-60天
+	Days90      Money  `json:"days_90"`     // 逾期61-90天
+	Over90      Money  `json:"over_90"`     // 逾期90天以上
+	Outstanding Money  `json:"outstanding"` // 未缴合计
+	OldestDue   string `json:"oldest_due"`  // 最早的未缴到期日
+}
+
+// ArrearsReport 欠费账龄汇总
+type ArrearsReport struct {
+	AsOf       string         `json:"as_of"`
+	Households []ArrearsAging `json:"households"`
+	Total      ArrearsAging   `json:"total"`
+}
+
+// HouseholdStatement 住户的账单与缴费记录
+type HouseholdStatement struct {
+	HouseholdID string    `json:"household_id"`
+	Invoices    []Invoice `json:"invoices"`
+	Payments    []Payment `json:"payments"`
+	TotalBilled Money     `json:"total_billed"`
+	TotalPaid   Money     `json:"total_paid"`
+	Outstanding Money     `json:"outstanding"`
+}
+
+// CheckFeeSchedule 校验收费方式、计费周期与费率
+func CheckFeeSchedule(schedule FeeSchedule) error {
+	if schedule.Method != FeeMethodFlat && schedule.Method != FeeMethodArea {
+		return fmt.Errorf("%w: method %s", ErrInvalidFee, schedule.Method)
+	}
+	if schedule.Cycle != FeeCycleMonthly && schedule.Cycle != FeeCycleQuarterly && schedule.Cycle != FeeCycleYearly {
+		return fmt.Errorf("%w: cycle %s", ErrInvalidFee, schedule.Cycle)
+	}
+	if schedule.Rate <= 0 || schedule.DueDays < 0 {
+		return fmt.Errorf("%w: rate must be positive and due days must not be negative", ErrInvalidFee)
+	}
+	return nil
+}
+
+// CheckPeriod 校验账期是否符合计费周期的格式
+func CheckPeriod(cycle, period string) error {
+	var err error
+	switch cycle {
+	case FeeCycleMonthly:
+		_, err = time.Parse("2006-01", period)
+	case FeeCycleYearly:
+		_, err = time.Parse("2006", period)
+	case FeeCycleQuarterly:
+		//季度账期如2024-Q1
+		if len(period) != 7 || period[4:6] != "-Q" || period[6] < '1' || period[6] > '4' {
+			err = ErrInvalidPeriod
+		} else {
+			_, err = time.Parse("2006", period[:4])
+		}
+	default:
+		err = ErrInvalidFee
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s for %s cycle", ErrInvalidPeriod, period, cycle)
+	}
+	return nil
+}
+
+// FeeAmount 按收费标准计算一户的应缴金额，按面积计费时四舍五入到分
+func FeeAmount(schedule FeeSchedule, area float64) Money {
+	if schedule.Method == FeeMethodArea {
+		return Money(math.Round(float64(schedule.Rate) * area))
+	}
+	return schedule.Rate
+}
+
+// SaveHousehold 新增或更新房屋信息
+func SaveHousehold(household *Household) error {
+	return db.DB.Save(household).Error
+}
+
+// GetHouseholdByID 查询房屋信息
+func GetHouseholdByID(id string) (*Household, error) {
+	var household Household
+	err := db.DB.First(&household, "household_id = ?", id).Error
+	return &household, err
+}
+
+// GetHouseholdsWithPagination 分页查询房屋信息
+func GetHouseholdsWithPagination(page *Page) ([]Household, error) {
+	var households []Household
+	err := paginate(db.DB.Model(&Household{}).Order("household_id"), page, &households)
+	return households, err
+}
+
+// CreateFeeSchedule 创建收费标准
+func CreateFeeSchedule(schedule *FeeSchedule) error {
+	return db.DB.Create(schedule).Error
+}
+
+// GetFeeScheduleByID 查询收费标准
+func GetFeeScheduleByID(id string) (*FeeSchedule, error) {
+	var schedule FeeSchedule
+	err := db.DB.First(&schedule, "schedule_id = ?", id).Error
+	return &schedule, err
+}
+
+// GetFeeSchedulesWithPagination 分页查询收费标准，fundID为空时不筛选
+func GetFeeSchedulesWithPagination(fundID string, page *Page) ([]FeeSchedule, error) {
+	var schedules []FeeSchedule
+	tx := db.DB.Model(&FeeSchedule{})
+	if fundID != "" {
+		tx = tx.Where("fund_id = ?", fundID)
+	}
+	err := paginate(tx.Order("create_time desc"), page, &schedules)
+	return schedules, err
+}
+
+// UpdateFeeSchedule 更新收费标准的名称、费率、到期天数与状态，只影响之后生成的账单
+func UpdateFeeSchedule(schedule *FeeSchedule) error {
+	return db.DB.Model(schedule).Select("name", "rate", "due_days", "status").Updates(schedule).Error
+}
+
+// DeleteFeeSchedule 删除收费标准，已生成的账单不受影响
+func DeleteFeeSchedule(id string) error {
+	result := db.DB.Delete(&FeeSchedule{}, "schedule_id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete fee schedule: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no fee schedule found with schedule_id: %s", id)
+	}
+	return nil
+}
+
+// GenerateInvoices 为所有登记了成员的户号生成指定账期的账单，已生成过的户号跳过
+// 按面积计费时没有登记面积的户号不生成账单，在skipped中返回
+func GenerateInvoices(schedule FeeSchedule, period, issueDate, createTime string, newID func() string) (created []Invoice, skipped []string, err error) {
+	if err := CheckPeriod(schedule.Cycle, period); err != nil {
+		return nil, nil, err
+	}
+	issue, err := time.Parse("2006-01-02", issueDate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: issue date %s", ErrInvalidPeriod, issueDate)
+	}
+	var households []struct {
+		HouseholdID string
+		Area        *float64
+	}
+	err = db.DB.Model(&Member{}).
+		Joins("LEFT JOIN household AS h ON h.household_id = member.household_id").
+		Where("member.household_id <> ''").
+		Distinct("member.household_id AS household_id, h.area AS area").
+		Order("member.household_id").
+		Scan(&households).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	dueDate := issue.AddDate(0, 0, schedule.DueDays).Format("2006-01-02")
+	invoices := make([]Invoice, 0, len(households))
+	for _, h := range households {
+		var area float64
+		if h.Area != nil {
+			area = *h.Area
+		}
+		if schedule.Method == FeeMethodArea && area <= 0 {
+			skipped = append(skipped, h.HouseholdID)
+			continue
+		}
+		invoices = append(invoices, Invoice{
+			InvoiceID:   newID(),
+			ScheduleID:  schedule.ScheduleID,
+			HouseholdID: h.HouseholdID,
+			Period:      period,
+			FundID:      schedule.FundID,
+			Name:        schedule.Name,
+			Amount:      FeeAmount(schedule, area),
+			Status:      InvoiceStatusUnpaid,
+			IssueDate:   issueDate,
+			DueDate:     dueDate,
+			CreateTime:  createTime,
+		})
+	}
+	if len(invoices) == 0 {
+		return []Invoice{}, skipped, nil
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []string
+		err := tx.Model(&Invoice{}).Where("schedule_id = ? AND period = ?", schedule.ScheduleID, period).
+			Pluck("household_id", &existing).Error
+		if err != nil {
+			return err
+		}
+		billed := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			billed[id] = true
+		}
+		created = make([]Invoice, 0, len(invoices))
+		for _, invoice := range invoices {
+			if !billed[invoice.HouseholdID] {
+				created = append(created, invoice)
+			}
+		}
+		if len(created) == 0 {
+			return nil
+		}
+		return tx.Create(&created).Error
+	})
+	return created, skipped, err
+}
+
+// GetInvoiceByID 查询账单
+func GetInvoiceByID(id string) (*Invoice, error) {
+	var invoice Invoice
+	err := db.DB.First(&invoice, "invoice_id = ?", id).Error
+	return &invoice, err
+}
+
+// GetInvoicesWithPagination 分页查询账单，条件为空时不筛选
+func GetInvoicesWithPagination(householdID, status, period string, page *Page) ([]Invoice, error) {
+	var invoices []Invoice
+	tx := db.DB.Model(&Invoice{})
+	if householdID != "" {
+		tx = tx.Where("household_id = ?", householdID)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if period != "" {
+		tx = tx.Where("period = ?", period)
+	}
+	err := paginate(tx.Order("issue_date desc, household_id"), page, &invoices)
+	return invoices, err
+}
+
+// PayInvoice 登记一笔缴费，在款项中记收入分录并执行anchor上链，任一步失败时整体回滚
+// 缴费金额不能超过未缴金额，作废或已缴清的账单不能缴费
+func PayInvoice(invoiceID string, payment *Payment, anchor func(invoice *Invoice) error) (*Invoice, error) {
+	var invoice Invoice
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, "invoice_id = ?", invoiceID).Error
+		if err != nil {
+			return err
+		}
+		if invoice.Status == InvoiceStatusVoid || invoice.Status == InvoiceStatusPaid {
+			return fmt.Errorf("%w: %s is %s", ErrInvoiceClosed, invoiceID, invoice.Status)
+		}
+		if payment.Amount <= 0 || payment.Amount > invoice.Outstanding() {
+			return fmt.Errorf("%w: outstanding %s, payment %s", ErrOverpayment, invoice.Outstanding(), payment.Amount)
+		}
+		payment.InvoiceID = invoice.InvoiceID
+		payment.HouseholdID = invoice.HouseholdID
+		payment.FundID = invoice.FundID
+		entry := JournalEntry{
+			EntryID:   payment.PaymentID,
+			FundID:    invoice.FundID,
+			Type:      EntryTypeIncome,
+			Source:    invoice.HouseholdID,
+			Explain:   invoice.Name + " " + invoice.Period,
+			Category:  invoice.Name,
+			Reference: payment.Reference,
+			Recorder:  payment.Recorder,
+			EntryTime: payment.PayTime,
+		}
+		if _, err := postFundRecord(tx, &entry, payment.Amount); err != nil {
+			return err
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		invoice.Paid += payment.Amount
+		invoice.Status = InvoiceStatusPartial
+		if invoice.Paid == invoice.Amount {
+			invoice.Status = InvoiceStatusPaid
+		}
+		if err := tx.Save(&invoice).Error; err != nil {
+			return err
+		}
+		return anchor(&invoice)
+	})
+	return &invoice, err
+}
+
+// VoidInvoice 作废尚未缴费的账单
+func VoidInvoice(id string) (*Invoice, error) {
+	var invoice Invoice
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, "invoice_id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		if invoice.Status == InvoiceStatusVoid {
+			return fmt.Errorf("%w: %s is %s", ErrInvoiceClosed, id, invoice.Status)
+		}
+		if invoice.Paid > 0 {
+			return fmt.Errorf("%w: %s paid %s", ErrInvoiceHasPaid, id, invoice.Paid)
+		}
+		invoice.Status = InvoiceStatusVoid
+		return tx.Save(&invoice).Error
+	})
+	return &invoice, err
+}
+
+// addAging 按截至日期的逾期天数把未缴金额计入账龄分段
+func (a *ArrearsAging) addAging(invoice Invoice, asOf time.Time) error {
+	due, err := time.Parse("2006-01-02", invoice.DueDate)
+	if err != nil {
+		return fmt.Errorf("invalid due date of %s: %s", invoice.InvoiceID, invoice.DueDate)
+	}
+	amount := invoice.Outstanding()
+	days := int(asOf.Sub(due).Hours() / 24)
+	switch {
+	case days <= 0:
+		a.Current += amount
+	case days <= 30:
+		a.Days30 += amount
+	case days <= 60:
+		a.Days60 += amount
+	case days <= 90:
+		a.Days90 += amount
+	default:
+		a.Over90 += amount
+	}
+	a.Outstanding += amount
+	if a.OldestDue == "" || invoice.DueDate < a.OldestDue {
+		a.OldestDue = invoice.DueDate
+	}
+	return nil
+}
+
+// GetArrearsReport 统计截至asOf已出账未缴清的账单账龄，fundID为空时统计全部款项
+func GetArrearsReport(asOf, fundID string) (*ArrearsReport, error) {
+	date, err := time.Parse("2006-01-02", asOf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: as of %s", ErrInvalidPeriod, asOf)
+	}
+	var invoices []Invoice
+	tx := db.DB.Where("status IN ? AND issue_date <= ?", []string{InvoiceStatusUnpaid, InvoiceStatusPartial}, asOf)
+	if fundID != "" {
+		tx = tx.Where("fund_id = ?", fundID)
+	}
+	if err := tx.Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	report := &ArrearsReport{AsOf: asOf, Households: []ArrearsAging{}}
+	index := make(map[string]int)
+	for _, invoice := range invoices {
+		i, ok := index[invoice.HouseholdID]
+		if !ok {
+			i = len(report.Households)
+			index[invoice.HouseholdID] = i
+			report.Households = append(report.Households, ArrearsAging{HouseholdID: invoice.HouseholdID})
+		}
+		if err := report.Households[i].addAging(invoice, date); err != nil {
+			return nil, err
+		}
+		if err := report.Total.addAging(invoice, date); err != nil {
+			return nil, err
+		}
+	}
+	//欠费最多的户号排在前面
+	sort.Slice(report.Households, func(i, j int) bool {
+		if report.Households[i].Outstanding != report.Households[j].Outstanding {
+			return report.Households[i].Outstanding > report.Households[j].Outstanding
+		}
+		return report.Households[i].HouseholdID < report.Households[j].HouseholdID
+	})
+	return report, nil
+}
+
+// GetHouseholdStatement 查询住户的全部账单与缴费记录
+func GetHouseholdStatement(householdID string) (*HouseholdStatement, error) {
+	statement := &HouseholdStatement{HouseholdID: householdID}
+	err := db.DB.Where("household_id = ?", householdID).Order("issue_date, period").Find(&statement.Invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.DB.Where("household_id = ?", householdID).Order("pay_time").Find(&statement.Payments).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, invoice := range statement.Invoices {
+		if invoice.Status == InvoiceStatusVoid {
+			continue
+		}
+		statement.TotalBilled += invoice.Amount
+		statement.TotalPaid += invoice.Paid
+		statement.Outstanding += invoice.Outstanding()
+	}
+	return statement, nil
+}
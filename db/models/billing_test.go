@@ -0,0 +1,264 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCheckPeriod(t *testing.T) {
+	cases := []struct {
+		cycle, period string
+		valid         bool
+	}{
+		{FeeCycleMonthly, "2024-06", true},
+		{FeeCycleMonthly, "2024-13", false},
+		{FeeCycleMonthly, "2024", false},
+		{FeeCycleQuarterly, "2024-Q1", true},
+		{FeeCycleQuarterly, "2024-Q4", true},
+		{FeeCycleQuarterly, "2024-Q5", false},
+		{FeeCycleQuarterly, "2024-06", false},
+		{FeeCycleQuarterly, "abcd-Q1", false},
+		{FeeCycleYearly, "2024", true},
+		{FeeCycleYearly, "2024-01", false},
+		{"weekly", "2024-01", false},
+	}
+	for _, c := range cases {
+		err := CheckPeriod(c.cycle, c.period)
+		if c.valid && err != nil {
+			t.Errorf("%s %s: unexpected error %v", c.cycle, c.period, err)
+		}
+		if !c.valid && !errors.Is(err, ErrInvalidPeriod) {
+			t.Errorf("%s %s: got %v, want ErrInvalidPeriod", c.cycle, c.period, err)
+		}
+	}
+}
+
+func TestFeeAmount(t *testing.T) {
+	cases := []struct {
+		method string
+		rate   Money
+		area   float64
+		want   Money
+	}{
+		{FeeMethodFlat, 12000, 88.5, 12000},
+		{FeeMethodArea, 250, 80, 20000},
+		{FeeMethodArea, 333, 100.5, 33467}, //334.665元四舍五入到分
+		{FeeMethodArea, 250, 0, 0},
+	}
+	for _, c := range cases {
+		if got := FeeAmount(FeeSchedule{Method: c.method, Rate: c.rate}, c.area); got != c.want {
+			t.Errorf("%s %s x %v: got %s, want %s", c.method, c.rate, c.area, got, c.want)
+		}
+	}
+}
+
+func TestGenerateInvoices(t *testing.T) {
+	useTestDB(t)
+	for i, household := range []string{"h1", "h1", "h2", "h3", ""} {
+		member := Member{MemberID: fmt.Sprintf("m%d", i), Name: "成员", HouseholdID: household}
+		if err := db.DB.Create(&member).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, household := range []Household{{HouseholdID: "h1", Area: 100.5}, {HouseholdID: "h2", Area: 80}} {
+		if err := SaveHousehold(&household); err != nil {
+			t.Fatal(err)
+		}
+	}
+	area := FeeSchedule{ScheduleID: "s1", Name: "物业费", FundID: "f1", Method: FeeMethodArea, Rate: 250, Cycle: FeeCycleMonthly, DueDays: 15}
+	flat := FeeSchedule{ScheduleID: "s2", Name: "垃圾清运费", FundID: "f1", Method: FeeMethodFlat, Rate: 1000, Cycle: FeeCycleQuarterly, DueDays: 30}
+	cases := []struct {
+		name     string
+		schedule FeeSchedule
+		period   string
+		created  map[string]Money
+		skipped  []string
+		err      error
+	}{
+		{"area skips households without area", area, "2024-06", map[string]Money{"h1": 25125, "h2": 20000}, []string{"h3"}, nil},
+		{"same period is not billed twice", area, "2024-06", map[string]Money{}, []string{"h3"}, nil},
+		{"next period", area, "2024-07", map[string]Money{"h1": 25125, "h2": 20000}, []string{"h3"}, nil},
+		{"flat bills every household", flat, "2024-Q2", map[string]Money{"h1": 1000, "h2": 1000, "h3": 1000}, nil, nil},
+		{"period must match cycle", flat, "2024-06", nil, nil, ErrInvalidPeriod},
+	}
+	seq := 0
+	newID := func() string {
+		seq++
+		return fmt.Sprintf("inv%d", seq)
+	}
+	for _, c := range cases {
+		created, skipped, err := GenerateInvoices(c.schedule, c.period, "2024-06-01", "2024-06-01 08:00:00", newID)
+		if c.err != nil {
+			if !errors.Is(err, c.err) {
+				t.Errorf("%s: got %v, want %v", c.name, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if fmt.Sprint(skipped) != fmt.Sprint(c.skipped) {
+			t.Errorf("%s: skipped %v, want %v", c.name, skipped, c.skipped)
+		}
+		if len(created) != len(c.created) {
+			t.Errorf("%s: created %d invoices, want %d", c.name, len(created), len(c.created))
+		}
+		due := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, c.schedule.DueDays).Format("2006-01-02")
+		for _, invoice := range created {
+			want, ok := c.created[invoice.HouseholdID]
+			if !ok || invoice.Amount != want {
+				t.Errorf("%s: invoice of %s is %s, want %s", c.name, invoice.HouseholdID, invoice.Amount, want)
+			}
+			if invoice.Status != InvoiceStatusUnpaid || invoice.DueDate != due || invoice.Period != c.period {
+				t.Errorf("%s: invoice %+v", c.name, invoice)
+			}
+		}
+	}
+}
+
+func TestAddAging(t *testing.T) {
+	asOf := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		due    string
+		status string
+		paid   Money
+		bucket string
+		amount Money
+	}{
+		{"2024-07-10", InvoiceStatusUnpaid, 0, "current", 10000},
+		{"2024-06-30", InvoiceStatusUnpaid, 0, "current", 10000},
+		{"2024-06-29", InvoiceStatusPartial, 4000, "30", 6000},
+		{"2024-05-31", InvoiceStatusUnpaid, 0, "30", 10000},
+		{"2024-05-30", InvoiceStatusUnpaid, 0, "60", 10000},
+		{"2024-04-01", InvoiceStatusUnpaid, 0, "90", 10000},
+		{"2024-03-31", InvoiceStatusUnpaid, 0, "over", 10000},
+		{"2024-03-31", InvoiceStatusVoid, 0, "over", 0},
+	}
+	for _, c := range cases {
+		var aging ArrearsAging
+		invoice := Invoice{InvoiceID: "inv1", Amount: 10000, Paid: c.paid, Status: c.status, DueDate: c.due}
+		if err := aging.addAging(invoice, asOf); err != nil {
+			t.Errorf("due %s: unexpected error %v", c.due, err)
+			continue
+		}
+		buckets := map[string]Money{"current": aging.Current, "30": aging.Days30, "60": aging.Days60, "90": aging.Days90, "over": aging.Over90}
+		for bucket, amount := range buckets {
+			want := Money(0)
+			if bucket == c.bucket {
+				want = c.amount
+			}
+			if amount != want {
+				t.Errorf("due %s: bucket %s is %s, want %s", c.due, bucket, amount, want)
+			}
+		}
+		if aging.Outstanding != c.amount || aging.OldestDue != c.due {
+			t.Errorf("due %s: outstanding %s oldest %s", c.due, aging.Outstanding, aging.OldestDue)
+		}
+	}
+	var aging ArrearsAging
+	if err := aging.addAging(Invoice{InvoiceID: "inv1", DueDate: "30/06/2024"}, asOf); err == nil {
+		t.Error("want error for illegal due date")
+	}
+}
+
+func TestGetArrearsReport(t *testing.T) {
+	useTestDB(t)
+	invoices := []Invoice{
+		{InvoiceID: "i1", HouseholdID: "h1", Period: "2024-04", FundID: "f1", Amount: 10000, Status: InvoiceStatusUnpaid, IssueDate: "2024-04-01", DueDate: "2024-04-15"},
+		{InvoiceID: "i2", HouseholdID: "h1", Period: "2024-05", FundID: "f1", Amount: 10000, Paid: 2000, Status: InvoiceStatusPartial, IssueDate: "2024-05-01", DueDate: "2024-05-15"},
+		{InvoiceID: "i3", HouseholdID: "h2", Period: "2024-05", FundID: "f1", Amount: 10000, Paid: 10000, Status: InvoiceStatusPaid, IssueDate: "2024-05-01", DueDate: "2024-05-15"},
+		{InvoiceID: "i4", HouseholdID: "h2", Period: "2024-06", FundID: "f2", Amount: 5000, Status: InvoiceStatusUnpaid, IssueDate: "2024-06-01", DueDate: "2024-06-15"},
+		{InvoiceID: "i5", HouseholdID: "h3", Period: "2024-07", FundID: "f1", Amount: 5000, Status: InvoiceStatusUnpaid, IssueDate: "2024-07-01", DueDate: "2024-07-15"},
+		{InvoiceID: "i6", HouseholdID: "h3", Period: "2024-06", FundID: "f1", Amount: 5000, Status: InvoiceStatusVoid, IssueDate: "2024-06-01", DueDate: "2024-06-15"},
+	}
+	for i := range invoices {
+		invoices[i].ScheduleID, invoices[i].Name, invoices[i].CreateTime = "s1", "物业费", "2024-04-01 08:00:00"
+	}
+	if err := db.DB.Create(&invoices).Error; err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name       string
+		fundID     string
+		households []string //按欠费金额排序的户号
+		total      ArrearsAging
+	}{
+		{"all funds", "", []string{"h1", "h2"},
+			ArrearsAging{Days30: 5000, Days60: 8000, Days90: 10000, Outstanding: 23000, OldestDue: "2024-04-15"}},
+		{"one fund", "f1", []string{"h1"},
+			ArrearsAging{Days60: 8000, Days90: 10000, Outstanding: 18000, OldestDue: "2024-04-15"}},
+		{"fund without arrears", "f3", []string{},
+			ArrearsAging{}},
+	}
+	for _, c := range cases {
+		report, err := GetArrearsReport("2024-06-30", c.fundID)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		households := make([]string, len(report.Households))
+		for i, h := range report.Households {
+			households[i] = h.HouseholdID
+		}
+		if fmt.Sprint(households) != fmt.Sprint(c.households) {
+			t.Errorf("%s: households %v, want %v", c.name, households, c.households)
+		}
+		if report.Total != c.total {
+			t.Errorf("%s: total %+v, want %+v", c.name, report.Total, c.total)
+		}
+	}
+	if _, err := GetArrearsReport("2024/06/30", ""); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("got %v, want ErrInvalidPeriod", err)
+	}
+}
+
+func TestPayInvoice(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    string
+		amount    Money
+		anchorErr error
+		err       error
+		paid      Money
+		newStatus string
+	}{
+		{"partial payment", InvoiceStatusUnpaid, 4000, nil, nil, 4000, InvoiceStatusPartial},
+		{"full payment", InvoiceStatusUnpaid, 10000, nil, nil, 10000, InvoiceStatusPaid},
+		{"overpayment", InvoiceStatusUnpaid, 10001, nil, ErrOverpayment, 0, InvoiceStatusUnpaid},
+		{"void invoice", InvoiceStatusVoid, 1000, nil, ErrInvoiceClosed, 0, InvoiceStatusVoid},
+		{"rolled back when anchor fails", InvoiceStatusUnpaid, 4000, errAnchor, errAnchor, 0, InvoiceStatusUnpaid},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			openTestFund(t, "f1", 0)
+			invoice := Invoice{InvoiceID: "i1", ScheduleID: "s1", HouseholdID: "h1", Period: "2024-06", FundID: "f1", Name: "物业费",
+				Amount: 10000, Status: c.status, IssueDate: "2024-06-01", DueDate: "2024-06-15", CreateTime: "2024-06-01 08:00:00"}
+			if err := db.DB.Create(&invoice).Error; err != nil {
+				t.Fatal(err)
+			}
+			payment := &Payment{PaymentID: "p1", Amount: c.amount, Recorder: "u1", PayTime: "2024-06-10 10:00:00"}
+			_, err := PayInvoice("i1", payment, func(*Invoice) error { return c.anchorErr })
+			if c.err == nil && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			stored, err := GetInvoiceByID("i1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Paid != c.paid || stored.Status != c.newStatus {
+				t.Errorf("invoice paid %s status %s, want %s %s", stored.Paid, stored.Status, c.paid, c.newStatus)
+			}
+			if balance := fundBalance(t, "f1"); balance != c.paid {
+				t.Errorf("fund balance %s, want %s", balance, c.paid)
+			}
+		})
+	}
+}
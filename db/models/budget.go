@@ -0,0 +1,318 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+)
+
+// 预算超支处理方式
+const (
+	BudgetModeWarn  = "warn"  // 超支时仍可入账，返回超支提醒
+	BudgetModeBlock = "block" // 超支时拒绝入账
+)
+
+// BudgetStatusActive 执行中的预算，归档后不再检查超支
+const BudgetStatusActive = "active"
+
+// UncategorizedCategory 未填写科目的收支记录归入的科目
+const UncategorizedCategory = "未分类"
+
+var (
+	ErrBudgetExceeded = errors.New("budget exceeded")
+	ErrBudgetExists   = errors.New("budget already exists")
+	ErrInvalidBudget  = errors.New("invalid budget")
+)
+
+// Budget 预算表，每个款项每年一份预算，按科目拆分为预算行
+type Budget struct {
+	BudgetID   string         `gorm:"primaryKey;type:varchar(64);not null" json:"budget_id"` // 预算ID
+	FundID     string         `gorm:"type:varchar(64);not null;index" json:"fund_id"`        // 所属款项
+	Year       int            `gorm:"not null;index" json:"year"`                            // 预算年度
+	Name       string         `gorm:"type:varchar(100);not null" json:"name"`                // 预算名称
+	Mode       string         `gorm:"type:varchar(10);not null" json:"mode"`                 // 超支处理方式
+	Status     string         `gorm:"type:varchar(10);not null" json:"status"`               // 预算状态
+	Creator    string         `gorm:"type:varchar(64);not null" json:"creator"`              // 创建人
+	CreateTime string         `gorm:"type:varchar(26);not null" json:"create_time"`          // 创建时间
+	Lines      []BudgetLine   `gorm:"foreignKey:BudgetID" json:"lines"`                      // 预算行
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Budget) TableName() string {
+	return "budget"
+}
+
+// BudgetLine 预算行，对应一个支出科目
+type BudgetLine struct {
+	LineID      uint64 `gorm:"primaryKey;autoIncrement" json:"line_id"`          // 预算行ID
+	BudgetID    string `gorm:"type:varchar(64);not null;index" json:"budget_id"` // 所属预算
+	Category    string `gorm:"type:varchar(50);not null" json:"category"`        // 科目
+	Amount      Money  `gorm:"type:decimal(20,2);not null" json:"amount"`        // 预算金额
+	Description string `gorm:"type:varchar(200)" json:"description"`             // 说明
+}
+
+func (BudgetLine) TableName() string {
+	return "budget_line"
+}
+
+// BudgetWarning 超支提醒
+type BudgetWarning struct {
+	BudgetID string `json:"budget_id"`
+	Category string `json:"category"`
+	Budget   Money  `json:"budget"` // 科目预算金额，科目不在预算中时为0
+	Actual   Money  `json:"actual"` // 含本次支出的年度累计支出
+	Over     Money  `json:"over"`   // 超支金额
+}
+
+// BudgetReportLine 预算执行情况中的一个科目
+type BudgetReportLine struct {
+	Category  string  `json:"category"`
+	Budget    Money   `json:"budget"`    // 科目预算金额
+	Actual    Money   `json:"actual"`    // 年度累计支出
+	Remaining Money   `json:"remaining"` // 剩余预算，超支时为负
+	Monthly   []Money `json:"monthly"`   // 1至12月的支出
+	Budgeted  bool    `json:"budgeted"`  // 科目是否在预算中
+}
+
+// BudgetReport 预算执行情况，按科目与月份对比预算与实际支出
+type BudgetReport struct {
+	Budget      Budget             `json:"budget"`
+	Lines       []BudgetReportLine `json:"lines"`
+	TotalBudget Money              `json:"total_budget"`
+	TotalActual Money              `json:"total_actual"`
+}
+
+// checkBudgetLines 校验预算行，科目不能为空或重复，金额不能为负
+func checkBudgetLines(lines []BudgetLine) error {
+	categories := make(map[string]bool)
+	for _, line := range lines {
+		if line.Category == "" {
+			return fmt.Errorf("%w: category is required", ErrInvalidBudget)
+		}
+		if categories[line.Category] {
+			return fmt.Errorf("%w: duplicate category %s", ErrInvalidBudget, line.Category)
+		}
+		if line.Amount < 0 {
+			return fmt.Errorf("%w: budget of %s must not be negative", ErrInvalidBudget, line.Category)
+		}
+		categories[line.Category] = true
+	}
+	return nil
+}
+
+// CreateBudget 创建预算及预算行，同一款项同一年度只能有一份预算
+func CreateBudget(budget *Budget) error {
+	if err := checkBudgetLines(budget.Lines); err != nil {
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Budget{}).Where("fund_id = ? AND year = ?", budget.FundID, budget.Year).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: fund %s year %d", ErrBudgetExists, budget.FundID, budget.Year)
+		}
+		return tx.Create(budget).Error
+	})
+}
+
+// GetBudgetByID 查询预算及预算行
+func GetBudgetByID(id string) (*Budget, error) {
+	var budget Budget
+	err := db.DB.Preload("Lines").First(&budget, "budget_id = ?", id).Error
+	return &budget, err
+}
+
+// UpdateBudget 更新预算，lines不为nil时整体替换预算行
+func UpdateBudget(id string, budget Budget) error {
+	if budget.Lines != nil {
+		if err := checkBudgetLines(budget.Lines); err != nil {
+			return err
+		}
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Budget{}).Where("budget_id = ?", id).Updates(Budget{Name: budget.Name, Mode: budget.Mode, Status: budget.Status})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := tx.First(&Budget{}, "budget_id = ?", id).Error; err != nil {
+				return err
+			}
+		}
+		if budget.Lines == nil {
+			return nil
+		}
+		if err := tx.Where("budget_id = ?", id).Delete(&BudgetLine{}).Error; err != nil {
+			return err
+		}
+		if len(budget.Lines) == 0 {
+			return nil
+		}
+		for i := range budget.Lines {
+			budget.Lines[i].LineID = 0
+			budget.Lines[i].BudgetID = id
+		}
+		return tx.Create(&budget.Lines).Error
+	})
+}
+
+// DeleteBudget 删除预算
+func DeleteBudget(id string) error {
+	result := db.DB.Delete(&Budget{}, "budget_id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete budget: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no budget found with budget_id: %s", id)
+	}
+	return nil
+}
+
+// GetBudgetsWithPagination 分页查询预算，fundID为空、year为0时不筛选
+func GetBudgetsWithPagination(fundID string, year int, page *Page) ([]Budget, error) {
+	var budgets []Budget
+	tx := db.DB.Model(&Budget{})
+	if fundID != "" {
+		tx = tx.Where("fund_id = ?", fundID)
+	}
+	if year != 0 {
+		tx = tx.Where("year = ?", year)
+	}
+	err := paginate(tx.Order("year desc"), page, &budgets)
+	return budgets, err
+}
+
+// entryYear 记账时间所在年度
+func entryYear(entryTime string) (int, error) {
+	if len(entryTime) < 4 {
+		return 0, fmt.Errorf("invalid entry time: %s", entryTime)
+	}
+	return strconv.Atoi(entryTime[:4])
+}
+
+// yearRange 年度的起止时间，按记账时间字符串比较
+func yearRange(year int) (string, string) {
+	return fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-01-01", year+1)
+}
+
+// CategoryOf 未填写科目的记录归入未分类
+func CategoryOf(category string) string {
+	if category == "" {
+		return UncategorizedCategory
+	}
+	return category
+}
+
+// checkBudget 检查支出是否超出款项年度预算中对应科目的金额，款项当年没有预算时不检查
+// 预算为拦截模式时超支返回ErrBudgetExceeded，提醒模式时返回超支提醒
+func checkBudget(tx *gorm.DB, fundID, category, entryTime string, amount Money) (*BudgetWarning, error) {
+	year, err := entryYear(entryTime)
+	if err != nil {
+		return nil, err
+	}
+	var budget Budget
+	err = tx.Preload("Lines").Where("fund_id = ? AND year = ? AND status <> ?", fundID, year, StatusArchived).First(&budget).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	category = CategoryOf(category)
+	categories := []string{category}
+	if category == UncategorizedCategory {
+		categories = append(categories, "")
+	}
+	var limit Money
+	for _, line := range budget.Lines {
+		if line.Category == category {
+			limit = line.Amount
+		}
+	}
+	start, end := yearRange(year)
+	var spent Money
+	err = tx.Table("posting AS p").
+		Joins("JOIN journal_entry AS e ON e.entry_id = p.entry_id").
+		Where("p.account_id = ? AND e.category IN ? AND e.entry_time >= ? AND e.entry_time < ? AND e.transfer_id = ''",
+			FundAccountID(fundID, AccountTypeExpense), categories, start, end).
+		Select("COALESCE(SUM(p.amount), 0)").Scan(&spent).Error
+	if err != nil {
+		return nil, err
+	}
+	actual := spent + amount
+	if actual <= limit {
+		return nil, nil
+	}
+	warning := &BudgetWarning{BudgetID: budget.BudgetID, Category: category, Budget: limit, Actual: actual, Over: actual - limit}
+	if budget.Mode == BudgetModeBlock {
+		return nil, fmt.Errorf("%w: %s budget %s, actual %s", ErrBudgetExceeded, category, limit, actual)
+	}
+	return warning, nil
+}
+
+// CheckBudget 检查一笔支出是否超出预算，用于提交审批前提示
+func CheckBudget(fundID, category, entryTime string, amount Money) (*BudgetWarning, error) {
+	return checkBudget(db.DB, fundID, category, entryTime, amount)
+}
+
+// GetBudgetReport 按科目与月份统计预算年度内的实际支出
+func GetBudgetReport(id string) (*BudgetReport, error) {
+	budget, err := GetBudgetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	start, end := yearRange(budget.Year)
+	var rows []struct {
+		Category string
+		Month    int
+		Amount   Money
+	}
+	err = db.DB.Table("posting AS p").
+		Joins("JOIN journal_entry AS e ON e.entry_id = p.entry_id").
+		Where("p.account_id = ? AND e.entry_time >= ? AND e.entry_time < ? AND e.transfer_id = ''",
+			FundAccountID(budget.FundID, AccountTypeExpense), start, end).
+		Select("e.category AS category, CAST(SUBSTRING(e.entry_time, 6, 2) AS UNSIGNED) AS month, SUM(p.amount) AS amount").
+		Group("e.category, month").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	report := &BudgetReport{Budget: *budget, Lines: []BudgetReportLine{}}
+	index := make(map[string]int)
+	for _, line := range budget.Lines {
+		index[line.Category] = len(report.Lines)
+		report.Lines = append(report.Lines, BudgetReportLine{
+			Category: line.Category,
+			Budget:   line.Amount,
+			Monthly:  make([]Money, 12),
+			Budgeted: true,
+		})
+		report.TotalBudget += line.Amount
+	}
+	//预算外科目的支出排在预算科目之后
+	for _, row := range rows {
+		category := CategoryOf(row.Category)
+		i, ok := index[category]
+		if !ok {
+			i = len(report.Lines)
+			index[category] = i
+			report.Lines = append(report.Lines, BudgetReportLine{Category: category, Monthly: make([]Money, 12)})
+		}
+		if row.Month >= 1 && row.Month <= 12 {
+			report.Lines[i].Monthly[row.Month-1] += row.Amount
+		}
+		report.Lines[i].Actual += row.Amount
+		report.TotalActual += row.Amount
+	}
+	extra := report.Lines[len(budget.Lines):]
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Category < extra[j].Category })
+	for i := range report.Lines {
+		report.Lines[i].Remaining = report.Lines[i].Budget - report.Lines[i].Actual
+	}
+	return report, nil
+}
@@ -0,0 +1,291 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// 折旧方法
+const (
+	DepreciationStraightLine = "straight_line"     // 年限平均法
+	DepreciationDeclining    = "declining_balance" // 双倍余额递减法，最后两年改为平均摊销
+)
+
+// AccountTypeDepreciation 款项的累计折旧账户，贷方为负，首次计提折旧时开设
+const AccountTypeDepreciation = "accum_dep"
+
+// EntryTypeDepreciation 折旧分录，不涉及资金账户
+const EntryTypeDepreciation = "depreciate"
+
+// DepreciationCategory 折旧分录的科目
+const DepreciationCategory = "折旧"
+
+var (
+	ErrInvalidDepreciation = errors.New("invalid depreciation")
+	ErrAssetDepreciated    = errors.New("asset has been depreciated")
+)
+
+// AssetDepreciation 资产年度折旧记录，每项资产每年只计提一次
+type AssetDepreciation struct {
+	AssetID   string `gorm:"primaryKey;type:varchar(64);not null" json:"asset_id"` // 资产ID
+	Year      int    `gorm:"primaryKey;not null" json:"year"`                      // 折旧年度
+	FundID    string `gorm:"type:varchar(64);not null;index" json:"fund_id"`       // 计入款项
+	Amount    Money  `gorm:"type:decimal(20,2);not null" json:"amount"`            // 本年折旧额
+	BookValue Money  `gorm:"type:decimal(20,2);not null" json:"book_value"`        // 年末账面价值
+	EntryID   string `gorm:"type:varchar(64);not null" json:"entry_id"`            // 折旧分录ID
+	Recorder  string `gorm:"type:varchar(64);not null" json:"recorder"`            // 计提人
+	RunTime   string `gorm:"type:varchar(26);not null" json:"run_time"`            // 计提时间
+}
+
+func (AssetDepreciation) TableName() string {
+	return "asset_depreciation"
+}
+
+// BookValue 资产在某一日期的折旧情况
+type BookValue struct {
+	AssetID     string `json:"asset_id"`
+	Date        string `json:"date"`
+	Cost        Money  `json:"cost"`
+	Months      int    `json:"months"`      // 已计提折旧的月数
+	Accumulated Money  `json:"accumulated"` // 累计折旧
+	BookValue   Money  `json:"book_value"`  // 账面价值
+}
+
+// AssetRegisterLine 资产台账中的一项资产
+type AssetRegisterLine struct {
+	Asset
+	Months      int   `json:"months"`      // 已计提折旧的月数
+	Accumulated Money `json:"accumulated"` // 累计折旧
+	BookValue   Money `json:"book_value"`  // 账面价值
+}
+
+// CheckDepreciation 校验资产的折旧参数，未设置折旧方法时不校验
+func CheckDepreciation(asset Asset) error {
+	if asset.Cost < 0 || asset.Residual < 0 {
+		return fmt.Errorf("%w: cost and residual must not be negative", ErrInvalidDepreciation)
+	}
+	if asset.Method == "" {
+		return nil
+	}
+	if asset.Method != DepreciationStraightLine && asset.Method != DepreciationDeclining {
+		return fmt.Errorf("%w: unknown method %s", ErrInvalidDepreciation, asset.Method)
+	}
+	if asset.Cost == 0 || asset.UsefulLife <= 0 || asset.FundID == "" {
+		return fmt.Errorf("%w: cost, useful life and fund are required", ErrInvalidDepreciation)
+	}
+	if asset.Residual > asset.Cost {
+		return fmt.Errorf("%w: residual exceeds cost", ErrInvalidDepreciation)
+	}
+	if _, err := purchaseMonth(asset.PurchaseDate); err != nil {
+		return err
+	}
+	return nil
+}
+
+// purchaseMonth 解析购买日期，日期可带时间部分
+func purchaseMonth(purchaseDate string) (time.Time, error) {
+	if len(purchaseDate) < 10 {
+		return time.Time{}, fmt.Errorf("%w: purchase date %s", ErrInvalidDepreciation, purchaseDate)
+	}
+	date, err := time.Parse("2006-01-02", purchaseDate[:10])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: purchase date %s", ErrInvalidDepreciation, purchaseDate)
+	}
+	return date, nil
+}
+
+// depreciationMonths 截至某日已计提折旧的月数，当月购入的资产从下月起计提，每月月末计提当月折旧
+func depreciationMonths(purchase, date time.Time) int {
+	months := (date.Year()*12 + int(date.Month())) - (purchase.Year()*12 + int(purchase.Month())) - 1
+	if date.AddDate(0, 0, 1).Day() == 1 {
+		months++
+	}
+	return max(months, 0)
+}
+
+// accumulatedDepreciation 计提months个月后的累计折旧，超过使用年限后折旧至净残值为止
+func accumulatedDepreciation(asset Asset, months int) Money {
+	total := asset.UsefulLife * 12
+	depreciable := asset.Cost - asset.Residual
+	if months <= 0 || depreciable <= 0 {
+		return 0
+	}
+	if months >= total {
+		return depreciable
+	}
+	if asset.Method == DepreciationStraightLine {
+		return Money(math.Round(float64(depreciable) * float64(months) / float64(total)))
+	}
+	// 双倍余额递减法按折旧年度计算年折旧额，年度内按月平均，最后两年将剩余净值平均摊销
+	life := float64(asset.UsefulLife)
+	residual := float64(asset.Residual)
+	switchYear := max(asset.UsefulLife-2, 0)
+	bookValue := float64(asset.Cost)
+	var accumulated, tail float64
+	for year := 0; months > 0; year++ {
+		n := min(months, 12)
+		annual := bookValue * 2 / life
+		if year >= switchYear {
+			if year == switchYear {
+				tail = (bookValue - residual) / float64(asset.UsefulLife-switchYear)
+			}
+			annual = tail
+		}
+		annual = max(min(annual, bookValue-residual), 0)
+		amount := annual * float64(n) / 12
+		accumulated += amount
+		bookValue -= amount
+		months -= n
+	}
+	return Money(math.Round(accumulated))
+}
+
+// CalculateBookValue 计算资产在某一日期的累计折旧与账面价值，未设置折旧方法的资产账面价值为购置成本
+func CalculateBookValue(asset Asset, date time.Time) (BookValue, error) {
+	value := BookValue{AssetID: asset.AssetID, Date: date.Format("2006-01-02"), Cost: asset.Cost, BookValue: asset.Cost}
+	if asset.Method == "" {
+		return value, nil
+	}
+	if err := CheckDepreciation(asset); err != nil {
+		return value, err
+	}
+	purchase, _ := purchaseMonth(asset.PurchaseDate)
+	value.Months = min(depreciationMonths(purchase, date), asset.UsefulLife*12)
+	value.Accumulated = accumulatedDepreciation(asset, value.Months)
+	value.BookValue = asset.Cost - value.Accumulated
+	return value, nil
+}
+
+// GetAssetRegister 资产台账，列出全部资产在某一日期的累计折旧与账面价值
+func GetAssetRegister(date time.Time) ([]AssetRegisterLine, error) {
+	var assets []Asset
+	if err := db.DB.Order("purchase_date, asset_id").Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	register := make([]AssetRegisterLine, 0, len(assets))
+	for _, asset := range assets {
+		value, err := CalculateBookValue(asset, date)
+		if err != nil {
+			return nil, fmt.Errorf("asset %s: %v", asset.AssetID, err)
+		}
+		register = append(register, AssetRegisterLine{Asset: asset, Months: value.Months, Accumulated: value.Accumulated, BookValue: value.BookValue})
+	}
+	return register, nil
+}
+
+// RunDepreciation 为全部计提折旧的资产计提年度折旧，借记款项支出、贷记累计折旧
+// 已处置的资产不再计提，已计提过该年度的资产跳过，因此可以重复执行
+func RunDepreciation(year int, recorder, runTime string) ([]AssetDepreciation, error) {
+	var assets []Asset
+	err := db.DB.Where("method <> '' AND fund_id <> '' AND status <> ?", AssetStatusDisposed).
+		Order("asset_id").Find(&assets).Error
+	if err != nil {
+		return nil, err
+	}
+	start := time.Date(year-1, time.December, 31, 0, 0, 0, 0, time.Local)
+	end := time.Date(year, time.December, 31, 0, 0, 0, 0, time.Local)
+	records := make([]AssetDepreciation, 0)
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var done []string
+		if err := tx.Model(&AssetDepreciation{}).Where("year = ?", year).Pluck("asset_id", &done).Error; err != nil {
+			return err
+		}
+		skip := make(map[string]bool, len(done))
+		for _, id := range done {
+			skip[id] = true
+		}
+		for _, asset := range assets {
+			if skip[asset.AssetID] {
+				continue
+			}
+			opening, err := CalculateBookValue(asset, start)
+			if err != nil {
+				return fmt.Errorf("asset %s: %v", asset.AssetID, err)
+			}
+			closing, err := CalculateBookValue(asset, end)
+			if err != nil {
+				return fmt.Errorf("asset %s: %v", asset.AssetID, err)
+			}
+			amount := opening.BookValue - closing.BookValue
+			if amount <= 0 {
+				continue
+			}
+			if err := ensureAccount(tx, asset.FundID, AccountTypeDepreciation, runTime); err != nil {
+				return err
+			}
+			entry := JournalEntry{
+				EntryID:   fmt.Sprintf("depreciation:%s:%d", asset.AssetID, year),
+				FundID:    asset.FundID,
+				Type:      EntryTypeDepreciation,
+				Source:    asset.AssetID,
+				Explain:   fmt.Sprintf("%d年度资产折旧:%s", year, asset.Name),
+				Category:  DepreciationCategory,
+				Recorder:  recorder,
+				EntryTime: end.Format("2006-01-02") + " 23:59:59",
+			}
+			err = postEntry(tx, &entry, []Posting{
+				{AccountID: FundAccountID(asset.FundID, AccountTypeExpense), Amount: amount},
+				{AccountID: FundAccountID(asset.FundID, AccountTypeDepreciation), Amount: -amount},
+			})
+			if err != nil {
+				return err
+			}
+			record := AssetDepreciation{
+				AssetID:   asset.AssetID,
+				Year:      year,
+				FundID:    asset.FundID,
+				Amount:    amount,
+				BookValue: closing.BookValue,
+				EntryID:   entry.EntryID,
+				Recorder:  recorder,
+				RunTime:   runTime,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// GetAssetDepreciations 查询资产的历年折旧记录
+func GetAssetDepreciations(assetID string) ([]AssetDepreciation, error) {
+	var records []AssetDepreciation
+	err := db.DB.Where("asset_id = ?", assetID).Order("year").Find(&records).Error
+	return records, err
+}
+
+// ensureAccount 款项尚未开设指定类型账户时开设该账户
+func ensureAccount(tx *gorm.DB, fundID, accountType, createTime string) error {
+	account := LedgerAccount{
+		AccountID:  FundAccountID(fundID, accountType),
+		FundID:     fundID,
+		Type:       accountType,
+		CreateTime: createTime,
+	}
+	return tx.Where("account_id = ?", account.AccountID).FirstOrCreate(&account).Error
+}
+
+// SetAssetDepreciation 设置资产的购置成本与折旧参数，已计提过折旧的资产不能修改
+func SetAssetDepreciation(asset Asset) error {
+	if err := CheckDepreciation(asset); err != nil {
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&AssetDepreciation{}).Where("asset_id = ?", asset.AssetID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAssetDepreciated
+		}
+		return tx.Model(&Asset{}).Where("asset_id = ?", asset.AssetID).
+			Select("cost", "residual", "useful_life", "method", "fund_id").Updates(&asset).Error
+	})
+}
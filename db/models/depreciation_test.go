@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDepreciationMonths(t *testing.T) {
+	cases := []struct {
+		name, purchase, date string
+		months               int
+	}{
+		{"purchase month does not count", "2024-01-15", "2024-01-31", 0},
+		{"purchased on the first day", "2024-01-01", "2024-01-31", 0},
+		{"first month not finished", "2024-01-15", "2024-02-15", 0},
+		{"first month finished", "2024-01-15", "2024-02-29", 1},
+		{"purchased at month end", "2024-01-31", "2024-02-29", 1},
+		{"year end", "2024-01-15", "2024-12-31", 11},
+		{"mid-month next year", "2024-01-15", "2025-01-15", 11},
+		{"before purchase", "2024-01-15", "2023-12-31", 0},
+		{"past useful life", "2019-06-10", "2026-10-19", 87},
+	}
+	for _, c := range cases {
+		purchase, _ := time.Parse("2006-01-02", c.purchase)
+		date, _ := time.Parse("2006-01-02", c.date)
+		if months := depreciationMonths(purchase, date); months != c.months {
+			t.Errorf("%s: %d months, want %d", c.name, months, c.months)
+		}
+	}
+}
+
+func TestAccumulatedDepreciation(t *testing.T) {
+	// 原值10000.00，净残值400.00，应计折旧9600.00
+	// 5年双倍余额递减：年折旧率40%，前三年4000.00、2400.00、1440.00，账面净值剩2160.00，
+	// 最后两年改为平均摊销(2160.00-400.00)/2=880.00
+	declining := func(life int) Asset {
+		return Asset{Cost: 1000000, Residual: 40000, UsefulLife: life, Method: DepreciationDeclining}
+	}
+	straight := func(life int) Asset {
+		return Asset{Cost: 1000000, Residual: 40000, UsefulLife: life, Method: DepreciationStraightLine}
+	}
+	cases := []struct {
+		name   string
+		asset  Asset
+		months int
+		want   Money
+	}{
+		{"declining no months", declining(5), 0, 0},
+		{"declining half of first year", declining(5), 6, 200000},
+		{"declining first year", declining(5), 12, 400000},
+		{"declining second year", declining(5), 24, 640000},
+		{"declining half of second year", declining(5), 18, 520000},
+		{"declining third year", declining(5), 36, 784000},
+		{"declining half of fourth year switches to even amortization", declining(5), 42, 828000},
+		{"declining fourth year", declining(5), 48, 872000},
+		{"declining half of fifth year", declining(5), 54, 916000},
+		{"declining one month before end", declining(5), 59, 952667},
+		{"declining end of life", declining(5), 60, 960000},
+		{"declining past useful life", declining(5), 87, 960000},
+		{"declining life 1", declining(1), 6, 480000},
+		{"declining life 1 end", declining(1), 12, 960000},
+		{"declining life 1 past end", declining(1), 13, 960000},
+		{"declining life 2 is even amortization", declining(2), 6, 240000},
+		{"declining life 2 second year", declining(2), 18, 720000},
+		{"declining life 2 end", declining(2), 24, 960000},
+		{"straight line", straight(5), 7, 112000},
+		{"straight line past useful life", straight(5), 61, 960000},
+		{"straight line life 1", straight(1), 6, 480000},
+		{"straight line life 2", straight(2), 18, 720000},
+		{"residual equals cost", Asset{Cost: 40000, Residual: 40000, UsefulLife: 5, Method: DepreciationDeclining}, 12, 0},
+	}
+	for _, c := range cases {
+		if got := accumulatedDepreciation(c.asset, c.months); got != c.want {
+			t.Errorf("%s: accumulated %d, want %d", c.name, got, c.want)
+		}
+	}
+}
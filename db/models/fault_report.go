@@ -0,0 +1,71 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+)
+
+// 故障报告状态
+const (
+	FaultStatusOpen    = "open"    // 待处理
+	FaultStatusHandled = "handled" // 已处理
+)
+
+var ErrFaultHandled = errors.New("fault report has been handled")
+
+// FaultReport 居民扫描资产或设施标签后提交的故障报告
+type FaultReport struct {
+	ReportID    string `gorm:"primaryKey;type:varchar(64);not null" json:"report_id"` // 报告ID
+	TargetType  string `gorm:"type:varchar(10);not null" json:"target_type"`          // 对象类型，与维护对象类型一致
+	TargetID    string `gorm:"type:varchar(64);not null;index" json:"target_id"`      // 资产ID或设施ID
+	Description string `gorm:"type:varchar(200);not null" json:"description"`         // 故障描述
+	Reporter    string `gorm:"type:varchar(64);not null" json:"reporter"`             // 报告人
+	Status      string `gorm:"type:varchar(10);not null;index" json:"status"`         // 处理状态
+	Handler     string `gorm:"type:varchar(64)" json:"handler"`                       // 处理人
+	Result      string `gorm:"type:varchar(200)" json:"result"`                       // 处理结果
+	CreateTime  string `gorm:"type:varchar(26);not null" json:"create_time"`          // 报告时间
+	HandleTime  string `gorm:"type:varchar(26)" json:"handle_time"`                   // 处理时间
+}
+
+func (FaultReport) TableName() string {
+	return "fault_report"
+}
+
+// CreateFaultReport 提交故障报告
+func CreateFaultReport(report *FaultReport) error {
+	return db.DB.Create(report).Error
+}
+
+// GetFaultReportByID 查询故障报告
+func GetFaultReportByID(id string) (*FaultReport, error) {
+	var report FaultReport
+	err := db.DB.First(&report, "report_id = ?", id).Error
+	return &report, err
+}
+
+// GetFaultReportsWithPagination 分页查询故障报告，筛选条件为空时不筛选
+func GetFaultReportsWithPagination(targetID, status string, page *Page) ([]FaultReport, error) {
+	var reports []FaultReport
+	tx := db.DB.Model(&FaultReport{})
+	if targetID != "" {
+		tx = tx.Where("target_id = ?", targetID)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err := paginate(tx.Order("create_time desc"), page, &reports)
+	return reports, err
+}
+
+// HandleFaultReport 处理待处理的故障报告
+func HandleFaultReport(report *FaultReport) error {
+	result := db.DB.Model(report).Where("status = ?", FaultStatusOpen).
+		Select("status", "handler", "result", "handle_time").Updates(report)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFaultHandled
+	}
+	return nil
+}
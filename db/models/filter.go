@@ -0,0 +1,198 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// 条件查询支持的操作符
+const (
+	OpEq       = "eq"       // 等于
+	OpNe       = "ne"       // 不等于
+	OpGt       = "gt"       // 大于
+	OpLt       = "lt"       // 小于
+	OpBetween  = "between"  // 区间，value为[起,止]
+	OpIn       = "in"       // 属于，value为数组
+	OpContains = "contains" // 包含子串
+)
+
+const (
+	maxFilters  = 20  // 单次查询最多条件数
+	maxInValues = 100 // in操作最多取值数
+)
+
+// ErrInvalidQuery 查询条件不合法，如字段不在白名单、操作符不支持或取值类型错误
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Filter 单个筛选条件
+type Filter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// Sort 排序字段
+type Sort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// Query 条件查询，所有字段都必须在对应数据表的白名单中
+type Query struct {
+	Filters []Filter `json:"filters"`
+	Sort    []Sort   `json:"sort"`
+	Fields  []string `json:"fields"`
+}
+
+// UnmarshalJSON 兼容旧的{"列名":"值"}写法，视为若干eq条件
+func (q *Query) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	_, hasFilters := raw["filters"]
+	_, hasSort := raw["sort"]
+	_, hasFields := raw["fields"]
+	if hasFilters || hasSort || hasFields || len(raw) == 0 {
+		type query Query
+		return json.Unmarshal(data, (*query)(q))
+	}
+	q.Filters = make([]Filter, 0, len(raw))
+	for field, value := range raw {
+		var v interface{}
+		if err := json.Unmarshal(value, &v); err != nil {
+			return err
+		}
+		q.Filters = append(q.Filters, Filter{Field: field, Op: OpEq, Value: v})
+	}
+	return nil
+}
+
+// QueryFields 数据表允许查询、排序与返回的列
+type QueryFields map[string]struct{}
+
+func NewQueryFields(columns ...string) QueryFields {
+	fields := make(QueryFields, len(columns))
+	for _, column := range columns {
+		fields[column] = struct{}{}
+	}
+	return fields
+}
+
+func (f QueryFields) check(field string) error {
+	if _, ok := f[field]; !ok {
+		return fmt.Errorf("%w: field %s is not queryable", ErrInvalidQuery, field)
+	}
+	return nil
+}
+
+// Apply 校验查询条件并构建WHERE、ORDER BY与SELECT子句
+func (q Query) Apply(tx *gorm.DB, fields QueryFields) (*gorm.DB, error) {
+	if len(q.Filters) > maxFilters {
+		return nil, fmt.Errorf("%w: too many filters, max %d", ErrInvalidQuery, maxFilters)
+	}
+	for _, filter := range q.Filters {
+		if err := fields.check(filter.Field); err != nil {
+			return nil, err
+		}
+		var err error
+		tx, err = applyFilter(tx, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, sort := range q.Sort {
+		if err := fields.check(sort.Field); err != nil {
+			return nil, err
+		}
+		if sort.Desc {
+			tx = tx.Order(sort.Field + " DESC")
+		} else {
+			tx = tx.Order(sort.Field)
+		}
+	}
+	if len(q.Fields) > 0 {
+		for _, field := range q.Fields {
+			if err := fields.check(field); err != nil {
+				return nil, err
+			}
+		}
+		tx = tx.Select(q.Fields)
+	}
+	return tx, nil
+}
+
+func applyFilter(tx *gorm.DB, filter Filter) (*gorm.DB, error) {
+	column := filter.Field
+	switch filter.Op {
+	case OpEq, "":
+		if !isScalar(filter.Value) {
+			return nil, fmt.Errorf("%w: %s: eq requires a scalar value", ErrInvalidQuery, column)
+		}
+		return tx.Where(column+" = ?", filter.Value), nil
+	case OpNe:
+		if !isScalar(filter.Value) {
+			return nil, fmt.Errorf("%w: %s: ne requires a scalar value", ErrInvalidQuery, column)
+		}
+		return tx.Where(column+" <> ?", filter.Value), nil
+	case OpGt:
+		if !isScalar(filter.Value) {
+			return nil, fmt.Errorf("%w: %s: gt requires a scalar value", ErrInvalidQuery, column)
+		}
+		return tx.Where(column+" > ?", filter.Value), nil
+	case OpLt:
+		if !isScalar(filter.Value) {
+			return nil, fmt.Errorf("%w: %s: lt requires a scalar value", ErrInvalidQuery, column)
+		}
+		return tx.Where(column+" < ?", filter.Value), nil
+	case OpBetween:
+		values, ok := scalarList(filter.Value)
+		if !ok || len(values) != 2 {
+			return nil, fmt.Errorf("%w: %s: between requires [from, to]", ErrInvalidQuery, column)
+		}
+		return tx.Where(column+" BETWEEN ? AND ?", values[0], values[1]), nil
+	case OpIn:
+		values, ok := scalarList(filter.Value)
+		if !ok || len(values) == 0 || len(values) > maxInValues {
+			return nil, fmt.Errorf("%w: %s: in requires 1 to %d values", ErrInvalidQuery, column, maxInValues)
+		}
+		return tx.Where(column+" IN ?", values), nil
+	case OpContains:
+		value, ok := filter.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: contains requires a string value", ErrInvalidQuery, column)
+		}
+		return tx.Where(column+" LIKE ?", "%"+escapeLike(value)+"%"), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported operator: %s", ErrInvalidQuery, filter.Op)
+	}
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case string, float64, bool, int, int64, json.Number:
+		return true
+	}
+	return false
+}
+
+func scalarList(value interface{}) ([]interface{}, bool) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	for _, v := range values {
+		if !isScalar(v) {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// escapeLike 转义LIKE中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"community-governance/db"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestQueryApply(t *testing.T) {
+	useTestDB(t)
+	cases := []struct {
+		name   string
+		fields QueryFields
+		query  string
+		sql    string //生成的SQL应包含的片段，为空时要求返回ErrInvalidQuery
+	}{
+		{"legacy map", fundQueryFields, `{"status":"active"}`, "WHERE status = "},
+		{"eq", fundQueryFields, `{"filters":[{"field":"name","op":"eq","value":"维修基金"}]}`, "WHERE name = "},
+		{"default op is eq", fundQueryFields, `{"filters":[{"field":"name","value":"维修基金"}]}`, "WHERE name = "},
+		{"between", fundQueryFields, `{"filters":[{"field":"establish_date","op":"between","value":["2024-01-01","2024-12-31"]}]}`, "establish_date BETWEEN "},
+		{"in", fundQueryFields, `{"filters":[{"field":"status","op":"in","value":["active","archived"]}]}`, "status IN ("},
+		{"contains", fundQueryFields, `{"filters":[{"field":"description","op":"contains","value":"50%"}]}`, "description LIKE "},
+		{"sort and fields", fundQueryFields, `{"sort":[{"field":"establish_date","desc":true}],"fields":["fund_id","name"]}`, "ORDER BY establish_date DESC"},
+		{"filter outside whitelist", memberQueryFields, `{"filters":[{"field":"password","op":"eq","value":"x"}]}`, ""},
+		{"legacy map outside whitelist", memberQueryFields, `{"password":"x"}`, ""},
+		{"injected column", fundQueryFields, `{"filters":[{"field":"1=1 OR name","op":"eq","value":"x"}]}`, ""},
+		{"sort outside whitelist", fundQueryFields, `{"sort":[{"field":"deleted_at"}]}`, ""},
+		{"injected sort", fundQueryFields, `{"sort":[{"field":"name; DROP TABLE fund"}]}`, ""},
+		{"fields outside whitelist", memberQueryFields, `{"fields":["member_id","password"]}`, ""},
+		{"unsupported operator", fundQueryFields, `{"filters":[{"field":"name","op":"regexp","value":"x"}]}`, ""},
+		{"eq with list", fundQueryFields, `{"filters":[{"field":"name","op":"eq","value":["x"]}]}`, ""},
+		{"between with one value", fundQueryFields, `{"filters":[{"field":"establish_date","op":"between","value":["2024-01-01"]}]}`, ""},
+		{"empty in", fundQueryFields, `{"filters":[{"field":"status","op":"in","value":[]}]}`, ""},
+		{"contains with number", fundQueryFields, `{"filters":[{"field":"name","op":"contains","value":1}]}`, ""},
+	}
+	for _, c := range cases {
+		var query Query
+		if err := json.Unmarshal([]byte(c.query), &query); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		tx, err := query.Apply(db.DB.Session(&gorm.Session{DryRun: true}).Model(&Fund{}), c.fields)
+		if c.sql == "" {
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("%s: got %v, want ErrInvalidQuery", c.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		sql := tx.Find(&[]Fund{}).Statement.SQL.String()
+		if !strings.Contains(sql, c.sql) {
+			t.Errorf("%s: sql %q does not contain %q", c.name, sql, c.sql)
+		}
+	}
+}
+
+func TestQueryApplyLimits(t *testing.T) {
+	useTestDB(t)
+	filters := make([]Filter, maxFilters+1)
+	for i := range filters {
+		filters[i] = Filter{Field: "name", Op: OpEq, Value: "x"}
+	}
+	if _, err := (Query{Filters: filters}).Apply(db.DB, fundQueryFields); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("%d filters: got %v, want ErrInvalidQuery", len(filters), err)
+	}
+	values := make([]interface{}, maxInValues+1)
+	for i := range values {
+		values[i] = "x"
+	}
+	query := Query{Filters: []Filter{{Field: "status", Op: OpIn, Value: values}}}
+	if _, err := query.Apply(db.DB, fundQueryFields); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("%d in values: got %v, want ErrInvalidQuery", len(values), err)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"plain":  "plain",
+		"50%":    `50\%`,
+		"a_b":    `a\_b`,
+		`c:\dir`: `c:\\dir`,
+	}
+	for value, want := range cases {
+		if got := escapeLike(value); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -3,34 +3,82 @@ package models
 import (
 	"community-governance/db"
 	"fmt"
+	"gorm.io/gorm"
 )
 
 // Fund 表对应的模型
 type Fund struct {
-	FundID            string `gorm:"primaryKey;type:varchar(64);not null" json:"fund_id"` // 款项ID
-	Description       string `gorm:"type:varchar(200);not null" json:"description"`       // 款项说明
-	Status            string `gorm:"type:varchar(10);not null" json:"status"`             // 款项状态
-	Name              string `gorm:"type:varchar(20);not null" json:"name"`               // 款项名称
-	Source            string `gorm:"type:varchar(10);not null" json:"source"`             // 款项来源
-	SourceDescription string `gorm:"type:varchar(100)" json:"source_description"`         // 款项来源说明
-	TotalAmount       string `gorm:"type:varchar(20);not null" json:"total_amount"`       // 款项的总金额
-	CurrentBalance    string `gorm:"type:varchar(20);not null" json:"current_balance"`    // 当前款项余额
-	Manager           string `gorm:"type:varchar(64);not null" json:"manager"`            // 负责人
-	EstablishDate     string `gorm:"type:varchar(26);not null" json:"establish_date"`     // 成立时间
+	FundID            string         `gorm:"primaryKey;type:varchar(64);not null" json:"fund_id"` // 款项ID
+	Description       string         `gorm:"type:varchar(200);not null" json:"description"`       // 款项说明
+	Status            string         `gorm:"type:varchar(10);not null" json:"status"`             // 款项状态
+	Name              string         `gorm:"type:varchar(20);not null" json:"name"`               // 款项名称
+	Source            string         `gorm:"type:varchar(10);not null" json:"source"`             // 款项来源
+	SourceDescription string         `gorm:"type:varchar(100)" json:"source_description"`         // 款项来源说明
+	TotalAmount       Money          `gorm:"type:decimal(20,2);not null" json:"total_amount"`     // 款项的期初金额
+	CurrentBalance    Money          `gorm:"-" json:"current_balance"`                            // 当前款项余额，由资金账户的过账明细汇总得出
+	Manager           string         `gorm:"type:varchar(64);not null" json:"manager"`            // 负责人
+	EstablishDate     string         `gorm:"type:varchar(26);not null" json:"establish_date"`     // 成立时间
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`                                      // 删除时间
 }
 
 func (f *Fund) TableName() string {
 	return "fund"
 }
-func CreateFunds(funds *Fund) error {
-	return db.DB.Create(&funds).Error
+
+// fundQueryFields 允许条件查询的列
+var fundQueryFields = NewQueryFields("fund_id", "description", "status", "name", "source", "source_description", "total_amount", "manager", "establish_date")
+
+// fillFundBalances 由资金账户汇总款项的当前余额，一次分组查询填充全部款项
+func fillFundBalances(tx *gorm.DB, funds []Fund) error {
+	if len(funds) == 0 {
+		return nil
+	}
+	accounts := make([]string, len(funds))
+	for i := range funds {
+		accounts[i] = FundAccountID(funds[i].FundID, AccountTypeAsset)
+	}
+	var balances []struct {
+		AccountID string
+		Balance   Money
+	}
+	err := tx.Model(&Posting{}).Where("account_id IN ?", accounts).Group("account_id").
+		Select("account_id, COALESCE(SUM(amount), 0) AS balance").Scan(&balances).Error
+	if err != nil {
+		return err
+	}
+	byAccount := make(map[string]Money, len(balances))
+	for _, b := range balances {
+		byAccount[b.AccountID] = b.Balance
+	}
+	for i := range funds {
+		funds[i].CurrentBalance = byAccount[accounts[i]]
+	}
+	return nil
+}
+
+// CreateFunds 创建款项并开设账户，期初金额记入资金账户
+// anchor在款项与账户写入后、事务提交前执行，用于将款项上链，上链失败时款项与账户一并回滚
+func CreateFunds(funds *Fund, recorder, createTime string, anchor func() error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(funds).Error; err != nil {
+			return err
+		}
+		if err := openFundLedger(tx, funds.FundID, funds.TotalAmount, recorder, createTime); err != nil {
+			return err
+		}
+		funds.CurrentBalance = funds.TotalAmount
+		return anchor()
+	})
 }
 
 // GetFundByID 查询公告
 func GetFundByID(id string) (*Fund, error) {
-	var Fund Fund
-	err := db.DB.First(&Fund, "fund_id = ?", id).Error
-	return &Fund, err
+	funds := make([]Fund, 1)
+	if err := db.DB.First(&funds[0], "fund_id = ?", id).Error; err != nil {
+		return &funds[0], err
+	}
+	err := fillFundBalances(db.DB, funds)
+	return &funds[0], err
 }
 
 // UpdateFund 更新公告
@@ -38,7 +86,7 @@ func UpdateFund(id string, updatedFields interface{}) error {
 	return db.DB.Model(&Fund{}).Where("fund_id = ?", id).Updates(updatedFields).Error
 }
 
-// DeleteFund 删除公告
+// DeleteFund 删除款项
 func DeleteFund(id string) error {
 
 	result := db.DB.Delete(&Fund{}, "fund_id = ?", id)
@@ -56,19 +104,31 @@ func DeleteFund(id string) error {
 	return nil
 }
 
-// GetAllFundsWithPagination 获取所有公告并分页
-func GetAllFundsWithPagination(page, pageSize int) ([]Fund, error) {
-	var fund []Fund
-	err := db.DB.Offset((page - 1) * pageSize).Limit(pageSize).Find(&fund).Error
-	return fund, err
+// GetAllFundIDs 查询全部款项ID，包含已删除的款项
+func GetAllFundIDs() ([]string, error) {
+	var ids []string
+	err := db.DB.Unscoped().Model(&Fund{}).Order("fund_id").Pluck("fund_id", &ids).Error
+	return ids, err
 }
-func GetAllFundsWithConditions(conditions map[string]interface{}, page, pageSize int) ([]Fund, error) {
-	var fund []Fund
 
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
+// GetAllFundsWithPagination 获取所有公告并分页
+func GetAllFundsWithPagination(page *Page) ([]Fund, error) {
+	var funds []Fund
+	if err := paginate(db.DB.Model(&Fund{}), page, &funds); err != nil {
+		return nil, err
+	}
+	return funds, fillFundBalances(db.DB, funds)
+}
+func GetAllFundsWithConditions(query Query, page *Page) ([]Fund, error) {
+	var funds []Fund
 
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&fund).Error
-	return fund, err
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&Fund{}), fundQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	if err := paginate(tx, page, &funds); err != nil {
+		return nil, err
+	}
+	return funds, fillFundBalances(db.DB, funds)
 }
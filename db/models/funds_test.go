@@ -0,0 +1,48 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"testing"
+)
+
+func TestCreateFunds(t *testing.T) {
+	cases := []struct {
+		name      string
+		anchorErr error
+		opening   Money
+		accounts  int64 //提交后款项的账户数，0表示款项被回滚
+	}{
+		{"fund with opening balance", nil, 10000, 4},
+		{"fund without opening balance", nil, 0, 4},
+		{"rolled back when anchor fails", errAnchor, 10000, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			fund := &Fund{FundID: "f1", Name: "维修基金", Status: "active", Source: "业主", TotalAmount: c.opening, Manager: "u1", EstablishDate: "2024-01-01"}
+			err := CreateFunds(fund, "u1", "2024-01-01 00:00:00", func() error { return c.anchorErr })
+			if !errors.Is(err, c.anchorErr) {
+				t.Fatalf("got %v, want %v", err, c.anchorErr)
+			}
+			var accounts int64
+			db.DB.Model(&LedgerAccount{}).Where("fund_id = ?", "f1").Count(&accounts)
+			if accounts != c.accounts {
+				t.Errorf("%d accounts, want %d", accounts, c.accounts)
+			}
+			_, err = GetFundByID("f1")
+			if created := err == nil; created != (c.accounts > 0) {
+				t.Errorf("fund created %v, want %v", created, c.accounts > 0)
+			}
+			if c.anchorErr != nil {
+				if count := countEntries(t, "fund_id = ?", "f1"); count != 0 {
+					t.Errorf("%d entries left after rollback", count)
+				}
+				return
+			}
+			if balance := fundBalance(t, "f1"); balance != c.opening {
+				t.Errorf("balance %s, want %s", balance, c.opening)
+			}
+		})
+	}
+}
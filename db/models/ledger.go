@@ -0,0 +1,326 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 账户类型，资产与费用类账户借方为正，收入与权益类账户贷方为负
+const (
+	AccountTypeAsset   = "asset"   // 款项资金
+	AccountTypeIncome  = "income"  // 款项收入
+	AccountTypeExpense = "expense" // 款项支出
+	AccountTypeEquity  = "equity"  // 款项期初资金
+)
+
+// 分录类型，收入与支出与链上记录类型保持一致
+const (
+	EntryTypeIncome  = "0"       // 收入
+	EntryTypeExpense = "1"       // 支出
+	EntryTypeOpening = "opening" // 期初资金
+)
+
+// TransferCategory 款项间划转分录的科目
+const TransferCategory = "款项划转"
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// LedgerAccount 账户表，每个款项拥有资金、收入、支出与期初资金四个账户
+type LedgerAccount struct {
+	AccountID  string `gorm:"primaryKey;type:varchar(80);not null" json:"account_id"` // 账户ID
+	FundID     string `gorm:"type:varchar(64);not null;index" json:"fund_id"`         // 所属款项
+	Type       string `gorm:"type:varchar(10);not null" json:"type"`                  // 账户类型
+	CreateTime string `gorm:"type:varchar(26);not null" json:"create_time"`           // 创建时间
+}
+
+func (LedgerAccount) TableName() string {
+	return "ledger_account"
+}
+
+// JournalEntry 分录表，每笔收支记录对应一条分录
+type JournalEntry struct {
+	EntryID    string    `gorm:"primaryKey;type:varchar(64);not null" json:"entry_id"`                                           // 分录ID
+	FundID     string    `gorm:"type:varchar(64);not null;index;index:idx_journal_entry_fund_time,priority:1" json:"fund_id"`    // 所属款项
+	Type       string    `gorm:"type:varchar(10);not null" json:"type"`                                                          // 分录类型
+	Source     string    `gorm:"type:varchar(100)" json:"source"`                                                                // 金额来源
+	Explain    string    `gorm:"type:varchar(200)" json:"explain"`                                                               // 说明
+	Category   string    `gorm:"type:varchar(50);index" json:"category"`                                                         // 预算科目
+	InfoHash   string    `gorm:"type:varchar(64);index" json:"info_hash"`                                                        // 证明文件SHA-256
+	Reference  string    `gorm:"type:varchar(100)" json:"reference"`                                                             // 银行流水号，用于对账
+	TransferID string    `gorm:"type:varchar(64);not null;default:'';index" json:"transfer_id"`                                  // 款项间划转ID，非划转分录为空
+	Corrects   string    `gorm:"type:varchar(64)" json:"corrects"`                                                               // 更正的原记录所在链上交易ID
+	Recorder   string    `gorm:"type:varchar(64);not null" json:"recorder"`                                                      // 记录人
+	EntryTime  string    `gorm:"type:varchar(26);not null;index;index:idx_journal_entry_fund_time,priority:2" json:"entry_time"` // 记账时间，与款项组成游标分页的索引
+	Postings   []Posting `gorm:"foreignKey:EntryID" json:"postings"`                                                             // 过账明细
+}
+
+func (JournalEntry) TableName() string {
+	return "journal_entry"
+}
+
+// Posting 过账明细表，借方为正、贷方为负，同一分录的金额之和为0
+type Posting struct {
+	PostingID uint64 `gorm:"primaryKey;autoIncrement" json:"posting_id"`        // 明细ID
+	EntryID   string `gorm:"type:varchar(64);not null;index" json:"entry_id"`   // 分录ID
+	AccountID string `gorm:"type:varchar(80);not null;index" json:"account_id"` // 账户ID
+	Amount    Money  `gorm:"type:decimal(20,2);not null" json:"amount"`         // 金额
+}
+
+func (Posting) TableName() string {
+	return "posting"
+}
+
+// FundAccountID 款项指定类型账户的ID
+func FundAccountID(fundID, accountType string) string {
+	return fundID + ":" + accountType
+}
+
+// OpenFundLedger 为款项开设账户，期初资金大于0时记一笔期初分录
+func OpenFundLedger(fundID string, opening Money, recorder, createTime string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return openFundLedger(tx, fundID, opening, recorder, createTime)
+	})
+}
+
+func openFundLedger(tx *gorm.DB, fundID string, opening Money, recorder, createTime string) error {
+	if opening < 0 {
+		return fmt.Errorf("%w: opening balance must not be negative", ErrInvalidMoney)
+	}
+	for _, accountType := range []string{AccountTypeAsset, AccountTypeIncome, AccountTypeExpense, AccountTypeEquity} {
+		account := LedgerAccount{
+			AccountID:  FundAccountID(fundID, accountType),
+			FundID:     fundID,
+			Type:       accountType,
+			CreateTime: createTime,
+		}
+		if err := tx.Create(&account).Error; err != nil {
+			return fmt.Errorf("failed to create account %s: %v", account.AccountID, err)
+		}
+	}
+	if opening == 0 {
+		return nil
+	}
+	entry := JournalEntry{
+		EntryID:   fundID + ":" + EntryTypeOpening,
+		FundID:    fundID,
+		Type:      EntryTypeOpening,
+		Explain:   "期初资金",
+		Recorder:  recorder,
+		EntryTime: createTime,
+	}
+	return postEntry(tx, &entry, []Posting{
+		{AccountID: FundAccountID(fundID, AccountTypeAsset), Amount: opening},
+		{AccountID: FundAccountID(fundID, AccountTypeEquity), Amount: -opening},
+	})
+}
+
+// PostFundRecord 记一笔款项收支分录，支出超过余额时返回ErrInsufficientBalance
+// 支出超出款项年度预算时，拦截模式返回ErrBudgetExceeded，提醒模式返回超支提醒
+// anchor在分录写入后、事务提交前执行，用于同步上链，上链失败时分录一并回滚
+// anchor执行期间持有该款项资金账户的行锁，同一款项的记账按上链顺序串行，其他款项不受影响；
+// 锁的持有时间受网关的背书与提交超时限制，同一款项并发记账时后到的请求最长等待至数据库的锁等待超时
+func PostFundRecord(entry *JournalEntry, amount Money, anchor func() error) (*BudgetWarning, error) {
+	var warning *BudgetWarning
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if warning, err = postFundRecord(tx, entry, amount); err != nil {
+			return err
+		}
+		if anchor != nil {
+			return anchor()
+		}
+		return nil
+	})
+	return warning, err
+}
+
+// PostFundTransfer 记一笔款项间划转，转出款项记支出分录、转入款项记收入分录，两条分录共用transferID
+// 转出款项余额不足时返回ErrInsufficientBalance，anchor在分录写入后执行，用于同步上链
+func PostFundTransfer(transferID, from, to string, amount Money, explain, recorder, entryTime string, anchor func() error) error {
+	if from == to {
+		return fmt.Errorf("cannot transfer within the same fund %s", from)
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		//按账户ID顺序锁定两个资金账户，避免相向划转时死锁
+		accounts := []string{FundAccountID(from, AccountTypeAsset), FundAccountID(to, AccountTypeAsset)}
+		if accounts[0] > accounts[1] {
+			accounts[0], accounts[1] = accounts[1], accounts[0]
+		}
+		for _, accountID := range accounts {
+			var account LedgerAccount
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "account_id = ?", accountID).Error; err != nil {
+				return fmt.Errorf("failed to get account %s: %v", accountID, err)
+			}
+		}
+		out := JournalEntry{
+			EntryID:    transferID + ":out",
+			FundID:     from,
+			Type:       EntryTypeExpense,
+			Source:     to,
+			Explain:    explain,
+			Category:   TransferCategory,
+			Recorder:   recorder,
+			EntryTime:  entryTime,
+			TransferID: transferID,
+		}
+		if _, err := postFundRecord(tx, &out, amount); err != nil {
+			return err
+		}
+		in := out
+		in.EntryID = transferID + ":in"
+		in.FundID = to
+		in.Type = EntryTypeIncome
+		in.Source = from
+		in.Postings = nil
+		if _, err := postFundRecord(tx, &in, amount); err != nil {
+			return err
+		}
+		if anchor != nil {
+			return anchor()
+		}
+		return nil
+	})
+}
+
+// GetTransferEntries 查询划转的两条分录及过账明细
+func GetTransferEntries(transferID string) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	err := db.DB.Preload("Postings").Where("transfer_id = ?", transferID).Order("type desc").Find(&entries).Error
+	return entries, err
+}
+
+// fundRecordPostings 按分录类型生成收支分录的过账明细
+func fundRecordPostings(entry *JournalEntry, amount Money) ([]Posting, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidMoney)
+	}
+	cash := FundAccountID(entry.FundID, AccountTypeAsset)
+	switch entry.Type {
+	case EntryTypeIncome:
+		return []Posting{
+			{AccountID: cash, Amount: amount},
+			{AccountID: FundAccountID(entry.FundID, AccountTypeIncome), Amount: -amount},
+		}, nil
+	case EntryTypeExpense:
+		return []Posting{
+			{AccountID: FundAccountID(entry.FundID, AccountTypeExpense), Amount: amount},
+			{AccountID: cash, Amount: -amount},
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid entry type: %s", entry.Type)
+}
+
+// postFundRecord 锁定资金账户后校验余额与预算并写入分录
+func postFundRecord(tx *gorm.DB, entry *JournalEntry, amount Money) (*BudgetWarning, error) {
+	postings, err := fundRecordPostings(entry, amount)
+	if err != nil {
+		return nil, err
+	}
+	warning, err := checkFundRecord(tx, entry, amount)
+	if err != nil {
+		return nil, err
+	}
+	return warning, postEntry(tx, entry, postings)
+}
+
+// checkFundRecord 锁定资金账户并校验支出的余额与预算，锁持有至事务结束，之后在同一事务中过账不会再因余额或预算失败
+func checkFundRecord(tx *gorm.DB, entry *JournalEntry, amount Money) (*BudgetWarning, error) {
+	entry.Category = CategoryOf(entry.Category)
+	// 锁定资金账户，保证余额与预算校验和过账串行执行
+	cash := FundAccountID(entry.FundID, AccountTypeAsset)
+	var account LedgerAccount
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, "account_id = ?", cash).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account %s: %v", cash, err)
+	}
+	if entry.Type != EntryTypeExpense {
+		return nil, nil
+	}
+	balance, err := getAccountBalance(tx, cash)
+	if err != nil {
+		return nil, err
+	}
+	if balance < amount {
+		return nil, fmt.Errorf("%w: balance %s, expense %s", ErrInsufficientBalance, balance, amount)
+	}
+	//款项间划转不占用预算
+	if entry.TransferID != "" {
+		return nil, nil
+	}
+	return checkBudget(tx, entry.FundID, entry.Category, entry.EntryTime, amount)
+}
+
+// postEntry 写入分录与过账明细，明细金额之和必须为0
+func postEntry(tx *gorm.DB, entry *JournalEntry, postings []Posting) error {
+	if len(postings) < 2 {
+		return fmt.Errorf("entry %s must have at least two postings", entry.EntryID)
+	}
+	var sum Money
+	for i := range postings {
+		postings[i].EntryID = entry.EntryID
+		sum += postings[i].Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("entry %s is unbalanced: %s", entry.EntryID, sum)
+	}
+	entry.Postings = postings
+	return tx.Create(entry).Error
+}
+
+// GetAccountBalance 账户余额，为全部过账明细之和
+func GetAccountBalance(accountID string) (Money, error) {
+	return getAccountBalance(db.DB, accountID)
+}
+
+func getAccountBalance(tx *gorm.DB, accountID string) (Money, error) {
+	var balance Money
+	err := tx.Model(&Posting{}).Where("account_id = ?", accountID).Select("COALESCE(SUM(amount), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// GetFundJournalWithPagination 分页获取款项的分录及过账明细
+func GetFundJournalWithPagination(fundID string, page *Page) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	tx := db.DB.Model(&JournalEntry{}).Where("fund_id = ?", fundID).Order("entry_time desc").Order("entry_id desc")
+	if err := paginate(tx, page, &entries); err != nil {
+		return entries, err
+	}
+	return entries, loadPostings(entries)
+}
+
+// GetFundJournalByCursor 按(记账时间, 分录ID)倒序游标分页获取款项的分录及过账明细，用于浏览大量收支记录
+func GetFundJournalByCursor(fundID, cursor string, limit int) ([]JournalEntry, string, bool, error) {
+	tx := db.DB.Model(&JournalEntry{}).Where("fund_id = ?", fundID)
+	entries, next, hasNext, err := keysetPaginate(tx, "entry_time", "entry_id", cursor, limit, func(entry JournalEntry) (string, string) {
+		return entry.EntryTime, entry.EntryID
+	})
+	if err != nil {
+		return nil, "", false, err
+	}
+	return entries, next, hasNext, loadPostings(entries)
+}
+
+// loadPostings 为一页分录填充过账明细
+func loadPostings(entries []JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	entryIDs := make([]string, len(entries))
+	index := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		entryIDs[i] = entry.EntryID
+		index[entry.EntryID] = i
+	}
+	var postings []Posting
+	if err := db.DB.Where("entry_id IN ?", entryIDs).Order("posting_id").Find(&postings).Error; err != nil {
+		return err
+	}
+	for _, posting := range postings {
+		i := index[posting.EntryID]
+		entries[i].Postings = append(entries[i].Postings, posting)
+	}
+	return nil
+}
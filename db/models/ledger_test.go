@@ -0,0 +1,252 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errAnchor = errors.New("anchor failed")
+
+// openTestFund 开设款项账户并记期初资金
+func openTestFund(t *testing.T, fundID string, opening Money) {
+	t.Helper()
+	if err := OpenFundLedger(fundID, opening, "u1", "2024-01-01 00:00:00"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fundBalance(t *testing.T, fundID string) Money {
+	t.Helper()
+	balance, err := GetAccountBalance(FundAccountID(fundID, AccountTypeAsset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance
+}
+
+func countEntries(t *testing.T, where string, args ...interface{}) int64 {
+	t.Helper()
+	var count int64
+	if err := db.DB.Model(&JournalEntry{}).Where(where, args...).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestPostFundRecord(t *testing.T) {
+	cases := []struct {
+		name      string
+		fundID    string
+		entryType string
+		amount    Money
+		anchorErr error
+		err       error //为nil时要求成功
+		anchored  bool  //anchor是否被调用
+		balance   Money
+	}{
+		{"income", "f1", EntryTypeIncome, 5000, nil, nil, true, 15000},
+		{"expense", "f1", EntryTypeExpense, 3000, nil, nil, true, 7000},
+		{"expense equal to balance", "f1", EntryTypeExpense, 10000, nil, nil, true, 0},
+		{"expense over balance", "f1", EntryTypeExpense, 10001, nil, ErrInsufficientBalance, false, 10000},
+		{"zero amount", "f1", EntryTypeIncome, 0, nil, ErrInvalidMoney, false, 10000},
+		{"income rolled back when anchor fails", "f1", EntryTypeIncome, 5000, errAnchor, errAnchor, true, 10000},
+		{"expense rolled back when anchor fails", "f1", EntryTypeExpense, 3000, errAnchor, errAnchor, true, 10000},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			openTestFund(t, "f1", 10000)
+			anchored := false
+			entry := &JournalEntry{EntryID: "e1", FundID: c.fundID, Type: c.entryType, Recorder: "u1", EntryTime: "2024-03-01 10:00:00"}
+			_, err := PostFundRecord(entry, c.amount, func() error {
+				anchored = true
+				return c.anchorErr
+			})
+			if c.err == nil && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			if anchored != c.anchored {
+				t.Errorf("anchored %v, want %v", anchored, c.anchored)
+			}
+			if balance := fundBalance(t, "f1"); balance != c.balance {
+				t.Errorf("balance %s, want %s", balance, c.balance)
+			}
+			want := int64(0)
+			if c.err == nil {
+				want = 1
+			}
+			if count := countEntries(t, "entry_id = ?", "e1"); count != want {
+				t.Errorf("%d entries, want %d", count, want)
+			}
+			var postings int64
+			db.DB.Model(&Posting{}).Where("entry_id = ?", "e1").Count(&postings)
+			if postings != want*2 {
+				t.Errorf("%d postings, want %d", postings, want*2)
+			}
+		})
+	}
+}
+
+func TestPostFundRecordBudget(t *testing.T) {
+	cases := []struct {
+		name     string
+		mode     string
+		category string
+		amount   Money
+		err      error
+		over     Money //超支提醒的超支金额，0表示没有提醒
+	}{
+		{"within budget", BudgetModeBlock, "维修", 2000, nil, 0},
+		{"block mode rejects overspend", BudgetModeBlock, "维修", 3001, ErrBudgetExceeded, 0},
+		{"warn mode records overspend", BudgetModeWarn, "维修", 3001, nil, 1},
+		{"category without line", BudgetModeBlock, "绿化", 1, ErrBudgetExceeded, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			openTestFund(t, "f1", 10000)
+			budget := Budget{BudgetID: "b1", FundID: "f1", Year: 2024, Name: "2024", Mode: c.mode, Status: "active",
+				Creator: "u1", CreateTime: "2024-01-01 00:00:00", Lines: []BudgetLine{{Category: "维修", Amount: 5000}}}
+			if err := db.DB.Create(&budget).Error; err != nil {
+				t.Fatal(err)
+			}
+			//已有2000的维修支出
+			spent := &JournalEntry{EntryID: "e0", FundID: "f1", Type: EntryTypeExpense, Category: "维修", Recorder: "u1", EntryTime: "2024-02-01 10:00:00"}
+			if _, err := PostFundRecord(spent, 2000, nil); err != nil {
+				t.Fatal(err)
+			}
+			entry := &JournalEntry{EntryID: "e1", FundID: "f1", Type: EntryTypeExpense, Category: c.category, Recorder: "u1", EntryTime: "2024-03-01 10:00:00"}
+			warning, err := PostFundRecord(entry, c.amount, nil)
+			if c.err == nil && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+			switch {
+			case c.over == 0 && warning != nil:
+				t.Errorf("unexpected warning %+v", warning)
+			case c.over != 0 && (warning == nil || warning.Over != c.over):
+				t.Errorf("warning %+v, want over %s", warning, c.over)
+			}
+		})
+	}
+}
+
+func TestPostFundTransfer(t *testing.T) {
+	cases := []struct {
+		name      string
+		from, to  string
+		amount    Money
+		anchorErr error
+		err       string   //为空时要求成功
+		balances  [2]Money //转出与转入款项的余额
+	}{
+		{"transfer", "f1", "f2", 4000, nil, "", [2]Money{6000, 4000}},
+		{"whole balance", "f1", "f2", 10000, nil, "", [2]Money{0, 10000}},
+		{"insufficient balance", "f1", "f2", 10001, nil, ErrInsufficientBalance.Error(), [2]Money{10000, 0}},
+		{"rolled back when anchor fails", "f1", "f2", 4000, errAnchor, errAnchor.Error(), [2]Money{10000, 0}},
+		{"unknown fund", "f1", "f3", 4000, nil, "failed to get account f3:asset", [2]Money{10000, 0}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			openTestFund(t, "f1", 10000)
+			openTestFund(t, "f2", 0)
+			err := PostFundTransfer("t1", c.from, c.to, c.amount, "划转", "u1", "2024-03-01 10:00:00", func() error { return c.anchorErr })
+			if c.err == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+				t.Fatalf("got %v, want error containing %q", err, c.err)
+			}
+			if balance := fundBalance(t, "f1"); balance != c.balances[0] {
+				t.Errorf("f1 balance %s, want %s", balance, c.balances[0])
+			}
+			if balance := fundBalance(t, "f2"); balance != c.balances[1] {
+				t.Errorf("f2 balance %s, want %s", balance, c.balances[1])
+			}
+			entries, err := GetTransferEntries("t1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.err != "" {
+				if len(entries) != 0 {
+					t.Errorf("%d entries left after failed transfer", len(entries))
+				}
+				return
+			}
+			if len(entries) != 2 {
+				t.Fatalf("%d entries, want 2", len(entries))
+			}
+			for _, entry := range entries {
+				if entry.Category != TransferCategory || len(entry.Postings) != 2 {
+					t.Errorf("entry %s: category %s with %d postings", entry.EntryID, entry.Category, len(entry.Postings))
+				}
+			}
+		})
+	}
+	t.Run("same fund", func(t *testing.T) {
+		if err := PostFundTransfer("t1", "f1", "f1", 100, "", "u1", "2024-03-01 10:00:00", nil); err == nil {
+			t.Error("want error for transfer within the same fund")
+		}
+	})
+}
+
+func TestGetFundJournalByCursor(t *testing.T) {
+	useTestDB(t)
+	openTestFund(t, "f1", 10000)
+	openTestFund(t, "f2", 10000)
+	//e2与e3记账时间相同，按分录ID倒序
+	for _, e := range []struct{ id, fundID, entryTime string }{
+		{"e1", "f1", "2024-03-01 10:00:00"},
+		{"e2", "f1", "2024-03-02 10:00:00"},
+		{"e3", "f1", "2024-03-02 10:00:00"},
+		{"e4", "f1", "2024-03-03 10:00:00"},
+		{"e5", "f2", "2024-03-04 10:00:00"},
+	} {
+		entry := &JournalEntry{EntryID: e.id, FundID: e.fundID, Type: EntryTypeIncome, Recorder: "u1", EntryTime: e.entryTime}
+		if _, err := PostFundRecord(entry, 100, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//期初资金分录的记账时间最早，排在最后
+	want := []string{"e4", "e3", "e2", "e1", "f1:" + EntryTypeOpening}
+	for _, limit := range []int{1, 2, 5, 10} {
+		var got []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			entries, next, hasNext, err := GetFundJournalByCursor("f1", cursor, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) > limit || pages > len(want) {
+				t.Fatalf("limit %d: page of %d entries after %d pages", limit, len(entries), pages)
+			}
+			for _, entry := range entries {
+				if len(entry.Postings) != 2 {
+					t.Errorf("entry %s has %d postings", entry.EntryID, len(entry.Postings))
+				}
+				got = append(got, entry.EntryID)
+			}
+			if hasNext != (next != "") {
+				t.Errorf("limit %d: hasNext %v with cursor %q", limit, hasNext, next)
+			}
+			if !hasNext {
+				break
+			}
+			cursor = next
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("limit %d: got %v, want %v", limit, got, want)
+		}
+	}
+	if _, _, _, err := GetFundJournalByCursor("f1", "not a cursor", 10); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("got %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,440 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// 维护对象类型
+const (
+	MaintenanceTargetAsset    = "asset"    // 资产
+	MaintenanceTargetFacility = "facility" // 公共设施
+)
+
+// 维护计划的排期方式
+const (
+	MaintenanceModeInterval = "interval" // 按间隔，上次完成后间隔天数到期
+	MaintenanceModeCalendar = "calendar" // 按日历，按月度、季度或年度周期固定到期
+)
+
+// MaintenancePlanStatusActive 执行中的维护计划，归档后不再生成工单
+const MaintenancePlanStatusActive = "active"
+
+// 工单状态
+const (
+	WorkOrderStatusOpen       = "open"        // 待开工
+	WorkOrderStatusInProgress = "in_progress" // 维护中
+	WorkOrderStatusCompleted  = "completed"   // 已完成
+	WorkOrderStatusCancelled  = "cancelled"   // 已取消
+)
+
+// 公共设施状态
+const (
+	FacilityStatusUse         = "use"         // 使用中
+	FacilityStatusMaintenance = "maintenance" // 维护中
+)
+
+// MaintenanceCategory 维护费用分录的科目
+const MaintenanceCategory = "维护"
+
+var (
+	ErrInvalidPlan       = errors.New("invalid maintenance plan")
+	ErrWorkOrderState    = errors.New("illegal work order status")
+	ErrInvalidWorkEntry  = errors.New("invalid work order expense")
+	ErrWorkEntryLinked   = errors.New("expense is linked to another work order")
+	ErrTargetUnavailable = errors.New("maintenance target is not available")
+)
+
+// MaintenancePlan 预防性维护计划，到期时为资产或设施生成维护工单
+type MaintenancePlan struct {
+	PlanID       string         `gorm:"primaryKey;type:varchar(64);not null" json:"plan_id"` // 计划ID
+	TargetType   string         `gorm:"type:varchar(10);not null" json:"target_type"`        // 维护对象类型
+	TargetID     string         `gorm:"type:varchar(64);not null;index" json:"target_id"`    // 资产ID或设施ID
+	Title        string         `gorm:"type:varchar(100);not null" json:"title"`             // 维护内容
+	Description  string         `gorm:"type:varchar(200)" json:"description"`                // 说明
+	Mode         string         `gorm:"type:varchar(10);not null" json:"mode"`               // 排期方式
+	IntervalDays int            `gorm:"not null" json:"interval_days"`                       // 按间隔排期时的间隔天数
+	Cycle        string         `gorm:"type:varchar(10)" json:"cycle"`                       // 按日历排期时的周期
+	NextDue      string         `gorm:"type:varchar(10);not null;index" json:"next_due"`     // 下次到期日期
+	Assignee     string         `gorm:"type:varchar(64)" json:"assignee"`                    // 默认负责人
+	FundID       string         `gorm:"type:varchar(64)" json:"fund_id"`                     // 维护费用默认计入的款项
+	Status       string         `gorm:"type:varchar(10);not null" json:"status"`             // 计划状态
+	Creator      string         `gorm:"type:varchar(64);not null" json:"creator"`            // 创建人
+	CreateTime   string         `gorm:"type:varchar(26);not null" json:"create_time"`        // 创建时间
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (MaintenancePlan) TableName() string {
+	return "maintenance_plan"
+}
+
+// WorkOrder 维护工单，开工后资产或设施在链上进入维护状态，完成时登记费用
+type WorkOrder struct {
+	OrderID      string `gorm:"primaryKey;type:varchar(64);not null" json:"order_id"`                     // 工单ID
+	PlanID       string `gorm:"type:varchar(64);not null;uniqueIndex:idx_work_order_due" json:"plan_id"`  // 维护计划ID
+	TargetType   string `gorm:"type:varchar(10);not null" json:"target_type"`                             // 维护对象类型
+	TargetID     string `gorm:"type:varchar(64);not null;index" json:"target_id"`                         // 资产ID或设施ID
+	Title        string `gorm:"type:varchar(100);not null" json:"title"`                                  // 维护内容
+	DueDate      string `gorm:"type:varchar(10);not null;uniqueIndex:idx_work_order_due" json:"due_date"` // 到期日期
+	Assignee     string `gorm:"type:varchar(64);index" json:"assignee"`                                   // 负责人
+	Status       string `gorm:"type:varchar(12);not null;index" json:"status"`                            // 工单状态
+	StartTime    string `gorm:"type:varchar(26)" json:"start_time"`                                       // 开工时间
+	CompleteTime string `gorm:"type:varchar(26)" json:"complete_time"`                                    // 完成或取消时间
+	Cost         Money  `gorm:"type:decimal(20,2);not null;default:0" json:"cost"`                        // 维护费用
+	EntryID      string `gorm:"type:varchar(64);index" json:"entry_id"`                                   // 费用对应的支出分录ID
+	Result       string `gorm:"type:varchar(200)" json:"result"`                                          // 维护结果或取消原因
+	CreateTime   string `gorm:"type:varchar(26);not null" json:"create_time"`                             // 创建时间
+}
+
+func (WorkOrder) TableName() string {
+	return "work_order"
+}
+
+// WorkOrderCompletion 完成工单时登记的结果与费用
+// Entry不为nil时新记一笔支出分录，EntryID不为空时关联已入账的支出分录，两者都为空时没有费用
+type WorkOrderCompletion struct {
+	Result       string
+	CompleteTime string
+	Entry        *JournalEntry
+	Cost         Money
+	EntryID      string
+}
+
+// CheckMaintenancePlan 校验维护对象、排期方式与到期日期
+func CheckMaintenancePlan(plan MaintenancePlan) error {
+	if plan.TargetType != MaintenanceTargetAsset && plan.TargetType != MaintenanceTargetFacility {
+		return fmt.Errorf("%w: target type %s", ErrInvalidPlan, plan.TargetType)
+	}
+	switch plan.Mode {
+	case MaintenanceModeInterval:
+		if plan.IntervalDays <= 0 {
+			return fmt.Errorf("%w: interval days must be positive", ErrInvalidPlan)
+		}
+	case MaintenanceModeCalendar:
+		if _, ok := cycleMonths[plan.Cycle]; !ok {
+			return fmt.Errorf("%w: cycle %s", ErrInvalidPlan, plan.Cycle)
+		}
+	default:
+		return fmt.Errorf("%w: mode %s", ErrInvalidPlan, plan.Mode)
+	}
+	if _, err := time.Parse("2006-01-02", plan.NextDue); err != nil {
+		return fmt.Errorf("%w: next due %s", ErrInvalidPlan, plan.NextDue)
+	}
+	return nil
+}
+
+// cycleMonths 日历周期对应的月数，与收费标准的计费周期一致
+var cycleMonths = map[string]int{
+	FeeCycleMonthly:   1,
+	FeeCycleQuarterly: 3,
+	FeeCycleYearly:    12,
+}
+
+// nextDueDate 工单完成后计划的下次到期日期
+// 按间隔排期时从完成日期起算，按日历排期时从原到期日期按周期顺延至完成日期之后
+func nextDueDate(plan MaintenancePlan, completeDate string) string {
+	done, _ := time.Parse("2006-01-02", completeDate[:10])
+	if plan.Mode == MaintenanceModeInterval {
+		return done.AddDate(0, 0, plan.IntervalDays).Format("2006-01-02")
+	}
+	due, _ := time.Parse("2006-01-02", plan.NextDue)
+	months := cycleMonths[plan.Cycle]
+	for n := months; ; n += months {
+		next := due.AddDate(0, n, 0)
+		if next.After(done) {
+			return next.Format("2006-01-02")
+		}
+	}
+}
+
+// CreateMaintenancePlan 创建维护计划
+func CreateMaintenancePlan(plan *MaintenancePlan) error {
+	return db.DB.Create(plan).Error
+}
+
+// GetMaintenancePlanByID 查询维护计划
+func GetMaintenancePlanByID(id string) (*MaintenancePlan, error) {
+	var plan MaintenancePlan
+	err := db.DB.First(&plan, "plan_id = ?", id).Error
+	return &plan, err
+}
+
+// GetMaintenancePlansWithPagination 分页查询维护计划，targetID为空时不筛选
+func GetMaintenancePlansWithPagination(targetID string, page *Page) ([]MaintenancePlan, error) {
+	var plans []MaintenancePlan
+	tx := db.DB.Model(&MaintenancePlan{})
+	if targetID != "" {
+		tx = tx.Where("target_id = ?", targetID)
+	}
+	err := paginate(tx.Order("next_due"), page, &plans)
+	return plans, err
+}
+
+// UpdateMaintenancePlan 更新维护计划的内容、排期、负责人与状态，维护对象不能修改
+func UpdateMaintenancePlan(plan *MaintenancePlan) error {
+	return db.DB.Model(plan).
+		Select("title", "description", "mode", "interval_days", "cycle", "next_due", "assignee", "fund_id", "status").
+		Updates(plan).Error
+}
+
+// DeleteMaintenancePlan 删除维护计划，已生成的工单不受影响
+func DeleteMaintenancePlan(id string) error {
+	result := db.DB.Delete(&MaintenancePlan{}, "plan_id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete maintenance plan: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no maintenance plan found with plan_id: %s", id)
+	}
+	return nil
+}
+
+// GenerateWorkOrders 为截至asOf已到期的执行中计划生成工单，计划已有未完成的工单时跳过
+func GenerateWorkOrders(asOf, createTime string, newID func() string) ([]WorkOrder, error) {
+	created := make([]WorkOrder, 0)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var plans []MaintenancePlan
+		err := tx.Where("status = ? AND next_due <= ?", MaintenancePlanStatusActive, asOf).
+			Where("NOT EXISTS (SELECT 1 FROM work_order AS o WHERE o.plan_id = maintenance_plan.plan_id AND o.status IN ?)",
+				[]string{WorkOrderStatusOpen, WorkOrderStatusInProgress}).
+			Order("next_due").Find(&plans).Error
+		if err != nil {
+			return err
+		}
+		for _, plan := range plans {
+			order := WorkOrder{
+				OrderID:    newID(),
+				PlanID:     plan.PlanID,
+				TargetType: plan.TargetType,
+				TargetID:   plan.TargetID,
+				Title:      plan.Title,
+				DueDate:    plan.NextDue,
+				Assignee:   plan.Assignee,
+				Status:     WorkOrderStatusOpen,
+				CreateTime: createTime,
+			}
+			//同一到期日期的工单已完成或取消时不重复生成
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&order)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				created = append(created, order)
+			}
+		}
+		return nil
+	})
+	return created, err
+}
+
+// GetWorkOrderByID 查询维护工单
+func GetWorkOrderByID(id string) (*WorkOrder, error) {
+	var order WorkOrder
+	err := db.DB.First(&order, "order_id = ?", id).Error
+	return &order, err
+}
+
+// GetWorkOrdersWithPagination 分页查询维护工单，筛选条件为空时不筛选
+func GetWorkOrdersWithPagination(status, assignee, targetID string, page *Page) ([]WorkOrder, error) {
+	var orders []WorkOrder
+	tx := db.DB.Model(&WorkOrder{})
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if assignee != "" {
+		tx = tx.Where("assignee = ?", assignee)
+	}
+	if targetID != "" {
+		tx = tx.Where("target_id = ?", targetID)
+	}
+	err := paginate(tx.Order("due_date desc"), page, &orders)
+	return orders, err
+}
+
+// lockWorkOrder 锁定工单并校验状态
+func lockWorkOrder(tx *gorm.DB, id string, statuses ...string) (*WorkOrder, error) {
+	var order WorkOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "order_id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if order.Status == status {
+			return &order, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: work order %s is %s", ErrWorkOrderState, id, order.Status)
+}
+
+// setTargetStatus 同步资产或设施的本地状态，from不为空时要求当前状态为from
+func setTargetStatus(tx *gorm.DB, order *WorkOrder, from, to string) error {
+	var result *gorm.DB
+	if order.TargetType == MaintenanceTargetAsset {
+		result = tx.Model(&Asset{}).Where("asset_id = ?", order.TargetID)
+	} else {
+		result = tx.Model(&PublicFacility{}).Where("facility_id = ?", order.TargetID)
+	}
+	if from != "" {
+		result = result.Where("status = ?", from)
+	}
+	result = result.Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s %s", ErrTargetUnavailable, order.TargetType, order.TargetID)
+	}
+	return nil
+}
+
+// targetStatuses 维护对象在使用中与维护中的本地状态
+func targetStatuses(targetType string) (string, string) {
+	if targetType == MaintenanceTargetAsset {
+		return AssetStatusActive, AssetStatusRepair
+	}
+	return FacilityStatusUse, FacilityStatusMaintenance
+}
+
+// AssignWorkOrder 指派待开工工单的负责人
+func AssignWorkOrder(id, assignee string) (*WorkOrder, error) {
+	var order *WorkOrder
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockWorkOrder(tx, id, WorkOrderStatusOpen); err != nil {
+			return err
+		}
+		order.Assignee = assignee
+		return tx.Model(order).Update("assignee", assignee).Error
+	})
+	return order, err
+}
+
+// StartWorkOrder 开工，维护对象需处于使用中，本地状态转为维护中，anchor在事务提交前执行，用于同步上链
+func StartWorkOrder(id, startTime string, anchor func(*WorkOrder) error) (*WorkOrder, error) {
+	var order *WorkOrder
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockWorkOrder(tx, id, WorkOrderStatusOpen); err != nil {
+			return err
+		}
+		if order.Assignee == "" {
+			return fmt.Errorf("%w: work order %s has no assignee", ErrWorkOrderState, id)
+		}
+		using, maintaining := targetStatuses(order.TargetType)
+		if err := setTargetStatus(tx, order, using, maintaining); err != nil {
+			return err
+		}
+		order.Status = WorkOrderStatusInProgress
+		order.StartTime = startTime
+		if err := tx.Model(order).Select("status", "start_time").Updates(order).Error; err != nil {
+			return err
+		}
+		return anchor(order)
+	})
+	return order, err
+}
+
+// CompleteWorkOrder 完成工单，登记费用并将维护对象恢复为使用中，计划按排期方式顺延下次到期日期
+// 新记的支出与其他收支记录一样校验余额与预算，anchor在事务提交前执行，用于同步上链
+func CompleteWorkOrder(id string, completion WorkOrderCompletion, anchor func(*WorkOrder) error) (*WorkOrder, *BudgetWarning, error) {
+	var order *WorkOrder
+	var warning *BudgetWarning
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockWorkOrder(tx, id, WorkOrderStatusInProgress); err != nil {
+			return err
+		}
+		switch {
+		case completion.Entry != nil:
+			if warning, err = postFundRecord(tx, completion.Entry, completion.Cost); err != nil {
+				return err
+			}
+			order.EntryID = completion.Entry.EntryID
+			order.Cost = completion.Cost
+		case completion.EntryID != "":
+			if order.Cost, err = linkWorkOrderEntry(tx, completion.EntryID); err != nil {
+				return err
+			}
+			order.EntryID = completion.EntryID
+		}
+		using, maintaining := targetStatuses(order.TargetType)
+		if err := setTargetStatus(tx, order, maintaining, using); err != nil {
+			return err
+		}
+		order.Status = WorkOrderStatusCompleted
+		order.CompleteTime = completion.CompleteTime
+		order.Result = completion.Result
+		err = tx.Model(order).Select("status", "complete_time", "result", "cost", "entry_id").Updates(order).Error
+		if err != nil {
+			return err
+		}
+		var plan MaintenancePlan
+		if err := tx.First(&plan, "plan_id = ?", order.PlanID).Error; err == nil {
+			next := nextDueDate(plan, completion.CompleteTime)
+			if err := tx.Model(&plan).Update("next_due", next).Error; err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return anchor(order)
+	})
+	return order, warning, err
+}
+
+// linkWorkOrderEntry 校验已入账的支出分录未关联其他工单，返回支出金额
+func linkWorkOrderEntry(tx *gorm.DB, entryID string) (Money, error) {
+	var entry JournalEntry
+	if err := tx.Preload("Postings").First(&entry, "entry_id = ?", entryID).Error; err != nil {
+		return 0, fmt.Errorf("%w: entry %s: %v", ErrInvalidWorkEntry, entryID, err)
+	}
+	if entry.Type != EntryTypeExpense || entry.TransferID != "" {
+		return 0, fmt.Errorf("%w: entry %s is not an expense", ErrInvalidWorkEntry, entryID)
+	}
+	var count int64
+	if err := tx.Model(&WorkOrder{}).Where("entry_id = ?", entryID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, fmt.Errorf("%w: %s", ErrWorkEntryLinked, entryID)
+	}
+	expense := FundAccountID(entry.FundID, AccountTypeExpense)
+	for _, posting := range entry.Postings {
+		if posting.AccountID == expense {
+			return posting.Amount, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: entry %s has no expense posting", ErrInvalidWorkEntry, entryID)
+}
+
+// CancelWorkOrder 取消待开工或维护中的工单，维护中的对象恢复为使用中，anchor在事务提交前执行
+func CancelWorkOrder(id, reason, cancelTime string, anchor func(*WorkOrder) error) (*WorkOrder, error) {
+	var order *WorkOrder
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if order, err = lockWorkOrder(tx, id, WorkOrderStatusOpen, WorkOrderStatusInProgress); err != nil {
+			return err
+		}
+		inProgress := order.Status == WorkOrderStatusInProgress
+		if inProgress {
+			using, maintaining := targetStatuses(order.TargetType)
+			if err := setTargetStatus(tx, order, maintaining, using); err != nil {
+				return err
+			}
+		}
+		order.Status = WorkOrderStatusCancelled
+		order.CompleteTime = cancelTime
+		order.Result = reason
+		if err := tx.Model(order).Select("status", "complete_time", "result").Updates(order).Error; err != nil {
+			return err
+		}
+		if !inProgress {
+			return nil
+		}
+		return anchor(order)
+	})
+	return order, err
+}
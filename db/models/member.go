@@ -4,31 +4,36 @@ import (
 	"community-governance/db"
 	"errors"
 	"fmt"
+	"gorm.io/gorm"
 )
 
 // Member 社区成员表
 type Member struct {
-	MemberID      string `gorm:"column:member_id;primaryKey;type:varchar(64);not null" json:"member_id"`
-	Name          string `gorm:"column:name;type:varchar(16);not null" json:"name"`
-	Type          string `gorm:"column:type;type:varchar(10);not null" json:"type"`
-	HouseholdID   string `gorm:"column:household_id;type:varchar(64);not null" json:"household_id"`
-	IDNumber      string `gorm:"column:id_number;type:varchar(18);not null" json:"id_number"`
-	Address       string `gorm:"column:address;type:varchar(100);not null" json:"address"`
-	Sex           string `gorm:"column:sex;type:varchar(10);not null" json:"sex"`
-	DateBirth     string `gorm:"column:date_birth;type:varchar(26);not null" json:"date_birth"`
-	State         string `gorm:"column:state;type:varchar(10);not null" json:"state"`
-	Phone         string `gorm:"column:phone;type:varchar(20);not null" json:"phone"`
-	NameUsed      string `gorm:"column:name_used;type:varchar(16)" json:"name_used"`
-	Remarks       string `gorm:"column:remarks;type:varchar(100)" json:"remarks"`
-	Education     string `gorm:"column:education;type:varchar(10);not null" json:"education"`
-	MaritalStatus string `gorm:"column:marital_status;type:varchar(10);not null" json:"marital_status"`
-	Password      string `gorm:"column:password;type:varchar(20);not null" json:"-"`
+	MemberID      string         `gorm:"column:member_id;primaryKey;type:varchar(64);not null" json:"member_id"`
+	Name          string         `gorm:"column:name;type:varchar(16);not null" json:"name"`
+	Type          string         `gorm:"column:type;type:varchar(10);not null" json:"type"`
+	HouseholdID   string         `gorm:"column:household_id;type:varchar(64);not null" json:"household_id"`
+	IDNumber      string         `gorm:"column:id_number;type:varchar(18);not null" json:"id_number"`
+	Address       string         `gorm:"column:address;type:varchar(100);not null" json:"address"`
+	Sex           string         `gorm:"column:sex;type:varchar(10);not null" json:"sex"`
+	DateBirth     string         `gorm:"column:date_birth;type:varchar(26);not null" json:"date_birth"`
+	State         string         `gorm:"column:state;type:varchar(10);not null" json:"state"`
+	Phone         string         `gorm:"column:phone;type:varchar(20);not null" json:"phone"`
+	NameUsed      string         `gorm:"column:name_used;type:varchar(16)" json:"name_used"`
+	Remarks       string         `gorm:"column:remarks;type:varchar(100)" json:"remarks"`
+	Education     string         `gorm:"column:education;type:varchar(10);not null" json:"education"`
+	MaritalStatus string         `gorm:"column:marital_status;type:varchar(10);not null" json:"marital_status"`
+	Password      string         `gorm:"column:password;type:varchar(20);not null" json:"-"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (Member) TableName() string {
 	return "member"
 }
 
+// memberQueryFields 允许条件查询的列，不包含密码
+var memberQueryFields = NewQueryFields("member_id", "name", "type", "household_id", "id_number", "address", "sex", "date_birth", "state", "phone", "name_used", "remarks", "education", "marital_status")
+
 // CreateMember 新增一名新成员
 func CreateMember(member *Member) error {
 	return db.DB.Create(member).Error
@@ -42,14 +47,9 @@ func GetMemberByID(memberID string) (*Member, error) {
 }
 
 // GetAllMembersWithPagination 获取分页后的成员信息
-func GetAllMembersWithPagination(page, pageSize int) ([]Member, error) {
+func GetAllMembersWithPagination(page *Page) ([]Member, error) {
 	var members []Member
-
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
-
-	// 使用 Limit 和 Offset 进行分页查询
-	err := db.DB.Limit(pageSize).Offset(offset).Find(&members).Error
+	err := paginate(db.DB.Model(&Member{}), page, &members)
 	return members, err
 }
 
@@ -81,14 +81,15 @@ func DeleteMember(memberID string) error {
 }
 
 // GetAllMembersWithConditions 获取满足多条件的成员信息
-func GetAllMembersWithConditions(conditions map[string]interface{}, page, pageSize int) ([]Member, error) {
+func GetAllMembersWithConditions(query Query, page *Page) ([]Member, error) {
 	var members []Member
 
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
-
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&members).Error
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&Member{}), memberQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	err = paginate(tx, page, &members)
 	return members, err
 }
 
@@ -8,9 +8,9 @@ import (
 func TestLogin(t *testing.T) {
 	err := db.InitDB()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	login, err := Login("110101199001010000", "123456")
+	login, _, err := Login("110101199001010000", "123456")
 	if err != nil {
 		t.Error(err)
 	}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"community-governance/db"
+	"fmt"
+	"time"
+)
+
+// AutoMigrate 创建或更新新增的数据表
+func AutoMigrate() error {
+	err := db.DB.AutoMigrate(
+		&Member{},
+		&Fund{},
+		&Notice{},
+		&Asset{},
+		&AssetRequest{},
+		&PublicFacility{},
+		&Vote{},
+		&VoteOption{},
+		&VoteRule{},
+		&AuditLog{},
+		&AuditAnchor{},
+		&LedgerAccount{},
+		&JournalEntry{},
+		&Posting{},
+		&PendingExpense{},
+		&Attachment{},
+		&Budget{},
+		&BudgetLine{},
+		&BankImport{},
+		&BankLine{},
+		&Household{},
+		&FeeSchedule{},
+		&Invoice{},
+		&Payment{},
+		&AssetDepreciation{},
+		&MaintenancePlan{},
+		&WorkOrder{},
+		&Stocktake{},
+		&StocktakeScan{},
+		&FaultReport{},
+		&WorkflowDefinition{},
+		&WorkflowStep{},
+		&WorkflowInstance{},
+		&WorkflowTask{},
+		&WorkflowDecision{},
+		&FacilitySchedule{},
+		&FacilityReservation{},
+		&FacilityPolicy{},
+		&FacilityWaitlist{},
+	)
+	if err != nil {
+		return err
+	}
+	return migrateFundLedger()
+}
+
+// migrateFundLedger 为尚未开设账户的款项开设账户，以原current_balance列的值作为期初资金，之后删除该列
+func migrateFundLedger() error {
+	migrator := db.DB.Migrator()
+	if !migrator.HasColumn(&Fund{}, "current_balance") {
+		return nil
+	}
+	var legacy []struct {
+		FundID         string
+		Manager        string
+		CurrentBalance string
+	}
+	err := db.DB.Table("fund").
+		Select("fund_id, manager, current_balance").
+		Where("NOT EXISTS (SELECT 1 FROM ledger_account WHERE ledger_account.fund_id = fund.fund_id)").
+		Scan(&legacy).Error
+	if err != nil {
+		return err
+	}
+	createTime := time.Now().Format("2006-01-02 15:04:05")
+	for _, fund := range legacy {
+		opening, err := ParseMoney(fund.CurrentBalance)
+		if err != nil {
+			return fmt.Errorf("fund %s has invalid current_balance: %v", fund.FundID, err)
+		}
+		if err := OpenFundLedger(fund.FundID, opening, fund.Manager, createTime); err != nil {
+			return err
+		}
+	}
+	return migrator.DropColumn(&Fund{}, "current_balance")
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，以分为单位的整数，避免浮点运算误差
+// 数据库中以decimal(20,2)保存，JSON中以"123.45"形式的字符串表示
+type Money int64
+
+// maxMoneyDigits 整数部分最多位数，保证换算为分后不会溢出
+const maxMoneyDigits = 16
+
+var ErrInvalidMoney = errors.New("invalid money")
+
+// ParseMoney 解析十进制金额字符串，最多两位小数
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" || len(intPart) > maxMoneyDigits || (hasDot && (fracPart == "" || len(fracPart) > 2)) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, ch := range part {
+			if ch < '0' || ch > '9' {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+			}
+		}
+	}
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	cents := int64(0)
+	if fracPart != "" {
+		cents, _ = strconv.ParseInt((fracPart + "0")[:2], 10, 64)
+	}
+	m := Money(yuan*100 + cents)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// Abs 金额的绝对值
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// String 格式化为保留两位小数的金额字符串
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON 同时接受字符串与数字形式的金额
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	v, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value 写入数据库时转换为decimal字符串
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan 读取decimal列或SUM结果
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		return m.scanString(strconv.FormatFloat(v, 'f', 2, 64))
+	default:
+		return fmt.Errorf("%w: unsupported type %T", ErrInvalidMoney, value)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	// decimal(20,2)可能返回更多位的0，如SUM结果"12.3400"
+	if intPart, fracPart, ok := strings.Cut(s, "."); ok && len(fracPart) > 2 {
+		if strings.Trim(fracPart[2:], "0") != "" {
+			return fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+		}
+		s = intPart + "." + fracPart[:2]
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -3,17 +3,23 @@ package models
 import (
 	"community-governance/db"
 	"fmt"
+	"gorm.io/gorm"
 )
 
+// NoticeStatusPublished 已发布的公告，归档后状态为StatusArchived
+const NoticeStatusPublished = "published"
+
 // Notice 表对应的模型
 type Notice struct {
-	NoticeID    string `gorm:"primaryKey;type:varchar(64);not null" json:"notice_id"` // 公告ID
-	Title       string `gorm:"type:varchar(20);not null" json:"title"`                // 公告标题
-	Content     string `gorm:"type:text;not null" json:"content"`                     // 公告内容
-	Type        string `gorm:"type:varchar(10);not null" json:"type"`                 // 类型
-	Author      string `gorm:"type:varchar(64);not null" json:"author"`               // 发布人
-	PublishTime string `gorm:"type:datetime;not null" json:"publish_time"`            // 发布时间
-	Version     int    `gorm:"not null" json:"version"`                               // 发布版本
+	NoticeID    string         `gorm:"primaryKey;type:varchar(64);not null" json:"notice_id"`       // 公告ID
+	Title       string         `gorm:"type:varchar(20);not null" json:"title"`                      // 公告标题
+	Content     string         `gorm:"type:text;not null" json:"content"`                           // 公告内容
+	Type        string         `gorm:"type:varchar(10);not null" json:"type"`                       // 类型
+	Author      string         `gorm:"type:varchar(64);not null" json:"author"`                     // 发布人
+	PublishTime string         `gorm:"type:datetime;not null" json:"publish_time"`                  // 发布时间
+	Version     int            `gorm:"not null" json:"version"`                                     // 发布版本
+	Status      string         `gorm:"type:varchar(10);not null;default:'published'" json:"status"` // 公告状态，已上链的公告不能删除，只能归档
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`                                              // 删除时间
 }
 type NoticeWithAuthorName struct {
 	NoticeID    string `json:"notice_id"`    // 公告ID
@@ -29,6 +35,9 @@ func (Notice) TableName() string {
 	return "notice"
 }
 
+// noticeQueryFields 允许条件查询的列
+var noticeQueryFields = NewQueryFields("notice_id", "title", "content", "type", "author", "publish_time", "version", "status")
+
 // CreateNotice 增加公告
 func CreateNotice(notice Notice) error {
 	return db.DB.Create(&notice).Error
@@ -64,20 +73,21 @@ func DeleteNotice(id string) error {
 }
 
 // GetAllNoticesWithPagination 获取所有公告并分页
-func GetAllNoticesWithPagination(page, pageSize int) ([]Notice, error) {
+func GetAllNoticesWithPagination(page *Page) ([]Notice, error) {
 	var notices []Notice
-	err := db.DB.Offset((page - 1) * pageSize).Limit(pageSize).Find(&notices).Error
+	err := paginate(db.DB.Model(&Notice{}), page, &notices)
 	return notices, err
 }
 
-func GetAllNoticesWithConditions(page, pageSize int, conditions map[string]interface{}) ([]Notice, error) {
+func GetAllNoticesWithConditions(page *Page, query Query) ([]Notice, error) {
 	var notices []Notice
 
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
-
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&notices).Error
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&Notice{}), noticeQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	err = paginate(tx, page, &notices)
 	return notices, err
 }
 func GetNoticeWithAuthorName(id string) (*NoticeWithAuthorName, error) {
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+const (
+	DefaultPageSize = 10  // 默认每页条数
+	MaxPageSize     = 100 // 每页最大条数，超过时按最大值返回
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page 分页参数与结果，Total为满足条件的总条数
+type Page struct {
+	Page     int   `json:"page"`
+	PageSize int   `json:"pageSize"`
+	Total    int64 `json:"total"`
+	HasNext  bool  `json:"hasNext"`
+}
+
+// NewPage 解析并校验分页参数，为空时使用默认值，pageSize超过上限时取上限
+func NewPage(page, pageSize string) (*Page, error) {
+	p := &Page{Page: 1, PageSize: DefaultPageSize}
+	if page != "" {
+		v, err := strconv.Atoi(page)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("page must be a positive integer: %s", page)
+		}
+		p.Page = v
+	}
+	if pageSize != "" {
+		v, err := strconv.Atoi(pageSize)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("pageSize must be a positive integer: %s", pageSize)
+		}
+		p.PageSize = min(v, MaxPageSize)
+	}
+	return p, nil
+}
+
+// Offset 计算 OFFSET
+func (p *Page) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// paginate 统计总条数并查询当前页
+func paginate(tx *gorm.DB, page *Page, dest interface{}) error {
+	if err := tx.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return err
+	}
+	if err := tx.Session(&gorm.Session{}).Limit(page.PageSize).Offset(page.Offset()).Find(dest).Error; err != nil {
+		return err
+	}
+	page.HasNext = int64(page.Offset()+page.PageSize) < page.Total
+	return nil
+}
+
+// NewCursorLimit 解析游标分页的条数，为空时使用默认值，超过上限时取上限
+func NewCursorLimit(limit string) (int, error) {
+	if limit == "" {
+		return DefaultPageSize, nil
+	}
+	v, err := strconv.Atoi(limit)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer: %s", limit)
+	}
+	return min(v, MaxPageSize), nil
+}
+
+// encodeCursor 将上一页最后一条记录的排序列值编码为游标
+func encodeCursor(sortValue, id string) string {
+	data, _ := json.Marshal([2]string{sortValue, id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (string, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	var values [2]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	return values[0], values[1], nil
+}
+
+// keysetPaginate 按(sortColumn, idColumn)倒序键集分页，从游标之后读取limit条，cursor为空时从第一条开始
+// 每页只按索引定位到游标处，代价与已翻过的页数无关；key返回记录的排序列值与ID，用于生成下一页游标
+func keysetPaginate[T any](tx *gorm.DB, sortColumn, idColumn, cursor string, limit int, key func(T) (string, string)) ([]T, string, bool, error) {
+	if cursor != "" {
+		sortValue, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", false, err
+		}
+		tx = tx.Where(fmt.Sprintf("(%s < ? OR (%s = ? AND %s < ?))", sortColumn, sortColumn, idColumn), sortValue, sortValue, id)
+	}
+	records := make([]T, 0, limit+1)
+	//多读一条判断是否还有下一页
+	if err := tx.Order(sortColumn + " DESC").Order(idColumn + " DESC").Limit(limit + 1).Find(&records).Error; err != nil {
+		return nil, "", false, err
+	}
+	if len(records) <= limit {
+		return records, "", false, nil
+	}
+	records = records[:limit]
+	return records, encodeCursor(key(records[limit-1])), true, nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// 待审批支出状态，与链上状态保持一致
+const (
+	PendingStatusPending  = "pending"  // 审批中
+	PendingStatusPosted   = "posted"   // 已批准并入账
+	PendingStatusRejected = "rejected" // 已驳回
+)
+
+var ErrPendingResolved = errors.New("pending expense already resolved")
+
+// PendingExpense 待审批支出表，审批以链上为准，本表用于列表查询
+type PendingExpense struct {
+	PendingID  string `gorm:"primaryKey;type:varchar(64);not null" json:"pending_id"` // 待审批支出ID，与链上一致
+	FundID     string `gorm:"type:varchar(64);not null;index" json:"fund_id"`         // 款项ID
+	Amount     Money  `gorm:"type:decimal(20,2);not null" json:"amount"`              // 支出金额
+	Source     string `gorm:"type:varchar(100)" json:"source"`                        // 金额去向
+	Explain    string `gorm:"type:varchar(200)" json:"explain"`                       // 说明
+	Category   string `gorm:"type:varchar(50)" json:"category"`                       // 预算科目
+	InfoHash   string `gorm:"type:varchar(64)" json:"info_hash"`                      // 证明文件SHA-256
+	Reference  string `gorm:"type:varchar(100)" json:"reference"`                     // 银行流水号
+	Proposer   string `gorm:"type:varchar(64);not null" json:"proposer"`              // 提交人
+	Required   int    `gorm:"not null" json:"required"`                               // 需要的批准人数
+	Approvers  string `gorm:"type:varchar(1000)" json:"approvers"`                    // 已批准的成员ID，逗号分隔
+	Rejecter   string `gorm:"type:varchar(64)" json:"rejecter"`                       // 驳回人
+	Reason     string `gorm:"type:varchar(200)" json:"reason"`                        // 驳回原因
+	Status     string `gorm:"type:varchar(10);not null;index" json:"status"`          // 审批状态
+	CreateTime string `gorm:"type:varchar(26);not null" json:"create_time"`           // 提交时间
+	UpdateTime string `gorm:"type:varchar(26)" json:"update_time"`                    // 上次修改时间
+}
+
+func (PendingExpense) TableName() string {
+	return "pending_expense"
+}
+
+// CreatePendingExpense 保存已提交上链的待审批支出
+func CreatePendingExpense(pending *PendingExpense) error {
+	return db.DB.Create(pending).Error
+}
+
+// GetPendingExpenseByID 查询待审批支出
+func GetPendingExpenseByID(id string) (*PendingExpense, error) {
+	var pending PendingExpense
+	err := db.DB.First(&pending, "pending_id = ?", id).Error
+	return &pending, err
+}
+
+// GetPendingExpensesWithPagination 分页查询待审批支出，fundID与status为空时不筛选
+func GetPendingExpensesWithPagination(fundID, status string, page *Page) ([]PendingExpense, error) {
+	var pending []PendingExpense
+	tx := db.DB.Model(&PendingExpense{})
+	if fundID != "" {
+		tx = tx.Where("fund_id = ?", fundID)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err := paginate(tx.Order("create_time desc"), page, &pending)
+	return pending, err
+}
+
+// ResolvePendingExpense 锁定审批中的支出后执行anchor提交链上审批，anchor按链上结果修改pending
+// approve为true且本次批准可能达到批准人数时，先锁定资金账户并校验余额与预算，校验不通过则不提交上链，
+// 避免链上已入账而本地过账失败；链上已入账时同时记一笔支出分录，任一步失败时整体回滚
+func ResolvePendingExpense(id, updateTime string, approve bool, anchor func(pending *PendingExpense) error) (*PendingExpense, error) {
+	var pending PendingExpense
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pending, "pending_id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		if pending.Status != PendingStatusPending {
+			return fmt.Errorf("%w: %s is %s", ErrPendingResolved, id, pending.Status)
+		}
+		entry := JournalEntry{
+			EntryID:   pending.PendingID,
+			FundID:    pending.FundID,
+			Type:      EntryTypeExpense,
+			Source:    pending.Source,
+			Explain:   pending.Explain,
+			Category:  pending.Category,
+			InfoHash:  pending.InfoHash,
+			Reference: pending.Reference,
+			Recorder:  pending.Proposer,
+			EntryTime: updateTime,
+		}
+		if approve && pending.approverCount()+1 >= pending.Required {
+			if _, err := checkFundRecord(tx, &entry, pending.Amount); err != nil {
+				return err
+			}
+		}
+		if err := anchor(&pending); err != nil {
+			return err
+		}
+		pending.UpdateTime = updateTime
+		if pending.Status == PendingStatusPosted {
+			//超支提醒已在提交审批时返回，这里只拦截超支
+			if _, err := postFundRecord(tx, &entry, pending.Amount); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&pending).Error
+	})
+	return &pending, err
+}
+
+// approverCount 已批准的人数
+func (p *PendingExpense) approverCount() int {
+	if p.Approvers == "" {
+		return 0
+	}
+	return len(strings.Split(p.Approvers, ","))
+}
@@ -3,22 +3,28 @@ package models
 import (
 	"community-governance/db"
 	"fmt"
+	"gorm.io/gorm"
 )
 
 // PublicFacility 公共设施信息模型
 type PublicFacility struct {
-	FacilityID  string `gorm:"primaryKey;type:varchar(64);not null" json:"facility_id"` // 设施ID
-	Name        string `gorm:"type:varchar(20);not null" json:"name"`                   // 设施名称
-	Description string `gorm:"type:varchar(200);not null" json:"description"`           // 设施说明
-	Location    string `gorm:"type:varchar(100);not null" json:"location"`              // 设施位置
-	Status      string `gorm:"type:varchar(64);not null" json:"status"`                 // 设施状态
-	Manager     string `gorm:"type:varchar(64);not null" json:"manager"`                // 负责人
-	CreateTime  string `gorm:"type:varchar(26);not null" json:"create_time"`            // 创建时间
+	FacilityID  string         `gorm:"primaryKey;type:varchar(64);not null" json:"facility_id"` // 设施ID
+	Name        string         `gorm:"type:varchar(20);not null" json:"name"`                   // 设施名称
+	Description string         `gorm:"type:varchar(200);not null" json:"description"`           // 设施说明
+	Location    string         `gorm:"type:varchar(100);not null" json:"location"`              // 设施位置
+	Status      string         `gorm:"type:varchar(64);not null" json:"status"`                 // 设施状态
+	Manager     string         `gorm:"type:varchar(64);not null" json:"manager"`                // 负责人
+	CreateTime  string         `gorm:"type:varchar(26);not null" json:"create_time"`            // 创建时间
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`                                          // 删除时间
 }
 
 func (PublicFacility) TableName() string {
 	return "public_facility"
 }
+
+// facilityQueryFields 允许条件查询的列
+var facilityQueryFields = NewQueryFields("facility_id", "name", "description", "location", "status", "manager", "create_time")
+
 func CreateFacility(p *PublicFacility) error {
 	return db.DB.Create(&p).Error
 }
@@ -53,20 +59,21 @@ func DeleteFacility(id string) error {
 }
 
 // GetAllFacilityWithPagination 获取所有公共设施并分页
-func GetAllFacilityWithPagination(page, pageSize int) ([]PublicFacility, error) {
-	var facility []PublicFacility
-	err := db.DB.Offset((page - 1) * pageSize).Limit(pageSize).Find(&facility).Error
-	return facility, err
+func GetAllFacilityWithPagination(page *Page) ([]PublicFacility, error) {
+	var facilities []PublicFacility
+	err := paginate(db.DB.Model(&PublicFacility{}), page, &facilities)
+	return facilities, err
 }
 
 // GetAllFacilityWithConditions 获取满足多条件的公共设施信息
-func GetAllFacilityWithConditions(conditions map[string]interface{}, page, pageSize int) ([]PublicFacility, error) {
-	var facility []PublicFacility
+func GetAllFacilityWithConditions(query Query, page *Page) ([]PublicFacility, error) {
+	var facilities []PublicFacility
 
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
-
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&facility).Error
-	return facility, err
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&PublicFacility{}), facilityQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	err = paginate(tx, page, &facilities)
+	return facilities, err
 }
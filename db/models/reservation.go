@@ -0,0 +1,334 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 预约状态，与链上一致
+const (
+	ReservationStatusBooked    = "booked"    // 已预约
+	ReservationStatusCancelled = "cancelled" // 已取消
+)
+
+// 预约时间格式，起止时间精确到分钟
+const (
+	ReservationLayout = "2006-01-02 15:04"
+	ReservationDate   = "2006-01-02"
+)
+
+// MaxCalendarDays 日历查询最多的天数，与链上一致
+const MaxCalendarDays = 62
+
+var (
+	ErrInvalidReservation  = errors.New("invalid reservation")
+	ErrNoSchedule          = errors.New("facility has no schedule")
+	ErrReservationConflict = errors.New("reservation conflict")
+	ErrReservationDenied   = errors.New("reservation cancellation denied")
+	ErrReservationState    = errors.New("illegal reservation state")
+	ErrQuotaExceeded       = errors.New("booking quota exceeded")
+)
+
+// FacilitySchedule 设施开放时间与预约规则表
+type FacilitySchedule struct {
+	FacilityID  string `gorm:"primaryKey;type:varchar(64);not null" json:"facility_id"` // 设施ID
+	Open        string `gorm:"type:varchar(5);not null" json:"open"`                    // 每日开放时间，格式为HH:mm
+	Close       string `gorm:"type:varchar(5);not null" json:"close"`                   // 每日关闭时间，格式为HH:mm
+	Days        string `gorm:"type:varchar(20);not null" json:"days"`                   // 开放的星期，0为星期日，逗号分隔
+	SlotMinutes int    `gorm:"not null" json:"slot_minutes"`                            // 预约时段长度
+	CancelHours int    `gorm:"not null" json:"cancel_hours"`                            // 开始前多少小时之前可以取消预约
+	Updater     string `gorm:"type:varchar(64);not null" json:"updater"`                // 设置人
+	UpdateTime  string `gorm:"type:varchar(26);not null" json:"update_time"`            // 设置时间
+}
+
+func (FacilitySchedule) TableName() string {
+	return "facility_schedule"
+}
+
+// FacilityReservation 设施预约表
+type FacilityReservation struct {
+	ReservationID string `gorm:"primaryKey;type:varchar(64);not null" json:"reservation_id"` // 预约ID
+	FacilityID    string `gorm:"type:varchar(64);not null;index" json:"facility_id"`         // 设施ID
+	Member        string `gorm:"type:varchar(64);not null;index" json:"member"`              // 预约人
+	Household     string `gorm:"type:varchar(64);index" json:"household"`                    // 预约人户号，用于预约配额
+	Date          string `gorm:"type:varchar(10);not null;index" json:"date"`                // 预约日期
+	Start         string `gorm:"type:varchar(16);not null" json:"start"`                     // 开始时间
+	End           string `gorm:"type:varchar(16);not null" json:"end"`                       // 结束时间
+	Purpose       string `gorm:"type:varchar(200)" json:"purpose"`                           // 用途
+	Status        string `gorm:"type:varchar(10);not null" json:"status"`                    // 预约状态
+	CreateTime    string `gorm:"type:varchar(26);not null" json:"create_time"`               // 预约时间
+	Canceller     string `gorm:"type:varchar(64)" json:"canceller"`                          // 取消人
+	CancelReason  string `gorm:"type:varchar(200)" json:"cancel_reason"`                     // 取消原因
+	CancelTime    string `gorm:"type:varchar(26)" json:"cancel_time"`                        // 取消时间
+	TxID          string `gorm:"type:varchar(64)" json:"tx_id"`                              // 链上交易ID
+}
+
+func (FacilityReservation) TableName() string {
+	return "facility_reservation"
+}
+
+// clockMinutes 解析HH:mm，返回当天的分钟数
+func clockMinutes(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time %s", ErrInvalidReservation, clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// CheckFacilitySchedule 校验开放时间，关闭时间须晚于开放时间且开放时长为时段长度的整数倍
+func CheckFacilitySchedule(schedule *FacilitySchedule) error {
+	open, err := clockMinutes(schedule.Open)
+	if err != nil {
+		return err
+	}
+	closing, err := clockMinutes(schedule.Close)
+	if err != nil {
+		return err
+	}
+	if open >= closing || schedule.SlotMinutes <= 0 || (closing-open)%schedule.SlotMinutes != 0 || schedule.CancelHours < 0 {
+		return fmt.Errorf("%w: schedule %s-%s with %d minute slots", ErrInvalidReservation, schedule.Open, schedule.Close, schedule.SlotMinutes)
+	}
+	for _, day := range strings.Split(schedule.Days, ",") {
+		if n, err := strconv.Atoi(day); err != nil || n < 0 || n > 6 {
+			return fmt.Errorf("%w: days %s", ErrInvalidReservation, schedule.Days)
+		}
+	}
+	return nil
+}
+
+// CheckReservationSlot 校验预约时段：同一天内、开放日的开放时间内、与时段对齐且晚于now
+func CheckReservationSlot(schedule *FacilitySchedule, start, end, now string) error {
+	from, err := time.Parse(ReservationLayout, start)
+	if err != nil {
+		return fmt.Errorf("%w: start %s", ErrInvalidReservation, start)
+	}
+	to, err := time.Parse(ReservationLayout, end)
+	if err != nil {
+		return fmt.Errorf("%w: end %s", ErrInvalidReservation, end)
+	}
+	if start[:10] != end[:10] || start >= end {
+		return fmt.Errorf("%w: reservation must start before it ends on the same day", ErrInvalidReservation)
+	}
+	if start <= now {
+		return fmt.Errorf("%w: reservation must start in the future", ErrInvalidReservation)
+	}
+	if !contains(strings.Split(schedule.Days, ","), strconv.Itoa(int(from.Weekday()))) {
+		return fmt.Errorf("%w: facility is closed on %s", ErrInvalidReservation, from.Weekday())
+	}
+	open, err := clockMinutes(schedule.Open)
+	if err != nil {
+		return err
+	}
+	closing, err := clockMinutes(schedule.Close)
+	if err != nil {
+		return err
+	}
+	begin, finish := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+	if begin < open || finish > closing {
+		return fmt.Errorf("%w: outside opening hours %s-%s", ErrInvalidReservation, schedule.Open, schedule.Close)
+	}
+	if (begin-open)%schedule.SlotMinutes != 0 || (finish-open)%schedule.SlotMinutes != 0 {
+		return fmt.Errorf("%w: must align to %d minute slots", ErrInvalidReservation, schedule.SlotMinutes)
+	}
+	return nil
+}
+
+// CancelDeadline 预约人取消预约的截止时间，为开始前cancel_hours小时
+func CancelDeadline(schedule *FacilitySchedule, start string) (string, error) {
+	from, err := time.Parse(ReservationLayout, start)
+	if err != nil {
+		return "", err
+	}
+	return from.Add(-time.Duration(schedule.CancelHours) * time.Hour).Format(ReservationLayout), nil
+}
+
+// SaveFacilitySchedule 保存设施开放时间，anchor在写库后执行，用于同步上链
+func SaveFacilitySchedule(schedule *FacilitySchedule, anchor func() error) error {
+	if err := CheckFacilitySchedule(schedule); err != nil {
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&PublicFacility{}, "facility_id = ?", schedule.FacilityID).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(schedule).Error; err != nil {
+			return err
+		}
+		return anchor()
+	})
+}
+
+// GetFacilitySchedule 查询设施开放时间
+func GetFacilitySchedule(facilityID string) (*FacilitySchedule, error) {
+	var schedule FacilitySchedule
+	err := db.DB.First(&schedule, "facility_id = ?", facilityID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNoSchedule, facilityID)
+	}
+	return &schedule, err
+}
+
+// CreateReservation 预约设施时段，锁定设施开放时间使同一设施的预约串行执行
+// 时段不合法返回ErrInvalidReservation，与已有预约或候补保留的时段重叠返回ErrReservationConflict，超出预约政策返回ErrQuotaExceeded
+// anchor在写入预约前执行，用于上链并回填交易ID
+func CreateReservation(reservation *FacilityReservation, now string, anchor func() error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockSchedule(tx, reservation.FacilityID); err != nil {
+			return err
+		}
+		return createReservation(tx, reservation, now, "", anchor)
+	})
+}
+
+// lockSchedule 锁定设施开放时间，同一设施的预约与候补串行执行
+func lockSchedule(tx *gorm.DB, facilityID string) (*FacilitySchedule, error) {
+	var schedule FacilitySchedule
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, "facility_id = ?", facilityID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNoSchedule, facilityID)
+	}
+	return &schedule, err
+}
+
+// createReservation 在已锁定开放时间的事务内创建预约，holder为确认候补时该候补的ID，其保留的时段不视为冲突
+func createReservation(tx *gorm.DB, reservation *FacilityReservation, now, holder string, anchor func() error) error {
+	var schedule FacilitySchedule
+	if err := tx.First(&schedule, "facility_id = ?", reservation.FacilityID).Error; err != nil {
+		return err
+	}
+	if err := CheckReservationSlot(&schedule, reservation.Start, reservation.End, now); err != nil {
+		return err
+	}
+	if err := checkSlotTaken(tx, reservation.FacilityID, reservation.Start, reservation.End, now, holder); err != nil {
+		return err
+	}
+	policy, err := getFacilityPolicy(tx, reservation.FacilityID)
+	if err != nil {
+		return err
+	}
+	if err := checkQuota(tx, policy, reservation, now); err != nil {
+		return err
+	}
+	reservation.Date = reservation.Start[:10]
+	reservation.Status = ReservationStatusBooked
+	if err := anchor(); err != nil {
+		return err
+	}
+	return tx.Create(reservation).Error
+}
+
+// checkSlotTaken 时段与已有预约或其他候补保留中的时段重叠时返回ErrReservationConflict
+func checkSlotTaken(tx *gorm.DB, facilityID, start, end, now, holder string) error {
+	var conflict FacilityReservation
+	err := tx.Where("facility_id = ? AND date = ? AND status = ? AND `start` < ? AND `end` > ?", facilityID,
+		start[:10], ReservationStatusBooked, end, start).First(&conflict).Error
+	if err == nil {
+		return fmt.Errorf("%w: %s (%s - %s)", ErrReservationConflict, conflict.ReservationID, conflict.Start, conflict.End)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var held FacilityWaitlist
+	err = tx.Where("facility_id = ? AND date = ? AND status = ? AND deadline > ? AND entry_id <> ? AND `start` < ? AND `end` > ?", facilityID,
+		start[:10], WaitStatusOffered, now, holder, end, start).First(&held).Error
+	if err == nil {
+		return fmt.Errorf("%w: held for waitlist entry %s until %s", ErrReservationConflict, held.EntryID, held.Deadline)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// CancelReservation 取消预约，预约人须在取消截止时间之前取消，override为true时可在结束前随时取消
+// anchor在写库后执行，用于同步上链
+func CancelReservation(id, canceller, reason, now string, override bool, anchor func(*FacilityReservation) error) (*FacilityReservation, error) {
+	var reservation FacilityReservation
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, "reservation_id = ?", id).Error; err != nil {
+			return err
+		}
+		if reservation.Status != ReservationStatusBooked {
+			return fmt.Errorf("%w: reservation %s is %s", ErrReservationState, id, reservation.Status)
+		}
+		if reservation.End <= now[:16] {
+			return fmt.Errorf("%w: reservation %s has ended", ErrReservationState, id)
+		}
+		if !override {
+			var schedule FacilitySchedule
+			if err := tx.First(&schedule, "facility_id = ?", reservation.FacilityID).Error; err != nil {
+				return err
+			}
+			deadline, err := CancelDeadline(&schedule, reservation.Start)
+			if err != nil {
+				return err
+			}
+			if canceller != reservation.Member || now[:16] > deadline {
+				return fmt.Errorf("%w: deadline %s", ErrReservationDenied, deadline)
+			}
+		}
+		reservation.Status = ReservationStatusCancelled
+		reservation.Canceller = canceller
+		reservation.CancelReason = reason
+		reservation.CancelTime = now
+		err := tx.Model(&reservation).Select("status", "canceller", "cancel_reason", "cancel_time").Updates(&reservation).Error
+		if err != nil {
+			return err
+		}
+		return anchor(&reservation)
+	})
+	return &reservation, err
+}
+
+// GetReservationByID 查询预约
+func GetReservationByID(id string) (*FacilityReservation, error) {
+	var reservation FacilityReservation
+	err := db.DB.First(&reservation, "reservation_id = ?", id).Error
+	return &reservation, err
+}
+
+// GetFacilityCalendar 查询设施在from至to日期内的预约，status为空时包含已取消的预约
+func GetFacilityCalendar(facilityID, from, to, status string) ([]FacilityReservation, error) {
+	first, err := time.Parse(ReservationDate, from)
+	if err != nil {
+		return nil, fmt.Errorf("%w: date %s", ErrInvalidReservation, from)
+	}
+	last, err := time.Parse(ReservationDate, to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: date %s", ErrInvalidReservation, to)
+	}
+	if last.Before(first) || last.Sub(first) > MaxCalendarDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: date range must be within %d days", ErrInvalidReservation, MaxCalendarDays)
+	}
+	reservations := make([]FacilityReservation, 0)
+	tx := db.DB.Where("facility_id = ? AND date >= ? AND date <= ?", facilityID, from, to)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err = tx.Order("`start`").Find(&reservations).Error
+	return reservations, err
+}
+
+// GetReservationsWithPagination 分页查询预约，参数为空时不筛选
+func GetReservationsWithPagination(member, facilityID, status string, page *Page) ([]FacilityReservation, error) {
+	var reservations []FacilityReservation
+	tx := db.DB.Model(&FacilityReservation{})
+	if member != "" {
+		tx = tx.Where("member = ?", member)
+	}
+	if facilityID != "" {
+		tx = tx.Where("facility_id = ?", facilityID)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err := paginate(tx.Order("`start` desc"), page, &reservations)
+	return reservations, err
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"community-governance/db"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// StatusArchived 归档状态，已上链或存在关联数据的记录不能删除，只能归档
+const StatusArchived = "archived"
+
+// softDeleteModel 支持软删除的数据表
+type softDeleteModel struct {
+	model       interface{}        // 数据表模型
+	key         string             // 主键列名
+	statusField string             // 状态列名，为空时不支持归档
+	newSlice    func() interface{} // 创建查询结果切片
+}
+
+var softDeleteModels = map[string]softDeleteModel{
+	"member":           {&Member{}, "member_id", "state", func() interface{} { return &[]Member{} }},
+	"fund":             {&Fund{}, "fund_id", "status", func() interface{} { return &[]Fund{} }},
+	"notice":           {&Notice{}, "notice_id", "status", func() interface{} { return &[]Notice{} }},
+	"asset":            {&Asset{}, "asset_id", "status", func() interface{} { return &[]Asset{} }},
+	"asset_request":    {&AssetRequest{}, "request_id", "status", func() interface{} { return &[]AssetRequest{} }},
+	"facility":         {&PublicFacility{}, "facility_id", "status", func() interface{} { return &[]PublicFacility{} }},
+	"vote":             {&Vote{}, "vote_id", "status", func() interface{} { return &[]Vote{} }},
+	"vote_option":      {&VoteOption{}, "option_id", "status", func() interface{} { return &[]VoteOption{} }},
+	"vote_rule":        {&VoteRule{}, "rule_id", "", func() interface{} { return &[]VoteRule{} }},
+	"budget":           {&Budget{}, "budget_id", "status", func() interface{} { return &[]Budget{} }},
+	"fee_schedule":     {&FeeSchedule{}, "schedule_id", "status", func() interface{} { return &[]FeeSchedule{} }},
+	"maintenance_plan": {&MaintenancePlan{}, "plan_id", "status", func() interface{} { return &[]MaintenancePlan{} }},
+}
+
+func getSoftDeleteModel(kind string) (softDeleteModel, error) {
+	m, ok := softDeleteModels[kind]
+	if !ok {
+		return softDeleteModel{}, fmt.Errorf("unsupported record type: %s", kind)
+	}
+	return m, nil
+}
+
+// RestoreRecord 恢复被删除的记录，投票会一并恢复其选项
+func RestoreRecord(kind, id string) error {
+	m, err := getSoftDeleteModel(kind)
+	if err != nil {
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(m.model).Where(m.key+" = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+		if result.Error != nil {
+			return fmt.Errorf("failed to restore %s: %v", kind, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("no deleted %s found with id: %s", kind, id)
+		}
+		if kind == "vote" {
+			return tx.Unscoped().Model(&VoteOption{}).Where("vote_id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil).Error
+		}
+		return nil
+	})
+}
+
+// GetDeletedRecords 分页获取已删除的记录
+func GetDeletedRecords(kind string, page *Page) (interface{}, error) {
+	m, err := getSoftDeleteModel(kind)
+	if err != nil {
+		return nil, err
+	}
+	records := m.newSlice()
+	err = paginate(db.DB.Unscoped().Model(m.model).Where("deleted_at IS NOT NULL"), page, records)
+	if err != nil {
+		return nil, err
+	}
+	//款项余额由资金账户汇总，不随查询自动填充
+	if funds, ok := records.(*[]Fund); ok {
+		return records, fillFundBalances(db.DB, *funds)
+	}
+	return records, nil
+}
+
+// ArchiveRecord 将记录状态置为归档
+func ArchiveRecord(kind, id string) error {
+	m, err := getSoftDeleteModel(kind)
+	if err != nil {
+		return err
+	}
+	if m.statusField == "" {
+		return fmt.Errorf("%s does not support archiving", kind)
+	}
+	result := db.DB.Model(m.model).Where(m.key+" = ?", id).Update(m.statusField, StatusArchived)
+	if result.Error != nil {
+		return fmt.Errorf("failed to archive %s: %v", kind, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no %s found with id: %s", kind, id)
+	}
+	return nil
+}
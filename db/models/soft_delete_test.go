@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestArchiveRecord(t *testing.T) {
+	useTestDB(t)
+	notice := Notice{NoticeID: "n1", Title: "停水通知", Content: "6月1日停水", Type: "notice", Author: "u1", PublishTime: "2024-05-30 10:00:00", Version: 1}
+	if err := CreateNotice(notice); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := GetNoticeByID("n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != NoticeStatusPublished {
+		t.Errorf("new notice is %q, want %s", saved.Status, NoticeStatusPublished)
+	}
+	cases := []struct {
+		kind, id string
+		ok       bool
+	}{
+		{"notice", "n1", true},
+		{"notice", "n2", false},
+		{"vote_rule", "r1", false},
+		{"unknown", "x", false},
+	}
+	for _, c := range cases {
+		if err := ArchiveRecord(c.kind, c.id); (err == nil) != c.ok {
+			t.Errorf("archive %s %s: got %v, want ok %v", c.kind, c.id, err, c.ok)
+		}
+	}
+	saved, err = GetNoticeByID("n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != StatusArchived {
+		t.Errorf("archived notice is %s, want %s", saved.Status, StatusArchived)
+	}
+}
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"community-governance/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 盘点状态
+const (
+	StocktakeStatusOpen   = "open"   // 盘点中
+	StocktakeStatusClosed = "closed" // 已结束
+)
+
+var ErrStocktakeClosed = errors.New("stocktake is closed")
+
+// Stocktake 资产盘点，盘点范围为一个位置，结束时生成盘点报告并将报告hash上链
+type Stocktake struct {
+	StocktakeID  string `gorm:"primaryKey;type:varchar(64);not null" json:"stocktake_id"` // 盘点ID
+	Location     string `gorm:"type:varchar(100);not null;index" json:"location"`         // 盘点范围的位置
+	Status       string `gorm:"type:varchar(10);not null" json:"status"`                  // 盘点状态
+	Opener       string `gorm:"type:varchar(64);not null" json:"opener"`                  // 发起人
+	OpenTime     string `gorm:"type:varchar(26);not null" json:"open_time"`               // 发起时间
+	Closer       string `gorm:"type:varchar(64)" json:"closer"`                           // 结束人
+	CloseTime    string `gorm:"type:varchar(26)" json:"close_time"`                       // 结束时间
+	Counted      int    `gorm:"not null;default:0" json:"counted"`                        // 已清点的资产数
+	Missing      int    `gorm:"not null;default:0" json:"missing"`                        // 盘亏的资产数
+	Misplaced    int    `gorm:"not null;default:0" json:"misplaced"`                      // 位置不符的资产数
+	Unregistered int    `gorm:"not null;default:0" json:"unregistered"`                   // 未登记的物品数
+	ReportHash   string `gorm:"type:varchar(64)" json:"report_hash"`                      // 盘点报告hash值
+	Report       string `gorm:"type:mediumtext" json:"-"`                                 // 结束时的盘点报告
+}
+
+func (Stocktake) TableName() string {
+	return "stocktake"
+}
+
+// StocktakeScan 盘点中的一次扫码或确认，同一盘点中同一编码只记录第一次
+type StocktakeScan struct {
+	StocktakeID string `gorm:"primaryKey;type:varchar(64);not null" json:"stocktake_id"` // 盘点ID
+	Code        string `gorm:"primaryKey;type:varchar(64);not null" json:"code"`         // 扫码或输入的资产编码
+	AssetID     string `gorm:"type:varchar(64)" json:"asset_id"`                         // 对应的资产ID，未登记的物品为空
+	Scanner     string `gorm:"type:varchar(64);not null" json:"scanner"`                 // 清点人
+	ScanTime    string `gorm:"type:varchar(26);not null" json:"scan_time"`               // 清点时间
+	Note        string `gorm:"type:varchar(200)" json:"note"`                            // 备注
+}
+
+func (StocktakeScan) TableName() string {
+	return "stocktake_scan"
+}
+
+// StocktakeItem 盘点报告中已清点的资产
+type StocktakeItem struct {
+	Asset    Asset  `json:"asset"`
+	Scanner  string `json:"scanner"`
+	ScanTime string `json:"scan_time"`
+}
+
+// StocktakeReport 盘点报告
+// 账实相符为该位置在册且已清点的资产，盘亏为该位置在册但未清点的资产，
+// 位置不符为已清点但登记在其他位置或已处置的资产，未登记为清点到但资产表中不存在的物品
+type StocktakeReport struct {
+	Stocktake    Stocktake       `json:"stocktake"`
+	Found        []StocktakeItem `json:"found"`
+	Missing      []Asset         `json:"missing"`
+	Misplaced    []StocktakeItem `json:"misplaced"`
+	Unregistered []StocktakeScan `json:"unregistered"`
+}
+
+// CreateStocktake 发起盘点
+func CreateStocktake(stocktake *Stocktake) error {
+	return db.DB.Create(stocktake).Error
+}
+
+// GetStocktakeByID 查询盘点
+func GetStocktakeByID(id string) (*Stocktake, error) {
+	var stocktake Stocktake
+	err := db.DB.First(&stocktake, "stocktake_id = ?", id).Error
+	return &stocktake, err
+}
+
+// GetStocktakesWithPagination 分页查询盘点，筛选条件为空时不筛选
+func GetStocktakesWithPagination(location, status string, page *Page) ([]Stocktake, error) {
+	var stocktakes []Stocktake
+	tx := db.DB.Model(&Stocktake{})
+	if location != "" {
+		tx = tx.Where("location = ?", location)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err := paginate(tx.Order("open_time desc"), page, &stocktakes)
+	return stocktakes, err
+}
+
+// lockStocktake 锁定盘点并要求盘点未结束
+func lockStocktake(tx *gorm.DB, id string) (*Stocktake, error) {
+	var stocktake Stocktake
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stocktake, "stocktake_id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if stocktake.Status != StocktakeStatusOpen {
+		return nil, fmt.Errorf("%w: %s", ErrStocktakeClosed, id)
+	}
+	return &stocktake, nil
+}
+
+// ScanStocktake 记录一次清点，编码对应的资产不存在时记为未登记物品，重复清点时返回第一次的记录
+func ScanStocktake(scan StocktakeScan) (*StocktakeScan, error) {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockStocktake(tx, scan.StocktakeID); err != nil {
+			return err
+		}
+		var asset Asset
+		err := tx.First(&asset, "asset_id = ?", scan.Code).Error
+		switch {
+		case err == nil:
+			scan.AssetID = asset.AssetID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&scan).Error; err != nil {
+			return err
+		}
+		return tx.First(&scan, "stocktake_id = ? AND code = ?", scan.StocktakeID, scan.Code).Error
+	})
+	return &scan, err
+}
+
+// GetStocktakeScans 查询盘点的清点记录
+func GetStocktakeScans(id string) ([]StocktakeScan, error) {
+	var scans []StocktakeScan
+	err := db.DB.Where("stocktake_id = ?", id).Order("scan_time").Find(&scans).Error
+	return scans, err
+}
+
+// buildStocktakeReport 按清点记录与资产表的当前状态生成盘点报告
+func buildStocktakeReport(tx *gorm.DB, stocktake Stocktake) (*StocktakeReport, error) {
+	var scans []StocktakeScan
+	if err := tx.Where("stocktake_id = ?", stocktake.StocktakeID).Order("scan_time, code").Find(&scans).Error; err != nil {
+		return nil, err
+	}
+	var expected []Asset
+	err := tx.Where("location = ? AND status <> ?", stocktake.Location, AssetStatusDisposed).Order("asset_id").Find(&expected).Error
+	if err != nil {
+		return nil, err
+	}
+	report := &StocktakeReport{
+		Found:        make([]StocktakeItem, 0),
+		Missing:      make([]Asset, 0),
+		Misplaced:    make([]StocktakeItem, 0),
+		Unregistered: make([]StocktakeScan, 0),
+	}
+	inScope := make(map[string]bool, len(expected))
+	for _, asset := range expected {
+		inScope[asset.AssetID] = true
+	}
+	scanned := make(map[string]bool, len(scans))
+	for _, scan := range scans {
+		var asset Asset
+		err := tx.First(&asset, "asset_id = ?", scan.Code).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			report.Unregistered = append(report.Unregistered, scan)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		item := StocktakeItem{Asset: asset, Scanner: scan.Scanner, ScanTime: scan.ScanTime}
+		if inScope[asset.AssetID] {
+			report.Found = append(report.Found, item)
+			scanned[asset.AssetID] = true
+		} else {
+			report.Misplaced = append(report.Misplaced, item)
+		}
+	}
+	for _, asset := range expected {
+		if !scanned[asset.AssetID] {
+			report.Missing = append(report.Missing, asset)
+		}
+	}
+	stocktake.Counted = len(report.Found) + len(report.Misplaced)
+	stocktake.Missing = len(report.Missing)
+	stocktake.Misplaced = len(report.Misplaced)
+	stocktake.Unregistered = len(report.Unregistered)
+	report.Stocktake = stocktake
+	return report, nil
+}
+
+// GetStocktakeReport 查询盘点报告，盘点中的报告按当前清点情况生成，已结束的盘点返回结束时保存的报告
+func GetStocktakeReport(id string) (*StocktakeReport, error) {
+	stocktake, err := GetStocktakeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if stocktake.Status == StocktakeStatusClosed {
+		var report StocktakeReport
+		if err := json.Unmarshal([]byte(stocktake.Report), &report); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stocktake report: %v", err)
+		}
+		return &report, nil
+	}
+	return buildStocktakeReport(db.DB, *stocktake)
+}
+
+// CloseStocktake 结束盘点，保存盘点报告及其hash值，anchor在事务提交前执行，用于将报告hash签名上链
+// 报告hash按report_hash为空时的报告计算，校验时需先清空该字段
+func CloseStocktake(id, closer, closeTime string, hash func(*StocktakeReport) (string, error), anchor func(*Stocktake) error) (*StocktakeReport, error) {
+	var report *StocktakeReport
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		stocktake, err := lockStocktake(tx, id)
+		if err != nil {
+			return err
+		}
+		stocktake.Status = StocktakeStatusClosed
+		stocktake.Closer = closer
+		stocktake.CloseTime = closeTime
+		if report, err = buildStocktakeReport(tx, *stocktake); err != nil {
+			return err
+		}
+		if report.Stocktake.ReportHash, err = hash(report); err != nil {
+			return err
+		}
+		data, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		*stocktake = report.Stocktake
+		stocktake.Report = string(data)
+		err = tx.Model(stocktake).
+			Select("status", "closer", "close_time", "counted", "missing", "misplaced", "unregistered", "report_hash", "report").
+			Updates(stocktake).Error
+		if err != nil {
+			return err
+		}
+		return anchor(stocktake)
+	})
+	return report, err
+}
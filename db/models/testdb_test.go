@@ -0,0 +1,35 @@
+package models
+
+import (
+	"community-governance/db"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestDB 将db.DB替换为内存SQLite数据库并建表，测试结束后恢复
+// SQLite不支持FOR UPDATE，行锁相关的并发行为不在此覆盖
+func useTestDB(t *testing.T) {
+	t.Helper()
+	conn, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//内存数据库随连接存在，只保留一个连接
+	sqlDB.SetMaxOpenConns(1)
+	previous := db.DB
+	db.DB = conn
+	t.Cleanup(func() {
+		db.DB = previous
+		sqlDB.Close()
+	})
+	if err := AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,24 +3,29 @@ package models
 import (
 	"community-governance/db"
 	"fmt"
+	"gorm.io/gorm"
 )
 
 // Vote 表示投票表
 type Vote struct {
-	VoteID      string `gorm:"primaryKey;type:varchar(64);not null" json:"vote_id"`
-	Name        string `gorm:"type:varchar(20);" json:"name"`
-	RuleID      string `gorm:"type:varchar(64);not null" json:"rule_id"`
-	StartTime   string `gorm:"type:varchar(26);not null" json:"start_time"`
-	Manager     string `gorm:"type:varchar(64);not null" json:"manager"`
-	Description string `gorm:"type:varchar(200);" json:"description"`
-	Status      string `gorm:"type:varchar(10);not null" json:"status"`
-	Result      string `gorm:"type:varchar(64);" json:"result"`
+	VoteID      string         `gorm:"primaryKey;type:varchar(64);not null" json:"vote_id"`
+	Name        string         `gorm:"type:varchar(20);" json:"name"`
+	RuleID      string         `gorm:"type:varchar(64);not null" json:"rule_id"`
+	StartTime   string         `gorm:"type:varchar(26);not null" json:"start_time"`
+	Manager     string         `gorm:"type:varchar(64);not null" json:"manager"`
+	Description string         `gorm:"type:varchar(200);" json:"description"`
+	Status      string         `gorm:"type:varchar(10);not null" json:"status"`
+	Result      string         `gorm:"type:varchar(64);" json:"result"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (Vote) TableName() string {
 	return "vote"
 }
 
+// voteQueryFields 允许条件查询的列
+var voteQueryFields = NewQueryFields("vote_id", "name", "rule_id", "start_time", "manager", "description", "status", "result")
+
 // CreateVote 创建投票
 func CreateVote(vote *Vote) error {
 	return db.DB.Create(vote).Error
@@ -34,14 +39,9 @@ func GetVoteByID(memberId string) (*Vote, error) {
 }
 
 // GetVoteAllWithPagination 获取分页后的投票信息
-func GetVoteAllWithPagination(page, pageSize int) ([]Vote, error) {
+func GetVoteAllWithPagination(page *Page) ([]Vote, error) {
 	var votes []Vote
-
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
-
-	// 使用 Limit 和 Offset 进行分页查询
-	err := db.DB.Limit(pageSize).Offset(offset).Find(&votes).Error
+	err := paginate(db.DB.Model(&Vote{}), page, &votes)
 	return votes, err
 }
 
@@ -52,28 +52,38 @@ func UpdateVote(voteId, vote interface{}) error {
 
 // DeleteVote 删除指定成员信息
 func DeleteVote(voteId string) error {
-	result := db.DB.Where("vote_id = ?", voteId).Delete(&Vote{})
-	// 检查是否发生错误
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete asset request: %v", result.Error)
-	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("vote_id = ?", voteId).Delete(&Vote{})
+		// 检查是否发生错误
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete vote: %v", result.Error)
+		}
 
-	// 检查影响的行数
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("no asset request found with request_id: %s", voteId)
-	}
+		// 检查影响的行数
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("no vote found with vote_id: %s", voteId)
+		}
 
-	// 删除成功
-	return nil
+		// 一并删除投票选项
+		return tx.Where("vote_id = ?", voteId).Delete(&VoteOption{}).Error
+	})
 }
 
-func GetVoteAllWithConditions(conditions map[string]interface{}, page, pageSize int) ([]Vote, error) {
-	var votes []Vote
+// CountVotesByRule 统计使用指定规则的投票数量
+func CountVotesByRule(ruleId string) (int64, error) {
+	var count int64
+	err := db.DB.Model(&Vote{}).Where("rule_id = ?", ruleId).Count(&count).Error
+	return count, err
+}
 
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
+func GetVoteAllWithConditions(query Query, page *Page) ([]Vote, error) {
+	var votes []Vote
 
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&votes).Error
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&Vote{}), voteQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	err = paginate(tx, page, &votes)
 	return votes, err
 }
@@ -1,15 +1,19 @@
 package models
 
-import "community-governance/db"
+import (
+	"community-governance/db"
+	"gorm.io/gorm"
+)
 
 // VoteOption 表示投票选项表
 type VoteOption struct {
-	OptionID    string `gorm:"primaryKey;type:varchar(64);not null" json:"option_id"`
-	VoteID      string `gorm:"type:varchar(64);not null" json:"vote_id"`
-	OptionValue string `gorm:"type:varchar(100);not null" json:"option_value"`
-	Status      string `gorm:"type:varchar(10);not null" json:"status"`
-	CreateDate  string `gorm:"type:varchar(26);not null" json:"create_date"`
-	Description string `gorm:"type:varchar(200);not null" json:"description"`
+	OptionID    string         `gorm:"primaryKey;type:varchar(64);not null" json:"option_id"`
+	VoteID      string         `gorm:"type:varchar(64);not null" json:"vote_id"`
+	OptionValue string         `gorm:"type:varchar(100);not null" json:"option_value"`
+	Status      string         `gorm:"type:varchar(10);not null" json:"status"`
+	CreateDate  string         `gorm:"type:varchar(26);not null" json:"create_date"`
+	Description string         `gorm:"type:varchar(200);not null" json:"description"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (VoteOption) TableName() string {
@@ -3,22 +3,27 @@ package models
 import (
 	"community-governance/db"
 	"fmt"
+	"gorm.io/gorm"
 )
 
 // VoteRule 表示投票规则表
 type VoteRule struct {
-	RuleID      string `gorm:"primaryKey;type:varchar(64);not null" json:"rule_id"`
-	RuleType    string `gorm:"type:varchar(10);not null" json:"rule_type"`
-	RuleValue   string `gorm:"type:varchar(50);not null" json:"rule_value"`
-	Description string `gorm:"type:varchar(200);not null" json:"description"`
-	CreateDate  string `gorm:"type:varchar(26);not null" json:"create_date"`
-	RuleName    string `gorm:"type:varchar(20);not null" json:"rule_name"`
+	RuleID      string         `gorm:"primaryKey;type:varchar(64);not null" json:"rule_id"`
+	RuleType    string         `gorm:"type:varchar(10);not null" json:"rule_type"`
+	RuleValue   string         `gorm:"type:varchar(50);not null" json:"rule_value"`
+	Description string         `gorm:"type:varchar(200);not null" json:"description"`
+	CreateDate  string         `gorm:"type:varchar(26);not null" json:"create_date"`
+	RuleName    string         `gorm:"type:varchar(20);not null" json:"rule_name"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (VoteRule) TableName() string {
 	return "vote_rule"
 }
 
+// voteRuleQueryFields 允许条件查询的列
+var voteRuleQueryFields = NewQueryFields("rule_id", "rule_type", "rule_value", "description", "create_date", "rule_name")
+
 // CreateVoteRule 创建投票规则
 func CreateVoteRule(rule *VoteRule) error {
 	return db.DB.Create(rule).Error
@@ -58,26 +63,21 @@ func GetVoteRuleNames() ([]string, error) {
 	return ruleTypes, err
 }
 
-func GetVoteRulesAllWithPagination(page, pageSize int) ([]VoteRule, error) {
+func GetVoteRulesAllWithPagination(page *Page) ([]VoteRule, error) {
 	var voteRules []VoteRule
-
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
-
-	// 使用 Limit 和 Offset 进行分页查询
-	err := db.DB.Limit(pageSize).Offset(offset).Find(&voteRules).Error
+	err := paginate(db.DB.Model(&VoteRule{}), page, &voteRules)
 	return voteRules, err
 }
-func GetVoteRulesAllWithConditions(conditions map[string]interface{}, page, pageSize int) ([]VoteRule, error) {
+func GetVoteRulesAllWithConditions(query Query, page *Page) ([]VoteRule, error) {
 	var voteRules []VoteRule
 
-	// 计算 OFFSET
-	offset := (page - 1) * pageSize
-
-	// 使用 Limit 和 Offset 进行分页查询，并根据条件构建 WHERE 子句
-	err := db.DB.Where(conditions).Limit(pageSize).Offset(offset).Find(&voteRules).Error
+	// 根据查询条件构建 WHERE 子句
+	tx, err := query.Apply(db.DB.Model(&VoteRule{}), voteRuleQueryFields)
+	if err != nil {
+		return nil, err
+	}
+	err = paginate(tx, page, &voteRules)
 	return voteRules, err
-
 }
 
 // GetRuleIdByName 根据rule_name查询rule id
@@ -6,7 +6,9 @@ import (
 )
 
 func TestGetVoteRuleById(t *testing.T) {
-	db.InitDB()
+	if err := db.InitDB(); err != nil {
+		t.Fatal(err)
+	}
 	ruleId, err := GetRuleIdByName("最低投票要求")
 	if err != nil {
 		t.Errorf("GetRuleIdByName failed: %v", err)
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// 候补状态，与链上一致
+const (
+	WaitStatusWaiting   = "waiting"   // 等待时段空出
+	WaitStatusOffered   = "offered"   // 时段已提供，等待确认
+	WaitStatusConfirmed = "confirmed" // 已确认并生成预约
+	WaitStatusExpired   = "expired"   // 未在时限内确认或时段已开始
+	WaitStatusWithdrawn = "withdrawn" // 已退出候补
+)
+
+var ErrSlotAvailable = errors.New("slot is available")
+
+// FacilityPolicy 设施预约政策表，各项为0表示不限制
+type FacilityPolicy struct {
+	FacilityID     string `gorm:"primaryKey;type:varchar(64);not null" json:"facility_id"` // 设施ID
+	MaxWeeklyHours int    `gorm:"not null" json:"max_weekly_hours"`                        // 每户每周（周一起算）最多预约的小时数
+	AdvanceDays    int    `gorm:"not null" json:"advance_days"`                            // 最多可提前预约的天数
+	MaxActive      int    `gorm:"not null" json:"max_active"`                              // 每户同时持有的未结束预约数上限
+	ConfirmMinutes int    `gorm:"not null" json:"confirm_minutes"`                         // 候补确认时限，为0时使用链上默认时限
+	Updater        string `gorm:"type:varchar(64)" json:"updater"`                         // 设置人
+	UpdateTime     string `gorm:"type:varchar(26)" json:"update_time"`                     // 设置时间
+}
+
+func (FacilityPolicy) TableName() string {
+	return "facility_policy"
+}
+
+// FacilityWaitlist 设施候补表，状态变化以链上为准同步
+type FacilityWaitlist struct {
+	EntryID       string `gorm:"primaryKey;type:varchar(64);not null" json:"entry_id"` // 候补ID
+	FacilityID    string `gorm:"type:varchar(64);not null;index" json:"facility_id"`   // 设施ID
+	Member        string `gorm:"type:varchar(64);not null;index" json:"member"`        // 候补人
+	Household     string `gorm:"type:varchar(64)" json:"household"`                    // 候补人户号
+	Date          string `gorm:"type:varchar(10);not null;index" json:"date"`          // 候补日期
+	Start         string `gorm:"type:varchar(16);not null" json:"start"`               // 开始时间
+	End           string `gorm:"type:varchar(16);not null" json:"end"`                 // 结束时间
+	Purpose       string `gorm:"type:varchar(200)" json:"purpose"`                     // 用途，确认后写入预约
+	Status        string `gorm:"type:varchar(10);not null;index" json:"status"`        // 候补状态
+	CreateTime    string `gorm:"type:varchar(26);not null" json:"create_time"`         // 加入时间
+	OfferTime     string `gorm:"type:varchar(26)" json:"offer_time"`                   // 时段提供给候补的时间
+	Deadline      string `gorm:"type:varchar(26)" json:"deadline"`                     // 确认截止时间
+	ReservationID string `gorm:"type:varchar(64)" json:"reservation_id"`               // 确认后生成的预约ID
+}
+
+func (FacilityWaitlist) TableName() string {
+	return "facility_waitlist"
+}
+
+// WaitlistDay 有候补待处理的设施与日期
+type WaitlistDay struct {
+	FacilityID string
+	Date       string
+}
+
+// SaveFacilityPolicy 保存设施预约政策，anchor在写库后执行，用于同步上链
+func SaveFacilityPolicy(policy *FacilityPolicy, anchor func() error) error {
+	if policy.MaxWeeklyHours < 0 || policy.AdvanceDays < 0 || policy.MaxActive < 0 || policy.ConfirmMinutes < 0 {
+		return fmt.Errorf("%w: policy values must not be negative", ErrInvalidReservation)
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&PublicFacility{}, "facility_id = ?", policy.FacilityID).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(policy).Error; err != nil {
+			return err
+		}
+		return anchor()
+	})
+}
+
+// GetFacilityPolicy 查询设施预约政策，未设置时返回不限制的政策
+func GetFacilityPolicy(facilityID string) (*FacilityPolicy, error) {
+	return getFacilityPolicy(db.DB, facilityID)
+}
+
+func getFacilityPolicy(tx *gorm.DB, facilityID string) (*FacilityPolicy, error) {
+	policy := FacilityPolicy{FacilityID: facilityID}
+	err := tx.First(&policy, "facility_id = ?", facilityID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &policy, nil
+	}
+	return &policy, err
+}
+
+// weekStart 返回t所在周的周一
+func weekStart(t time.Time) time.Time {
+	return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+}
+
+// checkQuota 校验预约是否符合提前预约天数、每户同时持有预约数与每户每周时长的限制
+func checkQuota(tx *gorm.DB, policy *FacilityPolicy, reservation *FacilityReservation, now string) error {
+	from, err := time.Parse(ReservationLayout, reservation.Start)
+	if err != nil {
+		return fmt.Errorf("%w: start %s", ErrInvalidReservation, reservation.Start)
+	}
+	to, err := time.Parse(ReservationLayout, reservation.End)
+	if err != nil {
+		return fmt.Errorf("%w: end %s", ErrInvalidReservation, reservation.End)
+	}
+	if policy.AdvanceDays > 0 {
+		today, err := time.Parse(ReservationDate, now[:10])
+		if err != nil {
+			return err
+		}
+		if last := today.AddDate(0, 0, policy.AdvanceDays).Format(ReservationDate); reservation.Start[:10] > last {
+			return fmt.Errorf("%w: reservation can be made at most %d days in advance", ErrQuotaExceeded, policy.AdvanceDays)
+		}
+	}
+	booked := tx.Model(&FacilityReservation{}).Where("facility_id = ? AND household = ? AND status = ?",
+		reservation.FacilityID, reservation.Household, ReservationStatusBooked)
+	if policy.MaxActive > 0 {
+		var active int64
+		if err := booked.Session(&gorm.Session{}).Where("`end` > ?", now[:16]).Count(&active).Error; err != nil {
+			return err
+		}
+		if active >= int64(policy.MaxActive) {
+			return fmt.Errorf("%w: household %s already holds %d active reservations", ErrQuotaExceeded, reservation.Household, active)
+		}
+	}
+	if policy.MaxWeeklyHours > 0 {
+		monday := weekStart(from)
+		var week []FacilityReservation
+		err := booked.Session(&gorm.Session{}).Where("date >= ? AND date <= ?", monday.Format(ReservationDate),
+			monday.AddDate(0, 0, 6).Format(ReservationDate)).Find(&week).Error
+		if err != nil {
+			return err
+		}
+		used := to.Sub(from)
+		for _, r := range week {
+			start, err := time.Parse(ReservationLayout, r.Start)
+			if err != nil {
+				return err
+			}
+			end, err := time.Parse(ReservationLayout, r.End)
+			if err != nil {
+				return err
+			}
+			used += end.Sub(start)
+		}
+		if used > time.Duration(policy.MaxWeeklyHours)*time.Hour {
+			return fmt.Errorf("%w: household %s exceeds %d hours in the week of %s", ErrQuotaExceeded,
+				reservation.Household, policy.MaxWeeklyHours, monday.Format(ReservationDate))
+		}
+	}
+	return nil
+}
+
+// JoinWaitlist 候补已被预约或保留的时段，时段空闲时返回ErrSlotAvailable，anchor在写入候补前执行，用于上链
+func JoinWaitlist(entry *FacilityWaitlist, now string, anchor func() error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		schedule, err := lockSchedule(tx, entry.FacilityID)
+		if err != nil {
+			return err
+		}
+		if err := CheckReservationSlot(schedule, entry.Start, entry.End, now); err != nil {
+			return err
+		}
+		err = checkSlotTaken(tx, entry.FacilityID, entry.Start, entry.End, now, "")
+		if err == nil {
+			return fmt.Errorf("%w: %s - %s, reserve it directly", ErrSlotAvailable, entry.Start, entry.End)
+		}
+		if !errors.Is(err, ErrReservationConflict) {
+			return err
+		}
+		var existing FacilityWaitlist
+		err = tx.Where("facility_id = ? AND member = ? AND `start` = ? AND `end` = ? AND status IN ?", entry.FacilityID, entry.Member,
+			entry.Start, entry.End, []string{WaitStatusWaiting, WaitStatusOffered}).First(&existing).Error
+		if err == nil {
+			return fmt.Errorf("%w: already on the waitlist as %s", ErrReservationConflict, existing.EntryID)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		entry.Date = entry.Start[:10]
+		entry.Status = WaitStatusWaiting
+		if err := anchor(); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+// LeaveWaitlist 退出候补，非候补人须override为true，anchor在写库后执行，用于同步上链
+func LeaveWaitlist(id, operator string, override bool, anchor func(*FacilityWaitlist) error) (*FacilityWaitlist, error) {
+	var entry FacilityWaitlist
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entry, "entry_id = ?", id).Error; err != nil {
+			return err
+		}
+		if entry.Member != operator && !override {
+			return fmt.Errorf("%w: %s is not the waitlist member", ErrReservationDenied, operator)
+		}
+		if entry.Status != WaitStatusWaiting && entry.Status != WaitStatusOffered {
+			return fmt.Errorf("%w: waitlist entry %s is %s", ErrReservationState, id, entry.Status)
+		}
+		entry.Status = WaitStatusWithdrawn
+		if err := tx.Model(&entry).Update("status", entry.Status).Error; err != nil {
+			return err
+		}
+		return anchor(&entry)
+	})
+	return &entry, err
+}
+
+// ConfirmWaitOffer 候补人在确认时限内确认提供的时段，生成预约，预约仍须符合设施的预约政策
+// anchor在写入预约前执行，用于上链并回填交易ID
+func ConfirmWaitOffer(id, member string, reservation *FacilityReservation, now string, anchor func() error) (*FacilityWaitlist, error) {
+	var entry FacilityWaitlist
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&entry, "entry_id = ?", id).Error; err != nil {
+			return err
+		}
+		if _, err := lockSchedule(tx, entry.FacilityID); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entry, "entry_id = ?", id).Error; err != nil {
+			return err
+		}
+		if entry.Member != member {
+			return fmt.Errorf("%w: %s is not the waitlist member", ErrReservationDenied, member)
+		}
+		if entry.Status != WaitStatusOffered || entry.Deadline <= now {
+			return fmt.Errorf("%w: waitlist entry %s has no pending offer", ErrReservationState, id)
+		}
+		reservation.FacilityID = entry.FacilityID
+		reservation.Start = entry.Start
+		reservation.End = entry.End
+		reservation.Purpose = entry.Purpose
+		if err := createReservation(tx, reservation, now, entry.EntryID, anchor); err != nil {
+			return err
+		}
+		entry.Status = WaitStatusConfirmed
+		entry.ReservationID = reservation.ReservationID
+		return tx.Model(&entry).Select("status", "reservation_id").Updates(&entry).Error
+	})
+	return &entry, err
+}
+
+// SyncWaitlist 按链上状态更新候补的状态、提供时间与确认截止时间
+func SyncWaitlist(entries []FacilityWaitlist) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range entries {
+			err := tx.Model(&FacilityWaitlist{}).Where("entry_id = ?", entries[i].EntryID).
+				Select("status", "offer_time", "deadline", "reservation_id").Updates(&entries[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetWaitlistEntryByID 查询候补
+func GetWaitlistEntryByID(id string) (*FacilityWaitlist, error) {
+	var entry FacilityWaitlist
+	err := db.DB.First(&entry, "entry_id = ?", id).Error
+	return &entry, err
+}
+
+// GetFacilityWaitlist 查询设施某天的候补，按加入顺序排列，status为空时不筛选
+func GetFacilityWaitlist(facilityID, date, status string) ([]FacilityWaitlist, error) {
+	entries := make([]FacilityWaitlist, 0)
+	tx := db.DB.Where("facility_id = ? AND date = ?", facilityID, date)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err := tx.Order("create_time, entry_id").Find(&entries).Error
+	return entries, err
+}
+
+// GetWaitlistWithPagination 分页查询候补，参数为空时不筛选
+func GetWaitlistWithPagination(member, facilityID, status string, page *Page) ([]FacilityWaitlist, error) {
+	var entries []FacilityWaitlist
+	tx := db.DB.Model(&FacilityWaitlist{})
+	if member != "" {
+		tx = tx.Where("member = ?", member)
+	}
+	if facilityID != "" {
+		tx = tx.Where("facility_id = ?", facilityID)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err := paginate(tx.Order("`start` desc, create_time"), page, &entries)
+	return entries, err
+}
+
+// GetLapsedWaitlistDays 查询有超过确认时限的候补或时段已开始仍在等待的候补的设施与日期
+func GetLapsedWaitlistDays(now string) ([]WaitlistDay, error) {
+	var days []WaitlistDay
+	err := db.DB.Model(&FacilityWaitlist{}).Distinct("facility_id", "date").
+		Where("(status = ? AND deadline <= ?) OR (status = ? AND `start` <= ?)", WaitStatusOffered, now, WaitStatusWaiting, now[:16]).
+		Find(&days).Error
+	return days, err
+}
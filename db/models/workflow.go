@@ -0,0 +1,561 @@
+package models
+
+import (
+	"community-governance/db"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// 审批对象类型，每种类型可配置一个审批流程
+const (
+	WorkflowKindAssetRequest    = "asset_request"    // 资产申请
+	WorkflowKindFundExpense     = "fund_expense"     // 款项支出
+	WorkflowKindFacilityBooking = "facility_booking" // 公共设施预约
+	WorkflowKindNotice          = "notice"           // 公告发布
+	WorkflowKindMemberRegister  = "member_register"  // 成员注册
+)
+
+// WorkflowKinds 可配置审批流程的对象类型
+var WorkflowKinds = map[string]bool{
+	WorkflowKindAssetRequest:    true,
+	WorkflowKindFundExpense:     true,
+	WorkflowKindFacilityBooking: true,
+	WorkflowKindNotice:          true,
+	WorkflowKindMemberRegister:  true,
+}
+
+// 审批流程实例状态
+const (
+	WorkflowStatusPending  = "pending"  // 审批中
+	WorkflowStatusApproved = "approved" // 已通过
+	WorkflowStatusRejected = "rejected" // 已驳回
+)
+
+// 审批步骤状态
+const (
+	TaskStatusWaiting   = "waiting"   // 等待前一阶段完成
+	TaskStatusPending   = "pending"   // 待审批
+	TaskStatusApproved  = "approved"  // 已批准
+	TaskStatusRejected  = "rejected"  // 已驳回
+	TaskStatusCancelled = "cancelled" // 流程驳回后未完成的步骤
+)
+
+// 审批决定，意见只记录在链下，批准与驳回同时上链
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+	DecisionComment = "comment"
+)
+
+var (
+	ErrInvalidWorkflow = errors.New("invalid workflow")
+	ErrNoWorkflow      = errors.New("no workflow is defined")
+	ErrWorkflowState   = errors.New("illegal workflow status")
+	ErrWorkflowDenied  = errors.New("not allowed to decide the workflow")
+	ErrWorkflowDecided = errors.New("task has been decided by the approver")
+)
+
+// WorkflowDefinition 审批流程定义，步骤按阶段顺序执行，同一阶段的步骤并行审批
+type WorkflowDefinition struct {
+	Kind       string         `gorm:"primaryKey;type:varchar(30);not null" json:"kind"` // 审批对象类型
+	Name       string         `gorm:"type:varchar(100);not null" json:"name"`           // 流程名称
+	Updater    string         `gorm:"type:varchar(64);not null" json:"updater"`         // 修改人
+	UpdateTime string         `gorm:"type:varchar(26);not null" json:"update_time"`     // 修改时间
+	Steps      []WorkflowStep `gorm:"foreignKey:Kind;references:Kind" json:"steps"`     // 审批步骤
+}
+
+func (WorkflowDefinition) TableName() string {
+	return "workflow_definition"
+}
+
+// WorkflowStep 审批步骤，超过期限未完成时可升级给其他角色审批
+type WorkflowStep struct {
+	StepID        uint64 `gorm:"primaryKey;autoIncrement" json:"step_id"`                   // 步骤ID
+	Kind          string `gorm:"type:varchar(30);not null;index" json:"kind"`               // 审批对象类型
+	Stage         int    `gorm:"not null" json:"stage"`                                     // 阶段，从1开始
+	Name          string `gorm:"type:varchar(50);not null" json:"name"`                     // 步骤名称
+	Role          string `gorm:"type:varchar(20);not null" json:"role"`                     // 审批角色
+	Required      int    `gorm:"not null" json:"required"`                                  // 需要的批准人数
+	DeadlineHours int    `gorm:"not null;default:0" json:"deadline_hours"`                  // 审批期限，0表示不限
+	EscalateRole  string `gorm:"type:varchar(20);not null;default:''" json:"escalate_role"` // 超期后可审批的角色，为空时不升级
+}
+
+func (WorkflowStep) TableName() string {
+	return "workflow_step"
+}
+
+// WorkflowInstance 审批流程实例，发起时复制流程定义的步骤，之后修改流程定义不影响进行中的实例
+type WorkflowInstance struct {
+	InstanceID string             `gorm:"primaryKey;type:varchar(64);not null" json:"instance_id"` // 实例ID
+	Kind       string             `gorm:"type:varchar(30);not null;index" json:"kind"`             // 审批对象类型
+	TargetID   string             `gorm:"type:varchar(64);not null;index" json:"target_id"`        // 审批对象ID
+	Title      string             `gorm:"type:varchar(100);not null" json:"title"`                 // 标题
+	Requester  string             `gorm:"type:varchar(64);not null;index" json:"requester"`        // 发起人
+	Status     string             `gorm:"type:varchar(10);not null;index" json:"status"`           // 实例状态
+	Stage      int                `gorm:"not null" json:"stage"`                                   // 当前阶段
+	CreateTime string             `gorm:"type:varchar(26);not null" json:"create_time"`            // 发起时间
+	FinishTime string             `gorm:"type:varchar(26)" json:"finish_time"`                     // 结束时间
+	Tasks      []WorkflowTask     `gorm:"foreignKey:InstanceID" json:"tasks,omitempty"`            // 审批步骤
+	Decisions  []WorkflowDecision `gorm:"foreignKey:InstanceID" json:"decisions,omitempty"`        // 审批决定与意见
+}
+
+func (WorkflowInstance) TableName() string {
+	return "workflow_instance"
+}
+
+// WorkflowTask 审批流程实例中的一个审批步骤
+type WorkflowTask struct {
+	TaskID       string `gorm:"primaryKey;type:varchar(80);not null" json:"task_id"`       // 步骤ID
+	InstanceID   string `gorm:"type:varchar(64);not null;index" json:"instance_id"`        // 实例ID
+	Stage        int    `gorm:"not null" json:"stage"`                                     // 阶段
+	Name         string `gorm:"type:varchar(50);not null" json:"name"`                     // 步骤名称
+	Role         string `gorm:"type:varchar(20);not null;index" json:"role"`               // 审批角色
+	Required     int    `gorm:"not null" json:"required"`                                  // 需要的批准人数
+	Approvals    int    `gorm:"not null;default:0" json:"approvals"`                       // 已批准人数
+	Hours        int    `gorm:"not null;default:0" json:"deadline_hours"`                  // 审批期限
+	Deadline     string `gorm:"type:varchar(26)" json:"deadline"`                          // 截止时间，步骤开始时计算
+	EscalateRole string `gorm:"type:varchar(20);not null;default:''" json:"escalate_role"` // 超期后可审批的角色
+	Escalated    bool   `gorm:"not null;default:false" json:"escalated"`                   // 是否已升级
+	Status       string `gorm:"type:varchar(10);not null;index" json:"status"`             // 步骤状态
+	StartTime    string `gorm:"type:varchar(26)" json:"start_time"`                        // 开始时间
+	FinishTime   string `gorm:"type:varchar(26)" json:"finish_time"`                       // 完成时间
+}
+
+func (WorkflowTask) TableName() string {
+	return "workflow_task"
+}
+
+// WorkflowDecision 审批决定或意见
+type WorkflowDecision struct {
+	DecisionID string `gorm:"primaryKey;type:varchar(64);not null" json:"decision_id"` // 决定ID
+	InstanceID string `gorm:"type:varchar(64);not null;index" json:"instance_id"`      // 实例ID
+	TaskID     string `gorm:"type:varchar(80)" json:"task_id"`                         // 步骤ID，意见为空
+	Approver   string `gorm:"type:varchar(64);not null" json:"approver"`               // 审批人或发表意见的成员
+	Role       string `gorm:"type:varchar(20)" json:"role"`                            // 审批人以该角色审批
+	Decision   string `gorm:"type:varchar(10);not null" json:"decision"`               // 决定
+	Comment    string `gorm:"type:varchar(500)" json:"comment"`                        // 意见
+	DecideTime string `gorm:"type:varchar(26);not null" json:"decide_time"`            // 时间
+	TxID       string `gorm:"type:varchar(64)" json:"tx_id"`                           // 上链交易ID，意见为空
+}
+
+func (WorkflowDecision) TableName() string {
+	return "workflow_decision"
+}
+
+// WorkflowTaskView 待审批步骤及其所属实例
+type WorkflowTaskView struct {
+	WorkflowTask
+	Kind      string `json:"kind"`
+	TargetID  string `json:"target_id"`
+	Title     string `json:"title"`
+	Requester string `json:"requester"`
+}
+
+// CheckWorkflowSteps 校验审批步骤，阶段从1开始连续编号，每个步骤需指定角色与批准人数
+func CheckWorkflowSteps(steps []WorkflowStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", ErrInvalidWorkflow)
+	}
+	stages := make(map[int]bool)
+	last := 0
+	for _, step := range steps {
+		if step.Name == "" || step.Role == "" {
+			return fmt.Errorf("%w: step name and role are required", ErrInvalidWorkflow)
+		}
+		if step.Required < 1 {
+			return fmt.Errorf("%w: step %s requires at least one approval", ErrInvalidWorkflow, step.Name)
+		}
+		if step.DeadlineHours < 0 {
+			return fmt.Errorf("%w: deadline of step %s must not be negative", ErrInvalidWorkflow, step.Name)
+		}
+		if step.EscalateRole != "" && step.DeadlineHours == 0 {
+			return fmt.Errorf("%w: step %s escalates without a deadline", ErrInvalidWorkflow, step.Name)
+		}
+		stages[step.Stage] = true
+		last = max(last, step.Stage)
+	}
+	for stage := 1; stage <= last; stage++ {
+		if !stages[stage] {
+			return fmt.Errorf("%w: stage %d has no step", ErrInvalidWorkflow, stage)
+		}
+	}
+	if stages[0] || len(stages) != last {
+		return fmt.Errorf("%w: stages must start from 1", ErrInvalidWorkflow)
+	}
+	return nil
+}
+
+// SaveWorkflowDefinition 保存审批流程定义，整体替换原有步骤
+// anchor在步骤写入后、事务提交前执行，用于同步链上的流程定义
+func SaveWorkflowDefinition(definition *WorkflowDefinition, anchor func(*WorkflowDefinition) error) error {
+	if err := CheckWorkflowSteps(definition.Steps); err != nil {
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		steps := definition.Steps
+		if err := tx.Omit("Steps").Save(definition).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("kind = ?", definition.Kind).Delete(&WorkflowStep{}).Error; err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].StepID = 0
+			steps[i].Kind = definition.Kind
+		}
+		if err := tx.Create(&steps).Error; err != nil {
+			return err
+		}
+		return anchor(definition)
+	})
+}
+
+// GetWorkflowDefinition 查询审批流程定义及其步骤
+func GetWorkflowDefinition(kind string) (*WorkflowDefinition, error) {
+	var definition WorkflowDefinition
+	err := db.DB.Preload("Steps", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("stage, step_id")
+	}).First(&definition, "kind = ?", kind).Error
+	return &definition, err
+}
+
+// GetWorkflowDefinitions 查询全部审批流程定义
+func GetWorkflowDefinitions() ([]WorkflowDefinition, error) {
+	var definitions []WorkflowDefinition
+	err := db.DB.Preload("Steps", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("stage, step_id")
+	}).Order("kind").Find(&definitions).Error
+	return definitions, err
+}
+
+// DeleteWorkflowDefinition 删除审批流程定义，之后发起的审批使用默认流程，进行中的实例不受影响
+// anchor在删除后、事务提交前执行，用于将链上的流程定义恢复为默认流程
+func DeleteWorkflowDefinition(kind string, anchor func() error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kind = ?", kind).Delete(&WorkflowStep{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&WorkflowDefinition{}, "kind = ?", kind)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return anchor()
+	})
+}
+
+// taskDeadline 步骤的截止时间，没有期限时为空
+func taskDeadline(start string, hours int) string {
+	if hours == 0 {
+		return ""
+	}
+	begin, err := time.ParseInLocation("2006-01-02 15:04:05", start, time.Local)
+	if err != nil {
+		return ""
+	}
+	return begin.Add(time.Duration(hours) * time.Hour).Format("2006-01-02 15:04:05")
+}
+
+// StartWorkflow 发起审批，按对象类型的流程定义生成审批步骤，未配置流程时使用fallback
+// target不为nil时在同一事务中创建审批对象，同一对象不能同时有两个审批中的实例
+// anchor在实例写入后、事务提交前执行，用于在链上发起实例，上链失败时实例一并回滚
+func StartWorkflow(instance *WorkflowInstance, fallback []WorkflowStep, target interface{}, anchor func(*WorkflowInstance) error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		steps := fallback
+		var definition WorkflowDefinition
+		err := tx.Preload("Steps", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("stage, step_id")
+		}).First(&definition, "kind = ?", instance.Kind).Error
+		switch {
+		case err == nil:
+			steps = definition.Steps
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		if len(steps) == 0 {
+			return fmt.Errorf("%w: %s", ErrNoWorkflow, instance.Kind)
+		}
+		var count int64
+		err = tx.Model(&WorkflowInstance{}).Where("kind = ? AND target_id = ? AND status = ?", instance.Kind, instance.TargetID, WorkflowStatusPending).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %s %s is under approval", ErrWorkflowState, instance.Kind, instance.TargetID)
+		}
+		if target != nil {
+			if err := tx.Create(target).Error; err != nil {
+				return err
+			}
+		}
+		instance.Status = WorkflowStatusPending
+		instance.Stage = 1
+		instance.Tasks = make([]WorkflowTask, len(steps))
+		for i, step := range steps {
+			task := WorkflowTask{
+				TaskID:       fmt.Sprintf("%s-%d", instance.InstanceID, i+1),
+				InstanceID:   instance.InstanceID,
+				Stage:        step.Stage,
+				Name:         step.Name,
+				Role:         step.Role,
+				Required:     step.Required,
+				Hours:        step.DeadlineHours,
+				EscalateRole: step.EscalateRole,
+				Status:       TaskStatusWaiting,
+			}
+			if step.Stage == 1 {
+				task.Status = TaskStatusPending
+				task.StartTime = instance.CreateTime
+				task.Deadline = taskDeadline(instance.CreateTime, task.Hours)
+			}
+			instance.Tasks[i] = task
+		}
+		if err := tx.Create(instance).Error; err != nil {
+			return err
+		}
+		return anchor(instance)
+	})
+}
+
+// GetWorkflowInstanceByID 查询审批流程实例及其步骤、决定与意见
+func GetWorkflowInstanceByID(id string) (*WorkflowInstance, error) {
+	var instance WorkflowInstance
+	err := db.DB.Preload("Tasks", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("stage, task_id")
+	}).Preload("Decisions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("decide_time, decision_id")
+	}).First(&instance, "instance_id = ?", id).Error
+	return &instance, err
+}
+
+// GetPendingWorkflowInstances 查询全部审批中的实例及其步骤与决定
+func GetPendingWorkflowInstances() ([]WorkflowInstance, error) {
+	var instances []WorkflowInstance
+	err := db.DB.Preload("Tasks", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("stage, task_id")
+	}).Preload("Decisions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("decide_time, decision_id")
+	}).Where("status = ?", WorkflowStatusPending).Order("create_time").Find(&instances).Error
+	return instances, err
+}
+
+// DecidedStages 各审批人已决定的阶段，实例须已加载步骤与决定
+func (i *WorkflowInstance) DecidedStages() map[string][]int {
+	stages := make(map[string]int, len(i.Tasks))
+	for _, task := range i.Tasks {
+		stages[task.TaskID] = task.Stage
+	}
+	decided := make(map[string][]int)
+	for _, d := range i.Decisions {
+		if d.Decision != DecisionApprove && d.Decision != DecisionReject {
+			continue
+		}
+		if stage, ok := stages[d.TaskID]; ok {
+			decided[d.Approver] = append(decided[d.Approver], stage)
+		}
+	}
+	return decided
+}
+
+// GetWorkflowInstanceByTarget 查询审批对象最近一次发起的审批流程实例
+func GetWorkflowInstanceByTarget(kind, targetID string) (*WorkflowInstance, error) {
+	var instance WorkflowInstance
+	err := db.DB.Where("kind = ? AND target_id = ?", kind, targetID).Order("create_time desc").First(&instance).Error
+	if err != nil {
+		return nil, err
+	}
+	return GetWorkflowInstanceByID(instance.InstanceID)
+}
+
+// GetWorkflowInstancesWithPagination 分页查询审批流程实例，筛选条件为空时不筛选
+func GetWorkflowInstancesWithPagination(kind, status, requester string, page *Page) ([]WorkflowInstance, error) {
+	var instances []WorkflowInstance
+	tx := db.DB.Model(&WorkflowInstance{})
+	if kind != "" {
+		tx = tx.Where("kind = ?", kind)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if requester != "" {
+		tx = tx.Where("requester = ?", requester)
+	}
+	err := paginate(tx.Order("create_time desc"), page, &instances)
+	return instances, err
+}
+
+// GetPendingWorkflowTasks 分页查询角色可审批的待审批步骤，包括已升级给该角色的步骤，roles为nil时查询全部
+func GetPendingWorkflowTasks(roles []string, page *Page) ([]WorkflowTaskView, error) {
+	var tasks []WorkflowTaskView
+	tx := db.DB.Table("workflow_task AS t").
+		Select("t.*, i.kind, i.target_id, i.title, i.requester").
+		Joins("JOIN workflow_instance AS i ON i.instance_id = t.instance_id").
+		Where("t.status = ?", TaskStatusPending)
+	if roles != nil {
+		tx = tx.Where("t.role IN ? OR (t.escalated AND t.escalate_role IN ?)", roles, roles)
+	}
+	err := paginate(tx.Order("t.start_time, t.task_id"), page, &tasks)
+	return tasks, err
+}
+
+// DecideWorkflowTask 审批人批准或驳回审批步骤，TaskID为空时选择审批人可审批的第一个步骤，同一审批人在同一阶段只能决定一个步骤
+// canDecide返回审批人审批该步骤时使用的角色，anchor在写入决定前执行，用于将决定上链
+// 步骤的批准人数达到要求后完成该步骤，阶段内步骤全部完成后进入下一阶段，任一步骤驳回时整个流程驳回
+// 流程结束时在事务提交前执行finish，用于执行审批对象的后续操作
+func DecideWorkflowTask(decision *WorkflowDecision, canDecide func(*WorkflowTask) (string, bool),
+	anchor func(*WorkflowInstance, *WorkflowTask, *WorkflowDecision) error, finish func(*WorkflowInstance) error) (*WorkflowInstance, error) {
+	if decision.Decision != DecisionApprove && decision.Decision != DecisionReject {
+		return nil, fmt.Errorf("%w: decision %s", ErrInvalidWorkflow, decision.Decision)
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var instance WorkflowInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&instance, "instance_id = ?", decision.InstanceID).Error; err != nil {
+			return err
+		}
+		if instance.Status != WorkflowStatusPending {
+			return fmt.Errorf("%w: workflow %s is %s", ErrWorkflowState, instance.InstanceID, instance.Status)
+		}
+		//发起人不能审批自己发起的流程
+		if decision.Approver == instance.Requester {
+			return fmt.Errorf("%w: requester %s cannot decide own workflow", ErrWorkflowDenied, decision.Approver)
+		}
+		var tasks []WorkflowTask
+		err := tx.Where("instance_id = ? AND stage = ?", instance.InstanceID, instance.Stage).Order("task_id").Find(&tasks).Error
+		if err != nil {
+			return err
+		}
+		var decided []string
+		err = tx.Model(&WorkflowDecision{}).Where("instance_id = ? AND approver = ? AND decision IN ?",
+			instance.InstanceID, decision.Approver, []string{DecisionApprove, DecisionReject}).Pluck("task_id", &decided).Error
+		if err != nil {
+			return err
+		}
+		//同一审批人在同一阶段只能决定一个步骤，不能以一人之力批准多个并行步骤
+		for i := range tasks {
+			if contains(decided, tasks[i].TaskID) {
+				return fmt.Errorf("%w: %s already decided stage %d of workflow %s", ErrWorkflowDecided, decision.Approver, instance.Stage, instance.InstanceID)
+			}
+		}
+		var task *WorkflowTask
+		for i := range tasks {
+			if tasks[i].Status != TaskStatusPending || (decision.TaskID != "" && tasks[i].TaskID != decision.TaskID) {
+				continue
+			}
+			if role, ok := canDecide(&tasks[i]); ok {
+				task = &tasks[i]
+				decision.Role = role
+				break
+			}
+		}
+		if task == nil {
+			return fmt.Errorf("%w: no pending task of workflow %s for %s", ErrWorkflowDenied, instance.InstanceID, decision.Approver)
+		}
+		decision.TaskID = task.TaskID
+		if err := anchor(&instance, task, decision); err != nil {
+			return err
+		}
+		if err := tx.Create(decision).Error; err != nil {
+			return err
+		}
+		if decision.Decision == DecisionReject {
+			return rejectWorkflow(tx, &instance, task, decision.DecideTime, finish)
+		}
+		task.Approvals++
+		if task.Approvals < task.Required {
+			return tx.Model(task).Update("approvals", task.Approvals).Error
+		}
+		task.Status = TaskStatusApproved
+		task.FinishTime = decision.DecideTime
+		if err := tx.Model(task).Select("approvals", "status", "finish_time").Updates(task).Error; err != nil {
+			return err
+		}
+		return advanceWorkflow(tx, &instance, decision.DecideTime, finish)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetWorkflowInstanceByID(decision.InstanceID)
+}
+
+// rejectWorkflow 驳回流程，取消尚未完成的步骤
+func rejectWorkflow(tx *gorm.DB, instance *WorkflowInstance, task *WorkflowTask, now string, finish func(*WorkflowInstance) error) error {
+	task.Status = TaskStatusRejected
+	task.FinishTime = now
+	if err := tx.Model(task).Select("status", "finish_time").Updates(task).Error; err != nil {
+		return err
+	}
+	err := tx.Model(&WorkflowTask{}).Where("instance_id = ? AND status IN ?", instance.InstanceID, []string{TaskStatusPending, TaskStatusWaiting}).
+		Updates(map[string]interface{}{"status": TaskStatusCancelled, "finish_time": now}).Error
+	if err != nil {
+		return err
+	}
+	return finishWorkflow(tx, instance, WorkflowStatusRejected, now, finish)
+}
+
+// advanceWorkflow 当前阶段的步骤全部完成后开始下一阶段，没有下一阶段时流程通过
+func advanceWorkflow(tx *gorm.DB, instance *WorkflowInstance, now string, finish func(*WorkflowInstance) error) error {
+	var pending int64
+	err := tx.Model(&WorkflowTask{}).Where("instance_id = ? AND stage = ? AND status = ?", instance.InstanceID, instance.Stage, TaskStatusPending).
+		Count(&pending).Error
+	if err != nil || pending > 0 {
+		return err
+	}
+	var next []WorkflowTask
+	err = tx.Where("instance_id = ? AND stage = ?", instance.InstanceID, instance.Stage+1).Find(&next).Error
+	if err != nil {
+		return err
+	}
+	if len(next) == 0 {
+		return finishWorkflow(tx, instance, WorkflowStatusApproved, now, finish)
+	}
+	for i := range next {
+		next[i].Status = TaskStatusPending
+		next[i].StartTime = now
+		next[i].Deadline = taskDeadline(now, next[i].Hours)
+		if err := tx.Model(&next[i]).Select("status", "start_time", "deadline").Updates(&next[i]).Error; err != nil {
+			return err
+		}
+	}
+	instance.Stage++
+	return tx.Model(instance).Update("stage", instance.Stage).Error
+}
+
+func finishWorkflow(tx *gorm.DB, instance *WorkflowInstance, status, now string, finish func(*WorkflowInstance) error) error {
+	instance.Status = status
+	instance.FinishTime = now
+	if err := tx.Model(instance).Select("status", "finish_time").Updates(instance).Error; err != nil {
+		return err
+	}
+	return finish(instance)
+}
+
+// AddWorkflowComment 发表审批意见，意见不影响审批进度
+func AddWorkflowComment(comment *WorkflowDecision) error {
+	comment.Decision = DecisionComment
+	comment.TaskID = ""
+	comment.TxID = ""
+	return db.DB.Create(comment).Error
+}
+
+// EscalateWorkflowTasks 将超过截止时间仍未完成且配置了升级角色的步骤升级，返回升级的步骤数
+func EscalateWorkflowTasks(now string) (int64, error) {
+	result := db.DB.Model(&WorkflowTask{}).
+		Where("status = ? AND escalated = ? AND escalate_role <> '' AND deadline <> '' AND deadline < ?", TaskStatusPending, false, now).
+		Update("escalated", true)
+	return result.RowsAffected, result.Error
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
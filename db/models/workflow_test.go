@@ -0,0 +1,164 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testWorkflowSteps 第1阶段为业委会（需2人批准）与财务负责人两个并行步骤，第2阶段为管理员
+var testWorkflowSteps = []WorkflowStep{
+	{Stage: 1, Name: "业委会审核", Role: "committee", Required: 2},
+	{Stage: 1, Name: "财务审核", Role: "treasurer", Required: 1},
+	{Stage: 2, Name: "管理员确认", Role: "admin", Required: 1},
+}
+
+// testApproverRoles 审批人的角色，*可审批任何步骤
+var testApproverRoles = map[string]string{"c1": "committee", "c2": "committee", "c3": "committee", "t1": "treasurer", "bad": "treasurer", "a1": "admin", "x": "*"}
+
+func startTestWorkflow(t *testing.T, instanceID string, anchor func(*WorkflowInstance) error) error {
+	t.Helper()
+	instance := &WorkflowInstance{InstanceID: instanceID, Kind: WorkflowKindAssetRequest, TargetID: "r1", Title: "申请",
+		Requester: "req", CreateTime: "2024-06-01 08:00:00"}
+	return StartWorkflow(instance, testWorkflowSteps, nil, anchor)
+}
+
+func TestDecideWorkflowTask(t *testing.T) {
+	type step struct {
+		approver string
+		decision string
+		err      error
+	}
+	approve := func(approver string, err error) step { return step{approver, DecisionApprove, err} }
+	cases := []struct {
+		name     string
+		steps    []step
+		stage    int
+		status   string
+		tasks    []string
+		finished int
+	}{
+		{"parallel stage waits for every step", []step{approve("c1", nil), approve("t1", nil)},
+			1, WorkflowStatusPending, []string{TaskStatusPending, TaskStatusApproved, TaskStatusWaiting}, 0},
+		{"parallel stage advances when all steps approved", []step{approve("c1", nil), approve("t1", nil), approve("c2", nil)},
+			2, WorkflowStatusPending, []string{TaskStatusApproved, TaskStatusApproved, TaskStatusPending}, 0},
+		{"last stage approves workflow", []step{approve("t1", nil), approve("c1", nil), approve("c2", nil), approve("a1", nil)},
+			2, WorkflowStatusApproved, []string{TaskStatusApproved, TaskStatusApproved, TaskStatusApproved}, 1},
+		{"one approver cannot approve two parallel steps", []step{approve("x", nil), approve("x", ErrWorkflowDecided)},
+			1, WorkflowStatusPending, []string{TaskStatusPending, TaskStatusPending, TaskStatusWaiting}, 0},
+		{"one approver counts once in a step", []step{approve("c1", nil), approve("c1", ErrWorkflowDecided)},
+			1, WorkflowStatusPending, []string{TaskStatusPending, TaskStatusPending, TaskStatusWaiting}, 0},
+		{"approver may decide again in next stage", []step{approve("x", nil), approve("c1", nil), approve("t1", nil), approve("x", nil)},
+			2, WorkflowStatusApproved, []string{TaskStatusApproved, TaskStatusApproved, TaskStatusApproved}, 1},
+		{"requester cannot decide", []step{approve("req", ErrWorkflowDenied)},
+			1, WorkflowStatusPending, []string{TaskStatusPending, TaskStatusPending, TaskStatusWaiting}, 0},
+		{"role of a later stage cannot decide", []step{approve("a1", ErrWorkflowDenied)},
+			1, WorkflowStatusPending, []string{TaskStatusPending, TaskStatusPending, TaskStatusWaiting}, 0},
+		{"reject cancels unfinished steps", []step{approve("c1", nil), {"t1", DecisionReject, nil}},
+			1, WorkflowStatusRejected, []string{TaskStatusCancelled, TaskStatusRejected, TaskStatusCancelled}, 1},
+		{"finished workflow cannot be decided", []step{{"t1", DecisionReject, nil}, approve("c1", ErrWorkflowState)},
+			1, WorkflowStatusRejected, []string{TaskStatusCancelled, TaskStatusRejected, TaskStatusCancelled}, 1},
+		{"decision rolled back when anchor fails", []step{approve("bad", errAnchor), approve("t1", nil)},
+			1, WorkflowStatusPending, []string{TaskStatusPending, TaskStatusApproved, TaskStatusWaiting}, 0},
+		{"comment is not a decision", []step{{"c1", DecisionComment, ErrInvalidWorkflow}},
+			1, WorkflowStatusPending, []string{TaskStatusPending, TaskStatusPending, TaskStatusWaiting}, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestDB(t)
+			if err := startTestWorkflow(t, "w1", func(*WorkflowInstance) error { return nil }); err != nil {
+				t.Fatal(err)
+			}
+			finished := 0
+			for i, s := range c.steps {
+				decision := &WorkflowDecision{DecisionID: fmt.Sprintf("d%d", i), InstanceID: "w1", Approver: s.approver,
+					Decision: s.decision, DecideTime: fmt.Sprintf("2024-06-01 09:%02d:00", i)}
+				canDecide := func(task *WorkflowTask) (string, bool) {
+					role := testApproverRoles[s.approver]
+					return role, role == "*" || role == task.Role
+				}
+				anchor := func(*WorkflowInstance, *WorkflowTask, *WorkflowDecision) error {
+					if s.approver == "bad" {
+						return errAnchor
+					}
+					return nil
+				}
+				_, err := DecideWorkflowTask(decision, canDecide, anchor, func(*WorkflowInstance) error {
+					finished++
+					return nil
+				})
+				if s.err == nil && err != nil {
+					t.Fatalf("decision %d by %s: unexpected error %v", i, s.approver, err)
+				}
+				if s.err != nil && !errors.Is(err, s.err) {
+					t.Fatalf("decision %d by %s: got %v, want %v", i, s.approver, err, s.err)
+				}
+			}
+			instance, err := GetWorkflowInstanceByID("w1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if instance.Stage != c.stage || instance.Status != c.status {
+				t.Errorf("stage %d status %s, want stage %d status %s", instance.Stage, instance.Status, c.stage, c.status)
+			}
+			for i, task := range instance.Tasks {
+				if task.Status != c.tasks[i] {
+					t.Errorf("task %s is %s, want %s", task.TaskID, task.Status, c.tasks[i])
+				}
+			}
+			if finished != c.finished {
+				t.Errorf("finished %d times, want %d", finished, c.finished)
+			}
+		})
+	}
+}
+
+func TestStartWorkflow(t *testing.T) {
+	useTestDB(t)
+	if err := startTestWorkflow(t, "w1", func(*WorkflowInstance) error { return errAnchor }); !errors.Is(err, errAnchor) {
+		t.Fatalf("got %v, want anchor error", err)
+	}
+	if _, err := GetWorkflowInstanceByID("w1"); err == nil {
+		t.Error("instance is kept after anchor failed")
+	}
+	if err := startTestWorkflow(t, "w2", func(*WorkflowInstance) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := startTestWorkflow(t, "w3", func(*WorkflowInstance) error { return nil }); !errors.Is(err, ErrWorkflowState) {
+		t.Errorf("second workflow of the same target: got %v, want ErrWorkflowState", err)
+	}
+	instance := &WorkflowInstance{InstanceID: "w4", Kind: WorkflowKindNotice, TargetID: "n1", Requester: "req", CreateTime: "2024-06-01 08:00:00"}
+	if err := StartWorkflow(instance, nil, nil, func(*WorkflowInstance) error { return nil }); !errors.Is(err, ErrNoWorkflow) {
+		t.Errorf("kind without workflow: got %v, want ErrNoWorkflow", err)
+	}
+	//已配置的流程定义优先于fallback
+	definition := &WorkflowDefinition{Kind: WorkflowKindNotice, Name: "公告发布", Updater: "u1", UpdateTime: "2024-06-01 08:00:00",
+		Steps: []WorkflowStep{{Stage: 1, Name: "业委会审核", Role: "committee", Required: 1}}}
+	if err := SaveWorkflowDefinition(definition, func(*WorkflowDefinition) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := StartWorkflow(instance, testWorkflowSteps, nil, func(*WorkflowInstance) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(instance.Tasks) != 1 || instance.Tasks[0].Role != "committee" || instance.Tasks[0].Status != TaskStatusPending {
+		t.Errorf("tasks %+v, want the defined committee step", instance.Tasks)
+	}
+}
+
+func TestDecidedStages(t *testing.T) {
+	instance := WorkflowInstance{
+		Tasks: []WorkflowTask{{TaskID: "w1-1", Stage: 1}, {TaskID: "w1-2", Stage: 1}, {TaskID: "w1-3", Stage: 2}},
+		Decisions: []WorkflowDecision{
+			{Approver: "c1", TaskID: "w1-1", Decision: DecisionApprove},
+			{Approver: "t1", TaskID: "w1-2", Decision: DecisionApprove},
+			{Approver: "c1", TaskID: "w1-3", Decision: DecisionReject},
+			{Approver: "c2", Decision: DecisionComment},
+			{Approver: "c3", TaskID: "other", Decision: DecisionApprove},
+		},
+	}
+	got := instance.DecidedStages()
+	want := map[string][]int{"c1": {1, 2}, "t1": {1}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -2,4 +2,6 @@
 ./network.sh deployCC -ccn financial -ccp /root/project/community-governance/chaincode/financial -ccl go
 ./network.sh deployCC -ccn asset -ccp /root/project/community-governance/chaincode/asset -ccl go
 ./network.sh deployCC -ccn notice -ccp /root/project/community-governance/chaincode/notice -ccl go
-./network.sh deployCC -ccn facility -ccp /root/project/community-governance/chaincode/facility -ccl go
\ No newline at end of file
+./network.sh deployCC -ccn facility -ccp /root/project/community-governance/chaincode/facility -ccl go
+./network.sh deployCC -ccn audit -ccp /root/project/community-governance/chaincode/audit -ccl go
+./network.sh deployCC -ccn workflow -ccp /root/project/community-governance/chaincode/workflow -ccl go
\ No newline at end of file
@@ -0,0 +1,150 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"strings"
+	"time"
+)
+
+// 已上链记录的类型
+const (
+	AnchorFund     = "fund"
+	AnchorAsset    = "asset"
+	AnchorNotice   = "notice"
+	AnchorFacility = "facility"
+	AnchorVote     = "vote"
+)
+
+// anchorQueries 记录类型对应的链码与查询函数
+var anchorQueries = map[string][2]string{
+	AnchorFund:     {financialChaincode, "GetFinancial"},
+	AnchorAsset:    {assetChaincode, "GetAsset"},
+	AnchorNotice:   {noticeChaincode, "GetNotice"},
+	AnchorFacility: {facilityChaincode, "GetFacility"},
+	AnchorVote:     {voteChaincode, "GetVote"},
+}
+
+// IsAnchored 判断记录是否已经上链
+func IsAnchored(kind, id string) (bool, error) {
+	query, ok := anchorQueries[kind]
+	if !ok {
+		return false, fmt.Errorf("unknown anchor kind:%s", kind)
+	}
+	result, err := evaluate(query[0], query[1], id)
+	if err != nil {
+		if isNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(result) > 0, nil
+}
+
+// HasVoteRecords 判断投票是否已经有人参与
+func HasVoteRecords(id string) (bool, error) {
+	result, err := evaluate(voteChaincode, "GetVoteRecordHistory", id)
+	if err != nil {
+		return false, err
+	}
+	if len(result) == 0 {
+		return false, nil
+	}
+	var records []VoteRecord
+	if err := json.Unmarshal(result, &records); err != nil {
+		return false, err
+	}
+	return len(records) > 0, nil
+}
+
+// HasFinancialRecords 判断款项是否已经有收支记录
+func HasFinancialRecords(id string) (bool, error) {
+	result, err := evaluate(financialChaincode, "GetFinancialRecordHistory", id)
+	if err != nil {
+		if isNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if len(result) == 0 {
+		return false, nil
+	}
+	var records []FinancialRecord
+	if err := json.Unmarshal(result, &records); err != nil {
+		return false, err
+	}
+	return len(records) > 0, nil
+}
+
+// evaluate 以应用默认身份查询链码
+func evaluate(chaincodeName, function string, args ...string) ([]byte, error) {
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	identity := newIdentity()
+	sign := newSign()
+
+	gw, err := client.Connect(
+		identity,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect:%s", err.Error())
+	}
+	defer gw.Close()
+
+	network := gw.GetNetwork(channel)
+	contract := network.GetContract(chaincodeName)
+	result, err := contract.EvaluateTransaction(function, args...)
+	if err != nil {
+		return nil, chainError("failed to evaluate transaction", err)
+	}
+	return result, nil
+}
+
+// submit 以指定成员身份提交链码交易
+func submit(chaincodeName, function, operator string, args ...string) ([]byte, error) {
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	gw, err := client.Connect(
+		identity,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect:%s", err.Error())
+	}
+	defer gw.Close()
+
+	network := gw.GetNetwork(channel)
+	contract := network.GetContract(chaincodeName)
+	result, err := contract.SubmitTransaction(function, args...)
+	if err != nil {
+		return nil, chainError("failed to submit transaction", err)
+	}
+	return result, nil
+}
+
+// isNotExist 链码对不存在的记录统一返回"not exist"错误
+func isNotExist(err error) bool {
+	return strings.Contains(err.Error(), "not exist")
+}
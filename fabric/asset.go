@@ -13,14 +13,27 @@ type Asset struct {
 	Owner      string `json:"owner"`     //资产拥有者
 	Recorder   string `json:"recorder"`
 	RecordDate string `json:"recordDate"`
+	Status     string `json:"status"`               //资产状态
+	Reason     string `json:"reason,omitempty"`     //最近一次状态变更的原因
+	WorkOrder  string `json:"workOrder,omitempty"`  //维修中资产的维护工单ID
+	Assignee   string `json:"assignee,omitempty"`   //维护工单的负责人ID
+	Request    string `json:"request,omitempty"`    //最近一次处置、调拨或借用的资产申请ID
+	Transferee string `json:"transferee,omitempty"` //调拨中待接收的保管人ID
+	Borrower   string `json:"borrower,omitempty"`   //借用人ID
+	DueDate    string `json:"dueDate,omitempty"`    //借用归还期限
+	Disposal   string `json:"disposal,omitempty"`   //处置方式
+	Proceeds   string `json:"proceeds,omitempty"`   //处置收入
+	Fund       string `json:"fund,omitempty"`       //处置收入入账的款项
 }
 
 func CreateAsset(assetID, asserHash, owner, recorder string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(recorder)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -39,22 +52,34 @@ func CreateAsset(assetID, asserHash, owner, recorder string) error {
 
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(assetChaincode)
-	_, err = contract.SubmitTransaction("CreateAsset", assetID, asserHash, owner, recorder)
+	_, err = contract.SubmitTransaction("CreateAsset", assetID, asserHash, owner)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
 
-// func GetAsset() {
-//
-// }
-func ExchangeOwner(assetID string, newOwner string) error {
+// GetAsset 查询链上资产
+func GetAsset(assetID string) (Asset, error) {
+	result, err := evaluate(assetChaincode, "GetAsset", assetID)
+	if err != nil {
+		return Asset{}, err
+	}
+	var asset Asset
+	if err := json.Unmarshal(result, &asset); err != nil {
+		return Asset{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return asset, nil
+}
+
+func ExchangeOwner(assetID, newOwner, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -75,7 +100,7 @@ func ExchangeOwner(assetID string, newOwner string) error {
 	contract := network.GetContract(assetChaincode)
 	_, err = contract.SubmitTransaction("ExchangeOwner", assetID, newOwner)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
@@ -106,7 +131,7 @@ func GetAssetHistory(assetID string) ([]Asset, error) {
 
 	result, err := contract.EvaluateTransaction("GetAssetHistory", assetID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate transaction:%s", err.Error())
+		return nil, chainError("failed to evaluate transaction", err)
 	}
 	var assetList []Asset
 	err = json.Unmarshal(result, &assetList)
@@ -120,8 +145,10 @@ func UpdateAsset(assetID, asserHash, owner, recorder string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(recorder)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -140,9 +167,9 @@ func UpdateAsset(assetID, asserHash, owner, recorder string) error {
 
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(assetChaincode)
-	_, err = contract.SubmitTransaction("UpdateAsset", assetID, asserHash, owner, recorder)
+	_, err = contract.SubmitTransaction("UpdateAsset", assetID, asserHash, owner)
 	if err != nil {
-		return err
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
@@ -0,0 +1,49 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// submitAsset 提交资产合约交易并解析返回的链上资产
+func submitAsset(function, operator string, args ...string) (Asset, error) {
+	result, err := submit(assetChaincode, function, operator, args...)
+	if err != nil {
+		return Asset{}, err
+	}
+	var asset Asset
+	if err := json.Unmarshal(result, &asset); err != nil {
+		return Asset{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return asset, nil
+}
+
+// DisposeAsset 按资产申请处置链上资产，记录处置方式、处置收入与入账款项
+func DisposeAsset(assetID, requestID, method, proceeds, fundID, reason, operator string) (Asset, error) {
+	return submitAsset("DisposeAsset", operator, assetID, requestID, method, proceeds, fundID, reason)
+}
+
+// StartAssetTransfer 按资产申请将链上资产调拨给新的保管人，待其接收
+func StartAssetTransfer(assetID, requestID, transferee, operator string) (Asset, error) {
+	return submitAsset("StartTransfer", operator, assetID, requestID, transferee)
+}
+
+// AcceptAssetTransfer 新保管人以本人身份接收调拨的资产
+func AcceptAssetTransfer(assetID, operator string) (Asset, error) {
+	return submitAsset("AcceptTransfer", operator, assetID)
+}
+
+// DeclineAssetTransfer 新保管人以本人身份拒绝接收调拨的资产
+func DeclineAssetTransfer(assetID, operator string) (Asset, error) {
+	return submitAsset("DeclineTransfer", operator, assetID)
+}
+
+// LendAsset 按资产申请将链上资产借给借用人
+func LendAsset(assetID, requestID, borrower, dueDate, operator string) (Asset, error) {
+	return submitAsset("LendAsset", operator, assetID, requestID, borrower, dueDate)
+}
+
+// ReturnAsset 借出的链上资产归还入库
+func ReturnAsset(assetID, reason, operator string) (Asset, error) {
+	return submitAsset("ReturnAsset", operator, assetID, reason)
+}
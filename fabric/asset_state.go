@@ -0,0 +1,44 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AssetStateChange 资产状态时间线中的一次状态变更
+type AssetStateChange struct {
+	TxID   string `json:"txId"`
+	From   string `json:"from"`   //变更前状态，登记时为空
+	State  string `json:"state"`  //变更后状态
+	Actor  string `json:"actor"`  //操作人ID
+	Reason string `json:"reason"` //变更原因
+	Date   string `json:"date"`   //变更时间
+}
+
+// ChangeAssetState 变更链上资产状态，链码校验状态转换是否合法
+func ChangeAssetState(assetID, state, reason, operator string) (Asset, error) {
+	result, err := submit(assetChaincode, "ChangeAssetState", operator, assetID, state, reason)
+	if err != nil {
+		return Asset{}, err
+	}
+	var asset Asset
+	if err := json.Unmarshal(result, &asset); err != nil {
+		return Asset{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return asset, nil
+}
+
+// GetAssetTimeline 查询资产的状态时间线
+func GetAssetTimeline(assetID string) ([]AssetStateChange, error) {
+	result, err := evaluate(assetChaincode, "GetAssetTimeline", assetID)
+	if err != nil {
+		return nil, err
+	}
+	timeline := make([]AssetStateChange, 0)
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &timeline); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+	}
+	return timeline, nil
+}
@@ -0,0 +1,94 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"time"
+)
+
+// AuditAnchor 审计链头
+type AuditAnchor struct {
+	LastLogID  uint64 `json:"last_log_id"` //链头日志ID
+	HeadHash   string `json:"head_hash"`   //链头hash
+	Anchorer   string `json:"anchorer"`    //锚定人
+	AnchorTime string `json:"anchor_time"` //锚定时间
+}
+
+type AuditAnchorRecord struct {
+	Tx     string      `json:"tx"`
+	Anchor AuditAnchor `json:"anchor"`
+}
+
+// AnchorAuditHead 以审计锚定身份锚定审计链头，返回交易ID
+func AnchorAuditHead(lastLogID uint64, headHash string) (string, error) {
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	identity, sign, err := newAuditorIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	gw, err := client.Connect(
+		identity,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect:%s", err.Error())
+	}
+	defer gw.Close()
+
+	network := gw.GetNetwork(channel)
+	contract := network.GetContract(auditChaincode)
+	result, err := contract.SubmitTransaction("AnchorHead", fmt.Sprint(lastLogID), headHash)
+	if err != nil {
+		return "", chainError("failed to submit transaction", err)
+	}
+	return string(result), nil
+}
+
+func GetAuditAnchorHistory() ([]AuditAnchorRecord, error) {
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	identity := newIdentity()
+	sign := newSign()
+
+	gw, err := client.Connect(
+		identity,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect:%s", err.Error())
+	}
+	defer gw.Close()
+
+	network := gw.GetNetwork(channel)
+	contract := network.GetContract(auditChaincode)
+	result, err := contract.EvaluateTransaction("GetHeadHistory")
+	if err != nil {
+		return nil, chainError("failed to evaluate transaction", err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	var records []AuditAnchorRecord
+	if err := json.Unmarshal(result, &records); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return records, nil
+}
@@ -0,0 +1,296 @@
+package fabric
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// caResponse Fabric CA REST接口的通用返回结构
+type caResponse struct {
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type caAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	ECert bool   `json:"ecert"`
+}
+
+// 证书吊销原因，取值见RFC 5280
+const (
+	RevokeSuperseded = "superseded"           // 成员角色或户号变更后重新登记，旧证书被取代
+	RevokeCessation  = "cessationofoperation" // 成员被删除或停用
+)
+
+// EnrollMember 为成员在CA上注册并登记身份，证书与私钥保存到服务端钱包
+// 成员ID作为登记ID（证书CN），成员类型与户号作为证书属性role、household写入证书
+// 重新登记时吊销旧证书，旧证书中的角色与户号随之失效
+func EnrollMember(memberID, role, household string) error {
+	wallet, err := getWallet()
+	if err != nil {
+		return err
+	}
+	var previous []byte
+	if wallet.Exists(memberID) {
+		id, err := readWalletCertificate(walletPath, memberID)
+		if err != nil {
+			return err
+		}
+		previous = id.Credentials()
+	}
+	secret, err := registerMember(memberID, role, household)
+	if err != nil {
+		return err
+	}
+	csrPEM, keyPEM, err := wallet.NewCertificateRequest(memberID)
+	if err != nil {
+		return err
+	}
+	certPEM, err := enroll(memberID, secret, csrPEM)
+	if err != nil {
+		return err
+	}
+	if err := wallet.Put(memberID, certPEM, keyPEM); err != nil {
+		return err
+	}
+	if previous == nil {
+		return nil
+	}
+	return revokeCertificate(previous, RevokeSuperseded)
+}
+
+// RevokeMember 吊销成员当前的证书并从钱包中移除，成员未登记时不做处理
+// 只吊销证书而不吊销CA上的身份，成员恢复后可以重新登记
+func RevokeMember(memberID string) error {
+	wallet, err := getWallet()
+	if err != nil {
+		return err
+	}
+	if !wallet.Exists(memberID) {
+		return nil
+	}
+	id, err := readWalletCertificate(walletPath, memberID)
+	if err != nil {
+		return err
+	}
+	if err := revokeCertificate(id.Credentials(), RevokeCessation); err != nil {
+		return err
+	}
+	return wallet.Remove(memberID)
+}
+
+// revokeCertificate 按序列号与AKI吊销证书
+func revokeCertificate(certPEM []byte, reason string) error {
+	certificate, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{
+		"serial": certificate.SerialNumber.Text(16),
+		"aki":    hex.EncodeToString(certificate.AuthorityKeyId),
+		"reason": reason,
+		"caname": caName,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := caRequestWithToken(http.MethodPost, "/api/v1/revoke", body); err != nil {
+		return fmt.Errorf("failed to revoke certificate of %s:%s", certificate.Subject.CommonName, err.Error())
+	}
+	return nil
+}
+
+// IsMemberEnrolled 判断成员是否已经登记链上身份
+func IsMemberEnrolled(memberID string) bool {
+	wallet, err := getWallet()
+	if err != nil {
+		return false
+	}
+	return wallet.Exists(memberID)
+}
+
+// registerMember 由登记员注册成员，成员已注册时重置其登记密码
+func registerMember(memberID, role, household string) (string, error) {
+	attrs := []caAttribute{{Name: "role", Value: role, ECert: true}, {Name: "household", Value: household, ECert: true}}
+	secret, err := newEnrollSecret()
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"id":          memberID,
+		"type":        "client",
+		"secret":      secret,
+		"affiliation": "",
+		"attrs":       attrs,
+		"caname":      caName,
+	})
+	if err != nil {
+		return "", err
+	}
+	_, err = caRequestWithToken(http.MethodPost, "/api/v1/register", body)
+	if err == nil {
+		return secret, nil
+	}
+	if !strings.Contains(err.Error(), "already registered") {
+		return "", fmt.Errorf("failed to register %s:%s", memberID, err.Error())
+	}
+	//已注册的身份无法取回密码，通过修改身份重置密码
+	body, err = json.Marshal(map[string]interface{}{
+		"id":     memberID,
+		"secret": secret,
+		"attrs":  attrs,
+		"caname": caName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := caRequestWithToken(http.MethodPut, "/api/v1/identities/"+memberID, body); err != nil {
+		return "", fmt.Errorf("failed to reset secret of %s:%s", memberID, err.Error())
+	}
+	return secret, nil
+}
+
+// enroll 以证书请求向CA换取登记证书
+func enroll(enrollID, secret string, csrPEM []byte) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"certificate_request": string(csrPEM),
+		"caname":              caName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, caURL+"/api/v1/enroll", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(enrollID, secret)
+	result, err := doCARequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enroll %s:%s", enrollID, err.Error())
+	}
+	var enrollResult struct {
+		Cert string `json:"Cert"`
+	}
+	if err := json.Unmarshal(result, &enrollResult); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal enroll result:%s", err.Error())
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(enrollResult.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode certificate:%s", err.Error())
+	}
+	return certPEM, nil
+}
+
+// getRegistrar 获取登记员身份，首次使用时向CA登记并保存到钱包
+func getRegistrar() (*identity.X509Identity, identity.Sign, error) {
+	wallet, err := getWallet()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !wallet.Exists(registrarID) {
+		csrPEM, keyPEM, err := wallet.NewCertificateRequest(registrarID)
+		if err != nil {
+			return nil, nil, err
+		}
+		certPEM, err := enroll(registrarID, registrarSecret, csrPEM)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := wallet.Put(registrarID, certPEM, keyPEM); err != nil {
+			return nil, nil, err
+		}
+	}
+	return wallet.Get(registrarID)
+}
+
+// caRequestWithToken 以登记员身份签名的token调用CA接口
+func caRequestWithToken(method, uri string, body []byte) (json.RawMessage, error) {
+	registrar, sign, err := getRegistrar()
+	if err != nil {
+		return nil, err
+	}
+	b64Cert := base64.StdEncoding.EncodeToString(registrar.Credentials())
+	payload := method + "." + base64.StdEncoding.EncodeToString([]byte(uri)) + "." +
+		base64.StdEncoding.EncodeToString(body) + "." + b64Cert
+	digest := sha256.Sum256([]byte(payload))
+	signature, err := sign(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token:%s", err.Error())
+	}
+	req, err := http.NewRequest(method, caURL+uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", b64Cert+"."+base64.StdEncoding.EncodeToString(signature))
+	return doCARequest(req)
+}
+
+func doCARequest(req *http.Request) (json.RawMessage, error) {
+	client, err := newCAHttpClient()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var caResp caResponse
+	if err := json.Unmarshal(data, &caResp); err != nil {
+		return nil, fmt.Errorf("invalid ca response(%d):%s", resp.StatusCode, string(data))
+	}
+	if !caResp.Success {
+		messages := make([]string, 0, len(caResp.Errors))
+		for _, e := range caResp.Errors {
+			messages = append(messages, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		return nil, fmt.Errorf("%s", strings.Join(messages, ";"))
+	}
+	return caResp.Result, nil
+}
+
+func newCAHttpClient() (*http.Client, error) {
+	certificatePEM, err := os.ReadFile(caTLSCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA TLS certificate file: %w", err)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(certificatePEM) {
+		return nil, fmt.Errorf("invalid CA TLS certificate")
+	}
+	return &http.Client{
+		Timeout:   15 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certPool}},
+	}, nil
+}
+
+func newEnrollSecret() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
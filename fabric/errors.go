@@ -0,0 +1,43 @@
+package fabric
+
+import (
+	"fmt"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/status"
+	"regexp"
+	"strings"
+)
+
+// 链码访问控制错误码，与chaincode/common/access.go保持一致
+const (
+	AccessNoIdentity = "ACCESS_NO_IDENTITY"
+)
+
+// accessCodePattern 链码AccessError以"[ACCESS_*]"开头
+var accessCodePattern = regexp.MustCompile(`\[(ACCESS_[A-Z_]+)\]`)
+
+// AccessError 链码拒绝调用者访问，Code为链码返回的错误码
+type AccessError struct {
+	Code    string
+	message string
+}
+
+func (e *AccessError) Error() string {
+	return e.message
+}
+
+// chainError 包装网关返回的错误
+// 背书失败时链码的错误信息只在gRPC状态的details中，需要拼接到错误信息后；链码拒绝访问时返回*AccessError
+func chainError(action string, err error) error {
+	var message strings.Builder
+	message.WriteString(action + ":" + err.Error())
+	for _, detail := range status.Convert(err).Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok {
+			message.WriteString(fmt.Sprintf("; %s(%s): %s", detail.GetAddress(), detail.GetMspId(), detail.GetMessage()))
+		}
+	}
+	if match := accessCodePattern.FindStringSubmatch(message.String()); match != nil {
+		return &AccessError{Code: match[1], message: message.String()}
+	}
+	return fmt.Errorf("%s", message.String())
+}
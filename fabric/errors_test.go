@@ -0,0 +1,52 @@
+package fabric
+
+import (
+	"errors"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func endorseError(t *testing.T, message string) error {
+	t.Helper()
+	st, err := status.New(codes.Aborted, "failed to endorse transaction, see attached details for more info").
+		WithDetails(&gateway.ErrorDetail{Address: "peer0.org1.example.com:7051", MspId: "Org1MSP", Message: "chaincode response 500, " + message})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Err()
+}
+
+func TestChainError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"role denied in details", endorseError(t, "[ACCESS_ROLE_DENIED] AddRecord: role owner is not allowed"), "ACCESS_ROLE_DENIED"},
+		{"no identity in details", endorseError(t, "[ACCESS_NO_IDENTITY] AddRecord: no client identity"), AccessNoIdentity},
+		{"evaluate message", errors.New("rpc error: code = Unknown desc = evaluate call to endorser returned error: [ACCESS_NOT_OWNER] GetReservation: not the owner"), "ACCESS_NOT_OWNER"},
+		{"business error", endorseError(t, "insufficient balance"), ""},
+		{"connection error", errors.New("rpc error: code = Unavailable desc = connection refused"), ""},
+	}
+	for _, c := range cases {
+		err := chainError("failed to submit transaction", c.err)
+		var accessErr *AccessError
+		if errors.As(err, &accessErr) != (c.code != "") {
+			t.Errorf("%s: got %T %v, want access error %q", c.name, err, err, c.code)
+			continue
+		}
+		if accessErr != nil && accessErr.Code != c.code {
+			t.Errorf("%s: code %s, want %s", c.name, accessErr.Code, c.code)
+		}
+		if !strings.HasPrefix(err.Error(), "failed to submit transaction:") {
+			t.Errorf("%s: message %q", c.name, err.Error())
+		}
+	}
+	//背书失败时链码的错误信息需要出现在错误中，供余额不足等判断使用
+	if err := chainError("failed to submit transaction", endorseError(t, "insufficient balance")); !strings.Contains(err.Error(), "insufficient balance") {
+		t.Errorf("chaincode message lost: %s", err.Error())
+	}
+}
@@ -24,6 +24,16 @@ const (
 	assetChaincode     = "asset"
 	noticeChaincode    = "notice"
 	facilityChaincode  = "facility"
+	auditChaincode     = "audit"
+	workflowChaincode  = "workflow"
+	caURL              = "https://localhost:7054"
+	caName             = "ca-org1"
+	caTLSCertPath      = "/root/fabric-samples/test-network/organizations/fabric-ca/org1/tls-cert.pem"
+	registrarID        = "admin"
+	registrarSecret    = "adminpw"
+	walletPath         = "./wallet"
+	auditorID          = "audit-anchor" // 审计锚定身份的登记ID
+	roleAuditor        = "auditor"      // 审计锚定身份证书的role属性，与链码一致
 )
 
 // newGrpcConnection creates a gRPC connection to the Gateway server.
@@ -89,6 +99,32 @@ func newSign() identity.Sign {
 	return sign
 }
 
+// newMemberIdentity 从钱包中加载成员身份，提交交易时以成员自己的证书签名
+func newMemberIdentity(memberID string) (*identity.X509Identity, identity.Sign, error) {
+	wallet, err := getWallet()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !wallet.Exists(memberID) {
+		return nil, nil, fmt.Errorf("member %s has not enrolled a fabric identity", memberID)
+	}
+	return wallet.Get(memberID)
+}
+
+// newAuditorIdentity 加载审计锚定身份，首次使用时向CA登记role为auditor的专用身份
+func newAuditorIdentity() (*identity.X509Identity, identity.Sign, error) {
+	wallet, err := getWallet()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !wallet.Exists(auditorID) {
+		if err := EnrollMember(auditorID, roleAuditor, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to enroll auditor identity:%s", err.Error())
+		}
+	}
+	return wallet.Get(auditorID)
+}
+
 func readFirstFile(dirPath string) ([]byte, error) {
 	dir, err := os.Open(dirPath)
 	if err != nil {
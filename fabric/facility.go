@@ -16,42 +16,14 @@ type UsageRecord struct {
 	Operation string `json:"operation"` //操作
 }
 
-func RegisterFacility(facilityID, messageHash string) error {
+func RegisterFacility(facilityID, messageHash, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
-
-	gw, err := client.Connect(
-		identity,
-		client.WithSign(sign),
-		client.WithHash(hash.SHA256),
-		client.WithClientConnection(clientConnection),
-		client.WithEvaluateTimeout(5*time.Second),
-		client.WithEndorseTimeout(15*time.Second),
-		client.WithSubmitTimeout(5*time.Second),
-		client.WithCommitStatusTimeout(1*time.Minute),
-	)
+	identity, sign, err := newMemberIdentity(operator)
 	if err != nil {
-		return fmt.Errorf("failed to connect:%s", err.Error())
+		return err
 	}
-	defer gw.Close()
-
-	network := gw.GetNetwork(channel)
-	contract := network.GetContract(facilityChaincode)
-	_, err = contract.SubmitTransaction("RegisterFacility", facilityID, messageHash)
-	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
-	}
-	return nil
-}
-func RequestFacility(facilityID, user string) error {
-	clientConnection := newGrpcConnection()
-	defer clientConnection.Close()
-
-	identity := newIdentity()
-	sign := newSign()
 
 	gw, err := client.Connect(
 		identity,
@@ -70,9 +42,9 @@ func RequestFacility(facilityID, user string) error {
 
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(facilityChaincode)
-	_, err = contract.SubmitTransaction("RequestFacility", facilityID, user)
+	_, err = contract.SubmitTransaction("RegisterFacility", facilityID, messageHash)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
@@ -80,8 +52,10 @@ func ReleaseFacility(facilityID, user string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(user)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -100,9 +74,9 @@ func ReleaseFacility(facilityID, user string) error {
 
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(facilityChaincode)
-	_, err = contract.SubmitTransaction("ReleaseFacility", facilityID, user)
+	_, err = contract.SubmitTransaction("ReleaseFacility", facilityID)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
@@ -133,7 +107,7 @@ func GetFacilityUsageHistory(id string) ([]UsageRecord, error) {
 	contract := network.GetContract(facilityChaincode)
 	transaction, err := contract.EvaluateTransaction("GetFacilityUsageHistory", id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return nil, chainError("failed to submit transaction", err)
 	}
 	//判断数据是否为空
 	if len(transaction) == 0 {
@@ -146,12 +120,14 @@ func GetFacilityUsageHistory(id string) ([]UsageRecord, error) {
 	return records, nil
 }
 
-func UpdateFacility(facilityID, messageHash, state string) error {
+func UpdateFacility(facilityID, messageHash, state, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -172,7 +148,7 @@ func UpdateFacility(facilityID, messageHash, state string) error {
 	contract := network.GetContract(facilityChaincode)
 	_, err = contract.SubmitTransaction("UpdateFacility", facilityID, messageHash, state)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
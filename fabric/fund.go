@@ -13,14 +13,39 @@ type Financial struct {
 	Principal   string `json:"principal"`   //负责人ID
 	UpdateDate  string `json:"update_date"` //上次修改时间
 	State       string `json:"state"`       //款项状态
+	Income      int64  `json:"income"`      //累计收入，单位分
+	Expense     int64  `json:"expense"`     //累计支出，单位分
+	Balance     int64  `json:"balance"`     //当前余额，单位分
+	Version     int    `json:"version"`     //收支汇总版本，为0时须先迁移
+}
+
+// FinancialTotalsVersion 链上当前的收支汇总版本
+const FinancialTotalsVersion = 1
+
+// FinancialTotals 链上款项收支汇总
+type FinancialTotals struct {
+	Income  string `json:"income"`
+	Expense string `json:"expense"`
+	Balance string `json:"balance"`
 }
 type FinancialRecord struct {
-	Type       string `json:"type"`      //记录类型，0-收入 1-支出
-	Amount     string `json:"amount"`    //记录金额
-	Source     string `json:"source"`    //金额来源
-	InfoHash   string `json:"info_hash"` //证明文件
-	Explain    string `json:"explain"`   //说明
-	RecorderID string `json:"record_id"` //记录人ID
+	Type       string `json:"type"`                  //记录类型，0-收入 1-支出
+	Amount     string `json:"amount"`                //记录金额
+	Source     string `json:"source"`                //金额来源
+	Category   string `json:"category"`              //预算科目
+	InfoHash   string `json:"info_hash"`             //证明文件
+	Explain    string `json:"explain"`               //说明
+	RecorderID string `json:"record_id"`             //记录人ID
+	TransferID string `json:"transfer_id,omitempty"` //款项间划转ID
+	Date       string `json:"date,omitempty"`        //记录日期
+	Corrects   string `json:"corrects,omitempty"`    //更正的原记录所在交易ID
+}
+
+// FinancialTransaction 收支记录及其所在交易
+type FinancialTransaction struct {
+	TxID      string          `json:"tx_id"`
+	Timestamp string          `json:"timestamp"` //交易时间，RFC3339格式
+	Record    FinancialRecord `json:"record"`
 }
 
 const (
@@ -29,16 +54,47 @@ const (
 )
 
 type ChainFundDetail struct {
-	BashHistory   []Financial       `json:"bash_history"`
-	RecordHistory []FinancialRecord `json:"record_history"`
+	BashHistory   []Financial            `json:"bash_history"`
+	RecordHistory []FinancialRecord      `json:"record_history"`
+	Totals        FinancialTotals        `json:"totals"`
+	Transactions  []FinancialTransaction `json:"transactions"` //收支记录及其交易ID
+}
+
+// GetFinancial 查询链上款项
+func GetFinancial(id string) (Financial, error) {
+	result, err := evaluate(financialChaincode, "GetFinancial", id)
+	if err != nil {
+		return Financial{}, err
+	}
+	var financial Financial
+	if err := json.Unmarshal(result, &financial); err != nil {
+		return Financial{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return financial, nil
+}
+
+// MigrateFinancialTotals 以管理员身份迁移汇总上线前创建的链上款项，balance为本地资金账户余额
+func MigrateFinancialTotals(id, balance, operator string) (FinancialTotals, error) {
+	result, err := submit(financialChaincode, "MigrateTotals", operator, id, balance)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
+	var totals FinancialTotals
+	if err := json.Unmarshal(result, &totals); err != nil {
+		return FinancialTotals{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return totals, nil
 }
 
-func CreateFinancial(id, finHash string) error {
+// CreateFinancial 创建链上款项，opening为期初资金
+func CreateFinancial(id, finHash, opening, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -58,18 +114,20 @@ func CreateFinancial(id, finHash string) error {
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(financialChaincode)
 
-	_, err = contract.SubmitTransaction("CreateFinancial", id, finHash)
+	_, err = contract.SubmitTransaction("CreateFinancial", id, finHash, opening)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
-func UpdateFinancial(id, finHash, state string) error {
+func UpdateFinancial(id, finHash, state, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -91,23 +149,27 @@ func UpdateFinancial(id, finHash, state string) error {
 
 	_, err = contract.SubmitTransaction("UpdateFinancial", id, finHash)
 	if err != nil {
-		return fmt.Errorf("failed to submit UpdateFinancial transaction:%s", err.Error())
+		return chainError("failed to submit UpdateFinancial transaction", err)
 	}
 	if state != foundStateActive {
 		_, err := contract.SubmitTransaction("ExchangeState", id, foundStateClose)
 		if err != nil {
-			return fmt.Errorf("failed to submit ExchangeState transaction:%s", err.Error())
+			return chainError("failed to submit ExchangeState transaction", err)
 		}
 	}
 	return nil
 }
 
-func AddFinancialRecord(id, finType, source, explain, recorder, amount string) error {
+// AddFinancialRecord 添加收支记录，返回链上最新的收支汇总
+// infoHash为证明文件的SHA-256，可为空，category为预算科目，date为记录日期
+func AddFinancialRecord(id, finType, source, explain, recorder, amount, infoHash, category, date string) (FinancialTotals, error) {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(recorder)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -120,25 +182,31 @@ func AddFinancialRecord(id, finType, source, explain, recorder, amount string) e
 		client.WithCommitStatusTimeout(1*time.Minute),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to connect:%s", err.Error())
+		return FinancialTotals{}, fmt.Errorf("failed to connect:%s", err.Error())
 	}
 	defer gw.Close()
 
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(financialChaincode)
 
-	_, err = contract.SubmitTransaction("AddRecord", id, finType, source, explain, recorder, amount)
+	result, err := contract.SubmitTransaction("AddRecord", id, finType, source, explain, amount, infoHash, category, date)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return FinancialTotals{}, chainError("failed to submit transaction", err)
 	}
-	return nil
+	var totals FinancialTotals
+	if err := json.Unmarshal(result, &totals); err != nil {
+		return FinancialTotals{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return totals, nil
 }
-func ExchangeState(id, state string) error {
+func ExchangeState(id, state, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -160,7 +228,7 @@ func ExchangeState(id, state string) error {
 
 	_, err = contract.SubmitTransaction("ExchangeState", id, state)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 
@@ -194,21 +262,53 @@ func GetFinancialDetail(id string) (ChainFundDetail, error) {
 
 	result, err := contract.EvaluateTransaction("GetFinancialHistory", id)
 	if err != nil {
-		return ChainFundDetail{}, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return ChainFundDetail{}, chainError("failed to submit transaction", err)
 	}
 	if result != nil {
 		if err := json.Unmarshal(result, &detail.BashHistory); err != nil {
-			return ChainFundDetail{}, fmt.Errorf("failed to submit transaction:%s", err.Error())
+			return ChainFundDetail{}, chainError("failed to submit transaction", err)
 		}
 	}
 	result, err = contract.EvaluateTransaction("GetFinancialRecordHistory", id)
 	if err != nil {
-		return ChainFundDetail{}, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return ChainFundDetail{}, chainError("failed to submit transaction", err)
 	}
 	if result != nil {
 		if err := json.Unmarshal(result, &detail.RecordHistory); err != nil {
-			return ChainFundDetail{}, fmt.Errorf("failed to submit transaction:%s", err.Error())
+			return ChainFundDetail{}, chainError("failed to submit transaction", err)
+		}
+	}
+	result, err = contract.EvaluateTransaction("GetFinancialTotals", id)
+	if err != nil {
+		return ChainFundDetail{}, chainError("failed to submit transaction", err)
+	}
+	if err := json.Unmarshal(result, &detail.Totals); err != nil {
+		return ChainFundDetail{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	result, err = contract.EvaluateTransaction("GetFinancialRecordTransactions", id)
+	if err != nil {
+		return ChainFundDetail{}, chainError("failed to evaluate transaction", err)
+	}
+	detail.Transactions = make([]FinancialTransaction, 0)
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &detail.Transactions); err != nil {
+			return ChainFundDetail{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
 		}
 	}
 	return detail, nil
 }
+
+// VerifyAttachment 查找款项中记录了指定证明文件hash的链上收支记录
+func VerifyAttachment(id, infoHash string) ([]FinancialTransaction, error) {
+	result, err := evaluate(financialChaincode, "VerifyAttachment", id, infoHash)
+	if err != nil {
+		return nil, err
+	}
+	proofs := make([]FinancialTransaction, 0)
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &proofs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+	}
+	return proofs, nil
+}
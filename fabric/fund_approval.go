@@ -0,0 +1,118 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ApprovalPolicy 链上大额支出审批策略
+type ApprovalPolicy struct {
+	Threshold int64 `json:"threshold"` //审批阈值，单位分
+	Required  int   `json:"required"`  //需要的批准人数M，为0表示不启用审批
+}
+
+// PendingExpense 链上待审批支出
+type PendingExpense struct {
+	ID          string   `json:"id"`
+	FundID      string   `json:"fund_id"`
+	Amount      string   `json:"amount"`
+	Source      string   `json:"source"`
+	Category    string   `json:"category"`
+	InfoHash    string   `json:"info_hash"`
+	Explain     string   `json:"explain"`
+	Proposer    string   `json:"proposer"`
+	ProposeDate string   `json:"propose_date"`
+	Required    int      `json:"required"`
+	Approvers   []string `json:"approvers"`
+	Rejecter    string   `json:"rejecter"`
+	Reason      string   `json:"reason"`
+	Status      string   `json:"status"`
+	UpdateDate  string   `json:"update_date"`
+}
+
+// 待审批支出状态
+const (
+	PendingStatusPending  = "pending"
+	PendingStatusPosted   = "posted"
+	PendingStatusRejected = "rejected"
+)
+
+// RequiresApproval 判断支出金额(单位分)是否需要审批
+func (p ApprovalPolicy) RequiresApproval(cents int64) bool {
+	return p.Required > 0 && cents > p.Threshold
+}
+
+// SetApprovalPolicy 设置大额支出审批策略
+func SetApprovalPolicy(threshold string, required int, operator string) error {
+	_, err := submit(financialChaincode, "SetApprovalPolicy", operator, threshold, strconv.Itoa(required))
+	return err
+}
+
+// GetApprovalPolicy 查询大额支出审批策略
+func GetApprovalPolicy() (ApprovalPolicy, error) {
+	result, err := evaluate(financialChaincode, "GetApprovalPolicy")
+	if err != nil {
+		return ApprovalPolicy{}, err
+	}
+	var policy ApprovalPolicy
+	if err := json.Unmarshal(result, &policy); err != nil {
+		return ApprovalPolicy{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return policy, nil
+}
+
+// ProposeExpense 提交待审批支出
+func ProposeExpense(id, source, explain, amount, infoHash, category, operator string) (PendingExpense, error) {
+	return submitPending("ProposeExpense", operator, id, source, explain, amount, infoHash, category)
+}
+
+// ApproveExpense 批准待审批支出，达到批准人数时链上自动入账
+func ApproveExpense(id, pendingID, operator string) (PendingExpense, error) {
+	return submitPending("ApproveExpense", operator, id, pendingID)
+}
+
+// RejectExpense 驳回待审批支出
+func RejectExpense(id, pendingID, reason, operator string) (PendingExpense, error) {
+	return submitPending("RejectExpense", operator, id, pendingID, reason)
+}
+
+// GetPendingExpense 查询链上待审批支出
+func GetPendingExpense(id, pendingID string) (PendingExpense, error) {
+	result, err := evaluate(financialChaincode, "GetPendingExpense", id, pendingID)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	var pending PendingExpense
+	if err := json.Unmarshal(result, &pending); err != nil {
+		return PendingExpense{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return pending, nil
+}
+
+// GetPendingExpenses 查询款项的待审批支出，status为空时返回所有状态
+func GetPendingExpenses(id, status string) ([]PendingExpense, error) {
+	result, err := evaluate(financialChaincode, "GetPendingExpenses", id, status)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]PendingExpense, 0)
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &pending); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+	}
+	return pending, nil
+}
+
+func submitPending(function, operator string, args ...string) (PendingExpense, error) {
+	result, err := submit(financialChaincode, function, operator, args...)
+	if err != nil {
+		return PendingExpense{}, err
+	}
+	var pending PendingExpense
+	if err := json.Unmarshal(result, &pending); err != nil {
+		return PendingExpense{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return pending, nil
+}
@@ -0,0 +1,54 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ClosedPeriod 链上已结账的会计期间
+type ClosedPeriod struct {
+	Period    string `json:"period"`     //会计期间，月度为2006-01，年度为2006
+	Closer    string `json:"closer"`     //结账人ID
+	CloseDate string `json:"close_date"` //结账时间
+}
+
+// ClosePeriod 结账，结账后期间内日期的收支记录被链上拒绝
+func ClosePeriod(period, operator string) (ClosedPeriod, error) {
+	result, err := submit(financialChaincode, "ClosePeriod", operator, period)
+	if err != nil {
+		return ClosedPeriod{}, err
+	}
+	var closed ClosedPeriod
+	if err := json.Unmarshal(result, &closed); err != nil {
+		return ClosedPeriod{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return closed, nil
+}
+
+// GetClosedPeriods 查询全部已结账的期间
+func GetClosedPeriods() ([]ClosedPeriod, error) {
+	result, err := evaluate(financialChaincode, "GetClosedPeriods")
+	if err != nil {
+		return nil, err
+	}
+	periods := make([]ClosedPeriod, 0)
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &periods); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+	}
+	return periods, nil
+}
+
+// CorrectFinancialRecord 在当前期间记一笔更正记录，关联被更正的原记录所在交易
+func CorrectFinancialRecord(id, originalTxID, finType, source, explain, amount, infoHash, category, operator string) (FinancialTotals, error) {
+	result, err := submit(financialChaincode, "CorrectRecord", operator, id, originalTxID, finType, source, explain, amount, infoHash, category)
+	if err != nil {
+		return FinancialTotals{}, err
+	}
+	var totals FinancialTotals
+	if err := json.Unmarshal(result, &totals); err != nil {
+		return FinancialTotals{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return totals, nil
+}
@@ -0,0 +1,45 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FundTransfer 链上款项间划转
+type FundTransfer struct {
+	ID         string          `json:"id"`          //划转ID
+	From       string          `json:"from"`        //转出款项ID
+	To         string          `json:"to"`          //转入款项ID
+	Amount     string          `json:"amount"`      //划转金额
+	Explain    string          `json:"explain"`     //说明
+	Operator   string          `json:"operator"`    //操作人ID
+	Date       string          `json:"date"`        //划转时间
+	FromTotals FinancialTotals `json:"from_totals"` //划转后转出款项的收支汇总
+	ToTotals   FinancialTotals `json:"to_totals"`   //划转后转入款项的收支汇总
+}
+
+// Transfer 在一笔交易中从from款项划转资金到to款项
+func Transfer(transferID, from, to, amount, explain, operator string) (FundTransfer, error) {
+	result, err := submit(financialChaincode, "Transfer", operator, transferID, from, to, amount, explain)
+	if err != nil {
+		return FundTransfer{}, err
+	}
+	var transfer FundTransfer
+	if err := json.Unmarshal(result, &transfer); err != nil {
+		return FundTransfer{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return transfer, nil
+}
+
+// GetTransfer 查询链上划转记录
+func GetTransfer(transferID string) (FundTransfer, error) {
+	result, err := evaluate(financialChaincode, "GetTransfer", transferID)
+	if err != nil {
+		return FundTransfer{}, err
+	}
+	var transfer FundTransfer
+	if err := json.Unmarshal(result, &transfer); err != nil {
+		return FundTransfer{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return transfer, nil
+}
@@ -0,0 +1,52 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Facility 链上公共设施
+type Facility struct {
+	MessageHash string `json:"message"`              //信息hash值
+	UpdateDate  string `json:"update_date"`          //上次修改时间
+	State       string `json:"state"`                //状态
+	WorkOrder   string `json:"work_order,omitempty"` //维护中设施的维护工单ID
+	Assignee    string `json:"assignee,omitempty"`   //维护工单的负责人ID
+}
+
+// GetFacility 查询链上公共设施
+func GetFacility(facilityID string) (Facility, error) {
+	result, err := evaluate(facilityChaincode, "GetFacility", facilityID)
+	if err != nil {
+		return Facility{}, err
+	}
+	var facility Facility
+	if err := json.Unmarshal(result, &facility); err != nil {
+		return Facility{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return facility, nil
+}
+
+// StartAssetMaintenance 按维护工单将链上资产转入维修中
+func StartAssetMaintenance(assetID, workOrderID, assignee, reason, operator string) error {
+	_, err := submit(assetChaincode, "StartAssetMaintenance", operator, assetID, workOrderID, assignee, reason)
+	return err
+}
+
+// EndAssetMaintenance 维护工单结束后链上资产恢复使用
+func EndAssetMaintenance(assetID, workOrderID, reason, operator string) error {
+	_, err := submit(assetChaincode, "EndAssetMaintenance", operator, assetID, workOrderID, reason)
+	return err
+}
+
+// StartFacilityMaintenance 按维护工单将链上设施转入维护中
+func StartFacilityMaintenance(facilityID, workOrderID, assignee, operator string) error {
+	_, err := submit(facilityChaincode, "StartMaintenance", operator, facilityID, workOrderID, assignee)
+	return err
+}
+
+// EndFacilityMaintenance 维护工单结束后链上设施恢复可用
+func EndFacilityMaintenance(facilityID, workOrderID, operator string) error {
+	_, err := submit(facilityChaincode, "EndMaintenance", operator, facilityID, workOrderID)
+	return err
+}
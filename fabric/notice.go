@@ -24,8 +24,10 @@ func CreateNotice(id, noticeHash, publisher string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(publisher)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -45,9 +47,9 @@ func CreateNotice(id, noticeHash, publisher string) error {
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(noticeChaincode)
 
-	_, err = contract.SubmitTransaction("CreateNotice", id, noticeHash, publisher)
+	_, err = contract.SubmitTransaction("CreateNotice", id, noticeHash)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
@@ -56,8 +58,10 @@ func UpdateNotice(id, noticeHash, publisher string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(publisher)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -77,9 +81,9 @@ func UpdateNotice(id, noticeHash, publisher string) error {
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(noticeChaincode)
 
-	_, err = contract.SubmitTransaction("UpdateNotice", id, noticeHash, publisher)
+	_, err = contract.SubmitTransaction("UpdateNotice", id, noticeHash)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
@@ -111,11 +115,11 @@ func GetNoticeHistory(id string) ([]ResultNotice, error) {
 
 	result, err := contract.EvaluateTransaction("GetHistory", id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return nil, chainError("failed to submit transaction", err)
 	}
 	var notices []ResultNotice
 	if err := json.Unmarshal(result, &notices); err != nil {
-		return nil, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return nil, chainError("failed to submit transaction", err)
 	}
 	return notices, nil
 }
@@ -148,11 +152,11 @@ func Verify(id, noticeHash string) (bool, error) {
 
 	result, err := contract.EvaluateTransaction("Verify", id, noticeHash)
 	if err != nil {
-		return false, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return false, chainError("failed to submit transaction", err)
 	}
 	var b bool
 	if err := json.Unmarshal(result, &b); err != nil {
-		return false, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return false, chainError("failed to submit transaction", err)
 	}
 	return b, nil
 }
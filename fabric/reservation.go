@@ -0,0 +1,98 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// FacilitySchedule 链上设施开放时间与预约规则
+type FacilitySchedule struct {
+	FacilityID  string `json:"facility_id"`
+	Open        string `json:"open"`
+	Close       string `json:"close"`
+	Days        string `json:"days"`
+	SlotMinutes int    `json:"slot_minutes"`
+	CancelHours int    `json:"cancel_hours"`
+	UpdateDate  string `json:"update_date"`
+}
+
+// Reservation 链上设施预约
+type Reservation struct {
+	ReservationID string `json:"reservation_id"`
+	FacilityID    string `json:"facility_id"`
+	Member        string `json:"member"`
+	Household     string `json:"household"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Status        string `json:"status"`
+	CreateDate    string `json:"create_date"`
+	Canceller     string `json:"canceller,omitempty"`
+	CancelReason  string `json:"cancel_reason,omitempty"`
+	CancelDate    string `json:"cancel_date,omitempty"`
+	TxID          string `json:"tx_id"`
+}
+
+// SetFacilitySchedule 设置链上设施的开放时间与预约规则
+func SetFacilitySchedule(schedule FacilitySchedule, operator string) (FacilitySchedule, error) {
+	result, err := submit(facilityChaincode, "SetSchedule", operator, schedule.FacilityID, schedule.Open, schedule.Close,
+		schedule.Days, strconv.Itoa(schedule.SlotMinutes), strconv.Itoa(schedule.CancelHours))
+	if err != nil {
+		return FacilitySchedule{}, err
+	}
+	var recorded FacilitySchedule
+	if err := json.Unmarshal(result, &recorded); err != nil {
+		return FacilitySchedule{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return recorded, nil
+}
+
+// ReserveFacility 以预约人身份在链上预约设施时段
+func ReserveFacility(reservationID, facilityID, start, end, member string) (Reservation, error) {
+	result, err := submit(facilityChaincode, "Reserve", member, reservationID, facilityID, start, end)
+	if err != nil {
+		return Reservation{}, err
+	}
+	return unmarshalReservation(result)
+}
+
+// CancelReservation 以取消人身份取消链上预约
+func CancelReservation(reservationID, reason, operator string) (Reservation, error) {
+	result, err := submit(facilityChaincode, "CancelReservation", operator, reservationID, reason)
+	if err != nil {
+		return Reservation{}, err
+	}
+	return unmarshalReservation(result)
+}
+
+// GetReservation 查询链上预约
+func GetReservation(reservationID string) (Reservation, error) {
+	result, err := evaluate(facilityChaincode, "GetReservation", reservationID)
+	if err != nil {
+		return Reservation{}, err
+	}
+	return unmarshalReservation(result)
+}
+
+// GetReservations 查询链上设施在日期范围内的预约
+func GetReservations(facilityID, from, to string) ([]Reservation, error) {
+	result, err := evaluate(facilityChaincode, "GetReservations", facilityID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	reservations := make([]Reservation, 0)
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &reservations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+	}
+	return reservations, nil
+}
+
+func unmarshalReservation(result []byte) (Reservation, error) {
+	var reservation Reservation
+	if err := json.Unmarshal(result, &reservation); err != nil {
+		return Reservation{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return reservation, nil
+}
@@ -0,0 +1,47 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Stocktake 链上的资产盘点记录
+type Stocktake struct {
+	StocktakeID  string `json:"stocktakeId"`
+	Location     string `json:"location"`     //盘点范围的位置
+	ReportHash   string `json:"reportHash"`   //盘点报告hash值
+	Counted      int    `json:"counted"`      //已清点的资产数
+	Missing      int    `json:"missing"`      //盘亏的资产数
+	Misplaced    int    `json:"misplaced"`    //位置不符的资产数
+	Unregistered int    `json:"unregistered"` //未登记的物品数
+	Closer       string `json:"closer"`       //结束盘点的管理员ID
+	CloseDate    string `json:"closeDate"`    //结束时间
+}
+
+// CloseStocktake 以结束盘点的管理员身份签名，将盘点报告hash与汇总数上链
+func CloseStocktake(stocktake Stocktake, operator string) (Stocktake, error) {
+	result, err := submit(assetChaincode, "CloseStocktake", operator, stocktake.StocktakeID, stocktake.Location, stocktake.ReportHash,
+		strconv.Itoa(stocktake.Counted), strconv.Itoa(stocktake.Missing), strconv.Itoa(stocktake.Misplaced), strconv.Itoa(stocktake.Unregistered))
+	if err != nil {
+		return Stocktake{}, err
+	}
+	var anchored Stocktake
+	if err := json.Unmarshal(result, &anchored); err != nil {
+		return Stocktake{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return anchored, nil
+}
+
+// GetStocktake 查询链上的盘点记录
+func GetStocktake(stocktakeID string) (Stocktake, error) {
+	result, err := evaluate(assetChaincode, "GetStocktake", stocktakeID)
+	if err != nil {
+		return Stocktake{}, err
+	}
+	var stocktake Stocktake
+	if err := json.Unmarshal(result, &stocktake); err != nil {
+		return Stocktake{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return stocktake, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"strconv"
 	"time"
 )
 
@@ -24,13 +25,20 @@ type VoteRecord struct {
 	Option   string `json:"option"`    //投票选项
 	VoteTime string `json:"vote_time"` //投票时间
 }
+type VoteRecordPage struct {
+	Records    []VoteRecord `json:"records"`
+	NextCursor string       `json:"next_cursor"` //下一页游标
+	HasNext    bool         `json:"has_next"`    //是否还有下一页
+}
 
-func CreatVote(id, base, ruleType, ruleValue, options string) error {
+func CreatVote(id, base, ruleType, ruleValue, options, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -51,7 +59,7 @@ func CreatVote(id, base, ruleType, ruleValue, options string) error {
 	contract := network.GetContract(voteChaincode)
 	_, err = contract.SubmitTransaction("CreatVote", id, base, ruleType, ruleValue, options)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
@@ -60,8 +68,10 @@ func VoteJoin(id, voter, option string) (string, error) {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(voter)
+	if err != nil {
+		return "", err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -80,9 +90,9 @@ func VoteJoin(id, voter, option string) (string, error) {
 
 	network := gw.GetNetwork(channel)
 	contract := network.GetContract(voteChaincode)
-	result, err := contract.SubmitTransaction("VoteJoin", id, voter, option)
+	result, err := contract.SubmitTransaction("VoteJoin", id, option)
 	if err != nil {
-		return "", fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return "", chainError("failed to submit transaction", err)
 	}
 	return string(result), nil
 }
@@ -113,10 +123,10 @@ func GetVoteRecordDetail(id string) (ChainVoteDetail, error) {
 	contract := network.GetContract(voteChaincode)
 	result, err := contract.EvaluateTransaction("GetVoteRecordHistory", id)
 	if err != nil {
-		return ChainVoteDetail{}, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return ChainVoteDetail{}, chainError("failed to submit transaction", err)
 	}
 	var records []VoteRecord
-	if result == nil {
+	if len(result) > 0 {
 		if err := json.Unmarshal(result, &records); err != nil {
 			return ChainVoteDetail{}, err
 		}
@@ -124,7 +134,7 @@ func GetVoteRecordDetail(id string) (ChainVoteDetail, error) {
 	//获取投票信息
 	result, err = contract.EvaluateTransaction("GetVote", id)
 	if err != nil {
-		return ChainVoteDetail{}, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return ChainVoteDetail{}, chainError("failed to submit transaction", err)
 	}
 	var vote Vote
 	if err := json.Unmarshal(result, &vote); err != nil {
@@ -159,7 +169,7 @@ func EndVote(id string) ([]string, error) {
 	contract := network.GetContract(voteChaincode)
 	result, err := contract.EvaluateTransaction("EndVote", id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return nil, chainError("failed to submit transaction", err)
 	}
 	var options []string
 	if err := json.Unmarshal(result, &options); err != nil {
@@ -167,12 +177,14 @@ func EndVote(id string) ([]string, error) {
 	}
 	return options, nil
 }
-func CloseVote(id string) error {
+func CloseVote(id, operator string) error {
 	clientConnection := newGrpcConnection()
 	defer clientConnection.Close()
 
-	identity := newIdentity()
-	sign := newSign()
+	identity, sign, err := newMemberIdentity(operator)
+	if err != nil {
+		return err
+	}
 
 	gw, err := client.Connect(
 		identity,
@@ -193,7 +205,20 @@ func CloseVote(id string) error {
 	contract := network.GetContract(voteChaincode)
 	_, err = contract.SubmitTransaction("CloseVote", id)
 	if err != nil {
-		return fmt.Errorf("failed to submit transaction:%s", err.Error())
+		return chainError("failed to submit transaction", err)
 	}
 	return nil
 }
+
+// GetVoteRecordPage 以账本分页书签为游标按投票顺序分页获取投票记录
+func GetVoteRecordPage(id, cursor string, limit int) (VoteRecordPage, error) {
+	result, err := evaluate(voteChaincode, "GetVoteRecordPage", id, cursor, strconv.Itoa(limit))
+	if err != nil {
+		return VoteRecordPage{}, err
+	}
+	var page VoteRecordPage
+	if err := json.Unmarshal(result, &page); err != nil {
+		return VoteRecordPage{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return page, nil
+}
@@ -0,0 +1,157 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"strconv"
+	"time"
+)
+
+// FacilityPolicy 链上设施预约政策，各项为0表示不限制
+type FacilityPolicy struct {
+	FacilityID     string `json:"facility_id"`
+	MaxWeeklyHours int    `json:"max_weekly_hours"`
+	AdvanceDays    int    `json:"advance_days"`
+	MaxActive      int    `json:"max_active"`
+	ConfirmMinutes int    `json:"confirm_minutes"`
+	UpdateDate     string `json:"update_date"`
+}
+
+// WaitEntry 链上设施候补
+type WaitEntry struct {
+	EntryID       string `json:"entry_id"`
+	FacilityID    string `json:"facility_id"`
+	Member        string `json:"member"`
+	Household     string `json:"household"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Status        string `json:"status"`
+	CreateDate    string `json:"create_date"`
+	OfferDate     string `json:"offer_date,omitempty"`
+	Deadline      string `json:"deadline,omitempty"`
+	ReservationID string `json:"reservation_id,omitempty"`
+}
+
+// SetFacilityPolicy 设置链上设施的预约政策
+func SetFacilityPolicy(policy FacilityPolicy, operator string) (FacilityPolicy, error) {
+	result, err := submit(facilityChaincode, "SetPolicy", operator, policy.FacilityID, strconv.Itoa(policy.MaxWeeklyHours),
+		strconv.Itoa(policy.AdvanceDays), strconv.Itoa(policy.MaxActive), strconv.Itoa(policy.ConfirmMinutes))
+	if err != nil {
+		return FacilityPolicy{}, err
+	}
+	var recorded FacilityPolicy
+	if err := json.Unmarshal(result, &recorded); err != nil {
+		return FacilityPolicy{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return recorded, nil
+}
+
+// GetFacilityPolicy 查询链上设施的预约政策
+func GetFacilityPolicy(facilityID string) (FacilityPolicy, error) {
+	result, err := evaluate(facilityChaincode, "GetPolicy", facilityID)
+	if err != nil {
+		return FacilityPolicy{}, err
+	}
+	var policy FacilityPolicy
+	if err := json.Unmarshal(result, &policy); err != nil {
+		return FacilityPolicy{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return policy, nil
+}
+
+// JoinWaitlist 以成员身份候补链上已被预约的时段
+func JoinWaitlist(entryID, facilityID, start, end, member string) (WaitEntry, error) {
+	result, err := submit(facilityChaincode, "JoinWaitlist", member, entryID, facilityID, start, end)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	return unmarshalWaitEntry(result)
+}
+
+// LeaveWaitlist 以成员身份退出候补
+func LeaveWaitlist(entryID, operator string) (WaitEntry, error) {
+	result, err := submit(facilityChaincode, "LeaveWaitlist", operator, entryID)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	return unmarshalWaitEntry(result)
+}
+
+// ConfirmWaitOffer 以候补成员身份确认提供的时段，链上生成预约
+func ConfirmWaitOffer(entryID, reservationID, member string) (WaitEntry, error) {
+	result, err := submit(facilityChaincode, "ConfirmOffer", member, entryID, reservationID)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	return unmarshalWaitEntry(result)
+}
+
+// ExpireWaitOffers 以应用身份将超过确认时限的候补置为过期，并将时段提供给后续候补
+func ExpireWaitOffers(facilityID, date string) ([]WaitEntry, error) {
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	identity := newIdentity()
+	sign := newSign()
+
+	gw, err := client.Connect(
+		identity,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect:%s", err.Error())
+	}
+	defer gw.Close()
+
+	network := gw.GetNetwork(channel)
+	contract := network.GetContract(facilityChaincode)
+	result, err := contract.SubmitTransaction("ExpireOffers", facilityID, date)
+	if err != nil {
+		return nil, chainError("failed to submit transaction", err)
+	}
+	return unmarshalWaitEntries(result)
+}
+
+// GetWaitEntry 查询链上候补
+func GetWaitEntry(entryID string) (WaitEntry, error) {
+	result, err := evaluate(facilityChaincode, "GetWaitEntry", entryID)
+	if err != nil {
+		return WaitEntry{}, err
+	}
+	return unmarshalWaitEntry(result)
+}
+
+// GetWaitlist 查询链上设施某天的候补
+func GetWaitlist(facilityID, date string) ([]WaitEntry, error) {
+	result, err := evaluate(facilityChaincode, "GetWaitlist", facilityID, date)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalWaitEntries(result)
+}
+
+func unmarshalWaitEntry(result []byte) (WaitEntry, error) {
+	var entry WaitEntry
+	if err := json.Unmarshal(result, &entry); err != nil {
+		return WaitEntry{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return entry, nil
+}
+
+func unmarshalWaitEntries(result []byte) ([]WaitEntry, error) {
+	entries := make([]WaitEntry, 0)
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &entries); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+		}
+	}
+	return entries, nil
+}
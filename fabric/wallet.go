@@ -0,0 +1,160 @@
+package fabric
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Wallet 服务端身份钱包，按成员ID保存证书与签名密钥
+type Wallet interface {
+	// NewCertificateRequest 为成员生成签名密钥与证书请求，返回证书请求PEM与需随证书保存的私钥PEM
+	NewCertificateRequest(label string) ([]byte, []byte, error)
+	// Put 保存成员的证书与私钥
+	Put(label string, certPEM, keyPEM []byte) error
+	// Get 加载成员身份与签名函数
+	Get(label string) (*identity.X509Identity, identity.Sign, error)
+	// Exists 判断成员身份是否已经存在
+	Exists(label string) bool
+	// Remove 移除成员身份，成员证书被吊销后不能再以其身份签名
+	Remove(label string) error
+}
+
+const (
+	walletTypeFile   = "file"
+	walletTypePKCS11 = "pkcs11"
+	certFileName     = "cert.pem"
+	keyFileName      = "key.pem"
+)
+
+var (
+	walletOnce     sync.Once
+	walletInstance Wallet
+	walletErr      error
+)
+
+// getWallet 根据环境变量FABRIC_WALLET选择钱包实现，默认为文件钱包
+func getWallet() (Wallet, error) {
+	walletOnce.Do(func() {
+		switch os.Getenv("FABRIC_WALLET") {
+		case "", walletTypeFile:
+			walletInstance, walletErr = NewFileWallet(walletPath)
+		case walletTypePKCS11:
+			walletInstance, walletErr = NewPKCS11Wallet(walletPath, os.Getenv("PKCS11_LIB"), os.Getenv("PKCS11_LABEL"), os.Getenv("PKCS11_PIN"))
+		default:
+			walletErr = fmt.Errorf("unknown wallet type:%s", os.Getenv("FABRIC_WALLET"))
+		}
+	})
+	return walletInstance, walletErr
+}
+
+// FileWallet 文件钱包，每个成员一个目录，保存cert.pem与key.pem
+type FileWallet struct {
+	dir string
+}
+
+func NewFileWallet(dir string) (*FileWallet, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create wallet dir:%s", err.Error())
+	}
+	return &FileWallet{dir: dir}, nil
+}
+
+// NewCertificateRequest 生成软件密钥，私钥PEM随证书保存到钱包
+func (w *FileWallet) NewCertificateRequest(label string) ([]byte, []byte, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key:%s", err.Error())
+	}
+	csrPEM, err := certificateRequest(label, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := identity.PrivateKeyToPEM(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return csrPEM, keyPEM, nil
+}
+
+func (w *FileWallet) Put(label string, certPEM, keyPEM []byte) error {
+	if label == "" {
+		return fmt.Errorf("label = ''")
+	}
+	dir := filepath.Join(w.dir, label)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create identity dir:%s", err.Error())
+	}
+	if err := os.WriteFile(filepath.Join(dir, certFileName), certPEM, 0600); err != nil {
+		return fmt.Errorf("failed to write certificate:%s", err.Error())
+	}
+	if err := os.WriteFile(filepath.Join(dir, keyFileName), keyPEM, 0600); err != nil {
+		return fmt.Errorf("failed to write private key:%s", err.Error())
+	}
+	return nil
+}
+
+func (w *FileWallet) Get(label string) (*identity.X509Identity, identity.Sign, error) {
+	id, err := readWalletCertificate(w.dir, label)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(w.dir, label, keyFileName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read private key of %s:%s", label, err.Error())
+	}
+	privateKey, err := identity.PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, sign, nil
+}
+
+func (w *FileWallet) Exists(label string) bool {
+	_, err := os.Stat(filepath.Join(w.dir, label, certFileName))
+	return err == nil
+}
+
+func (w *FileWallet) Remove(label string) error {
+	if label == "" {
+		return fmt.Errorf("label = ''")
+	}
+	return os.RemoveAll(filepath.Join(w.dir, label))
+}
+
+// readWalletCertificate 读取钱包中的成员证书
+func readWalletCertificate(dir, label string) (*identity.X509Identity, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, label, certFileName))
+	if err != nil {
+		return nil, fmt.Errorf("identity %s not found in wallet:%s", label, err.Error())
+	}
+	certificate, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	return identity.NewX509Identity(mspID, certificate)
+}
+
+// certificateRequest 以成员ID为CN生成证书请求
+func certificateRequest(commonName string, signer crypto.Signer) ([]byte, error) {
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create csr:%s", err.Error())
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}), nil
+}
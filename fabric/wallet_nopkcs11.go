@@ -0,0 +1,10 @@
+//go:build !pkcs11
+
+package fabric
+
+import "fmt"
+
+// NewPKCS11Wallet 未启用pkcs11构建标签时不支持HSM钱包
+func NewPKCS11Wallet(dir, library, label, pin string) (Wallet, error) {
+	return nil, fmt.Errorf("pkcs11 wallet is not supported, rebuild with -tags pkcs11")
+}
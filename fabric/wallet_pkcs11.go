@@ -0,0 +1,257 @@
+//go:build pkcs11
+
+package fabric
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/miekg/pkcs11"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PKCS11Wallet HSM钱包，证书保存在本地目录，私钥在HSM中生成且不可导出
+// 私钥的CKA_ID为公钥的SKI（与Fabric BCCSP的约定一致）
+type PKCS11Wallet struct {
+	dir     string
+	label   string
+	pin     string
+	factory *identity.HSMSignerFactory
+	ctx     *pkcs11.Ctx
+	mu      sync.Mutex
+	signs   map[string]identity.Sign
+}
+
+// oidP256 P-256曲线的OID，作为CKA_EC_PARAMS
+var oidP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+
+func NewPKCS11Wallet(dir, library, label, pin string) (Wallet, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create wallet dir:%s", err.Error())
+	}
+	factory, err := identity.NewHSMSignerFactory(library)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hsm signer factory:%s", err.Error())
+	}
+	//签名由factory完成，生成密钥另开上下文，库已由factory初始化
+	ctx := pkcs11.New(library)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load pkcs11 library %s", library)
+	}
+	if err := ctx.Initialize(); err != nil && err != pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		return nil, fmt.Errorf("failed to initialize pkcs11 library:%s", err.Error())
+	}
+	return &PKCS11Wallet{
+		dir:     dir,
+		label:   label,
+		pin:     pin,
+		factory: factory,
+		ctx:     ctx,
+		signs:   make(map[string]identity.Sign),
+	}, nil
+}
+
+// NewCertificateRequest 在HSM中生成密钥对并以HSM签名证书请求，私钥不出HSM，返回的私钥PEM为空
+func (w *PKCS11Wallet) NewCertificateRequest(label string) ([]byte, []byte, error) {
+	public, keyID, err := w.generateKey(label)
+	if err != nil {
+		return nil, nil, err
+	}
+	sign, closeSign, err := w.factory.NewHSMSigner(identity.HSMSignerOptions{
+		Label:      w.label,
+		Pin:        w.pin,
+		Identifier: string(keyID),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create hsm signer for %s:%s", label, err.Error())
+	}
+	defer closeSign()
+	csrPEM, err := certificateRequest(label, hsmKey{public: public, sign: sign})
+	if err != nil {
+		return nil, nil, err
+	}
+	return csrPEM, nil, nil
+}
+
+// generateKey 在HSM中生成P-256密钥对，生成后将CKA_ID设置为公钥的SKI，返回公钥与SKI
+func (w *PKCS11Wallet) generateKey(label string) (*ecdsa.PublicKey, []byte, error) {
+	slot, err := w.findSlot()
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := w.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open session:%s", err.Error())
+	}
+	defer w.ctx.CloseSession(session)
+	if err := w.ctx.Login(session, pkcs11.CKU_USER, w.pin); err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+		return nil, nil, fmt.Errorf("failed to login:%s", err.Error())
+	}
+	params, err := asn1.Marshal(oidP256)
+	if err != nil {
+		return nil, nil, err
+	}
+	//SKI在公钥生成后才能计算，生成时先使用随机ID
+	tempID := make([]byte, 16)
+	if _, err := rand.Read(tempID); err != nil {
+		return nil, nil, err
+	}
+	publicHandle, privateHandle, err := w.ctx.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, tempID),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		},
+		[]*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_ID, tempID),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key pair:%s", err.Error())
+	}
+	attrs, err := w.ctx.GetAttributeValue(session, publicHandle, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil)})
+	if err != nil || len(attrs) == 0 {
+		return nil, nil, fmt.Errorf("failed to read public key:%v", err)
+	}
+	//CKA_EC_POINT按规范为DER编码的OCTET STRING，部分HSM直接返回公钥点
+	point := attrs[0].Value
+	var octets []byte
+	if rest, err := asn1.Unmarshal(point, &octets); err == nil && len(rest) == 0 {
+		point = octets
+	}
+	ecdhKey, err := ecdh.P256().NewPublicKey(point)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid public key:%s", err.Error())
+	}
+	keyID := ski(ecdhKey)
+	for _, handle := range []pkcs11.ObjectHandle{publicHandle, privateHandle} {
+		if err := w.ctx.SetAttributeValue(session, handle, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_ID, keyID)}); err != nil {
+			return nil, nil, fmt.Errorf("failed to set key id:%s", err.Error())
+		}
+	}
+	public := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(point[1:33]),
+		Y:     new(big.Int).SetBytes(point[33:]),
+	}
+	return public, keyID, nil
+}
+
+func (w *PKCS11Wallet) findSlot() (uint, error) {
+	slots, err := w.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get slot list:%s", err.Error())
+	}
+	for _, slot := range slots {
+		if info, err := w.ctx.GetTokenInfo(slot); err == nil && info.Label == w.label {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("token %s not found", w.label)
+}
+
+// hsmKey 以HSM签名函数实现crypto.Signer，用于签名证书请求
+type hsmKey struct {
+	public *ecdsa.PublicKey
+	sign   identity.Sign
+}
+
+func (k hsmKey) Public() crypto.PublicKey {
+	return k.public
+}
+
+func (k hsmKey) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return k.sign(digest)
+}
+
+// Put 只保存证书，私钥由NewCertificateRequest在HSM中生成
+func (w *PKCS11Wallet) Put(label string, certPEM, keyPEM []byte) error {
+	if len(keyPEM) != 0 {
+		return fmt.Errorf("pkcs11 wallet does not accept software private keys")
+	}
+	dir := filepath.Join(w.dir, label)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create identity dir:%s", err.Error())
+	}
+	//重新登记后证书对应新的私钥，丢弃缓存的签名函数
+	w.mu.Lock()
+	delete(w.signs, label)
+	w.mu.Unlock()
+	return os.WriteFile(filepath.Join(dir, certFileName), certPEM, 0600)
+}
+
+func (w *PKCS11Wallet) Get(label string) (*identity.X509Identity, identity.Sign, error) {
+	id, err := readWalletCertificate(w.dir, label)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sign, ok := w.signs[label]; ok {
+		return id, sign, nil
+	}
+	certificate, err := identity.CertificateFromPEM(id.Credentials())
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, ok := certificate.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported public key type of %s", label)
+	}
+	ecdhKey, err := publicKey.ECDH()
+	if err != nil {
+		return nil, nil, err
+	}
+	keyID := ski(ecdhKey)
+	sign, _, err := w.factory.NewHSMSigner(identity.HSMSignerOptions{
+		Label:      w.label,
+		Pin:        w.pin,
+		Identifier: string(keyID),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create hsm signer for %s:%s", label, err.Error())
+	}
+	w.signs[label] = sign
+	return id, sign, nil
+}
+
+func (w *PKCS11Wallet) Exists(label string) bool {
+	_, err := os.Stat(filepath.Join(w.dir, label, certFileName))
+	return err == nil
+}
+
+// Remove 移除成员证书，HSM中的私钥保留，证书吊销后该私钥不能再用于签名
+func (w *PKCS11Wallet) Remove(label string) error {
+	w.mu.Lock()
+	delete(w.signs, label)
+	w.mu.Unlock()
+	return os.RemoveAll(filepath.Join(w.dir, label))
+}
+
+// ski 计算公钥的SKI：未压缩公钥点的SHA256
+func ski(publicKey *ecdh.PublicKey) []byte {
+	hash := sha256.Sum256(publicKey.Bytes())
+	return hash[:]
+}
@@ -0,0 +1,142 @@
+package fabric
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WorkflowDecision 链上的审批决定
+type WorkflowDecision struct {
+	DecisionID  string `json:"decision_id"`  //决定ID
+	InstanceID  string `json:"instance_id"`  //审批流程实例ID
+	Kind        string `json:"kind"`         //审批对象类型
+	TargetID    string `json:"target_id"`    //审批对象ID
+	TaskID      string `json:"task_id"`      //审批步骤ID
+	Stage       int    `json:"stage"`        //审批阶段
+	Role        string `json:"role"`         //审批人以该角色审批
+	Decision    string `json:"decision"`     //approve或reject
+	CommentHash string `json:"comment_hash"` //审批意见的SHA-256，没有意见时为空
+	Approver    string `json:"approver"`     //审批人ID
+	DecideDate  string `json:"decide_date"`  //审批时间
+	TxID        string `json:"tx_id"`
+}
+
+// WorkflowStep 链上的审批步骤定义
+type WorkflowStep struct {
+	Stage         int    `json:"stage"`          //阶段，从1开始
+	Role          string `json:"role"`           //审批角色
+	Required      int    `json:"required"`       //需要的批准人数
+	DeadlineHours int    `json:"deadline_hours"` //审批期限，0表示不限
+	EscalateRole  string `json:"escalate_role"`  //超期后可审批的角色
+}
+
+// WorkflowTask 链上审批流程实例的步骤
+type WorkflowTask struct {
+	TaskID    string       `json:"task_id"`   //步骤ID
+	Step      WorkflowStep `json:"step"`      //步骤定义
+	Approvals int          `json:"approvals"` //已批准人数
+	Status    string       `json:"status"`    //步骤状态
+}
+
+// WorkflowInstance 链上的审批流程实例
+type WorkflowInstance struct {
+	InstanceID string         `json:"instance_id"` //实例ID
+	Kind       string         `json:"kind"`        //审批对象类型
+	TargetID   string         `json:"target_id"`   //审批对象ID
+	Requester  string         `json:"requester"`   //发起人ID
+	Stage      int            `json:"stage"`       //当前阶段
+	StageStart string         `json:"stage_start"` //当前阶段开始时间
+	Status     string         `json:"status"`      //实例状态
+	Tasks      []WorkflowTask `json:"tasks"`       //审批步骤
+}
+
+// SetWorkflowDefinition 以管理员身份设置链上的审批流程定义，steps为空时删除
+func SetWorkflowDefinition(kind string, steps []WorkflowStep, operator string) error {
+	if steps == nil {
+		steps = []WorkflowStep{}
+	}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	_, err = submit(workflowChaincode, "SetDefinition", operator, kind, string(data))
+	return err
+}
+
+// StartWorkflowInstance 按链上的审批流程定义发起审批流程实例
+func StartWorkflowInstance(instanceID, kind, targetID, requester, operator string) (WorkflowInstance, error) {
+	result, err := submit(workflowChaincode, "StartInstance", operator, instanceID, kind, targetID, requester)
+	if err != nil {
+		return WorkflowInstance{}, err
+	}
+	return unmarshalWorkflowInstance(result)
+}
+
+// RestoreWorkflowInstance 以管理员身份恢复链上审批流程上线前发起的实例，decided为各审批人已决定的阶段
+func RestoreWorkflowInstance(instance WorkflowInstance, decided map[string][]int, operator string) (WorkflowInstance, error) {
+	instanceData, err := json.Marshal(instance)
+	if err != nil {
+		return WorkflowInstance{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	decidedData, err := json.Marshal(decided)
+	if err != nil {
+		return WorkflowInstance{}, fmt.Errorf("failed to marshal:%s", err.Error())
+	}
+	result, err := submit(workflowChaincode, "RestoreInstance", operator, string(instanceData), string(decidedData))
+	if err != nil {
+		return WorkflowInstance{}, err
+	}
+	return unmarshalWorkflowInstance(result)
+}
+
+// GetWorkflowInstance 查询链上的审批流程实例，实例不存在时found为false
+func GetWorkflowInstance(instanceID string) (instance WorkflowInstance, found bool, err error) {
+	result, err := evaluate(workflowChaincode, "GetInstance", instanceID)
+	if err != nil {
+		if isNotExist(err) {
+			return WorkflowInstance{}, false, nil
+		}
+		return WorkflowInstance{}, false, err
+	}
+	instance, err = unmarshalWorkflowInstance(result)
+	return instance, err == nil, err
+}
+
+func unmarshalWorkflowInstance(result []byte) (WorkflowInstance, error) {
+	var instance WorkflowInstance
+	if err := json.Unmarshal(result, &instance); err != nil {
+		return WorkflowInstance{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return instance, nil
+}
+
+// RecordWorkflowDecision 以审批人身份将审批决定上链，返回链上记录
+// 审批阶段与角色由链码按链上的步骤定义确定
+func RecordWorkflowDecision(decision WorkflowDecision, operator string) (WorkflowDecision, error) {
+	result, err := submit(workflowChaincode, "RecordDecision", operator, decision.DecisionID, decision.InstanceID,
+		decision.TaskID, decision.Decision, decision.CommentHash)
+	if err != nil {
+		return WorkflowDecision{}, err
+	}
+	var recorded WorkflowDecision
+	if err := json.Unmarshal(result, &recorded); err != nil {
+		return WorkflowDecision{}, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return recorded, nil
+}
+
+// GetWorkflowDecisions 查询审批流程实例在链上的全部审批决定
+func GetWorkflowDecisions(instanceID string) ([]WorkflowDecision, error) {
+	result, err := evaluate(workflowChaincode, "GetDecisions", instanceID)
+	if err != nil {
+		return nil, err
+	}
+	var decisions []WorkflowDecision
+	if len(result) == 0 {
+		return decisions, nil
+	}
+	if err := json.Unmarshal(result, &decisions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal:%s", err.Error())
+	}
+	return decisions, nil
+}
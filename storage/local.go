@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalBackend 本地存储，按hash前两位分目录保存
+type LocalBackend struct {
+	dir string
+}
+
+func NewLocalBackend(dir string) (*LocalBackend, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir:%s", err.Error())
+	}
+	return &LocalBackend{dir: dir}, nil
+}
+
+func (b *LocalBackend) path(key string) string {
+	return filepath.Join(b.dir, key[:2], key)
+}
+
+func (b *LocalBackend) Put(key string, data []byte) error {
+	if !ValidHash(key) {
+		return ErrInvalidKey
+	}
+	path := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create storage dir:%s", err.Error())
+	}
+	//先写临时文件再改名，避免读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create file:%s", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file:%s", err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file:%s", err.Error())
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (b *LocalBackend) Get(key string) (io.ReadCloser, error) {
+	if !ValidHash(key) {
+		return nil, ErrInvalidKey
+	}
+	file, err := os.Open(b.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (b *LocalBackend) Exists(key string) (bool, error) {
+	if !ValidHash(key) {
+		return false, ErrInvalidKey
+	}
+	_, err := os.Stat(b.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
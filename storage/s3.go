@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config S3兼容存储的连接配置，Endpoint形如https://minio.example.com
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Backend S3兼容存储，使用路径风格的地址与SigV4签名，可对接MinIO等服务
+type S3Backend struct {
+	config S3Config
+	client *http.Client
+}
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func NewS3Backend(config S3Config) (*S3Backend, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("s3 endpoint, bucket and credentials are required")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	return &S3Backend{config: config, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (b *S3Backend) url(key string) string {
+	return fmt.Sprintf("%s/%s/%s", b.config.Endpoint, b.config.Bucket, key)
+}
+
+func (b *S3Backend) do(method, key string, body []byte, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequest(method, b.url(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	signV4(req, b.config, payloadHash, time.Now().UTC())
+	return b.client.Do(req)
+}
+
+func (b *S3Backend) Put(key string, data []byte) error {
+	if !ValidHash(key) {
+		return ErrInvalidKey
+	}
+	//key即为内容的SHA-256，可直接作为签名的payload hash
+	resp, err := b.do(http.MethodPut, key, data, key)
+	if err != nil {
+		return fmt.Errorf("failed to put object:%s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to put object:%s %s", resp.Status, msg)
+	}
+	return nil
+}
+
+func (b *S3Backend) Get(key string) (io.ReadCloser, error) {
+	if !ValidHash(key) {
+		return nil, ErrInvalidKey
+	}
+	resp, err := b.do(http.MethodGet, key, nil, emptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object:%s", err.Error())
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("failed to get object:%s", resp.Status)
+}
+
+func (b *S3Backend) Exists(key string) (bool, error) {
+	if !ValidHash(key) {
+		return false, ErrInvalidKey
+	}
+	resp, err := b.do(http.MethodHead, key, nil, emptyPayloadHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to head object:%s", err.Error())
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to head object:%s", resp.Status)
+}
+
+// signV4 按AWS Signature Version 4为请求签名，签入host及所有已设置的请求头
+func signV4(req *http.Request, config S3Config, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Backend 附件存储后端，以文件内容的SHA-256作为key
+type Backend interface {
+	// Put 保存文件内容
+	Put(key string, data []byte) error
+	// Get 读取文件内容，文件不存在时返回ErrNotFound
+	Get(key string) (io.ReadCloser, error)
+	// Exists 判断文件是否已经存在
+	Exists(key string) (bool, error)
+}
+
+const (
+	backendTypeLocal = "local"
+	backendTypeS3    = "s3"
+	defaultLocalDir  = "./attachments"
+)
+
+var (
+	ErrNotFound   = errors.New("attachment not found")
+	ErrInvalidKey = errors.New("invalid attachment hash")
+	hashPattern   = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	backendOnce     sync.Once
+	backendInstance Backend
+	backendErr      error
+)
+
+// getBackend 根据环境变量STORAGE_BACKEND选择存储后端，默认为本地存储
+func getBackend() (Backend, error) {
+	backendOnce.Do(func() {
+		switch os.Getenv("STORAGE_BACKEND") {
+		case "", backendTypeLocal:
+			dir := os.Getenv("STORAGE_DIR")
+			if dir == "" {
+				dir = defaultLocalDir
+			}
+			backendInstance, backendErr = NewLocalBackend(dir)
+		case backendTypeS3:
+			backendInstance, backendErr = NewS3Backend(S3Config{
+				Endpoint:  os.Getenv("S3_ENDPOINT"),
+				Region:    os.Getenv("S3_REGION"),
+				Bucket:    os.Getenv("S3_BUCKET"),
+				AccessKey: os.Getenv("S3_ACCESS_KEY"),
+				SecretKey: os.Getenv("S3_SECRET_KEY"),
+			})
+		default:
+			backendErr = fmt.Errorf("unknown storage backend:%s", os.Getenv("STORAGE_BACKEND"))
+		}
+	})
+	return backendInstance, backendErr
+}
+
+// Hash 计算文件内容的SHA-256
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidHash 判断是否为合法的SHA-256十六进制字符串
+func ValidHash(hash string) bool {
+	return hashPattern.MatchString(hash)
+}
+
+// Save 保存文件并返回其SHA-256，内容相同的文件只保存一份
+func Save(data []byte) (string, error) {
+	backend, err := getBackend()
+	if err != nil {
+		return "", err
+	}
+	hash := Hash(data)
+	exist, err := backend.Exists(hash)
+	if err != nil {
+		return "", err
+	}
+	if exist {
+		return hash, nil
+	}
+	if err := backend.Put(hash, data); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Open 按SHA-256读取文件
+func Open(hash string) (io.ReadCloser, error) {
+	if !ValidHash(hash) {
+		return nil, ErrInvalidKey
+	}
+	backend, err := getBackend()
+	if err != nil {
+		return nil, err
+	}
+	return backend.Get(hash)
+}